@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultAlbumFile is the folder-level metadata file that satisfies the
+// album check.
+const defaultAlbumFile = "album.yaml"
+
+// albumConfig configures the album check. When Required is set every folder
+// with relevant files must have at least one file with one of Keywords, or an
+// album metadata File.
+type albumConfig struct {
+	Required bool     `yaml:"required"`
+	Keywords []string `yaml:"keywords"`
+	File     string   `yaml:"file"`
+}
+
+// albumCheck is a rowWriter recording, per folder, whether any file had an
+// album keyword.
+type albumCheck struct {
+	config  albumConfig
+	folders map[string]bool
+}
+
+// newAlbumCheck returns an albumCheck for ac.
+func newAlbumCheck(ac albumConfig) *albumCheck {
+	if ac.File == "" {
+		ac.File = defaultAlbumFile
+	}
+	return &albumCheck{config: ac, folders: map[string]bool{}}
+}
+
+// Write notes the row's folder and whether it has an album keyword.
+func (ac *albumCheck) Write(row []string) error {
+	folder := filepath.Dir(row[column("Path")])
+	ac.folders[folder] = ac.folders[folder] || ac.hasKeyword(row[column("Keywords")])
+	return nil
+}
+
+// hasKeyword returns if the comma or semicolon separated keywords include an
+// album keyword, ignoring case.
+func (ac *albumCheck) hasKeyword(keywords string) bool {
+	for _, kw := range splitKeywords(keywords) {
+		for _, want := range ac.config.Keywords {
+			if strings.EqualFold(kw, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// missing returns the folders with neither an album keyword nor an album
+// file, sorted.
+func (ac *albumCheck) missing() []string {
+	var folders []string
+	for folder, ok := range ac.folders {
+		if ok {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(folder, ac.config.File)); err == nil {
+			continue
+		}
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+	return folders
+}
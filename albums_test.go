@@ -0,0 +1,31 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAlbumCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"tagged", "withfile", "bare"} {
+		equals(t, os.Mkdir(filepath.Join(dir, d), 0755), nil)
+	}
+	equals(t, ioutil.WriteFile(filepath.Join(dir, "withfile", "album.yaml"), []byte("title: STS-1\n"), 0644), nil)
+
+	row := func(p, kw string) []string {
+		r := make([]string, len(csvHeader))
+		r[column("Path")], r[column("Keywords")] = filepath.Join(dir, p), kw
+		return r
+	}
+	ac := newAlbumCheck(albumConfig{Required: true, Keywords: []string{"STS-1 Album"}})
+	ac.Write(row("tagged/a.jpg", "shuttle, launch"))
+	ac.Write(row("tagged/b.jpg", "shuttle, sts-1 album"))
+	ac.Write(row("tagged/c.jpg", ""))
+	ac.Write(row("withfile/a.jpg", "shuttle"))
+	ac.Write(row("bare/a.jpg", "shuttle, album"))
+	equals(t, ac.missing(), []string{filepath.Join(dir, "bare")})
+}
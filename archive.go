@@ -0,0 +1,330 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// archiveSep separates an archive's path from the name of a file in it, as
+// in bundle.zip!/photos/img001.jpg. Archives in archives have one for each,
+// like bundle.zip!/more.tar.gz!/img002.jpg.
+const archiveSep = "!/"
+
+// archiveExts are the extensions of the archives -archives descends into.
+var archiveExts = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// maxMemberSize is the most of a file in an archive that's copied out of it,
+// so a bad or hostile archive can't fill the disk.
+const maxMemberSize = 4 << 30
+
+// errMemberFound stops listArchive once readMember has the member.
+var errMemberFound = errors.New("member found")
+
+// isArchive is whether the file named name is an archive we can read.
+func isArchive(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// isArchiveMember is whether p is a file in an archive rather than on disk.
+func isArchiveMember(p string) bool {
+	return strings.Contains(p, archiveSep)
+}
+
+// listArchive calls fn with the name and content of each regular file in
+// the archive at p, in the order they're stored. The content can only be
+// read until fn returns.
+func listArchive(p string, fn func(name string, r io.Reader) error) error {
+	lower := strings.ToLower(p)
+	if strings.HasSuffix(lower, ".zip") {
+		zr, err := zip.OpenReader(p)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			name := memberName(f.Name)
+			if f.FileInfo().IsDir() || name == "" {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return fmt.Errorf("%s: %s", f.Name, err)
+			}
+			err = fn(name, rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := memberName(hdr.Name)
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA || name == "" {
+			continue
+		}
+		if err = fn(name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// memberName cleans the name of a file in an archive, which tar often
+// starts with ./, or returns "" if it can't be told apart from the path of
+// an archive it's in.
+func memberName(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if strings.Contains(name, archiveSep) {
+		return ""
+	}
+	return name
+}
+
+// tempCopy copies r to a temporary file, with the extension of name so
+// listArchive knows what it is, and returns its path. It's an error for r
+// to have more than maxMemberSize bytes.
+func tempCopy(name string, r io.Reader) (string, error) {
+	ext := path.Ext(name)
+	if strings.HasSuffix(strings.ToLower(name), ".tar.gz") {
+		ext = ".tar.gz"
+	}
+	f, err := ioutil.TempFile("", "chkmd-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(f, io.LimitReader(r, maxMemberSize+1))
+	if err == nil && n > maxMemberSize {
+		err = fmt.Errorf("%s is over %d bytes", name, int64(maxMemberSize))
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// withTempCopy copies r to a temporary file with tempCopy and calls fn
+// with its path before removing it.
+func withTempCopy(name string, r io.Reader, fn func(string) error) error {
+	tmp, err := tempCopy(name, r)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	return fn(tmp)
+}
+
+// unpacked is the temporary copies walkArchive makes of the files it sends,
+// so the workers don't have to find each one in its archive again. A nil
+// one makes no copies.
+type unpacked struct {
+	sync.Mutex
+	files map[string]string
+}
+
+// newUnpacked returns an empty unpacked.
+func newUnpacked() *unpacked {
+	return &unpacked{files: map[string]string{}}
+}
+
+// add copies content to a temporary file for the file in an archive p.
+func (u *unpacked) add(p string, content io.Reader) error {
+	if u == nil {
+		return nil
+	}
+	tmp, err := tempCopy(path.Base(p), content)
+	if err != nil {
+		return err
+	}
+	u.Lock()
+	defer u.Unlock()
+	if old, ok := u.files[p]; ok {
+		os.Remove(old)
+	}
+	u.files[p] = tmp
+	return nil
+}
+
+// copyOf returns the temporary copy of p, if there is one.
+func (u *unpacked) copyOf(p string) (string, bool) {
+	if u == nil {
+		return "", false
+	}
+	u.Lock()
+	defer u.Unlock()
+	tmp, ok := u.files[p]
+	return tmp, ok
+}
+
+// release removes the temporary copy of p once it's been processed.
+func (u *unpacked) release(p string) {
+	if u == nil {
+		return
+	}
+	u.Lock()
+	defer u.Unlock()
+	if tmp, ok := u.files[p]; ok {
+		os.Remove(tmp)
+		delete(u.files, p)
+	}
+}
+
+// removeAll removes the copies that are left, as when a run is stopped.
+func (u *unpacked) removeAll() {
+	if u == nil {
+		return
+	}
+	u.Lock()
+	defer u.Unlock()
+	for p, tmp := range u.files {
+		os.Remove(tmp)
+		delete(u.files, p)
+	}
+}
+
+// walkArchive is makeWalker for the archive at file, shown as p. It sends
+// the files in it that are in the shard and have relevant extensions to the
+// files channel as p!/name, descending into the archives in it while depth
+// is more than 1. Each file sent is copied to r.unpacked first, so an
+// archive is only read once. Derivatives are only skipped on disk, where
+// their originals can be looked for.
+func (r *runner) walkArchive(ctx context.Context, file, p string, depth int, sh shard, files chan string, stats *statistics) error {
+	return listArchive(file, func(name string, content io.Reader) error {
+		member := p + archiveSep + name
+		if depth > 1 && isArchive(name) {
+			return withTempCopy(name, content, func(tmp string) error {
+				return r.walkArchive(ctx, tmp, member, depth-1, sh, files, stats)
+			})
+		}
+		if !sh.mine(member) {
+			return nil
+		}
+		atomic.AddInt32(&stats.Total, 1)
+		if !r.types[mime.TypeByExtension(path.Ext(name))] {
+			return nil
+		}
+		if err := r.unpacked.add(member, content); err != nil {
+			log.Printf("Error copying %s out of its archive: %s\n", member, err)
+			return nil
+		}
+		if err := sendFile(ctx, files, member); err != nil {
+			r.unpacked.release(member)
+			return err
+		}
+		atomic.AddInt32(&stats.Relevant, 1)
+		return nil
+	})
+}
+
+// walkArchiveFile walks the archive at p for makeWalker. One that can't be
+// read is logged and skipped, rather than stopping the walk.
+func (r *runner) walkArchiveFile(ctx context.Context, p string, depth int, sh shard, files chan string, stats *statistics) error {
+	err := r.walkArchive(ctx, p, p, depth, sh, files, stats)
+	if err != nil && err != ctx.Err() {
+		log.Printf("Error reading archive %s: %s\n", p, err)
+		return nil
+	}
+	return err
+}
+
+// readArchiveMember copies the file in an archive at p, like
+// bundle.zip!/photos/img001.jpg, to w.
+func readArchiveMember(p string, w io.Writer) error {
+	names := strings.Split(p, archiveSep)
+	return readMember(names[0], names[1:], w)
+}
+
+// readMember copies the file names[0] in the archive at file to w, or if
+// there are more names, the file they name in that archive.
+func readMember(file string, names []string, w io.Writer) error {
+	err := listArchive(file, func(name string, content io.Reader) error {
+		if name != names[0] {
+			return nil
+		}
+		var err error
+		if len(names) == 1 {
+			_, err = io.Copy(w, content)
+		} else {
+			err = withTempCopy(name, content, func(tmp string) error {
+				return readMember(tmp, names[1:], w)
+			})
+		}
+		if err != nil {
+			return err
+		}
+		return errMemberFound
+	})
+	switch err {
+	case errMemberFound:
+		return nil
+	case nil:
+		return fmt.Errorf("%s isn't in the archive", names[0])
+	}
+	return err
+}
+
+// archiveExtract wraps extract so files in archives are extracted from
+// their copy in u, or copied out to a temporary file to extract if there
+// isn't one. Other paths are passed through.
+func archiveExtract(u *unpacked, extract func(string) (exif, error)) func(string) (exif, error) {
+	return func(p string) (exif, error) {
+		if !isArchiveMember(p) {
+			return extract(p)
+		}
+		if tmp, ok := u.copyOf(p); ok {
+			e, err := extract(tmp)
+			if err == nil {
+				e.Data["FileName"] = path.Base(p)
+			}
+			return e, err
+		}
+		return extractDownload(path.Base(p), func(w io.Writer) error {
+			return readArchiveMember(p, w)
+		}, extract)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTarGz returns a .tar.gz of the named files.
+func writeTarGz(t *testing.T, files map[string]string, names ...string) []byte {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		equals(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name]))}), nil)
+		_, err := tw.Write([]byte(files[name]))
+		equals(t, err, nil)
+	}
+	equals(t, tw.Close(), nil)
+	equals(t, gz.Close(), nil)
+	return b.Bytes()
+}
+
+// writeTestArchives writes bundle.zip, with a .tar.gz in it, to dir.
+func writeTestArchives(t *testing.T, dir string) string {
+	inner := writeTarGz(t, map[string]string{"./deep.jpg": "deep", "notes.txt": "notes"}, "./deep.jpg", "notes.txt")
+	p := filepath.Join(dir, "bundle.zip")
+	f, err := os.Create(p)
+	equals(t, err, nil)
+	zw := zip.NewWriter(f)
+	for _, m := range []struct{ name, content string }{
+		{"photos/", ""},
+		{"photos/img001.jpg", "img001"},
+		{"readme.txt", "readme"},
+		{"more.tar.gz", string(inner)},
+	} {
+		w, err := zw.Create(m.name)
+		equals(t, err, nil)
+		w.Write([]byte(m.content))
+	}
+	equals(t, zw.Close(), nil)
+	equals(t, f.Close(), nil)
+	return p
+}
+
+func TestWalkArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	p := writeTestArchives(t, dir)
+
+	walk := func(depth int) ([]string, *statistics) {
+		r := newTestRunner(t, "test-config.yaml")
+		r.archives = depth
+		files := make(chan string, 10)
+		stats := &statistics{}
+		equals(t, filepath.Walk(dir, r.makeWalker(context.Background(), shard{}, files, stats)), nil)
+		close(files)
+		var got []string
+		for f := range files {
+			got = append(got, f)
+		}
+		return got, stats
+	}
+	got, stats := walk(0)
+	equals(t, got, []string(nil))
+	equals(t, stats.Total, int32(1))
+	got, stats = walk(1)
+	equals(t, got, []string{p + "!/photos/img001.jpg"})
+	equals(t, stats.Total, int32(3))
+	got, stats = walk(2)
+	equals(t, got, []string{p + "!/photos/img001.jpg", p + "!/more.tar.gz!/deep.jpg"})
+	equals(t, stats.Total, int32(4))
+
+	// An archive that can't be read is skipped.
+	ioutil.WriteFile(filepath.Join(dir, "broken.zip"), []byte("not a zip"), 0644)
+	got, _ = walk(1)
+	equals(t, got, []string{p + "!/photos/img001.jpg"})
+}
+
+func TestReadArchiveMember(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	p := writeTestArchives(t, dir)
+
+	var b bytes.Buffer
+	equals(t, readArchiveMember(p+"!/photos/img001.jpg", &b), nil)
+	equals(t, b.String(), "img001")
+	b.Reset()
+	equals(t, readArchiveMember(p+"!/more.tar.gz!/deep.jpg", &b), nil)
+	equals(t, b.String(), "deep")
+	equals(t, readArchiveMember(p+"!/photos/img002.jpg", &b).Error(), "photos/img002.jpg isn't in the archive")
+
+	extract := archiveExtract(nil, func(tmp string) (exif, error) {
+		e := newExif()
+		b, err := ioutil.ReadFile(tmp)
+		e.XMP["Title"] = string(b)
+		return e, err
+	})
+	e, err := extract(p + "!/more.tar.gz!/deep.jpg")
+	equals(t, err, nil)
+	equals(t, e.XMP["Title"], "deep")
+	equals(t, e.Data["FileName"], "deep.jpg")
+}
+
+func TestUnpacked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	p := writeTestArchives(t, dir)
+
+	r := newTestRunner(t, "test-config.yaml")
+	r.archives = 2
+	r.unpacked = newUnpacked()
+	files := make(chan string, 10)
+	equals(t, r.walkArchiveFile(context.Background(), p, r.archives, shard{}, files, &statistics{}), nil)
+	close(files)
+	equals(t, len(r.unpacked.files), 2)
+
+	// The files are extracted from their copies, not the archive.
+	equals(t, os.Remove(p), nil)
+	extract := archiveExtract(r.unpacked, func(tmp string) (exif, error) {
+		e := newExif()
+		b, err := ioutil.ReadFile(tmp)
+		e.XMP["Title"] = string(b)
+		return e, err
+	})
+	deep := p + "!/more.tar.gz!/deep.jpg"
+	e, err := extract(deep)
+	equals(t, err, nil)
+	equals(t, e.XMP["Title"], "deep")
+	equals(t, e.Data["FileName"], "deep.jpg")
+
+	tmp, ok := r.unpacked.copyOf(deep)
+	equals(t, ok, true)
+	r.unpacked.release(deep)
+	_, err = os.Stat(tmp)
+	equals(t, os.IsNotExist(err), true)
+	_, err = extract(deep)
+	equals(t, err != nil, true)
+
+	tmp, _ = r.unpacked.copyOf(p + "!/photos/img001.jpg")
+	r.unpacked.removeAll()
+	equals(t, len(r.unpacked.files), 0)
+	_, err = os.Stat(tmp)
+	equals(t, os.IsNotExist(err), true)
+}
+
+func TestIsArchive(t *testing.T) {
+	equals(t, isArchive("a.ZIP"), true)
+	equals(t, isArchive("a.tar.gz"), true)
+	equals(t, isArchive("a.tgz"), true)
+	equals(t, isArchive("a.gz"), false)
+	equals(t, isArchive("a.jpg"), false)
+	equals(t, memberName("./photos/../a.jpg"), "a.jpg")
+	equals(t, memberName("odd!/name.jpg"), "")
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// tuneInterval is how often -p auto measures the throughput.
+	tuneInterval = 5 * time.Second
+	// tuneTolerance is how much the throughput must change by for -p auto
+	// to count it as better or worse, as it varies by file.
+	tuneTolerance = 0.05
+	// tuneMaxPerCPU bounds the workers -p auto runs, at this many per CPU.
+	tuneMaxPerCPU = 8
+)
+
+// procCount is the -p flag, a number of workers or auto to tune it as the
+// run goes, starting with one per CPU.
+type procCount struct {
+	n    int
+	auto bool
+}
+
+func (pc *procCount) Set(s string) error {
+	if s == "auto" {
+		pc.n, pc.auto = runtime.NumCPU(), true
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return fmt.Errorf("not a number of processes or auto: %q", s)
+	}
+	pc.n, pc.auto = n, false
+	return nil
+}
+
+func (pc procCount) String() string {
+	if pc.auto {
+		return "auto"
+	}
+	return strconv.Itoa(pc.n)
+}
+
+// tuner decides how many workers there should be by hill climbing: it keeps
+// adding workers while the throughput rises, and takes them away while it
+// doesn't fall, as the more there are the more memory they use, and turns
+// around when it falls. exiftool waits on disks and networks more than it
+// uses a CPU, so it's usually many more than one per CPU.
+type tuner struct {
+	min, max, n int
+	// dir is +1 when adding workers and -1 when taking them away.
+	dir int
+	// rate was the throughput, in files a second, with the last n.
+	rate float64
+	// low and high are the fewest and most workers there have been.
+	low, high int
+}
+
+// newTuner returns a tuner starting with n workers.
+func newTuner(n int) *tuner {
+	max := tuneMaxPerCPU * runtime.NumCPU()
+	if n > max {
+		max = n
+	}
+	return &tuner{min: 1, max: max, n: n, dir: 1, low: n, high: n}
+}
+
+// next returns how many workers there should be now that there have been
+// t.n checking rate files a second, with queued files waiting for them.
+func (t *tuner) next(rate float64, queued int) int {
+	switch {
+	case queued == 0 && t.dir > 0:
+		// The workers are waiting on the walk, more won't help, and the
+		// rate isn't theirs to compare with.
+		t.rate = 0
+		return t.n
+	case t.rate > 0 && rate < t.rate*(1-tuneTolerance):
+		t.dir = -t.dir
+	case t.rate > 0 && rate < t.rate*(1+tuneTolerance) && t.dir > 0:
+		// No better for the last we added, so try with fewer.
+		t.dir = -1
+	}
+	t.rate = rate
+	step := t.n / 4
+	if step < 1 {
+		step = 1
+	}
+	t.n += t.dir * step
+	switch {
+	case t.n < t.min:
+		t.n, t.dir = t.min, 1
+	case t.n > t.max:
+		t.n, t.dir = t.max, -1
+	}
+	if t.n < t.low {
+		t.low = t.n
+	}
+	if t.n > t.high {
+		t.high = t.n
+	}
+	return t.n
+}
+
+// String describes the workers there were.
+func (t *tuner) String() string {
+	return fmt.Sprintf("%d, between %d and %d", t.n, t.low, t.high)
+}
+
+// next returns the next file to process, or false when there are no more or
+// the worker has been retired by -p auto.
+func (r *runner) next(files chan string) (string, bool) {
+	select {
+	case <-r.retire:
+		return "", false
+	case p, ok := <-files:
+		return p, ok
+	}
+}
+
+// autoTune runs processFiles workers for -p auto, starting with t.n and
+// changing how many every interval, until they're all done. It's in wg until
+// then.
+func (r *runner) autoTune(ctx context.Context, t *tuner, files chan string, results chan []string, stats *statistics, wg *sync.WaitGroup, every time.Duration) {
+	defer wg.Done()
+	r.retire = make(chan struct{}, t.max)
+	exited := make(chan bool)
+	var workers sync.WaitGroup
+	start := func() {
+		workers.Add(1)
+		go func() {
+			r.processFiles(ctx, files, results, stats, &workers)
+			exited <- true
+		}()
+	}
+	live := 0
+	for ; live < t.n; live++ {
+		start()
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	done := atomic.LoadInt32(&stats.Accept) + atomic.LoadInt32(&stats.Reject)
+	last := time.Now()
+	for live > 0 {
+		select {
+		case <-exited:
+			live--
+		case now := <-ticker.C:
+			checked := atomic.LoadInt32(&stats.Accept) + atomic.LoadInt32(&stats.Reject)
+			rate := float64(checked-done) / now.Sub(last).Seconds()
+			done, last = checked, now
+			was := t.n
+			n := t.next(rate, len(files))
+			if r.verbose && n != was {
+				log.Printf("Workers: %d at %.1f files/s, now %d\n", was, rate, n)
+			}
+			for ; n > was; was++ {
+				// Take back a retirement not yet taken up before starting
+				// another worker.
+				select {
+				case <-r.retire:
+				default:
+					live++
+					start()
+				}
+			}
+			for ; n < was; was-- {
+				r.retire <- struct{}{}
+			}
+		}
+	}
+	workers.Wait()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProcCount(t *testing.T) {
+	var pc procCount
+	equals(t, pc.Set("12"), nil)
+	equals(t, pc, procCount{n: 12})
+	equals(t, pc.Set("auto"), nil)
+	equals(t, pc, procCount{n: runtime.NumCPU(), auto: true})
+	equals(t, pc.String(), "auto")
+	equals(t, pc.Set("0") != nil, true)
+	equals(t, pc.Set("lots") != nil, true)
+}
+
+func TestTuner(t *testing.T) {
+	tu := &tuner{min: 1, max: 20, n: 4, dir: 1, low: 4, high: 4}
+	var got []int
+	// Faster with more workers up to 7, no faster with 7, so fewer, which is
+	// slower, so more again.
+	for _, rate := range []float64{10, 20, 30, 31, 25, 31} {
+		got = append(got, tu.next(rate, 10))
+	}
+	equals(t, got, []int{5, 6, 7, 6, 7, 8})
+
+	// Waiting on the walk, so no more.
+	tu = &tuner{min: 1, max: 20, n: 4, dir: 1, low: 4, high: 4}
+	equals(t, tu.next(10, 0), 4)
+	equals(t, tu.next(10, 5), 5)
+
+	// Never more than max, or fewer than min.
+	tu = &tuner{min: 1, max: 4, n: 4, dir: 1, low: 4, high: 4}
+	equals(t, tu.next(10, 5), 4)
+	equals(t, tu.dir, -1)
+	tu = &tuner{min: 1, max: 4, n: 1, dir: -1, low: 1, high: 1}
+	equals(t, tu.next(10, 5), 1)
+	equals(t, tu.dir, 1)
+	equals(t, newTuner(4).String(), "4, between 4 and 4")
+}
+
+func TestAutoTune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	r := newTestRunner(t, "test-config.yaml")
+	r.replay = dir
+
+	files, results := make(chan string, 64), make(chan []string, 64)
+	stats := &statistics{}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	tu := &tuner{min: 1, max: 6, n: 2, dir: 1, low: 2, high: 2}
+	go r.autoTune(context.Background(), tu, files, results, stats, &wg, time.Millisecond)
+	go func() {
+		for i := 0; i < 300; i++ {
+			files <- fmt.Sprintf("%s/%03d.jpg", dir, i)
+			if i%50 == 0 {
+				time.Sleep(5 * time.Millisecond)
+			}
+		}
+		close(files)
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	n := 0
+	for range results {
+		n++
+	}
+	equals(t, n, 300)
+	equals(t, stats.Reject, int32(300))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// azureVersion is the Blob service REST API version we speak.
+const azureVersion = "2020-04-08"
+
+// azureClient reads from Azure Blob Storage, az://container/prefix URIs in
+// the account AZURE_STORAGE_ACCOUNT, with the shared access signature in
+// AZURE_STORAGE_SAS_TOKEN, which needs read and list permissions.
+type azureClient struct {
+	client   *http.Client
+	endpoint string
+	sas      url.Values
+}
+
+// newAzureClient returns an azureClient configured from the environment.
+// Set AZURE_STORAGE_BLOB_ENDPOINT to use another endpoint, like Azurite's.
+func newAzureClient() (*azureClient, error) {
+	c := &azureClient{client: &http.Client{}, endpoint: strings.TrimRight(os.Getenv("AZURE_STORAGE_BLOB_ENDPOINT"), "/")}
+	if c.endpoint == "" {
+		account := os.Getenv("AZURE_STORAGE_ACCOUNT")
+		if account == "" {
+			return nil, fmt.Errorf("AZURE_STORAGE_ACCOUNT must be set to read from Azure Blob Storage")
+		}
+		c.endpoint = "https://" + account + ".blob.core.windows.net"
+	}
+	sas := os.Getenv("AZURE_STORAGE_SAS_TOKEN")
+	if sas == "" {
+		return nil, fmt.Errorf("AZURE_STORAGE_SAS_TOKEN must be set to read from Azure Blob Storage")
+	}
+	var err error
+	if c.sas, err = url.ParseQuery(strings.TrimPrefix(sas, "?")); err != nil {
+		return nil, fmt.Errorf("AZURE_STORAGE_SAS_TOKEN: %s", err)
+	}
+	return c, nil
+}
+
+// get makes a GET request for path with the query and the SAS, returning the
+// response if it was a 200.
+func (c *azureClient) get(path string, q url.Values) (*http.Response, error) {
+	for k, v := range c.sas {
+		q[k] = v
+	}
+	req, err := http.NewRequest("GET", c.endpoint+"/"+awsEscape(path, true)+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", azureVersion)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// Not the URL, which has the SAS in it.
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		return nil, fmt.Errorf("GET %s: %s", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s: %s", path, resp.Status, strings.TrimSpace(string(b)))
+	}
+	return resp, nil
+}
+
+// azureList is the part of a List Blobs response we use.
+type azureList struct {
+	Blobs []struct {
+		Name          string
+		ContentLength int64 `xml:"Properties>Content-Length"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string
+}
+
+// list calls fn with each blob under prefix in container.
+func (c *azureClient) list(container, prefix string, fn func(key string, size int64) error) error {
+	marker := ""
+	for {
+		q := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {prefix}}
+		if marker != "" {
+			q.Set("marker", marker)
+		}
+		resp, err := c.get(container, q)
+		if err != nil {
+			return err
+		}
+		var l azureList
+		err = xml.NewDecoder(resp.Body).Decode(&l)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		for _, b := range l.Blobs {
+			if err = fn(b.Name, b.ContentLength); err != nil {
+				return err
+			}
+		}
+		if l.NextMarker == "" {
+			return nil
+		}
+		marker = l.NextMarker
+	}
+}
+
+// download copies the blob to w.
+func (c *azureClient) download(container, key string, w io.Writer) error {
+	resp, err := c.get(container+"/"+key, url.Values{})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// metadataCache is the -cache directory of each local file's extracted
+// metadata, so a repeat run only runs exiftool on the files that changed. An
+// entry is used while the file's size and modification time, and those of
+// its sidecars, are as they were, and the settings are the same.
+//
+// Entries are JSON files named for a hash of the path, in a directory per
+// first two hex digits so none gets too big, written to a temporary file
+// and renamed so processFiles goroutines never see half of one.
+type metadataCache struct {
+	dir string
+	// settings is a hash of what, besides the file, the metadata depends
+	// on, like the exiftool options.
+	settings string
+}
+
+// cacheEntry is one file's metadata in the cache.
+type cacheEntry struct {
+	Path       string            `json:"path"`
+	Stamp      string            `json:"stamp"`
+	Companions map[string]string `json:"companions"`
+	Settings   string            `json:"settings"`
+	Exif       exif              `json:"exif"`
+}
+
+// newMetadataCache returns the cache in dir, making it, for metadata
+// extracted with settings.
+func newMetadataCache(dir string, settings ...string) (*metadataCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(strings.Join(settings, "\n")))
+	return &metadataCache{dir: dir, settings: hex.EncodeToString(sum[:])}, nil
+}
+
+// fileStamp returns the size and modification time of the file at p, or ""
+// if there isn't one.
+func fileStamp(p string) string {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d %s", fi.Size(), fi.ModTime().UTC().Format(time.RFC3339Nano))
+}
+
+// companionStamps returns the stamps of the sidecars whose metadata is
+// merged into p's, "" for those that aren't there.
+func companionStamps(p string) map[string]string {
+	stamps := map[string]string{}
+	for _, side := range sidecarPaths(p) {
+		stamps[side] = fileStamp(side)
+	}
+	side := modelSidecarPath(p)
+	stamps[side] = fileStamp(side)
+	return stamps
+}
+
+// entryPath returns where p's entry goes.
+func (mc *metadataCache) entryPath(p string) string {
+	sum := sha256.Sum256([]byte(p))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(mc.dir, name[:2], name+".json")
+}
+
+// get returns p's cached metadata if it's current for ce's stamps.
+func (mc *metadataCache) get(ce cacheEntry) (exif, bool) {
+	b, err := ioutil.ReadFile(mc.entryPath(ce.Path))
+	if err != nil {
+		return exif{}, false
+	}
+	var cached cacheEntry
+	if err = json.Unmarshal(b, &cached); err != nil {
+		return exif{}, false
+	}
+	if cached.Path != ce.Path || cached.Stamp != ce.Stamp || cached.Settings != mc.settings ||
+		len(cached.Companions) != len(ce.Companions) {
+		return exif{}, false
+	}
+	for side, stamp := range ce.Companions {
+		if cached.Companions[side] != stamp {
+			return exif{}, false
+		}
+	}
+	return cached.Exif, true
+}
+
+// put caches ce.
+func (mc *metadataCache) put(ce cacheEntry) error {
+	ce.Settings = mc.settings
+	b, err := json.Marshal(ce)
+	if err != nil {
+		return err
+	}
+	p := mc.entryPath(ce.Path)
+	if err = os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(p), ".entry")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), p)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// cacheExtract wraps extract so local files' metadata comes from mc when
+// it's current, counting those in cached, and is cached when it isn't. The
+// stamps are taken before extracting, so a file that changes meanwhile is
+// extracted again next time. Failures aren't cached, nor are objects in
+// stores and URLs, which aren't stamped.
+func cacheExtract(mc *metadataCache, cached *int32, extract func(string) (exif, error)) func(string) (exif, error) {
+	return func(p string) (exif, error) {
+		if isURL(p) || isObject(p) {
+			return extract(p)
+		}
+		ce := cacheEntry{Path: p, Stamp: fileStamp(p), Companions: companionStamps(p)}
+		if ce.Stamp == "" {
+			return extract(p)
+		}
+		if e, ok := mc.get(ce); ok {
+			atomic.AddInt32(cached, 1)
+			return e, nil
+		}
+		e, err := extract(p)
+		if err != nil {
+			return e, err
+		}
+		ce.Exif = e
+		if err = mc.put(ce); err != nil {
+			log.Printf("Error caching %s: %s\n", displayPath(p), err)
+		}
+		return e, nil
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheExtract(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	img := filepath.Join(dir, "a.jpg")
+	ioutil.WriteFile(img, []byte("jpeg"), 0644)
+
+	calls := 0
+	extract := func(p string) (exif, error) {
+		calls++
+		if p == filepath.Join(dir, "missing.jpg") {
+			return newExif(), errors.New("File not found")
+		}
+		e := newExif()
+		e.IPTC["ObjectName"] = "Launch"
+		return e, nil
+	}
+	mc, err := newMetadataCache(filepath.Join(dir, "cache"), "-G -s -a -all")
+	equals(t, err, nil)
+	var cached int32
+	cx := cacheExtract(mc, &cached, extract)
+
+	e, err := cx(img)
+	equals(t, err, nil)
+	equals(t, e.IPTC["ObjectName"], "Launch")
+	e, err = cx(img)
+	equals(t, err, nil)
+	equals(t, e.IPTC["ObjectName"], "Launch")
+	equals(t, calls, 1)
+	equals(t, cached, int32(1))
+
+	// Changing the file, or adding a sidecar, means extracting it again.
+	ioutil.WriteFile(img, []byte("jpeg, edited"), 0644)
+	cx(img)
+	equals(t, calls, 2)
+	ioutil.WriteFile(sidecarPath(img), []byte("xmp"), 0644)
+	cx(img)
+	cx(img)
+	equals(t, calls, 3)
+
+	// As do different settings.
+	other, err := newMetadataCache(filepath.Join(dir, "cache"), "-G -s -a -IPTC:ObjectName")
+	equals(t, err, nil)
+	cacheExtract(other, &cached, extract)(img)
+	equals(t, calls, 4)
+
+	// Failures, URLs and S3 objects aren't cached.
+	for _, p := range []string{filepath.Join(dir, "missing.jpg"), "https://example.com/a.jpg", "s3://bucket/a.jpg"} {
+		cx(p)
+		cx(p)
+	}
+	equals(t, calls, 10)
+	equals(t, cached, int32(2))
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// centerName maps Center values matching a regular expression to a name, so
+// the variants photographers use come out the same.
+type centerName struct {
+	Match  string `yaml:"match"`
+	Center string `yaml:"center"`
+	re     *regexp.Regexp
+}
+
+// compileCenters compiles the Match expressions.
+func compileCenters(names []centerName) error {
+	for i := range names {
+		re, err := regexp.Compile(names[i].Match)
+		if err != nil {
+			return fmt.Errorf("centers: %s", err)
+		}
+		names[i].re = re
+	}
+	return nil
+}
+
+// normalizeCenter returns the Center of the first name whose expression
+// matches c, or c if none do.
+func normalizeCenter(names []centerName, c string) string {
+	for _, n := range names {
+		if n.re != nil && n.re.MatchString(c) {
+			return n.Center
+		}
+	}
+	return c
+}
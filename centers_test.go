@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestCenter(t *testing.T) {
+	centers := []centerName{
+		{Match: `(?i)\bJPL\b|jet propulsion`, Center: "Jet Propulsion Laboratory"},
+		{Match: `(?i)\bKSC\b`, Center: "Kennedy Space Center"},
+	}
+	equals(t, compileCenters(centers), nil)
+
+	values := []struct {
+		credit, source, xmpCredit, folder, want string
+	}{
+		{"NASA/JPL-Caltech", "KSC", "", "", "Jet Propulsion Laboratory"},
+		{"", "NASA KSC", "JPL", "", "Kennedy Space Center"},
+		{"", "", "Goddard Space Flight Center", "JPL", "Goddard Space Flight Center"},
+		{"", "", "", "Jet Propulsion Lab", "Jet Propulsion Laboratory"},
+		{"", "", "", "", ""},
+	}
+	for _, v := range values {
+		e := newExif()
+		e.centers = centers
+		e.IPTC["Credit"] = v.credit
+		e.IPTC["Source"] = v.source
+		e.XMP["Credit"] = v.xmpCredit
+		e.Folder["Center"] = v.folder
+		equals(t, e.Center(), v.want)
+	}
+}
+
+func TestCompileCentersError(t *testing.T) {
+	err := compileCenters([]centerName{{Match: "(JPL", Center: "Jet Propulsion Laboratory"}})
+	equals(t, err.Error(), "centers: error parsing regexp: missing closing ): `(JPL`")
+}
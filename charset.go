@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// iptcCharsetArgs has exiftool pass IPTC text through as it's stored rather
+// than decoding it as Latin unless CodedCharacterSet says UTF-8, so that
+// decodeIPTC can tell which it really is.
+var iptcCharsetArgs = []string{"-charset", "iptc=UTF8"}
+
+// cp1252 are the characters Windows-1252 has in place of Latin-1's C1
+// controls, 0x80 to 0x9F. Those it leaves undefined stay as they are.
+var cp1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// decodeLatin returns s, Windows-1252, the Latin-1 superset IPTC written by
+// Windows tools is in, as UTF-8.
+func decodeLatin(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := rune(s[i])
+		if c >= 0x80 && c <= 0x9F {
+			c = cp1252[c-0x80]
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// decodeIPTC converts the IPTC text values to UTF-8. IPTC IIM is often
+// Latin-1 whatever its CodedCharacterSet (1:90) says, and often has none.
+// Values are taken to be UTF-8 if they're valid UTF-8, which Latin-1 with
+// accents almost never is, and CodedCharacterSet says so or there's none.
+// Otherwise they're Windows-1252.
+func decodeIPTC(iptc map[string]string) {
+	set := iptc["CodedCharacterSet"]
+	utf8Set := set == "" || strings.EqualFold(set, "UTF8") || set == "\x1b%G"
+	for k, v := range iptc {
+		if isASCII(v) || utf8Set && utf8.ValidString(v) {
+			continue
+		}
+		iptc[k] = decodeLatin(v)
+	}
+}
+
+// isASCII is whether s is all ASCII, and so the same in any character set.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestDecodeIPTC(t *testing.T) {
+	for _, v := range []struct {
+		set, value, want string
+	}{
+		{"", "Launch", "Launch"},
+		{"", "São Paulo", "São Paulo"},
+		{"", "S\xe3o Paulo", "São Paulo"},
+		{"", "\x93Blue Marble\x94", "“Blue Marble”"},
+		{"UTF8", "São Paulo", "São Paulo"},
+		{"UTF8", "S\xe3o Paulo", "São Paulo"},
+		{"\x1b.A", "S\xe3o Paulo", "São Paulo"},
+		{"\x1b.A", "S\xc3\xa3o", "SÃ£o"},
+	} {
+		iptc := map[string]string{"Caption-Abstract": v.value}
+		if v.set != "" {
+			iptc["CodedCharacterSet"] = v.set
+		}
+		decodeIPTC(iptc)
+		equals(t, iptc["Caption-Abstract"], v.want)
+	}
+}
+
+func TestParseExifOutputLatin(t *testing.T) {
+	e, err := parseExifOutput("[IPTC]          Keywords                        : Cabo Ca\xf1averal\n")
+	equals(t, err, nil)
+	equals(t, e.IPTC["Keywords"], "Cabo Cañaveral")
+
+	// exiftool's JSON has what isn't UTF-8 in base64.
+	e, err = parseExifOutput(`[{"SourceFile": "a.jpg", "IPTC:Keywords": "base64:Q2FibyBDYfFhdmVyYWw="}]`)
+	equals(t, err, nil)
+	equals(t, e.IPTC["Keywords"], "Cabo Cañaveral")
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// defaultClusterSize is the number of files sharing a Description before we
+// report them as a cluster.
+const defaultClusterSize = 20
+
+// cluster is a group of files with the same Description.
+type cluster struct {
+	Description string
+	Paths       []string
+}
+
+// descriptionClusters is a rowWriter that groups paths by Description, to
+// find boilerplate captions pasted across many unrelated files.
+type descriptionClusters struct {
+	paths map[string][]string
+}
+
+// newDescriptionClusters returns an empty descriptionClusters.
+func newDescriptionClusters() *descriptionClusters {
+	return &descriptionClusters{paths: map[string][]string{}}
+}
+
+// Write records the row's path against its Description.
+func (dc *descriptionClusters) Write(row []string) error {
+	d := strings.TrimSpace(row[column("Description")])
+	if d != "" {
+		dc.paths[d] = append(dc.paths[d], row[column("Path")])
+	}
+	return nil
+}
+
+// clusters returns the groups of at least min files, largest first.
+func (dc *descriptionClusters) clusters(min int) []cluster {
+	var cs []cluster
+	for d, paths := range dc.paths {
+		if len(paths) >= min {
+			cs = append(cs, cluster{d, paths})
+		}
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		if len(cs[i].Paths) != len(cs[j].Paths) {
+			return len(cs[i].Paths) > len(cs[j].Paths)
+		}
+		return cs[i].Description < cs[j].Description
+	})
+	return cs
+}
+
+// summarizeClusters describes the clusters in a line each.
+func summarizeClusters(cs []cluster) string {
+	var b strings.Builder
+	for _, c := range cs {
+		d := []rune(c.Description)
+		if len(d) > 60 {
+			d = append(d[:57], []rune("...")...)
+		}
+		fmt.Fprintf(&b, "%d files: %q\n", len(c.Paths), string(d))
+	}
+	return b.String()
+}
+
+// writeClusters writes a CSV of each clustered file and its Description.
+func writeClusters(p string, cs []cluster) error {
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	err = w.Write([]string{"Description", "Count", "Path"})
+	for _, c := range cs {
+		for _, path := range c.Paths {
+			if err == nil {
+				err = w.Write([]string{c.Description, fmt.Sprint(len(c.Paths)), path})
+			}
+		}
+	}
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDescriptionClusters(t *testing.T) {
+	dc := newDescriptionClusters()
+	row := func(p, d string) []string {
+		r := make([]string, len(csvHeader))
+		r[column("Path")], r[column("Description")] = p, d
+		return r
+	}
+	dc.Write(row("a.jpg", "Photo courtesy of NASA."))
+	dc.Write(row("b.jpg", " Photo courtesy of NASA. "))
+	dc.Write(row("c.jpg", "Photo courtesy of NASA."))
+	dc.Write(row("d.jpg", "The crew of STS-1."))
+	dc.Write(row("e.jpg", "The crew of STS-1."))
+	dc.Write(row("f.jpg", ""))
+	dc.Write(row("g.jpg", ""))
+
+	cs := dc.clusters(2)
+	equals(t, cs, []cluster{
+		{"Photo courtesy of NASA.", []string{"a.jpg", "b.jpg", "c.jpg"}},
+		{"The crew of STS-1.", []string{"d.jpg", "e.jpg"}},
+	})
+	equals(t, len(dc.clusters(3)), 1)
+	equals(t, summarizeClusters(cs), "3 files: \"Photo courtesy of NASA.\"\n2 files: \"The crew of STS-1.\"\n")
+
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "clusters.csv")
+	equals(t, writeClusters(p, cs[1:]), nil)
+	b, err := ioutil.ReadFile(p)
+	equals(t, err, nil)
+	equals(t, string(b), "Description,Count,Path\nThe crew of STS-1.,2,d.jpg\nThe crew of STS-1.,2,e.jpg\n")
+}
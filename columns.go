@@ -0,0 +1,65 @@
+package main
+
+import "fmt"
+
+// selectColumns is a rowWriter writing only the named columns of each row to
+// w, in their order, for the config's columns.
+type selectColumns struct {
+	w    rowWriter
+	keep []int
+}
+
+// newSelectColumns returns a selectColumns keeping the named columns, which
+// must be in csvHeader.
+func newSelectColumns(w rowWriter, names []string) selectColumns {
+	s := selectColumns{w: w}
+	for _, n := range names {
+		s.keep = append(s.keep, column(n))
+	}
+	return s
+}
+
+// Write writes the kept columns of the row.
+func (s selectColumns) Write(row []string) error {
+	kept := make([]string, len(s.keep))
+	for i, c := range s.keep {
+		kept[i] = row[c]
+	}
+	return s.w.Write(kept)
+}
+
+// renameColumns returns the header with the columns in headers renamed.
+func renameColumns(header []string, headers map[string]string) []string {
+	renamed := make([]string, len(header))
+	for i, h := range header {
+		renamed[i] = h
+		if to, ok := headers[h]; ok {
+			renamed[i] = to
+		}
+	}
+	return renamed
+}
+
+// validColumns checks the config's columns and column_headers only name
+// columns we output, each once.
+func validColumns(columns []string, headers map[string]string) error {
+	seen := map[string]bool{}
+	for _, c := range columns {
+		if column(c) < 0 {
+			return fmt.Errorf("columns: unknown column %q", c)
+		}
+		if seen[c] {
+			return fmt.Errorf("columns: %q is listed twice", c)
+		}
+		seen[c] = true
+	}
+	for c, h := range headers {
+		if column(c) < 0 {
+			return fmt.Errorf("column_headers: unknown column %q", c)
+		}
+		if h == "" {
+			return fmt.Errorf("column_headers: %q has no header", c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+func TestSelectColumns(t *testing.T) {
+	var b bytes.Buffer
+	out := csv.NewWriter(&b)
+	s := newSelectColumns(out, []string{"NASA ID", "Title", "Copyright", "Path"})
+	equals(t, s.Write(renameColumns(csvHeader, map[string]string{"NASA ID": "nasa_id"})), nil)
+	row := make([]string, len(csvHeader))
+	row[column("Path")], row[column("NASA ID")], row[column("Copyright")] = "a.jpg", "KSC-1", "NASA"
+	equals(t, s.Write(row), nil)
+	out.Flush()
+	equals(t, b.String(), "nasa_id,Title,Copyright,Path\nKSC-1,,NASA,a.jpg\n")
+}
+
+func TestValidColumns(t *testing.T) {
+	values := []struct {
+		columns []string
+		headers map[string]string
+		err     string
+	}{
+		{nil, nil, ""},
+		{[]string{"Path", "Status", "Usage Terms"}, map[string]string{"Path": "file"}, ""},
+		{[]string{"Path", "Caption"}, nil, `columns: unknown column "Caption"`},
+		{[]string{"Path", "Title", "Path"}, nil, `columns: "Path" is listed twice`},
+		{nil, map[string]string{"Caption": "caption"}, `column_headers: unknown column "Caption"`},
+		{nil, map[string]string{"Title": ""}, `column_headers: "Title" has no header`},
+	}
+	for _, v := range values {
+		err := validColumns(v.columns, v.headers)
+		if v.err == "" {
+			equals(t, err, nil)
+		} else {
+			equals(t, err.Error(), v.err)
+		}
+	}
+}
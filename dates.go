@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// configDate is how dates are written in the config.
+	configDate = "2006-01-02"
+	// defaultMinDate is when NASA was founded, before which a Date Created
+	// is implausible.
+	defaultMinDate = "1958-01-01"
+	// dateToday is the Max meaning no date after today.
+	dateToday = "today"
+)
+
+// defaultSuspectDates are the dates cameras are set to before the clock is,
+// the FAT and Unix epochs.
+var defaultSuspectDates = []string{"1980-01-01", "1970-01-01"}
+
+// dateConfig is the dates section of the config, the window a Date Created
+// must be in to count as one. Min is defaultMinDate unless it's set, and Max
+// is today unless it's set to a date. A Date Created at midnight on one of
+// the Suspect dates, defaultSuspectDates unless they're set, is a camera
+// default and doesn't count either.
+type dateConfig struct {
+	Min     string   `yaml:"min"`
+	Max     string   `yaml:"max"`
+	Suspect []string `yaml:"suspect"`
+}
+
+// window returns the earliest and latest plausible dates, the latest
+// inclusive of that whole day.
+func (dc dateConfig) window() (min, max time.Time, err error) {
+	m := dc.Min
+	if m == "" {
+		m = defaultMinDate
+	}
+	if min, err = time.Parse(configDate, m); err != nil {
+		return min, max, fmt.Errorf("dates: min %q isn't a date like %s", dc.Min, configDate)
+	}
+	if dc.Max == "" || dc.Max == dateToday {
+		y, mo, d := time.Now().Date()
+		max = time.Date(y, mo, d, 0, 0, 0, 0, time.UTC)
+	} else if max, err = time.Parse(configDate, dc.Max); err != nil {
+		return min, max, fmt.Errorf("dates: max %q isn't a date like %s or %s", dc.Max, configDate, dateToday)
+	}
+	return min, max.AddDate(0, 0, 1), nil
+}
+
+// suspect returns the Suspect dates.
+func (dc dateConfig) suspect() []string {
+	if dc.Suspect == nil {
+		return defaultSuspectDates
+	}
+	return dc.Suspect
+}
+
+// validate checks the dates parse.
+func (dc dateConfig) validate() error {
+	if _, _, err := dc.window(); err != nil {
+		return err
+	}
+	for _, s := range dc.suspect() {
+		if _, err := time.Parse(configDate, s); err != nil {
+			return fmt.Errorf("dates: suspect %q isn't a date like %s", s, configDate)
+		}
+	}
+	return nil
+}
+
+// problem returns why t isn't a plausible Date Created, or "" if it is. The
+// date is compared as written, whatever its time zone.
+func (dc dateConfig) problem(t time.Time) string {
+	day := t.Format(configDate)
+	min, max, err := dc.window()
+	if err != nil {
+		return ""
+	}
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	switch {
+	case wall.Before(min):
+		return fmt.Sprintf("Date Created %s before %s", day, min.Format(configDate))
+	case !wall.Before(max):
+		return fmt.Sprintf("Date Created %s after %s", day, max.AddDate(0, 0, -1).Format(configDate))
+	}
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		for _, s := range dc.suspect() {
+			if day == s {
+				return fmt.Sprintf("Date Created %s is a camera default", day)
+			}
+		}
+	}
+	return ""
+}
+
+// DateProblem returns why the Date Created isn't plausible, see
+// dateConfig, or "" if it is or there's none. Without the config's dates
+// any date that parses is plausible.
+func (e exif) DateProblem() string {
+	if e.dates == nil {
+		return ""
+	}
+	d := e.dateCreatedText()
+	if d == "" {
+		return ""
+	}
+	t, err := parseDate(d)
+	if err != nil {
+		return fmt.Sprintf("Date Created %s isn't a date", d)
+	}
+	return e.dates.problem(t)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateProblem(t *testing.T) {
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(exifDateOnly)
+	for _, tc := range []struct {
+		dates *dateConfig
+		date  string
+		want  string
+	}{
+		{&dateConfig{}, "1969:07:20 20:17:40", ""},
+		{&dateConfig{}, "", ""},
+		{&dateConfig{}, "0000:00:00 00:00:00", "Date Created 0000:00:00 00:00:00 isn't a date"},
+		{&dateConfig{}, "1957:10:04 19:28:34", "Date Created 1957-10-04 before 1958-01-01"},
+		{&dateConfig{}, tomorrow, "Date Created " + time.Now().AddDate(0, 0, 1).Format(configDate) +
+			" after " + time.Now().Format(configDate)},
+		{&dateConfig{}, "1980:01:01 00:00:00", "Date Created 1980-01-01 is a camera default"},
+		{&dateConfig{}, "1980:01:01 09:30:00", ""},
+		{&dateConfig{Suspect: []string{}}, "1980:01:01", ""},
+		{&dateConfig{Min: "1990-01-01", Max: "1999-12-31"}, "1999:12:31 23:59:59-05:00", ""},
+		{&dateConfig{Min: "1990-01-01", Max: "1999-12-31"}, "2000:01:01", "Date Created 2000-01-01 after 1999-12-31"},
+		{nil, "1957:10:04 19:28:34", ""},
+		{nil, "0000:00:00 00:00:00", ""},
+	} {
+		e := newExif()
+		e.IPTC["DateCreated"] = tc.date
+		e.dates = tc.dates
+		equals(t, e.DateProblem(), tc.want)
+		_, err := e.DateCreated()
+		equals(t, e.HasDateCreated(), err == nil && tc.want == "")
+	}
+}
+
+func TestDateConfigValidate(t *testing.T) {
+	equals(t, dateConfig{}.validate(), nil)
+	equals(t, dateConfig{Min: "1958-01-01", Max: "today", Suspect: []string{"2000-01-01"}}.validate(), nil)
+	equals(t, dateConfig{Min: "1958"}.validate().Error(), `dates: min "1958" isn't a date like 2006-01-02`)
+	equals(t, dateConfig{Max: "tomorrow"}.validate().Error(), `dates: max "tomorrow" isn't a date like 2006-01-02 or today`)
+	equals(t, dateConfig{Suspect: []string{"1980:01:01"}}.validate().Error(), `dates: suspect "1980:01:01" isn't a date like 2006-01-02`)
+}
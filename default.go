@@ -13,7 +13,17 @@ var defaultTypes = []string{
 	"image/jpeg",
 	"image/png",
 	"image/tiff",
+	// Camera RAW, see rawTypes.
+	"image/x-adobe-dng",
+	"image/x-canon-cr2",
+	"image/x-canon-cr3",
+	"image/x-nikon-nef",
+	"image/x-sony-arw",
 	// "image/webp",
+	"model/gltf+json",
+	"model/gltf-binary",
+	"model/obj",
+	"model/vnd.usdz+zip",
 	"video/mpeg",
 	"video/mp4",
 	"video/quicktime",
@@ -26,3 +36,6 @@ var defaultTypes = []string{
 	// "video/x-ms-wvx",
 	// "video/x-msvideo",
 }
+
+// defaultMediaTypes are the top level MIME types a Media Type may be.
+var defaultMediaTypes = []string{"audio", "image", "model", "video"}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// rollupFile is the -deliveries file summarizing every delivery.
+const rollupFile = "rollup.json"
+
+// deliverySummary is the summary of one delivery's report, and its entry in
+// the rollup.
+type deliverySummary struct {
+	Delivery   string                    `json:"delivery"`
+	Root       string                    `json:"root"`
+	Report     string                    `json:"report"`
+	Total      int                       `json:"total"`
+	Accepted   int                       `json:"accepted"`
+	Rejected   int                       `json:"rejected"`
+	Incomplete int                       `json:"incomplete"`
+	Reasons    map[string]int            `json:"reasons"`
+	MediaTypes map[string]map[string]int `json:"media_types"`
+	Extensions map[string]map[string]int `json:"extensions"`
+}
+
+// deliveryReport is one delivery's open report.
+type deliveryReport struct {
+	f         *os.File
+	csv       *csv.Writer
+	w         rowWriter
+	breakdown *statusBreakdown
+	summary   deliverySummary
+}
+
+// deliveryReports is a rowWriter for -deliveries, writing each delivery
+// folder's rows to a report of its own, shaped like the -o output by open,
+// with a summary of each and a rollup of them all, as deliveries are
+// reviewed and returned to vendors one by one.
+type deliveryReports struct {
+	dir   string
+	roots dirList
+	// open returns the rowWriter for a report writing to w, having written
+	// the header.
+	open    func(w *csv.Writer) (rowWriter, error)
+	reports map[string]*deliveryReport
+}
+
+// newDeliveryReports returns a deliveryReports writing to dir, making it,
+// for the trees at roots.
+func newDeliveryReports(dir string, roots dirList, open func(*csv.Writer) (rowWriter, error)) (*deliveryReports, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &deliveryReports{dir: dir, roots: roots, open: open, reports: map[string]*deliveryReport{}}, nil
+}
+
+// reportBase returns the name, without extension, of the report for the
+// delivery under root. Files directly in root are named for it, and with
+// several roots every report is.
+func (dr *deliveryReports) reportBase(root, name string) string {
+	switch {
+	case name == ".":
+		return filepath.Base(root)
+	case len(dr.roots) > 1:
+		return filepath.Base(root) + "_" + name
+	}
+	return name
+}
+
+// Write writes the row to its delivery's report, opening it if it's the
+// first.
+func (dr *deliveryReports) Write(row []string) error {
+	p := row[column("Path")]
+	root := dr.roots.rootOf(p)
+	name := deliveryOf(root, p)
+	base := dr.reportBase(root, name)
+	d := dr.reports[base]
+	if d == nil {
+		f, err := os.Create(filepath.Join(dr.dir, base+".csv"))
+		if err != nil {
+			return err
+		}
+		d = &deliveryReport{f: f, csv: csv.NewWriter(f), breakdown: newStatusBreakdown()}
+		if d.w, err = dr.open(d.csv); err != nil {
+			f.Close()
+			return err
+		}
+		d.summary = deliverySummary{Delivery: name, Root: root, Report: base + ".csv", Reasons: map[string]int{}}
+		dr.reports[base] = d
+	}
+	s := &d.summary
+	s.Total++
+	switch row[column("Status")] {
+	case "Accepted", statusWarned:
+		s.Accepted++
+	case "Incomplete":
+		s.Incomplete++
+		s.Reasons[row[column("Reason")]]++
+	default:
+		s.Rejected++
+		s.Reasons[row[column("Reason")]]++
+	}
+	d.breakdown.Write(row)
+	return d.w.Write(row)
+}
+
+// Close finishes each report, writes its summary next to it and writes the
+// rollup, returning the first error.
+func (dr *deliveryReports) Close() error {
+	var first error
+	var bases []string
+	for base := range dr.reports {
+		bases = append(bases, base)
+	}
+	sort.Strings(bases)
+	rollup := []deliverySummary{}
+	for _, base := range bases {
+		d := dr.reports[base]
+		d.csv.Flush()
+		err := d.csv.Error()
+		if cerr := d.f.Close(); err == nil {
+			err = cerr
+		}
+		d.summary.MediaTypes, d.summary.Extensions = d.breakdown.mediaTypes, d.breakdown.extensions
+		if err == nil {
+			err = writeJSON(filepath.Join(dr.dir, base+".summary.json"), d.summary)
+		}
+		if err != nil && first == nil {
+			first = fmt.Errorf("%s: %s", base, err)
+		}
+		rollup = append(rollup, d.summary)
+	}
+	if err := writeJSON(filepath.Join(dr.dir, rollupFile), rollup); err != nil && first == nil {
+		first = err
+	}
+	return first
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeliveryReports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	open := func(out *csv.Writer) (rowWriter, error) {
+		w := newSelectColumns(out, []string{"Path", "Status"})
+		return w, w.Write(csvHeader)
+	}
+	dr, err := newDeliveryReports(filepath.Join(dir, "reports"), dirList{"/media"}, open)
+	equals(t, err, nil)
+	row := func(p, status, reason, mediaType string) []string {
+		r := testRow(p, status, reason)
+		r[column("Media Type")] = mediaType
+		return r
+	}
+	equals(t, dr.Write(row("/media/acme/1.jpg", "Accepted", "", "image")), nil)
+	equals(t, dr.Write(row("/media/acme/2.jpg", "Incomplete", "Missing: Title", "image")), nil)
+	equals(t, dr.Write(row("/media/zenith/3.tif", "Rejected", "Not an image", "")), nil)
+	equals(t, dr.Write(row("/media/4.jpg", "Accepted", "", "image")), nil)
+	equals(t, dr.Close(), nil)
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "reports", "acme.csv"))
+	equals(t, err, nil)
+	equals(t, string(b), "Path,Status\n/media/acme/1.jpg,Accepted\n/media/acme/2.jpg,Incomplete\n")
+	b, err = ioutil.ReadFile(filepath.Join(dir, "reports", "media.csv"))
+	equals(t, err, nil)
+	equals(t, string(b), "Path,Status\n/media/4.jpg,Accepted\n")
+
+	var s deliverySummary
+	b, err = ioutil.ReadFile(filepath.Join(dir, "reports", "acme.summary.json"))
+	equals(t, err, nil)
+	equals(t, json.Unmarshal(b, &s), nil)
+	equals(t, s, deliverySummary{
+		Delivery: "acme", Root: "/media", Report: "acme.csv", Total: 2, Accepted: 1, Incomplete: 1,
+		Reasons:    map[string]int{"Missing: Title": 1},
+		MediaTypes: map[string]map[string]int{"image": {"Accepted": 1, "Incomplete": 1}},
+		Extensions: map[string]map[string]int{".jpg": {"Accepted": 1, "Incomplete": 1}},
+	})
+
+	var rollup []deliverySummary
+	b, err = ioutil.ReadFile(filepath.Join(dir, "reports", rollupFile))
+	equals(t, err, nil)
+	equals(t, json.Unmarshal(b, &rollup), nil)
+	equals(t, len(rollup), 3)
+	equals(t, rollup[0].Delivery, "acme")
+	equals(t, rollup[1].Delivery, ".")
+	equals(t, rollup[1].Report, "media.csv")
+	equals(t, rollup[2].Rejected, 1)
+	equals(t, rollup[2].Reasons, map[string]int{"Not an image": 1})
+}
+
+func TestReportBase(t *testing.T) {
+	dr := &deliveryReports{roots: dirList{"/mnt/a"}}
+	equals(t, dr.reportBase("/mnt/a", "acme"), "acme")
+	equals(t, dr.reportBase("/mnt/a", "."), "a")
+	dr.roots = dirList{"/mnt/a", "/mnt/b"}
+	equals(t, dr.reportBase("/mnt/b", "acme"), "b_acme")
+}
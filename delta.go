@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// volatileTags change without the embedded metadata changing, by moving or
+// touching the file or upgrading exiftool, so they're left out of the
+// metadata hash.
+var volatileTags = map[string]bool{
+	"Directory":           true,
+	"ExifToolVersion":     true,
+	"FileAccessDate":      true,
+	"FileInodeChangeDate": true,
+	"FileModifyDate":      true,
+	"FileName":            true,
+	"FilePermissions":     true,
+	"SourceFile":          true,
+}
+
+// embeddedMetadata returns the metadata extracted from the file by group,
+// without the volatile tags.
+func embeddedMetadata(e exif) map[string]map[string]string {
+	file := map[string]string{}
+	for tag, v := range e.Data {
+		if !volatileTags[tag] {
+			file[tag] = v
+		}
+	}
+	return map[string]map[string]string{"File": file, "EXIF": e.Exif, "IPTC": e.IPTC, "XMP": e.XMP, "ID3": e.ID3, "RIFF": e.RIFF, "PDF": e.PDF, "Model": e.Model}
+}
+
+// metadataHash returns a hash of the embedded metadata.
+func metadataHash(md map[string]map[string]string) string {
+	var lines []string
+	for group, tags := range md {
+		for tag, v := range tags {
+			lines = append(lines, group+":"+tag+"="+v)
+		}
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// dumpEntry is a file's line in a -dump: its size and modification time, if
+// it's local, and its embedded metadata with a hash of it.
+type dumpEntry struct {
+	Path     string                       `json:"path"`
+	Size     int64                        `json:"size,omitempty"`
+	Modified string                       `json:"modified,omitempty"`
+	Hash     string                       `json:"hash"`
+	Metadata map[string]map[string]string `json:"metadata"`
+}
+
+// readDump reads a -dump, a dumpEntry per line, keyed by path.
+func readDump(r io.Reader) (map[string]dumpEntry, error) {
+	dump := map[string]dumpEntry{}
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for n := 1; s.Scan(); n++ {
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+		var de dumpEntry
+		if err := json.Unmarshal(s.Bytes(), &de); err != nil {
+			return nil, fmt.Errorf("line %d: %s", n, err)
+		}
+		dump[de.Path] = de
+	}
+	return dump, s.Err()
+}
+
+// readDumpFile reads the -dump at p.
+func readDumpFile(p string) (map[string]dumpEntry, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readDump(f)
+}
+
+// metadataChange is a file whose metadata changed since the previous dump:
+// New, Changed, with the tags that did, or Removed.
+type metadataChange struct {
+	Path   string
+	Change string
+	Tags   []string
+}
+
+// audit compares each file checked with a previous -dump, skipping those
+// that haven't been touched, and writes a new dump. It's safe for use by
+// multiple processFiles goroutines, and a nil *audit does nothing.
+type audit struct {
+	sync.Mutex
+	previous map[string]dumpEntry
+	seen     map[string]bool
+	dump     *json.Encoder
+	err      error
+	found    []metadataChange
+	// hits and misses count the files that could be skipped, and why those
+	// that couldn't weren't, to show the previous dump works as a cache.
+	hits   int
+	misses map[string]int
+}
+
+// Why a file had to be extracted again with -since.
+const (
+	missNew        = "new"
+	missUnrecorded = "no size or time recorded"
+	missNotLocal   = "not local"
+	missChanged    = "size or time changed"
+)
+
+// newAudit returns an audit against previous, which may be nil, writing the
+// new dump to w, which may be nil too.
+func newAudit(previous map[string]dumpEntry, w io.Writer) *audit {
+	a := &audit{previous: previous, seen: map[string]bool{}, misses: map[string]int{}}
+	if w != nil {
+		a.dump = json.NewEncoder(w)
+	}
+	return a
+}
+
+// stat returns the size and modification time of the local file at p, or
+// false if it's not local.
+func stat(p string) (int64, string, bool) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return 0, "", false
+	}
+	return fi.Size(), fi.ModTime().UTC().Format(time.RFC3339Nano), true
+}
+
+// unchanged is whether the local file at p has the size and modification
+// time it had in the previous dump, so its metadata can't have changed and
+// it needn't be extracted again. Its previous entry goes in the new dump.
+func (a *audit) unchanged(p string) bool {
+	if a == nil || a.previous == nil {
+		return false
+	}
+	var miss string
+	prev, ok := a.previous[p]
+	switch {
+	case !ok:
+		miss = missNew
+	case prev.Modified == "":
+		miss = missUnrecorded
+	default:
+		size, modified, ok := stat(p)
+		if !ok {
+			miss = missNotLocal
+		} else if size != prev.Size || modified != prev.Modified {
+			miss = missChanged
+		}
+	}
+	a.Lock()
+	defer a.Unlock()
+	if miss != "" {
+		a.misses[miss]++
+		return false
+	}
+	a.hits++
+	a.seen[p] = true
+	a.write(prev)
+	return true
+}
+
+// record compares the metadata of the file shown as p, read from local, with
+// the previous dump and writes it to the new one.
+func (a *audit) record(p, local string, e exif) {
+	if a == nil {
+		return
+	}
+	de := dumpEntry{Path: p, Metadata: embeddedMetadata(e)}
+	de.Hash = metadataHash(de.Metadata)
+	de.Size, de.Modified, _ = stat(local)
+	a.Lock()
+	defer a.Unlock()
+	a.seen[p] = true
+	if a.previous != nil {
+		prev, ok := a.previous[p]
+		switch {
+		case !ok:
+			a.found = append(a.found, metadataChange{p, "New", nil})
+		case prev.Hash != de.Hash:
+			a.found = append(a.found, metadataChange{p, "Changed", changedTags(prev.Metadata, de.Metadata)})
+		}
+	}
+	a.write(de)
+}
+
+// write writes de to the new dump, keeping the first error for Err.
+func (a *audit) write(de dumpEntry) {
+	if a.dump == nil || a.err != nil {
+		return
+	}
+	a.err = a.dump.Encode(de)
+}
+
+// cacheSummary is how well the previous dump worked as a cache: how many
+// files were skipped as unchanged, how many were extracted again and why.
+type cacheSummary struct {
+	FromCache int            `json:"from_cache"`
+	Extracted int            `json:"extracted"`
+	HitRate   float64        `json:"hit_rate"`
+	Misses    map[string]int `json:"misses"`
+}
+
+func (cs cacheSummary) String() string {
+	var misses []string
+	for _, m := range []string{missNew, missChanged, missNotLocal, missUnrecorded} {
+		if n := cs.Misses[m]; n > 0 {
+			misses = append(misses, fmt.Sprintf("%d %s", n, m))
+		}
+	}
+	s := fmt.Sprintf("%.1f%%, %d from cache, %d extracted", 100*cs.HitRate, cs.FromCache, cs.Extracted)
+	if len(misses) > 0 {
+		s += ": " + strings.Join(misses, ", ")
+	}
+	return s
+}
+
+// cache returns the cacheSummary so far.
+func (a *audit) cache() cacheSummary {
+	a.Lock()
+	defer a.Unlock()
+	cs := cacheSummary{FromCache: a.hits, Misses: map[string]int{}}
+	for m, n := range a.misses {
+		cs.Misses[m] = n
+		cs.Extracted += n
+	}
+	if total := cs.FromCache + cs.Extracted; total > 0 {
+		cs.HitRate = float64(cs.FromCache) / float64(total)
+	}
+	return cs
+}
+
+// Err returns the first error writing the new dump.
+func (a *audit) Err() error {
+	a.Lock()
+	defer a.Unlock()
+	return a.err
+}
+
+// changedTags returns the group:tag of each tag added, removed or changed
+// between was and is.
+func changedTags(was, is map[string]map[string]string) []string {
+	var tags []string
+	for group, vs := range is {
+		for tag, v := range vs {
+			if old, ok := was[group][tag]; !ok || old != v {
+				tags = append(tags, group+":"+tag)
+			}
+		}
+	}
+	for group, vs := range was {
+		for tag := range vs {
+			if _, ok := is[group][tag]; !ok {
+				tags = append(tags, group+":"+tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// changes returns the files whose metadata changed, and those in the
+// previous dump that weren't checked, by path.
+func (a *audit) changes() []metadataChange {
+	a.Lock()
+	defer a.Unlock()
+	cs := append([]metadataChange{}, a.found...)
+	for p := range a.previous {
+		if !a.seen[p] {
+			cs = append(cs, metadataChange{p, "Removed", nil})
+		}
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].Path < cs[j].Path })
+	return cs
+}
+
+// writeChanges writes the changes as CSV to the file at p, or stderr if p
+// is "".
+func writeChanges(p string, cs []metadataChange) error {
+	out := os.Stderr
+	if p != "" {
+		f, err := os.Create(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := csv.NewWriter(out)
+	err := w.Write([]string{"Path", "Change", "Tags"})
+	for _, c := range cs {
+		if err == nil {
+			err = w.Write([]string{c.Path, c.Change, strings.Join(c.Tags, ", ")})
+		}
+	}
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetadataHash(t *testing.T) {
+	e := newExif()
+	e.IPTC["ObjectName"] = "A Title"
+	e.Data["FileName"] = "a.jpg"
+	e.Data["FileModifyDate"] = "2015:05:26 10:00:00"
+	want := metadataHash(embeddedMetadata(e))
+
+	// Moving or touching the file doesn't change it.
+	e.Data["FileName"] = "b.jpg"
+	e.Data["FileModifyDate"] = "2016:05:26 10:00:00"
+	equals(t, metadataHash(embeddedMetadata(e)), want)
+
+	e.IPTC["ObjectName"] = "Another Title"
+	equals(t, metadataHash(embeddedMetadata(e)) != want, true)
+}
+
+func TestChangedTags(t *testing.T) {
+	was := map[string]map[string]string{"IPTC": {"ObjectName": "A", "City": "Houston"}, "XMP": {}}
+	is := map[string]map[string]string{"IPTC": {"ObjectName": "B"}, "XMP": {"Title": "B"}}
+	equals(t, changedTags(was, is), []string{"IPTC:City", "IPTC:ObjectName", "XMP:Title"})
+}
+
+func TestAudit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a.jpg"), filepath.Join(dir, "b.jpg")
+	equals(t, ioutil.WriteFile(a, []byte("a"), 0644), nil)
+	equals(t, ioutil.WriteFile(b, []byte("b"), 0644), nil)
+	e := newExif()
+	e.IPTC["ObjectName"] = "A Title"
+
+	var dump bytes.Buffer
+	first := newAudit(nil, &dump)
+	equals(t, first.unchanged(a), false)
+	first.record(a, a, e)
+	first.record(b, b, e)
+	first.record("s3://media/c.jpg", "/tmp/chkmd-1.jpg", e)
+	equals(t, first.Err(), nil)
+	equals(t, len(first.changes()), 0)
+
+	previous, err := readDump(strings.NewReader(dump.String()))
+	equals(t, err, nil)
+	equals(t, previous[a].Size, int64(1))
+	equals(t, previous["s3://media/c.jpg"].Modified, "")
+
+	// b is touched but its metadata's the same, c changed and a's
+	// untouched.
+	later := time.Now().Add(time.Hour)
+	equals(t, os.Chtimes(b, later, later), nil)
+	dump.Reset()
+	second := newAudit(previous, &dump)
+	equals(t, second.unchanged(a), true)
+	equals(t, second.unchanged(b), false)
+	second.record(b, b, e)
+	equals(t, second.unchanged("s3://media/c.jpg"), false)
+	changed := newExif()
+	changed.IPTC["ObjectName"] = "New Title"
+	second.record("s3://media/c.jpg", "/tmp/chkmd-1.jpg", changed)
+	equals(t, second.unchanged("s3://media/d.jpg"), false)
+	second.record("s3://media/d.jpg", "/tmp/chkmd-2.jpg", e)
+	equals(t, second.changes(), []metadataChange{
+		{"s3://media/c.jpg", "Changed", []string{"IPTC:ObjectName"}},
+		{"s3://media/d.jpg", "New", nil},
+	})
+	// a's previous entry is carried over.
+	equals(t, strings.Count(dump.String(), "\n"), 4)
+	cache := second.cache()
+	equals(t, cache, cacheSummary{FromCache: 1, Extracted: 3, HitRate: 0.25, Misses: map[string]int{
+		missChanged:    1,
+		missUnrecorded: 1,
+		missNew:        1,
+	}})
+	equals(t, cache.String(), "25.0%, 1 from cache, 3 extracted: 1 new, 1 size or time changed, 1 no size or time recorded")
+	// Without -since there's no cache.
+	equals(t, first.cache(), cacheSummary{Misses: map[string]int{}})
+
+	third := newAudit(previous, nil)
+	third.record(a, a, e)
+	equals(t, third.changes(), []metadataChange{
+		{b, "Removed", nil},
+		{"s3://media/c.jpg", "Removed", nil},
+	})
+}
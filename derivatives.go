@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// derivativeConfig is how to spot derivatives: files like thumbnails and
+// previews made from a master in the same directory. Suffixes are regular
+// expressions matched at the end of a file's name without its extension;
+// with the suffix removed the name is the master's. PreviewExtensions are
+// extensions of previews named the same as their master.
+type derivativeConfig struct {
+	Off               bool     `yaml:"off"`
+	Suffixes          []string `yaml:"suffixes"`
+	PreviewExtensions []string `yaml:"preview_extensions"`
+}
+
+// defaultDerivatives is used when the config doesn't list any.
+var defaultDerivatives = derivativeConfig{
+	Suffixes:          []string{`_thumb[^.]*`, `-[0-9]+x[0-9]*`},
+	PreviewExtensions: []string{".webp"},
+}
+
+// derivatives finds derivatives, reading each directory's names once.
+type derivatives struct {
+	suffixes []*regexp.Regexp
+	previews map[string]bool
+	sync.Mutex
+	dirs map[string]map[string][]string
+}
+
+// newDerivatives returns the derivatives for the config, or nil if it's Off.
+func newDerivatives(dc derivativeConfig) (*derivatives, error) {
+	if dc.Off {
+		return nil, nil
+	}
+	if len(dc.Suffixes) == 0 {
+		dc.Suffixes = defaultDerivatives.Suffixes
+	}
+	if len(dc.PreviewExtensions) == 0 {
+		dc.PreviewExtensions = defaultDerivatives.PreviewExtensions
+	}
+	d := &derivatives{previews: map[string]bool{}, dirs: map[string]map[string][]string{}}
+	for _, s := range dc.Suffixes {
+		re, err := regexp.Compile("^(.+?)(?:" + s + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("derivatives: %s", err)
+		}
+		d.suffixes = append(d.suffixes, re)
+	}
+	for _, ext := range dc.PreviewExtensions {
+		d.previews[strings.ToLower(ext)] = true
+	}
+	return d, nil
+}
+
+// stems returns the files in dir by their name without extension.
+func (d *derivatives) stems(dir string) map[string][]string {
+	d.Lock()
+	defer d.Unlock()
+	if s, ok := d.dirs[dir]; ok {
+		return s
+	}
+	s := map[string][]string{}
+	fis, _ := ioutil.ReadDir(dir)
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			name := fi.Name()
+			stem := strings.TrimSuffix(name, filepath.Ext(name))
+			s[stem] = append(s[stem], name)
+		}
+	}
+	d.dirs[dir] = s
+	return s
+}
+
+// derivative is whether p is a derivative of another file in its directory.
+func (d *derivatives) derivative(p string) bool {
+	if d == nil {
+		return false
+	}
+	dir, name := filepath.Split(p)
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	stems := d.stems(filepath.Clean(dir))
+	if d.previews[strings.ToLower(ext)] {
+		for _, other := range stems[stem] {
+			if other != name && !d.previews[strings.ToLower(filepath.Ext(other))] {
+				return true
+			}
+		}
+	}
+	for _, re := range d.suffixes {
+		if m := re.FindStringSubmatch(stem); m != nil && len(stems[m[1]]) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDerivatives(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{
+		"launch.jpg", "launch_thumb.jpg", "launch-1024x768.jpg", "launch.webp",
+		"landing_thumb_small.jpg", "orphan.webp", "crew-300x.png", "crew.tif",
+	} {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte("x"), 0644)
+	}
+	d, err := newDerivatives(derivativeConfig{})
+	equals(t, err, nil)
+	values := []struct {
+		name string
+		want bool
+	}{
+		{"launch.jpg", false},
+		{"launch_thumb.jpg", true},
+		{"launch-1024x768.jpg", true},
+		{"launch.webp", true},
+		{"crew-300x.png", true},
+		// No master to be derived from.
+		{"landing_thumb_small.jpg", false},
+		{"orphan.webp", false},
+	}
+	for _, v := range values {
+		equals(t, d.derivative(filepath.Join(dir, v.name)), v.want)
+	}
+
+	d, err = newDerivatives(derivativeConfig{Off: true})
+	equals(t, err, nil)
+	equals(t, d.derivative(filepath.Join(dir, "launch_thumb.jpg")), false)
+
+	_, err = newDerivatives(derivativeConfig{Suffixes: []string{"(thumb"}})
+	equals(t, err.Error(), "derivatives: error parsing regexp: missing closing ): `^(.+?)(?:(thumb)$`")
+}
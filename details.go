@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// detailRecord is a file's line in the -details output. The CSV row stays the
+// same for people reading it, while this has everything behind it for
+// programs: the row keyed by column, how each check of the acceptance rule
+// came out, and for each field every source consulted, in order, with its
+// value and which was used. Files that couldn't be extracted have only the
+// row.
+type detailRecord struct {
+	Path       string                `json:"path"`
+	Status     string                `json:"status"`
+	Reason     string                `json:"reason"`
+	Fields     map[string]string     `json:"fields"`
+	MediaType  string                `json:"media_type,omitempty"`
+	Rules      []ruleOutcome         `json:"rules,omitempty"`
+	Provenance map[string]provenance `json:"provenance,omitempty"`
+}
+
+// provenance is where a field's value came from.
+type provenance struct {
+	Used      string      `json:"used,omitempty"`
+	Consulted []consulted `json:"consulted"`
+}
+
+// consulted is a source of a field and its value, if it has one.
+type consulted struct {
+	Source string `json:"source"`
+	Value  string `json:"value,omitempty"`
+}
+
+// details writes a detailRecord per file, as JSON lines, for -details.
+type details struct {
+	sync.Mutex
+	rules rules
+	enc   *json.Encoder
+	err   error
+}
+
+// newDetails returns a details writing to w, with the outcomes of rs.
+func newDetails(w io.Writer, rs rules) *details {
+	return &details{rules: rs, enc: json.NewEncoder(w)}
+}
+
+// detail returns the detailRecord for a row and the exif it was made from.
+func (d *details) detail(row []string, e exif) detailRecord {
+	dr := detailRecord{
+		Path:   row[column("Path")],
+		Status: row[column("Status")],
+		Reason: row[column("Reason")],
+		Fields: map[string]string{},
+	}
+	for i, h := range csvHeader {
+		dr.Fields[h] = row[i]
+	}
+	if dr.Status == "Rejected" {
+		return dr
+	}
+	dr.MediaType = e.MediaType()
+	dr.Rules = d.rules.outcomes(e)
+	dr.Provenance = map[string]provenance{}
+	for field := range fieldSources {
+		var pv provenance
+		for _, s := range e.sources(field) {
+			v := s.get(e)
+			pv.Consulted = append(pv.Consulted, consulted{s.name, v})
+			if v != "" && pv.Used == "" {
+				pv.Used = s.name
+			}
+		}
+		dr.Provenance[field] = pv
+	}
+	return dr
+}
+
+// record writes the detailRecord for the row, keeping the first error for
+// Err.
+func (d *details) record(row []string, e exif) {
+	dr := d.detail(row, e)
+	d.Lock()
+	defer d.Unlock()
+	if d.err == nil {
+		d.err = d.enc.Encode(dr)
+	}
+}
+
+// Err returns the first error writing the details.
+func (d *details) Err() error {
+	d.Lock()
+	defer d.Unlock()
+	return d.err
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestDetails(t *testing.T) {
+	var b bytes.Buffer
+	d := newDetails(&b, rules{})
+
+	e := newExif()
+	e.Data["MIMEType"] = "image/jpeg"
+	e.IPTC["DateCreated"] = "2015:01:09"
+	e.XMP["Title"] = "Launch"
+	row := testRow("/media/a/KSC-1.jpg", "Incomplete", "Minimum metadata not provided")
+	row[column("Title")] = "Launch"
+	d.record(row, e)
+	d.record(testRow("/media/a/KSC-2.jpg", "Rejected", "exiftool: timed out"), newExif())
+	equals(t, d.Err(), nil)
+
+	dec := json.NewDecoder(&b)
+	var got detailRecord
+	equals(t, dec.Decode(&got), nil)
+	equals(t, got.Path, "/media/a/KSC-1.jpg")
+	equals(t, got.Status, "Incomplete")
+	equals(t, got.Fields["Title"], "Launch")
+	equals(t, got.MediaType, "image")
+	equals(t, got.Rules, []ruleOutcome{
+		{"required", []string{"DateCreated"}, true},
+		{"any_of", []string{"Keywords", "Description"}, false},
+		{"warn", []string{"Title"}, true},
+		{"warn", []string{"Location"}, false},
+	})
+	title := got.Provenance["Title"]
+	equals(t, title.Used, "XMP:Title")
+	equals(t, title.Consulted[0], consulted{Source: "IPTC:ObjectName"})
+	equals(t, len(title.Consulted), len(e.sources("Title")))
+	equals(t, got.Provenance["Location"].Used, "")
+
+	// Files that couldn't be extracted have only the row.
+	got = detailRecord{}
+	equals(t, dec.Decode(&got), nil)
+	equals(t, got.Status, "Rejected")
+	equals(t, got.Reason, "exiftool: timed out")
+	equals(t, got.Rules, []ruleOutcome(nil))
+	equals(t, got.Provenance, map[string]provenance(nil))
+}
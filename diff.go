@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+)
+
+// diffUsage is printed for chkmd diff -h.
+const diffUsage = `Usage: chkmd diff [-o diff.csv] old.csv new.csv
+
+Compares two chkmd results CSVs, like this month's and last's, and reports
+the files added and removed, those newly Accepted or Rejected and the other
+columns that changed. Columns in only one of the CSVs are left out.
+`
+
+// The changes in a diff report.
+const (
+	diffAdded    = "Added"
+	diffRemoved  = "Removed"
+	diffAccepted = "Newly Accepted"
+	diffRejected = "Newly Rejected"
+	diffChanged  = "Changed"
+)
+
+// resultChange is a difference between two runs' results for a file. Column
+// is the column that changed, or "" for an added or removed file.
+type resultChange struct {
+	Path, Change, Column, Old, New string
+}
+
+// diffCounts is the summary of a diff, in files.
+type diffCounts struct {
+	Added    int
+	Removed  int
+	Accepted int
+	Rejected int
+	Changed  int
+}
+
+// diffResults compares the old and new results CSVs, returning the changes
+// sorted by Path. A file whose Status changed has that change first, then
+// any to its other columns.
+func diffResults(old, new io.Reader) ([]resultChange, diffCounts, error) {
+	var counts diffCounts
+	before, oldColumns, err := readResultsByPath(old)
+	if err != nil {
+		return nil, counts, err
+	}
+	after, newColumns, err := readResultsByPath(new)
+	if err != nil {
+		return nil, counts, err
+	}
+	inOld := map[string]bool{}
+	for _, c := range oldColumns {
+		inOld[c] = true
+	}
+	var columns []string
+	for _, c := range newColumns {
+		if inOld[c] && c != "Path" && c != "Status" {
+			columns = append(columns, c)
+		}
+	}
+
+	paths := make([]string, 0, len(before)+len(after))
+	for p := range before {
+		paths = append(paths, p)
+	}
+	for p := range after {
+		if _, ok := before[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var changes []resultChange
+	for _, p := range paths {
+		o, wasThere := before[p]
+		n, isThere := after[p]
+		switch {
+		case !wasThere:
+			counts.Added++
+			changes = append(changes, resultChange{p, diffAdded, "", "", n["Status"]})
+			continue
+		case !isThere:
+			counts.Removed++
+			changes = append(changes, resultChange{p, diffRemoved, "", o["Status"], ""})
+			continue
+		}
+		if o["Status"] != n["Status"] {
+			change := diffRejected
+			if accepted(n["Status"]) && !accepted(o["Status"]) {
+				change = diffAccepted
+				counts.Accepted++
+			} else if accepted(o["Status"]) && !accepted(n["Status"]) {
+				counts.Rejected++
+			} else {
+				// Still Accepted, with warnings or without, or still not,
+				// for another reason, like an error.
+				change = diffChanged
+			}
+			changes = append(changes, resultChange{p, change, "Status", o["Status"], n["Status"]})
+		}
+		changed := false
+		for _, c := range columns {
+			if o[c] != n[c] {
+				changed = true
+				changes = append(changes, resultChange{p, diffChanged, c, o[c], n[c]})
+			}
+		}
+		if changed {
+			counts.Changed++
+		}
+	}
+	return changes, counts, nil
+}
+
+// readResultsByPath reads a chkmd results CSV, returning its rows by Path and
+// its columns.
+func readResultsByPath(r io.Reader) (map[string]map[string]string, []string, error) {
+	rows, err := csv.NewReader(textReader(r)).ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	results := map[string]map[string]string{}
+	if len(rows) == 0 {
+		return results, nil, nil
+	}
+	found := 0
+	for _, h := range rows[0] {
+		if h == "Path" || h == "Status" {
+			found++
+		}
+	}
+	if found < 2 {
+		return nil, nil, fmt.Errorf("no Path and Status columns")
+	}
+	for _, row := range rows[1:] {
+		m := map[string]string{}
+		for i, h := range rows[0] {
+			if i < len(row) {
+				m[h] = row[i]
+			}
+		}
+		results[m["Path"]] = m
+	}
+	return results, rows[0], nil
+}
+
+// writeResultChanges writes the changes to w as CSV.
+func writeResultChanges(w *csv.Writer, changes []resultChange) error {
+	err := w.Write([]string{"Path", "Change", "Column", "Old", "New"})
+	for _, c := range changes {
+		if err == nil {
+			err = w.Write([]string{c.Path, c.Change, c.Column, c.Old, c.New})
+		}
+	}
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	return err
+}
+
+// diffFiles compares the results CSVs at oldPath and newPath, writing the
+// report to out.
+func diffFiles(oldPath, newPath string, out io.Writer) (diffCounts, error) {
+	old, err := os.Open(oldPath)
+	if err != nil {
+		return diffCounts{}, err
+	}
+	defer old.Close()
+	new, err := os.Open(newPath)
+	if err != nil {
+		return diffCounts{}, err
+	}
+	defer new.Close()
+	changes, counts, err := diffResults(old, new)
+	if err != nil {
+		return counts, err
+	}
+	return counts, writeResultChanges(csv.NewWriter(out), changes)
+}
+
+// writeBaselineChanges compares a run's results, at output, to the baseline's,
+// writing the changes to the file at p, or stderr if p is "".
+func writeBaselineChanges(baseline, output, p string) (diffCounts, error) {
+	out := os.Stderr
+	if p != "" {
+		f, err := os.Create(p)
+		if err != nil {
+			return diffCounts{}, err
+		}
+		defer f.Close()
+		out = f
+	}
+	return diffFiles(baseline, output, out)
+}
+
+// logDiffCounts logs the summary of a diff against the baseline.
+func logDiffCounts(baseline string, counts diffCounts) {
+	log.Printf("\nChanges from %s:\nAdded Files: %d\nRemoved Files: %d\nNewly Accepted: %d\nNewly Rejected: %d\nFiles Changed: %d\n",
+		baseline, counts.Added, counts.Removed, counts.Accepted, counts.Rejected, counts.Changed)
+}
+
+// diffCommand is chkmd diff.
+func diffCommand(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	output := fs.String("o", "", "A file to output to, instead of stdout.")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, diffUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Printf("Error opening output file: %s", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	counts, err := diffFiles(fs.Arg(0), fs.Arg(1), out)
+	if err != nil {
+		log.Printf("Error comparing %s and %s: %s", fs.Arg(0), fs.Arg(1), err)
+		return 1
+	}
+	logDiffCounts(fs.Arg(0), counts)
+	return 0
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestDiffResults(t *testing.T) {
+	old := "Path,Status,Title,Copyright\n" +
+		"a.jpg,Accepted,Moon,\n" +
+		"b.jpg,Incomplete,,\n" +
+		"c.jpg,Accepted,Mars,\n" +
+		"d.jpg,Rejected,,\n" +
+		"e.jpg,Incomplete,,\n"
+	new := "Path,Status,Title,Keywords\n" +
+		"a.jpg,Accepted,The Moon,moon\n" +
+		"b.jpg,Accepted,Earth,\n" +
+		"c.jpg,Incomplete,Mars,\n" +
+		"d.jpg,Incomplete,,\n" +
+		"f.jpg,Accepted,Venus,\n"
+	changes, counts, err := diffResults(strings.NewReader(old), strings.NewReader(new))
+	equals(t, err, nil)
+	equals(t, changes, []resultChange{
+		{"a.jpg", diffChanged, "Title", "Moon", "The Moon"},
+		{"b.jpg", diffAccepted, "Status", "Incomplete", "Accepted"},
+		{"b.jpg", diffChanged, "Title", "", "Earth"},
+		{"c.jpg", diffRejected, "Status", "Accepted", "Incomplete"},
+		{"d.jpg", diffChanged, "Status", "Rejected", "Incomplete"},
+		{"e.jpg", diffRemoved, "", "Incomplete", ""},
+		{"f.jpg", diffAdded, "", "", "Accepted"},
+	})
+	equals(t, counts, diffCounts{Added: 1, Removed: 1, Accepted: 1, Rejected: 1, Changed: 2})
+
+	var b bytes.Buffer
+	equals(t, writeResultChanges(csv.NewWriter(&b), changes[:1]), nil)
+	equals(t, b.String(), "Path,Change,Column,Old,New\na.jpg,Changed,Title,Moon,The Moon\n")
+
+	_, _, err = diffResults(strings.NewReader("NASA ID,Title\n"), strings.NewReader(new))
+	equals(t, err.Error(), "no Path and Status columns")
+	changes, counts, err = diffResults(strings.NewReader(""), strings.NewReader(new))
+	equals(t, err, nil)
+	equals(t, len(changes), 5)
+	equals(t, counts, diffCounts{Added: 5})
+}
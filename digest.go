@@ -0,0 +1,30 @@
+package main
+
+import "strings"
+
+// iptcModifiedReason is the Reason for files whose IPTC was changed by a tool
+// that didn't update the XMP to match.
+const iptcModifiedReason = "IPTC modified after XMP"
+
+// IPTCModified is whether the IPTC was changed after the XMP was last synced
+// with it. Photoshop and other MWG compliant tools store an MD5 digest of
+// the IPTC in IPTCDigest when they write both. If the IPTC's digest now,
+// CurrentIPTCDigest, differs, something else wrote the IPTC since, and it
+// likely disagrees with the XMP. Files without a stored digest can't tell.
+func (e exif) IPTCModified() bool {
+	stored, current := e.Data["IPTCDigest"], e.Data["CurrentIPTCDigest"]
+	if stored == "" || current == "" || strings.Trim(current, "0") == "" {
+		// exiftool gives all zeros if it doesn't have Digest::MD5.
+		return false
+	}
+	return !strings.EqualFold(stored, current)
+}
+
+// iptcModified returns the Reason for a file with IPTCModified, with when
+// the XMP was last written if we know.
+func (e exif) iptcModified() string {
+	if d := e.XMP["MetadataDate"]; d != "" {
+		return iptcModifiedReason + " (XMP written " + d + ")"
+	}
+	return iptcModifiedReason
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestIPTCModified(t *testing.T) {
+	values := []struct {
+		stored, current string
+		want            bool
+	}{
+		{"0b7a3a3b0a8e1a2a5e1c9f8c7d6e5f40", "0b7a3a3b0a8e1a2a5e1c9f8c7d6e5f40", false},
+		{"0b7a3a3b0a8e1a2a5e1c9f8c7d6e5f40", "0B7A3A3B0A8E1A2A5E1C9F8C7D6E5F40", false},
+		{"0b7a3a3b0a8e1a2a5e1c9f8c7d6e5f40", "9c1e2f3a4b5c6d7e8f9a0b1c2d3e4f50", true},
+		{"", "9c1e2f3a4b5c6d7e8f9a0b1c2d3e4f50", false},
+		{"0b7a3a3b0a8e1a2a5e1c9f8c7d6e5f40", "00000000000000000000000000000000", false},
+	}
+	for _, v := range values {
+		e := newExif()
+		e.Data["IPTCDigest"] = v.stored
+		e.Data["CurrentIPTCDigest"] = v.current
+		equals(t, e.IPTCModified(), v.want)
+	}
+
+	e := newExif()
+	equals(t, e.iptcModified(), "IPTC modified after XMP")
+	e.XMP["MetadataDate"] = "2009:03:28 19:05:04-04:00"
+	equals(t, e.iptcModified(), "IPTC modified after XMP (XMP written 2009:03:28 19:05:04-04:00)")
+}
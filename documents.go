@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+)
+
+// documentMediaType is the Media Type of documents, which aren't any one top
+// level MIME type.
+const documentMediaType = "document"
+
+// documentTypes are the MIME types of documents, checked with -documents or
+// when the config lists them.
+var documentTypes = []string{"application/pdf"}
+
+// mediaTypeOf returns the Media Type for a MIME type: document for
+// documentTypes, if it's one of the media types, otherwise its top level
+// type, as configs listing application for PDFs have it.
+func (e exif) mediaTypeOf(mimeType string) string {
+	if containsString(documentTypes, mimeType) && e.mediaType(documentMediaType) {
+		return documentMediaType
+	}
+	return strings.Split(mimeType, "/")[0]
+}
+
+// isDocument is whether e is a document, by its MIME type.
+func (e exif) isDocument() bool {
+	return containsString(documentTypes, e.Data["MIMEType"])
+}
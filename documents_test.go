@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestDocuments(t *testing.T) {
+	r, err := newRunner(options{documents: true}, config{MimeTypes: defaultTypes})
+	equals(t, err, nil)
+	pdf := func(tags map[string]string) exif {
+		e, err := r.configure(func(p string) (exif, error) {
+			e := newExif()
+			e.Data["MIMEType"], e.Data["FileType"] = "application/pdf", "PDF"
+			for tag, v := range tags {
+				group, tag := splitSource(tag)
+				m, _ := e.group(group)
+				m[tag] = v
+			}
+			return e, nil
+		})("report.pdf")
+		equals(t, err, nil)
+		return e
+	}
+
+	e := pdf(map[string]string{
+		"PDF:Title":      "Mission Report",
+		"PDF:Subject":    "Results of the mission",
+		"PDF:Author":     "Jane Doe",
+		"PDF:Keywords":   "mission; report",
+		"PDF:CreateDate": "2014:06:07 10:11:12-04:00",
+	})
+	equals(t, e.MediaType(), "document")
+	equals(t, e.FileFormat(), "PDF")
+	equals(t, []string{e.Title(), e.Description(), e.Photographer()}, []string{"Mission Report", "Results of the mission", "Jane Doe"})
+	equals(t, e.KeywordList(), []string{"mission", "report"})
+	equals(t, e.dateCreatedText(), "2014:06:07 10:11:12-04:00")
+	equals(t, r.cfg.Rules.accepts(e), true)
+
+	// The XMP comes first, xmp:CreateDate included.
+	e = pdf(map[string]string{
+		"XMP:Title":      "From XMP",
+		"XMP:CreateDate": "2015:01:02 03:04:05",
+		"PDF:Title":      "From Info",
+		"PDF:CreateDate": "2014:06:07 10:11:12-04:00",
+	})
+	equals(t, e.Title(), "From XMP")
+	equals(t, e.dateCreatedText(), "2015:01:02 03:04:05")
+
+	// An image's xmp:CreateDate isn't when it was created.
+	image := newExif()
+	image.Data["MIMEType"], image.XMP["CreateDate"] = "image/jpeg", "2015:01:02 03:04:05"
+	equals(t, image.dateCreatedText(), "")
+
+	// Without -documents PDFs aren't checked, and configs with application
+	// for them still have it.
+	r, err = newRunner(options{}, config{MimeTypes: defaultTypes})
+	equals(t, err, nil)
+	equals(t, r.types["application/pdf"], false)
+	e = newExif()
+	e.Data["MIMEType"] = "application/pdf"
+	e.types, e.media = map[string]bool{"application/pdf": true}, map[string]bool{"application": true}
+	equals(t, e.MediaType(), "application")
+}
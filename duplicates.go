@@ -0,0 +1,282 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"math/bits"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Content hashes for the duplicates config.
+const (
+	hashSHA256 = "sha256"
+	hashXX     = "xxhash"
+)
+
+// duplicateConfig is the duplicates section of the config. Hash is the
+// content hash, sha256 (the default) or the much faster but non
+// cryptographic xxhash.
+type duplicateConfig struct {
+	Hash string `yaml:"hash"`
+}
+
+// newHash returns a new hash.Hash for the config's Hash.
+func (dc duplicateConfig) newHash() (hash.Hash, error) {
+	switch dc.Hash {
+	case "", hashSHA256:
+		return sha256.New(), nil
+	case hashXX:
+		return newXXHash(), nil
+	}
+	return nil, fmt.Errorf("duplicates: unknown hash %q, use %s or %s", dc.Hash, hashSHA256, hashXX)
+}
+
+// validate checks the Hash is one we know.
+func (dc duplicateConfig) validate() error {
+	_, err := dc.newHash()
+	return err
+}
+
+// fileHash returns the hex hash of the file at p's content.
+func fileHash(dc duplicateConfig, p string) (string, error) {
+	h, err := dc.newHash()
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashExtract wraps extract so the exif has the Hash of the file's content.
+// It goes next to exiftool, so S3 objects and URLs are hashed from the
+// download exiftool reads.
+func hashExtract(dc duplicateConfig, extract func(string) (exif, error)) func(string) (exif, error) {
+	return func(p string) (exif, error) {
+		e, err := extract(p)
+		if err != nil {
+			return e, err
+		}
+		e.Hash, err = fileHash(dc, p)
+		return e, err
+	}
+}
+
+// duplicate is a group of files with the same content or NASA ID.
+type duplicate struct {
+	Kind  string
+	Value string
+	Paths []string
+}
+
+// duplicates collects the files by content hash and NASA ID to report those
+// sharing either once the scan's done, since duplicate IDs break the AVAIL
+// importer. It's safe for use by multiple processFiles goroutines.
+type duplicates struct {
+	sync.Mutex
+	hashes map[string][]string
+	ids    map[string][]string
+}
+
+// newDuplicates returns an empty duplicates.
+func newDuplicates() *duplicates {
+	return &duplicates{hashes: map[string][]string{}, ids: map[string][]string{}}
+}
+
+// add records the file at p with its NASA ID and content hash.
+func (d *duplicates) add(p, id, hash string) {
+	d.Lock()
+	defer d.Unlock()
+	if hash != "" {
+		d.hashes[hash] = append(d.hashes[hash], p)
+	}
+	if id != "" {
+		d.ids[id] = append(d.ids[id], p)
+	}
+}
+
+// found returns the groups of duplicate content, then NASA IDs, each sorted
+// by value.
+func (d *duplicates) found() []duplicate {
+	d.Lock()
+	defer d.Unlock()
+	var dups []duplicate
+	for _, g := range []struct {
+		kind  string
+		paths map[string][]string
+	}{{"Content", d.hashes}, {"NASA ID", d.ids}} {
+		var values []string
+		for v, paths := range g.paths {
+			if len(paths) > 1 {
+				values = append(values, v)
+			}
+		}
+		sort.Strings(values)
+		for _, v := range values {
+			paths := append([]string{}, g.paths[v]...)
+			sort.Strings(paths)
+			dups = append(dups, duplicate{g.kind, v, paths})
+		}
+	}
+	return dups
+}
+
+// countDuplicates returns how many files of kind are duplicates.
+func countDuplicates(dups []duplicate, kind string) int {
+	n := 0
+	for _, d := range dups {
+		if d.Kind == kind {
+			n += len(d.Paths)
+		}
+	}
+	return n
+}
+
+// writeDuplicates writes a CSV of each duplicated file and what it shares.
+func writeDuplicates(p string, dups []duplicate) error {
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	err = w.Write([]string{"Duplicate", "Value", "Count", "Path"})
+	for _, d := range dups {
+		for _, path := range d.Paths {
+			if err == nil {
+				err = w.Write([]string{d.Kind, d.Value, fmt.Sprint(len(d.Paths)), path})
+			}
+		}
+	}
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// The xxHash64 primes. They're variables so sums of them wrap.
+var (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+// xxHash is a streaming xxHash64 with a seed of 0, as a hash.Hash.
+type xxHash struct {
+	v     [4]uint64
+	total uint64
+	mem   [32]byte
+	n     int
+}
+
+// newXXHash returns a new xxHash.
+func newXXHash() *xxHash {
+	x := &xxHash{}
+	x.Reset()
+	return x
+}
+
+func (x *xxHash) Reset() {
+	x.v = [4]uint64{xxPrime1 + xxPrime2, xxPrime2, 0, -xxPrime1}
+	x.total, x.n = 0, 0
+}
+
+func (x *xxHash) Size() int      { return 8 }
+func (x *xxHash) BlockSize() int { return 32 }
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMerge(acc, v uint64) uint64 {
+	acc ^= xxRound(0, v)
+	return acc*xxPrime1 + xxPrime4
+}
+
+// stripe mixes a 32 byte stripe into the accumulators.
+func (x *xxHash) stripe(b []byte) {
+	for i := range x.v {
+		x.v[i] = xxRound(x.v[i], binary.LittleEndian.Uint64(b[8*i:]))
+	}
+}
+
+func (x *xxHash) Write(b []byte) (int, error) {
+	n := len(b)
+	x.total += uint64(n)
+	if x.n > 0 {
+		c := copy(x.mem[x.n:], b)
+		x.n += c
+		b = b[c:]
+		if x.n < len(x.mem) {
+			return n, nil
+		}
+		x.stripe(x.mem[:])
+		x.n = 0
+	}
+	for ; len(b) >= 32; b = b[32:] {
+		x.stripe(b)
+	}
+	x.n = copy(x.mem[:], b)
+	return n, nil
+}
+
+// Sum64 returns the hash of what's been written so far.
+func (x *xxHash) Sum64() uint64 {
+	var h uint64
+	if x.total >= 32 {
+		h = bits.RotateLeft64(x.v[0], 1) + bits.RotateLeft64(x.v[1], 7) +
+			bits.RotateLeft64(x.v[2], 12) + bits.RotateLeft64(x.v[3], 18)
+		for _, v := range x.v {
+			h = xxMerge(h, v)
+		}
+	} else {
+		h = xxPrime5
+	}
+	h += x.total
+	b := x.mem[:x.n]
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(b))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(b)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		b = b[4:]
+	}
+	for _, c := range b {
+		h ^= uint64(c) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+func (x *xxHash) Sum(b []byte) []byte {
+	var s [8]byte
+	binary.BigEndian.PutUint64(s[:], x.Sum64())
+	return append(b, s[:]...)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestXXHash(t *testing.T) {
+	values := []struct {
+		in   string
+		want uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"abc", 0x44bc2cf5ad770999},
+		{"Nobody inspects the spammish repetition", 0xfbcea83c8a378bf1},
+	}
+	for _, v := range values {
+		x := newXXHash()
+		x.Write([]byte(v.in))
+		equals(t, x.Sum64(), v.want)
+
+		// Written a byte at a time it's the same.
+		x.Reset()
+		for i := range v.in {
+			x.Write([]byte{v.in[i]})
+		}
+		equals(t, x.Sum64(), v.want)
+	}
+	long := strings.Repeat("0123456789", 100)
+	x := newXXHash()
+	x.Write([]byte(long))
+	want := x.Sum64()
+	x.Reset()
+	x.Write([]byte(long[:33]))
+	x.Write([]byte(long[33:500]))
+	x.Write([]byte(long[500:]))
+	equals(t, x.Sum64(), want)
+}
+
+func TestFileHash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "a.jpg")
+	equals(t, ioutil.WriteFile(p, []byte("abc"), 0644), nil)
+
+	h, err := fileHash(duplicateConfig{}, p)
+	equals(t, err, nil)
+	equals(t, h, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad")
+	h, err = fileHash(duplicateConfig{Hash: hashXX}, p)
+	equals(t, err, nil)
+	equals(t, h, "44bc2cf5ad770999")
+	_, err = fileHash(duplicateConfig{Hash: "md5"}, p)
+	equals(t, err.Error(), `duplicates: unknown hash "md5", use sha256 or xxhash`)
+}
+
+func TestDuplicates(t *testing.T) {
+	d := newDuplicates()
+	d.add("/media/a/1.jpg", "KSC-1", "aaa")
+	d.add("/media/b/1.jpg", "KSC-1", "bbb")
+	d.add("/media/b/copy.jpg", "KSC-2", "aaa")
+	d.add("/media/c/3.jpg", "KSC-3", "ccc")
+	d.add("/media/c/4.jpg", "", "")
+	dups := d.found()
+	equals(t, dups, []duplicate{
+		{"Content", "aaa", []string{"/media/a/1.jpg", "/media/b/copy.jpg"}},
+		{"NASA ID", "KSC-1", []string{"/media/a/1.jpg", "/media/b/1.jpg"}},
+	})
+	equals(t, countDuplicates(dups, "Content"), 2)
+
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "dups.csv")
+	equals(t, writeDuplicates(p, dups), nil)
+	b, err := ioutil.ReadFile(p)
+	equals(t, err, nil)
+	equals(t, string(b), `Duplicate,Value,Count,Path
+Content,aaa,2,/media/a/1.jpg
+Content,aaa,2,/media/b/copy.jpg
+NASA ID,KSC-1,2,/media/a/1.jpg
+NASA ID,KSC-1,2,/media/b/1.jpg
+`)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// byteSize is a number of bytes, as a flag.Value taking e.g. 500MB or 2GB.
+// The units are powers of 1024.
+type byteSize int64
+
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+func (b *byteSize) Set(s string) error {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	unit := int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("not a size like 500MB: %q", s)
+	}
+	*b = byteSize(n * float64(unit))
+	return nil
+}
+
+func (b byteSize) String() string {
+	for _, u := range byteUnits {
+		if int64(b) >= u.size {
+			return strconv.FormatFloat(float64(b)/float64(u.size), 'f', -1, 64) + u.suffix
+		}
+	}
+	return "0B"
+}
+
+// egress counts the bytes downloaded from S3 and URLs against a budget, so
+// an exploratory scan can't run up a surprise bill. Once the budget's used
+// it calls stop, which should stop new files being checked; the files
+// already downloading are finished. It's safe for use by multiple
+// processFiles goroutines, and a nil *egress counts nothing.
+type egress struct {
+	budget int64
+	used   int64
+	stop   func()
+	once   sync.Once
+}
+
+// newEgress returns an egress with the budget, or no budget if it's 0.
+func newEgress(budget byteSize, stop func()) *egress {
+	return &egress{budget: int64(budget), stop: stop}
+}
+
+// meter returns w counting what's written to it as downloaded.
+func (eg *egress) meter(w io.Writer) io.Writer {
+	if eg == nil {
+		return w
+	}
+	return egressWriter{eg, w}
+}
+
+// add counts n more bytes downloaded.
+func (eg *egress) add(n int) {
+	used := atomic.AddInt64(&eg.used, int64(n))
+	if eg.budget > 0 && used > eg.budget {
+		eg.once.Do(func() {
+			log.Printf("Egress budget of %s used, stopping after the files in progress.\n", byteSize(eg.budget))
+			eg.stop()
+		})
+	}
+}
+
+// exceeded is whether the budget was used up.
+func (eg *egress) exceeded() bool {
+	return eg != nil && eg.budget > 0 && atomic.LoadInt64(&eg.used) > eg.budget
+}
+
+// String describes how much was downloaded.
+func (eg *egress) String() string {
+	s := byteSize(atomic.LoadInt64(&eg.used)).String()
+	if eg.budget > 0 {
+		s += " of " + byteSize(eg.budget).String()
+	}
+	return s
+}
+
+// egressWriter counts what's written through it.
+type egressWriter struct {
+	eg *egress
+	w  io.Writer
+}
+
+func (ew egressWriter) Write(p []byte) (int, error) {
+	n, err := ew.w.Write(p)
+	ew.eg.add(n)
+	return n, err
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestByteSize(t *testing.T) {
+	values := []struct {
+		in, want string
+		size     byteSize
+	}{
+		{"500MB", "500MB", 500 << 20},
+		{"1.5gb", "1.5GB", 3 << 29},
+		{"2 TB", "2TB", 2 << 40},
+		{"100", "100B", 100},
+		{"0", "0B", 0},
+	}
+	for _, v := range values {
+		var b byteSize
+		equals(t, b.Set(v.in), nil)
+		equals(t, b, v.size)
+		equals(t, b.String(), v.want)
+	}
+	var b byteSize
+	equals(t, b.Set("lots").Error(), `not a size like 500MB: "LOTS"`)
+}
+
+func TestEgress(t *testing.T) {
+	stopped := 0
+	eg := newEgress(10, func() { stopped++ })
+	var buf bytes.Buffer
+	w := eg.meter(&buf)
+	w.Write([]byte("12345"))
+	equals(t, eg.exceeded(), false)
+	w.Write([]byte("678901"))
+	w.Write([]byte("2"))
+	// The download in progress carries on, but it's only stopped once.
+	equals(t, buf.String(), "123456789012")
+	equals(t, eg.exceeded(), true)
+	equals(t, stopped, 1)
+	equals(t, eg.String(), "12B of 10B")
+
+	eg = newEgress(0, func() { stopped++ })
+	eg.meter(ioutil.Discard).Write(make([]byte, 2048))
+	equals(t, eg.exceeded(), false)
+	equals(t, eg.String(), "2KB")
+
+	var none *egress
+	equals(t, none.meter(&buf), &buf)
+	equals(t, none.exceeded(), false)
+}
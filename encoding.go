@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	utf8BOM    = "\xef\xbb\xbf"
+	utf16LEBOM = "\xff\xfe"
+)
+
+// outputEncoding is how the CSV output is encoded: UTF-8, with a byte order
+// mark for -bom, which Excel needs to open it as UTF-8 rather than the
+// system's code page, or UTF-16LE, with one, for -utf16.
+type outputEncoding struct {
+	bom   bool
+	utf16 bool
+}
+
+// writer returns a writer encoding to w, writing the byte order mark first
+// if it's a new file rather than one being appended to.
+func (oe outputEncoding) writer(w io.Writer, fresh bool) (io.Writer, error) {
+	switch {
+	case oe.utf16:
+		if fresh {
+			if _, err := io.WriteString(w, utf16LEBOM); err != nil {
+				return nil, err
+			}
+		}
+		return &utf16LEWriter{w: w}, nil
+	case oe.bom && fresh:
+		if _, err := io.WriteString(w, utf8BOM); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// utf16LEWriter writes the UTF-8 written to it to w as UTF-16LE. A rune
+// split between writes is kept until the rest of it is written.
+type utf16LEWriter struct {
+	w       io.Writer
+	partial []byte
+}
+
+func (u *utf16LEWriter) Write(b []byte) (int, error) {
+	n := len(b)
+	if len(u.partial) > 0 {
+		b = append(u.partial, b...)
+		u.partial = nil
+	}
+	out := make([]byte, 0, 2*len(b))
+	for len(b) > 0 {
+		if !utf8.FullRune(b) {
+			u.partial = append([]byte(nil), b...)
+			break
+		}
+		r, size := utf8.DecodeRune(b)
+		b = b[size:]
+		if r >= 0x10000 {
+			r1, r2 := utf16.EncodeRune(r)
+			out = append(out, byte(r1), byte(r1>>8), byte(r2), byte(r2>>8))
+			continue
+		}
+		out = append(out, byte(r), byte(r>>8))
+	}
+	if _, err := u.w.Write(out); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// textReader returns r as UTF-8, without any byte order mark, decoding it
+// from UTF-16LE if its mark says it is, so our own output can be read back
+// however it was written.
+func textReader(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	mark, _ := br.Peek(3)
+	switch {
+	case bytes.HasPrefix(mark, []byte(utf8BOM)):
+		br.Discard(len(utf8BOM))
+	case bytes.HasPrefix(mark, []byte(utf16LEBOM)):
+		br.Discard(len(utf16LEBOM))
+		return &utf16LEReader{r: br}
+	}
+	return br
+}
+
+// utf16LEReader reads UTF-16LE from r as UTF-8.
+type utf16LEReader struct {
+	r   *bufio.Reader
+	buf bytes.Buffer
+}
+
+func (u *utf16LEReader) Read(b []byte) (int, error) {
+	for u.buf.Len() < len(b) {
+		r, err := u.unit()
+		if err != nil {
+			if u.buf.Len() > 0 {
+				break
+			}
+			return 0, err
+		}
+		if utf16.IsSurrogate(r) {
+			r2, err := u.unit()
+			if err != nil {
+				return 0, err
+			}
+			r = utf16.DecodeRune(r, r2)
+		}
+		u.buf.WriteRune(r)
+	}
+	return u.buf.Read(b)
+}
+
+// unit reads the next UTF-16 code unit.
+func (u *utf16LEReader) unit() (rune, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(u.r, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return 0, err
+	}
+	return rune(b[0]) | rune(b[1])<<8, nil
+}
+
+// validUTF8 returns s with any bytes that aren't UTF-8, from tags in other
+// character sets than IPTC's, as the Unicode replacement character, so
+// they don't garble the rest of the value wherever it's written.
+func validUTF8(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	var b strings.Builder
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		b.WriteRune(r)
+	}
+	return b.String()
+}
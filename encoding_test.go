@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputEncoding(t *testing.T) {
+	rows := [][]string{{"Path", "Title"}, {"/media/a.jpg", "東京 café 🚀"}}
+	encode := func(oe outputEncoding, fresh bool) []byte {
+		var b bytes.Buffer
+		w, err := oe.writer(&b, fresh)
+		equals(t, err, nil)
+		// Small writes, so runes are split between them.
+		cw := csv.NewWriter(&oneByteWriter{w})
+		equals(t, cw.WriteAll(rows), nil)
+		return b.Bytes()
+	}
+	plain := "Path,Title\n/media/a.jpg,東京 café 🚀\n"
+	equals(t, string(encode(outputEncoding{}, true)), plain)
+	equals(t, string(encode(outputEncoding{bom: true}, true)), utf8BOM+plain)
+	equals(t, string(encode(outputEncoding{bom: true}, false)), plain)
+
+	utf16 := encode(outputEncoding{utf16: true}, true)
+	equals(t, utf16[:6], []byte{0xff, 0xfe, 'P', 0, 'a', 0})
+	equals(t, len(utf16), 2+2*len([]rune(plain))+2)
+
+	for _, b := range [][]byte{utf16, encode(outputEncoding{bom: true}, true), []byte(plain)} {
+		got, err := csv.NewReader(textReader(bytes.NewReader(b))).ReadAll()
+		equals(t, err, nil)
+		equals(t, got, rows)
+	}
+}
+
+// oneByteWriter writes to w a byte at a time.
+type oneByteWriter struct {
+	w io.Writer
+}
+
+func (o *oneByteWriter) Write(b []byte) (int, error) {
+	for i := range b {
+		if _, err := o.w.Write(b[i : i+1]); err != nil {
+			return i, err
+		}
+	}
+	return len(b), nil
+}
+
+func TestAppendWithBOM(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "out.csv")
+	equals(t, ioutil.WriteFile(p, []byte(utf8BOM+"Path,Status\n/media/a.jpg,Accepted\n"), 0644), nil)
+	n, err := countRows(p, []string{"Path", "Status"})
+	equals(t, err, nil)
+	equals(t, n, 1)
+}
+
+func TestValidUTF8(t *testing.T) {
+	equals(t, validUTF8("café"), "café")
+	equals(t, validUTF8("caf\xe9 au lait"), "caf� au lait")
+
+	e := newExif()
+	e.XMP["Title"] = "Launch at Cap Canav\xe9ral"
+	rows := make(chan []string, 1)
+	e.MakeRow(rows, "/media/caf\xe9.jpg", "Accepted", "", "")
+	row := <-rows
+	equals(t, row[column("Title")], "Launch at Cap Canav�ral")
+	equals(t, row[column("Path")], "/media/caf\xe9.jpg")
+}
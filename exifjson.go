@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// base64Prefix marks a JSON value exiftool encoded because it isn't valid
+// UTF-8, like Latin-1 IPTC passed through by iptcCharsetArgs.
+const base64Prefix = "base64:"
+
+// parseExifJSON loads the output of `exiftool -j -G -struct -a` for one file
+// into an exif struct. The object is read a key at a time as -a can repeat
+// one, like ExifTool:Warning, see set. List tags are kept in Lists too, and
+// structures are kept as JSON, except language alternatives, which are
+// given a tag per language as exiftool does without -struct.
+func parseExifJSON(s string) (exif, error) {
+	e := newExif()
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	for _, want := range []json.Delim{'[', '{'} {
+		t, err := dec.Token()
+		if err != nil {
+			return e, fmt.Errorf("reading exiftool output: %s", err)
+		}
+		if t != want {
+			return e, fmt.Errorf("reading exiftool output: expected %s, got %v", want, t)
+		}
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return e, fmt.Errorf("reading exiftool output: %s", err)
+		}
+		key, _ := t.(string)
+		var v interface{}
+		if err = dec.Decode(&v); err != nil {
+			return e, fmt.Errorf("reading exiftool output: %s", err)
+		}
+		i := strings.Index(key, ":")
+		if i < 0 {
+			// SourceFile, which we know.
+			continue
+		}
+		e.setJSON(key[:i], key[i+1:], v)
+	}
+	return e, nil
+}
+
+// setJSON sets the tag in exiftool's group to the JSON value v.
+func (e *exif) setJSON(group, tag string, v interface{}) {
+	switch v := v.(type) {
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = jsonText(item)
+		}
+		e.set(group, tag, items...)
+		if _, ok := e.Lists[group+":"+tag]; !ok {
+			// A list of one is still a list.
+			e.Lists[group+":"+tag] = items
+		}
+	case map[string]interface{}:
+		if _, ok := v[langDefault]; ok {
+			langs := make([]string, 0, len(v))
+			for lang := range v {
+				langs = append(langs, lang)
+			}
+			sort.Strings(langs)
+			for _, lang := range langs {
+				name := tag
+				if lang != langDefault {
+					name += "-" + lang
+				}
+				e.set(group, name, jsonText(v[lang]))
+			}
+			return
+		}
+		e.set(group, tag, jsonText(v))
+	default:
+		e.set(group, tag, jsonText(v))
+	}
+}
+
+// jsonText returns a JSON value as text: strings as they are, decoded from
+// base64 if exiftool had to encode them, numbers as written and anything
+// else as JSON.
+func jsonText(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		if strings.HasPrefix(v, base64Prefix) {
+			if b, err := base64.StdEncoding.DecodeString(v[len(base64Prefix):]); err == nil {
+				return string(b)
+			}
+		}
+		return v
+	case json.Number:
+		return v.String()
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestParseExifJSON(t *testing.T) {
+	e, err := parseExifOutput(`[{
+  "SourceFile": "a.jpg",
+  "ExifTool:Warning": "Bad IPTC data",
+  "ExifTool:Warning": "[minor] Fixed incorrect URI for xmlns:MicrosoftPhoto",
+  "File:FileType": "JPEG",
+  "EXIF:FNumber": 2.8,
+  "IPTC:ObjectName": 1969,
+  "IPTC:Keywords": ["launch", "Cape Canaveral, Florida"],
+  "IPTC:Caption-Abstract": "The first line\nand the second",
+  "XMP:Title": {"x-default": "Launch", "de": "Start"},
+  "XMP:LocationCreated": {"City": "Cape Canaveral"},
+  "XMP:ACaseOfAVeryLongTagNameIndeedLongerThanTheColumn": "fits"
+}]`)
+	equals(t, err, nil)
+	equals(t, e.Warnings(), []string{"Bad IPTC data", "[minor] Fixed incorrect URI for xmlns:MicrosoftPhoto"})
+	equals(t, e.Data["FileType"], "JPEG")
+	equals(t, e.Exif["FNumber"], "2.8")
+	equals(t, e.IPTC["ObjectName"], "1969")
+	equals(t, e.Keywords(), "launch, Cape Canaveral, Florida")
+	equals(t, e.Lists["IPTC:Keywords"], []string{"launch", "Cape Canaveral, Florida"})
+	equals(t, e.Description(), "The first line\nand the second")
+	equals(t, e.XMP["Title"], "Launch")
+	equals(t, e.XMP["Title-de"], "Start")
+	equals(t, e.XMP["LocationCreated"], `{"City":"Cape Canaveral"}`)
+	equals(t, e.XMP["ACaseOfAVeryLongTagNameIndeedLongerThanTheColumn"], "fits")
+	_, ok := e.Data["SourceFile"]
+	equals(t, ok, false)
+
+	_, err = parseExifOutput(`[{"SourceFile": "a.jpg",`)
+	equals(t, err.Error(), "reading exiftool output: unexpected end of JSON input")
+}
+
+func TestMultiValued(t *testing.T) {
+	e, err := parseExifOutput(`[{
+  "SourceFile": "a.jpg",
+  "IPTC:By-line": "Bill Ingalls",
+  "IPTC:By-line": "Joel Kowsky",
+  "IPTC:By-line": "Bill Ingalls",
+  "IPTC:Keywords": ["Artemis"],
+  "IPTC:Keywords": ["Cape Canaveral, Florida", "Artemis"],
+  "XMP:Title": "Launch",
+  "File:ImageWidth": 160,
+  "File:ImageWidth": 1920
+}]`)
+	equals(t, err, nil)
+	equals(t, e.Photographer(), "Bill Ingalls, Joel Kowsky")
+	equals(t, e.Values("IPTC", "By-line"), []string{"Bill Ingalls", "Joel Kowsky"})
+	equals(t, e.Keywords(), "Artemis, Cape Canaveral, Florida")
+	equals(t, e.KeywordList(), []string{"Artemis", "Cape Canaveral, Florida"})
+	equals(t, e.Values("XMP", "Title"), []string{"Launch"})
+	equals(t, e.Values("XMP", "Subject"), []string(nil))
+	equals(t, e.Data["ImageWidth"], "1920")
+
+	// Once something else sets the tag, the list no longer counts.
+	e.IPTC["Keywords"] = "Artemis; Orion"
+	equals(t, e.Values("IPTC", "Keywords"), []string{"Artemis; Orion"})
+	equals(t, e.KeywordList(), []string{"Artemis", "Orion"})
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// exiftoolArgs are the options we run exiftool with, before the path. Asking
+// for CurrentIPTCDigest, which isn't extracted unless asked for, means asking
+// for -all of the rest too. The IPTC is left for decodeIPTC.
+var exiftoolArgs = append(append(outputArgs(), iptcCharsetArgs...), "-all", "-CurrentIPTCDigest")
+
+// outputArgs are the options for the output parseExifOutput reads: JSON,
+// with each tag's group, structures as they are and every tag, duplicates
+// included.
+func outputArgs() []string {
+	return []string{"-j", "-G", "-struct", "-a"}
+}
+
+// errTimeout is the error for a file exiftool took longer than -timeout on.
+var errTimeout = errors.New("extraction timeout")
+
+// errExited is the error for a file exiftool exited, or crashed, on.
+var errExited = errors.New("exiftool exited")
+
+// ready is what exiftool prints when it has finished a -stay_open command.
+const ready = "{ready}"
+
+// poolConfig is the exiftool section of the config. Each processFiles
+// goroutine's exiftool is recycled, closed and started afresh, after
+// MaxFiles files or once it's using more than MaxMemoryMB of memory, so
+// perl's slow growth doesn't build up over a long -watch. 0 uses the
+// default and a negative number turns the check off. Tags is which tags are
+// extracted, tagsAll or tagsFields, with ExtraTags as well for tagsFields.
+// A file failing with a transient error is tried again Retries times, the
+// first after RetryBackoffMS, see retryExtract, with 0 the default and a
+// negative number none or no wait.
+type poolConfig struct {
+	MaxFiles       int      `yaml:"max_files"`
+	MaxMemoryMB    int      `yaml:"max_memory_mb"`
+	Tags           string   `yaml:"tags"`
+	ExtraTags      []string `yaml:"extra_tags"`
+	Retries        int      `yaml:"retries"`
+	RetryBackoffMS int      `yaml:"retry_backoff_ms"`
+}
+
+// defaultPool is the poolConfig used for anything not in the config.
+var defaultPool = poolConfig{MaxFiles: 10000, MaxMemoryMB: 512, Retries: 2, RetryBackoffMS: 500}
+
+// withDefaults returns pc with the defaults filled in.
+func (pc poolConfig) withDefaults() poolConfig {
+	if pc.MaxFiles == 0 {
+		pc.MaxFiles = defaultPool.MaxFiles
+	}
+	if pc.MaxMemoryMB == 0 {
+		pc.MaxMemoryMB = defaultPool.MaxMemoryMB
+	}
+	if pc.Retries == 0 {
+		pc.Retries = defaultPool.Retries
+	}
+	if pc.RetryBackoffMS == 0 {
+		pc.RetryBackoffMS = defaultPool.RetryBackoffMS
+	}
+	return pc
+}
+
+// poolStats counts what the exiftools of all the processFiles goroutines
+// have done: how many were started, recycled, killed for taking longer than
+// the timeout, or died on their own.
+type poolStats struct {
+	Started  int32
+	Recycled int32
+	Wedged   int32
+	Died     int32
+}
+
+func (ps *poolStats) String() string {
+	return fmt.Sprintf("%d started, %d recycled, %d wedged, %d died",
+		atomic.LoadInt32(&ps.Started), atomic.LoadInt32(&ps.Recycled),
+		atomic.LoadInt32(&ps.Wedged), atomic.LoadInt32(&ps.Died))
+}
+
+// exiftool is a running `exiftool -stay_open True -@ -`. Arguments are
+// written one per line to its stdin followed by -execute, and the output is
+// everything up to the {ready} line. Starting perl and loading exiftool is
+// most of the cost of running exiftool on a file, so keeping one open per
+// processFiles goroutine makes each file a round trip on the pipes.
+//
+// It looks after itself: a file taking longer than timeout, if it's set,
+// kills it, and it's started again if it dies or is due recycling.
+type exiftool struct {
+	args []string
+	// tags are the options each file is extracted with.
+	tags    []string
+	timeout time.Duration
+	pool    poolConfig
+	stats   *poolStats
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr *bufio.Reader
+	files  int
+}
+
+// newExiftool starts an exiftool in -stay_open mode, which extracts with the
+// options args, counting what it does in stats.
+func newExiftool(args []string, timeout time.Duration, pool poolConfig, stats *poolStats) (*exiftool, error) {
+	et := &exiftool{
+		args:    []string{"exiftool", "-stay_open", "True", "-@", "-"},
+		tags:    args,
+		timeout: timeout,
+		pool:    pool.withDefaults(),
+		stats:   stats,
+	}
+	if err := et.start(); err != nil {
+		return nil, err
+	}
+	return et, nil
+}
+
+// start starts the exiftool process.
+func (et *exiftool) start() error {
+	cmd := detach(exec.Command(et.args[0], et.args[1:]...))
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	et.cmd, et.stdin = cmd, stdin
+	et.stdout, et.stderr = bufio.NewReader(stdout), bufio.NewReader(stderr)
+	et.files = 0
+	atomic.AddInt32(&et.stats.Started, 1)
+	return nil
+}
+
+// restart starts exiftool again after it failed on a file with err, which
+// it returns, noting if the restart failed too.
+func (et *exiftool) restart(err error) error {
+	if serr := et.start(); serr != nil {
+		return fmt.Errorf("%s, and restarting exiftool failed: %s", err, serr)
+	}
+	return err
+}
+
+// Extract runs exiftool on p and parses the output into an exif struct. If
+// it takes longer than the timeout the error is errTimeout, and exiftool is
+// killed and restarted for the next file. It's restarted too if it dies,
+// and recycled when it's worn.
+func (et *exiftool) Extract(p string) (exif, error) {
+	e, err := et.extractWithin(p)
+	switch err {
+	case errTimeout:
+		atomic.AddInt32(&et.stats.Wedged, 1)
+		return e, et.restart(err)
+	case errExited:
+		et.cmd.Wait()
+		atomic.AddInt32(&et.stats.Died, 1)
+		return e, et.restart(err)
+	}
+	et.files++
+	if et.worn() {
+		atomic.AddInt32(&et.stats.Recycled, 1)
+		if cerr := et.Close(); cerr != nil {
+			et.cmd.Process.Kill()
+		}
+		if serr := et.start(); serr != nil {
+			log.Printf("Error restarting exiftool: %s", serr)
+		}
+	}
+	return e, err
+}
+
+// worn is whether exiftool is due recycling, having checked MaxFiles files
+// or grown past MaxMemoryMB.
+func (et *exiftool) worn() bool {
+	if et.pool.MaxFiles > 0 && et.files >= et.pool.MaxFiles {
+		return true
+	}
+	if et.pool.MaxMemoryMB > 0 {
+		rss, ok := processMemory(et.cmd.Process.Pid)
+		return ok && rss > uint64(et.pool.MaxMemoryMB)<<20
+	}
+	return false
+}
+
+// processMemory returns the resident memory of process pid in bytes. It's
+// read from /proc, so on systems without it ok is false.
+func processMemory(pid int) (rss uint64, ok bool) {
+	b, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		var kb uint64
+		if _, err := fmt.Sscanf(line, "VmRSS: %d kB", &kb); err == nil {
+			return kb << 10, true
+		}
+	}
+	return 0, false
+}
+
+// extractWithin runs extract, giving up with errTimeout and killing
+// exiftool if it takes longer than the timeout.
+func (et *exiftool) extractWithin(p string) (exif, error) {
+	if et.timeout <= 0 {
+		return et.extract(p)
+	}
+	type result struct {
+		e   exif
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		e, err := et.extract(p)
+		done <- result{e, err}
+	}()
+	timer := time.NewTimer(et.timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.e, r.err
+	case <-timer.C:
+	}
+	// Killing exiftool ends the reads extract is blocked in.
+	if err := et.cmd.Process.Kill(); err != nil {
+		return newExif(), err
+	}
+	<-done
+	et.cmd.Wait()
+	return newExif(), errTimeout
+}
+
+// extract does the work of Extract. exiftool has no exit status in
+// -stay_open mode, so we have it echo {ready} to stderr as well and treat any
+// Error lines before it as failure.
+func (et *exiftool) extract(p string) (exif, error) {
+	if strings.ContainsAny(p, "\r\n") {
+		return newExif(), fmt.Errorf("can't pass a path containing a newline to exiftool: %q", p)
+	}
+	args := append(append([]string{}, et.tags...), exiftoolPath(p), "-echo4", ready, "-execute")
+	// Failing to talk to exiftool means it's gone.
+	if _, err := io.WriteString(et.stdin, strings.Join(args, "\n")+"\n"); err != nil {
+		return newExif(), errExited
+	}
+	out, err := readUntilReady(et.stdout)
+	if err != nil {
+		return newExif(), errExited
+	}
+	diag, err := readUntilReady(et.stderr)
+	if err != nil {
+		return newExif(), errExited
+	}
+	for _, line := range strings.Split(diag, "\n") {
+		if strings.HasPrefix(line, "Error") {
+			return newExif(), errors.New(strings.TrimSpace(line))
+		}
+	}
+	return parseExifOutput(out)
+}
+
+// Close asks exiftool to exit and waits for it to do so.
+func (et *exiftool) Close() error {
+	if _, err := io.WriteString(et.stdin, "-stay_open\nFalse\n"); err != nil {
+		return err
+	}
+	if err := et.stdin.Close(); err != nil {
+		return err
+	}
+	return et.cmd.Wait()
+}
+
+// readUntilReady reads lines from r up to the {ready} line.
+func readUntilReady(r *bufio.Reader) (string, error) {
+	var out strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if strings.TrimSpace(line) == ready {
+			return out.String(), nil
+		}
+		out.WriteString(line)
+		if err != nil {
+			return out.String(), err
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadUntilReady(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("one\ntwo\n{ready}\nthree\n{ready}\n"))
+	out, err := readUntilReady(r)
+	equals(t, err, nil)
+	equals(t, out, "one\ntwo\n")
+	out, err = readUntilReady(r)
+	equals(t, err, nil)
+	equals(t, out, "three\n")
+}
+
+func TestExiftoolExtract(t *testing.T) {
+	if !haveExiftool() {
+		t.Skip("exiftool isn't installed")
+	}
+	et, err := newExiftool(exiftoolArgs, 0, poolConfig{}, &poolStats{})
+	equals(t, err, nil)
+	e, err := et.Extract("image.jpg")
+	equals(t, err, nil)
+	want, err := getExifData("image.jpg", exiftoolArgs, 0)
+	equals(t, err, nil)
+	equals(t, e, want)
+
+	e, err = et.Extract("noimage.jpg")
+	equals(t, e, newExif())
+	equals(t, err.Error(), "Error: File not found - noimage.jpg")
+	equals(t, et.Close(), nil)
+}
+
+func TestExiftoolTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("no sleep to stand in for a hung exiftool")
+	}
+	// sleep never answers, like an exiftool stuck on a file.
+	stats := &poolStats{}
+	et := &exiftool{args: []string{"sleep", "60"}, timeout: 50 * time.Millisecond, stats: stats}
+	equals(t, et.start(), nil)
+	pid := et.cmd.Process.Pid
+	e, err := et.Extract("image.jpg")
+	equals(t, e, newExif())
+	equals(t, err, errTimeout)
+	equals(t, et.cmd.Args, []string{"sleep", "60"})
+	equals(t, et.cmd.Process.Pid != pid, true)
+	equals(t, stats.String(), "2 started, 0 recycled, 1 wedged, 0 died")
+	et.cmd.Process.Kill()
+	et.cmd.Wait()
+}
+
+func TestExiftoolDied(t *testing.T) {
+	if _, err := exec.LookPath("true"); err != nil {
+		t.Skip("no true to stand in for a crashing exiftool")
+	}
+	// true exits straight away, like an exiftool crashing on a file.
+	stats := &poolStats{}
+	et := &exiftool{args: []string{"true"}, stats: stats}
+	equals(t, et.start(), nil)
+	_, err := et.Extract("image.jpg")
+	equals(t, err, errExited)
+	equals(t, stats.String(), "2 started, 0 recycled, 0 wedged, 1 died")
+	et.cmd.Wait()
+}
+
+func TestExiftoolWorn(t *testing.T) {
+	et := &exiftool{pool: poolConfig{MaxFiles: 2, MaxMemoryMB: -1}}
+	et.files = 1
+	equals(t, et.worn(), false)
+	et.files = 2
+	equals(t, et.worn(), true)
+	equals(t, poolConfig{MaxFiles: -1}.withDefaults(), poolConfig{MaxFiles: -1, MaxMemoryMB: 512, Retries: 2, RetryBackoffMS: 500})
+
+	// This process is well over 1MB.
+	et = &exiftool{pool: poolConfig{MaxFiles: -1, MaxMemoryMB: 1}, cmd: exec.Command("self")}
+	et.cmd.Process = &os.Process{Pid: os.Getpid()}
+	if _, ok := processMemory(os.Getpid()); !ok {
+		t.Skip("no /proc to read memory from")
+	}
+	equals(t, et.worn(), true)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The exit statuses of a run, for CI. -object has its own, by Status.
+const (
+	exitOK = 0
+	// exitStopped is for a run that couldn't start, or was interrupted or
+	// used its egress budget and so only checked some of the files. -watch
+	// and -serve run until they're interrupted, so for them that's exitOK.
+	exitStopped = 1
+	// exitRejects is for a run with more Rejected and Incomplete files than
+	// -fail-on-reject or -max-reject-rate allow.
+	exitRejects = 2
+)
+
+// percent is a percentage, as a flag.Value taking e.g. 5% or 2.5. It's
+// negative when it isn't set.
+type percent float64
+
+func (pc *percent) Set(s string) error {
+	n, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || n < 0 || n > 100 {
+		return fmt.Errorf("not a percentage like 5%%: %q", s)
+	}
+	*pc = percent(n)
+	return nil
+}
+
+func (pc percent) String() string {
+	if pc < 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(pc), 'f', -1, 64) + "%"
+}
+
+// tooManyRejects returns why the run has more Rejected and Incomplete files
+// than failOnReject or maxRate allow, or "" if it doesn't.
+func tooManyRejects(failOnReject bool, maxRate percent, stats *statistics) string {
+	checked := stats.Accept + stats.Reject
+	switch {
+	case stats.Reject == 0:
+		return ""
+	case failOnReject:
+		return fmt.Sprintf("%d files Rejected or Incomplete", stats.Reject)
+	case maxRate >= 0 && checked > 0:
+		rate := 100 * float64(stats.Reject) / float64(checked)
+		if rate > float64(maxRate) {
+			return fmt.Sprintf("%.1f%% of files Rejected or Incomplete, more than %s", rate, maxRate)
+		}
+	}
+	return ""
+}
+
+// stoppedEarly returns whether a run that was interrupted, or used its
+// egress budget, stopped before checking all the files. -watch only ends
+// when it's interrupted, so that's it finishing, unless its budget ran out.
+func stoppedEarly(interrupted, watch, budgetUsed bool) bool {
+	return budgetUsed || interrupted && !watch
+}
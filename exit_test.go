@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestPercent(t *testing.T) {
+	values := []struct {
+		in, want string
+		pc       percent
+	}{
+		{"5%", "5%", 5},
+		{"2.5", "2.5%", 2.5},
+		{" 0% ", "0%", 0},
+	}
+	for _, v := range values {
+		var pc percent
+		equals(t, pc.Set(v.in), nil)
+		equals(t, pc, v.pc)
+		equals(t, pc.String(), v.want)
+	}
+	var pc percent
+	equals(t, pc.Set("lots").Error(), `not a percentage like 5%: "lots"`)
+	equals(t, pc.Set("150%").Error(), `not a percentage like 5%: "150%"`)
+	equals(t, percent(-1).String(), "")
+}
+
+func TestTooManyRejects(t *testing.T) {
+	values := []struct {
+		fail           bool
+		rate           percent
+		accept, reject int32
+		want           string
+	}{
+		{false, -1, 90, 10, ""},
+		{true, -1, 100, 0, ""},
+		{true, -1, 99, 1, "1 files Rejected or Incomplete"},
+		{false, 10, 90, 10, ""},
+		{false, 5, 90, 10, "10.0% of files Rejected or Incomplete, more than 5%"},
+		{false, 0, 0, 0, ""},
+	}
+	for _, v := range values {
+		stats := &statistics{Accept: v.accept, Reject: v.reject}
+		equals(t, tooManyRejects(v.fail, v.rate, stats), v.want)
+	}
+}
+
+func TestStoppedEarly(t *testing.T) {
+	values := []struct {
+		interrupted, watch, budgetUsed bool
+		want                           bool
+	}{
+		{false, false, false, false},
+		{true, false, false, true},
+		{true, false, true, true},
+		{true, true, false, false},
+		{true, true, true, true},
+		{false, true, false, false},
+	}
+	for _, v := range values {
+		equals(t, stoppedEarly(v.interrupted, v.watch, v.budgetUsed), v.want)
+	}
+}
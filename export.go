@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultExportLayout is where -export copies each accepted file to.
+const defaultExportLayout = "{{.Center}}/{{.Year}}/{{.NasaID}}{{.Ext}}"
+
+// exportData is what the -export-layout template is executed with. Empty
+// values are "Unknown" so they still make a directory.
+type exportData struct {
+	NasaID    string
+	Title     string
+	Center    string
+	Year      string
+	Month     string
+	MediaType string
+	Delivery  string
+	Name      string
+	Ext       string
+}
+
+// exporter is a rowWriter copying each accepted file to the path from its
+// layout template under root, verifying the copy's checksum.
+type exporter struct {
+	root     string
+	dirs     dirList
+	layout   *template.Template
+	download func(string, io.Writer) error
+	exported int
+	failed   int
+}
+
+// newExporter returns an exporter to root with the layout template. dirs are
+// the -d being processed, which delivery folders are relative to.
+func newExporter(root, layout string, dirs ...string) (*exporter, error) {
+	t, err := template.New("layout").Option("missingkey=error").Parse(layout)
+	if err != nil {
+		return nil, err
+	}
+	return &exporter{root: root, dirs: dirs, layout: t}, nil
+}
+
+// layoutValue cleans v to be a single path element.
+func layoutValue(v string) string {
+	v = strings.TrimSpace(strings.NewReplacer("/", "-", `\`, "-").Replace(v))
+	if v == "" || v == "." || v == ".." {
+		return "Unknown"
+	}
+	return v
+}
+
+// target returns where the row's file goes.
+func (x *exporter) target(row []string) (string, error) {
+	p := row[column("Path")]
+	d := exportData{
+		NasaID:    layoutValue(row[column("NASA ID")]),
+		Title:     layoutValue(row[column("Title")]),
+		Center:    layoutValue(row[column("Center")]),
+		Year:      "Unknown",
+		Month:     "Unknown",
+		MediaType: layoutValue(row[column("Media Type")]),
+		Delivery:  layoutValue(deliveryOf(x.dirs.rootOf(p), p)),
+		Name:      filepath.Base(p),
+		Ext:       strings.ToLower(filepath.Ext(p)),
+	}
+	if t, err := time.Parse(time.RFC3339, row[column("Date Created")]); err == nil {
+		d.Year, d.Month = t.Format("2006"), t.Format("01")
+	}
+	var b bytes.Buffer
+	if err := x.layout.Execute(&b, d); err != nil {
+		return "", err
+	}
+	rel := filepath.Clean(filepath.FromSlash(b.String()))
+	if rel == "." || filepath.IsAbs(rel) || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("layout gives %q, which isn't under %s", b.String(), x.root)
+	}
+	return filepath.Join(x.root, rel), nil
+}
+
+// Write copies the row's file if it was accepted. Errors are counted and
+// returned so makeOutput logs them, but don't stop the run.
+func (x *exporter) Write(row []string) error {
+	if !accepted(row[column("Status")]) {
+		return nil
+	}
+	err := x.export(row)
+	if err != nil {
+		x.failed++
+		return fmt.Errorf("exporting: %s", err)
+	}
+	x.exported++
+	return nil
+}
+
+func (x *exporter) export(row []string) error {
+	to, err := x.target(row)
+	if err != nil {
+		return err
+	}
+	src := row[column("Path")]
+	if err = os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(to), ".chkmd-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	err = x.copy(src, io.MultiWriter(tmp, h))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	sum := h.Sum(nil)
+	if got, err := fileSum(tmp.Name()); err != nil {
+		return err
+	} else if !bytes.Equal(got, sum) {
+		return fmt.Errorf("checksum of the copy of %s doesn't match", src)
+	}
+	if existing, err := fileSum(to); err == nil {
+		if bytes.Equal(existing, sum) {
+			return nil
+		}
+		return fmt.Errorf("%s already exists with different content than %s", to, src)
+	}
+	return os.Rename(tmp.Name(), to)
+}
+
+// copy writes the file at p, which may be on S3 or in an archive, to w.
+func (x *exporter) copy(p string, w io.Writer) error {
+	if isObject(p) {
+		if x.download == nil {
+			return fmt.Errorf("can't read %s", p)
+		}
+		return x.download(p, w)
+	}
+	if isArchiveMember(p) {
+		return readArchiveMember(p, w)
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// fileSum returns the SHA-256 of the file at p.
+func fileSum(p string) ([]byte, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func exportRow(p, id, center, date string) []string {
+	row := testRow(p, "Accepted", "")
+	row[column("NASA ID")], row[column("Center")], row[column("Date Created")] = id, center, date
+	return row
+}
+
+func TestExporterTarget(t *testing.T) {
+	x, err := newExporter("/staging", defaultExportLayout, "/media")
+	equals(t, err, nil)
+	got, err := x.target(exportRow("/media/a/IMG_1.JPG", "KSC-1", "KSC", "2015-06-01T10:00:00Z"))
+	equals(t, err, nil)
+	equals(t, got, filepath.FromSlash("/staging/KSC/2015/KSC-1.jpg"))
+
+	got, err = x.target(exportRow("/media/a/IMG_1.jpg", "../KSC-1", "", ""))
+	equals(t, err, nil)
+	equals(t, got, filepath.FromSlash("/staging/Unknown/Unknown/..-KSC-1.jpg"))
+
+	x, err = newExporter("/staging", "../{{.Name}}", "/media")
+	equals(t, err, nil)
+	_, err = x.target(exportRow("/media/a/IMG_1.jpg", "KSC-1", "", ""))
+	equals(t, err.Error(), `layout gives "../IMG_1.jpg", which isn't under /staging`)
+
+	_, err = newExporter("/staging", "{{.Center", "/media")
+	equals(t, err != nil, true)
+}
+
+func TestExporterWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "media", "a", "IMG_1.jpg")
+	os.MkdirAll(filepath.Dir(src), 0755)
+	ioutil.WriteFile(src, []byte("jpeg"), 0644)
+
+	x, err := newExporter(filepath.Join(dir, "out"), "{{.Delivery}}/{{.NasaID}}{{.Ext}}", filepath.Join(dir, "media"))
+	equals(t, err, nil)
+	row := exportRow(src, "KSC-1", "KSC", "")
+	equals(t, x.Write(row), nil)
+	b, err := ioutil.ReadFile(filepath.Join(dir, "out", "a", "KSC-1.jpg"))
+	equals(t, err, nil)
+	equals(t, string(b), "jpeg")
+
+	// The same file again is fine, a different one isn't.
+	equals(t, x.Write(row), nil)
+	ioutil.WriteFile(src, []byte("other"), 0644)
+	equals(t, x.Write(row) != nil, true)
+	equals(t, x.exported, 2)
+	equals(t, x.failed, 1)
+
+	equals(t, x.Write(testRow(src, "Incomplete", "")), nil)
+	equals(t, x.exported, 2)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sourceGroups are the groups a fields source may name, as in -trace-field.
+// Composite and File are both exiftool's Data.
+var sourceGroups = map[string]bool{
+	"IPTC":          true,
+	"Exif":          true,
+	"XMP":           true,
+	"ID3":           true,
+	"RIFF":          true,
+	"PDF":           true,
+	"Model":         true,
+	"File":          true,
+	"Composite":     true,
+	"metadata.yaml": true,
+	folderGroup:     true,
+	"filename":      true,
+}
+
+// fieldMapping is the fields section of the config. It lists, for any field,
+// the group:tag sources to try in order instead of those in fieldSources, e.g.
+//
+//	fields:
+//	  NasaID: [IPTC:JobID, XMP:Identifier, File:FileName]
+//
+// A source named as in fieldSources for the field, like NasaID's
+// File:FileName, which drops the extension, works the same way it does
+// there. A source may also be script:name, a function in the scripts
+// deriving the value, see scriptSource. GPS can't be mapped; Location uses City, State and
+// Country.
+type fieldMapping map[string][]string
+
+// sources returns the sources for each mapped field, finding any script:
+// sources in sc.
+func (fm fieldMapping) sources(sc scripts) (map[string][]source, error) {
+	if len(fm) == 0 {
+		return nil, nil
+	}
+	var fields []string
+	for f := range fm {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	mapped := map[string][]source{}
+	for _, f := range fields {
+		if _, ok := fieldSources[f]; !ok || f == "GPS" {
+			return nil, fmt.Errorf("fields: can't map unknown field %q", f)
+		}
+		if len(fm[f]) == 0 {
+			return nil, fmt.Errorf("fields: %s has no sources", f)
+		}
+		for _, name := range fm[f] {
+			s, err := parseSource(f, name, sc)
+			if err != nil {
+				return nil, fmt.Errorf("fields: %s: %s", f, err)
+			}
+			mapped[f] = append(mapped[f], s)
+		}
+	}
+	return mapped, nil
+}
+
+// validate checks every field and source is one we know.
+func (fm fieldMapping) validate(sc scripts) error {
+	_, err := fm.sources(sc)
+	return err
+}
+
+// parseSource returns the source for a group:tag name, or a script:name
+// function in sc, for field.
+func parseSource(field, name string, sc scripts) (source, error) {
+	if strings.HasPrefix(name, scriptPrefix) {
+		return scriptSource(sc, strings.TrimPrefix(name, scriptPrefix))
+	}
+	for _, s := range fieldSources[field] {
+		if s.name == name {
+			return s, nil
+		}
+	}
+	i := strings.Index(name, ":")
+	if i <= 0 || i == len(name)-1 {
+		return source{}, fmt.Errorf("%q isn't a group:tag like XMP:Title", name)
+	}
+	if !sourceGroups[name[:i]] {
+		return source{}, fmt.Errorf("unknown group %q in %q", name[:i], name)
+	}
+	return tagSource(name[:i], name[i+1:]), nil
+}
+
+// sources returns where e looks for field's value, in order: the config's
+// mapping if it has one for field, or fieldSources, less the -lang sources
+// without -lang.
+func (e exif) sources(field string) []source {
+	if s, ok := e.fields[field]; ok {
+		return s
+	}
+	if e.lang != "" {
+		return fieldSources[field]
+	}
+	var sources []source
+	for _, s := range fieldSources[field] {
+		if !strings.HasSuffix(s.name, langSuffix) {
+			sources = append(sources, s)
+		}
+	}
+	return sources
+}
+
+// field returns field's value: the first of its sources, see sources, that
+// e has. The accessors read it, so what -trace-field shows is what they do.
+func (e exif) field(field string) string {
+	for _, s := range e.sources(field) {
+		if v := s.get(e); v != "" {
+			return v
+		}
+	}
+	return ""
+}
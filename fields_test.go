@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestFieldMappingValidate(t *testing.T) {
+	values := []struct {
+		fm  fieldMapping
+		err string
+	}{
+		{nil, ""},
+		{fieldMapping{"NasaID": {"IPTC:JobID", "File:FileName"}, "City": {"XMP:City"}}, ""},
+		{fieldMapping{"Caption": {"IPTC:Caption-Abstract"}}, `fields: can't map unknown field "Caption"`},
+		{fieldMapping{"GPS": {"Exif:GPSLatitude"}}, `fields: can't map unknown field "GPS"`},
+		{fieldMapping{"Title": {}}, "fields: Title has no sources"},
+		{fieldMapping{"Title": {"ObjectName"}}, `fields: Title: "ObjectName" isn't a group:tag like XMP:Title`},
+		{fieldMapping{"Title": {"QuickTime:Title"}}, `fields: Title: unknown group "QuickTime" in "QuickTime:Title"`},
+		{fieldMapping{"Title": {"script:title"}}, "fields: Title: there's no function title in scripts"},
+	}
+	for _, v := range values {
+		err := v.fm.validate(nil)
+		if v.err == "" {
+			equals(t, err, nil)
+		} else {
+			equals(t, err.Error(), v.err)
+		}
+	}
+}
+
+func TestFieldMapping(t *testing.T) {
+	r, err := newRunner(options{}, config{Fields: fieldMapping{
+		"NasaID":  {"XMP:Identifier", "File:FileName"},
+		"Title":   {"XMP:Title", "IPTC:ObjectName"},
+		"Country": {"XMP:Country"},
+	}})
+	equals(t, err, nil)
+	extract := r.configure(func(p string) (exif, error) {
+		e := newExif()
+		e.Data["FileName"] = "jsc2015e012345.jpg"
+		e.IPTC["JobID"] = "From IPTC"
+		e.IPTC["ObjectName"] = "IPTC Title"
+		e.XMP["Title"] = "XMP Title"
+		e.IPTC["City"] = "Houston"
+		e.IPTC["Country-PrimaryLocationName"] = "United States"
+		e.IPTC["By-line"] = "A Photographer"
+		return e, nil
+	})
+	e, err := extract("jsc2015e012345.jpg")
+	equals(t, err, nil)
+	// Mapped fields use only the config's sources, in its order.
+	equals(t, e.NasaID(), "jsc2015e012345")
+	equals(t, e.Title(), "XMP Title")
+	equals(t, e.Location(), "Houston")
+	// The rest keep the built in chain.
+	equals(t, e.Photographer(), "A Photographer")
+
+	equals(t, trace("a.jpg", e, "Title", nil), `a.jpg Title:
+  1. XMP:Title = "XMP Title"  <- used
+  2. IPTC:ObjectName = "IPTC Title"
+`)
+}
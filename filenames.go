@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// filenameWarning starts the Metadata Warnings note for a path the CDN sync can't copy:
+// one that isn't UTF-8 or that has a name Windows can't have.
+const filenameWarning = "FILENAME_ENCODING"
+
+// illegalNameChars can't be in a Windows file name, nor can control
+// characters.
+const illegalNameChars = `<>:"\|?*`
+
+// reservedNames are Windows device names, which can't be file names whatever
+// their extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// illegalNameChar is whether c can't be in a file name.
+func illegalNameChar(c rune) bool {
+	return c < 0x20 || c == 0x7f || strings.ContainsRune(illegalNameChars, c)
+}
+
+// reservedName is whether name is a device name, with any extension.
+func reservedName(name string) bool {
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+	return reservedNames[strings.ToUpper(name)]
+}
+
+// nameProblems returns what's wrong with the path element name.
+func nameProblems(name string) []string {
+	var problems []string
+	if !utf8.ValidString(name) {
+		problems = append(problems, "invalid UTF-8")
+	}
+	if strings.IndexFunc(name, illegalNameChar) >= 0 {
+		problems = append(problems, "illegal characters")
+	}
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		problems = append(problems, "ends in a dot or space")
+	}
+	if reservedName(name) {
+		problems = append(problems, "reserved name")
+	}
+	return problems
+}
+
+// sanitizeName returns name fixed. Bytes that aren't UTF-8 are taken to be
+// Latin-1, as names from old systems usually are, illegal characters become
+// _, trailing dots and spaces are dropped and reserved names get a _.
+func sanitizeName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); {
+		c, size := utf8.DecodeRuneInString(name[i:])
+		if c == utf8.RuneError && size == 1 {
+			c = rune(name[i])
+		}
+		if illegalNameChar(c) {
+			c = '_'
+		}
+		b.WriteRune(c)
+		i += size
+	}
+	fixed := strings.TrimRight(b.String(), ". ")
+	if fixed == "" && name != "" {
+		fixed = "_"
+	}
+	if reservedName(fixed) {
+		ext := path.Ext(fixed)
+		fixed = strings.TrimSuffix(fixed, ext) + "_" + ext
+	}
+	return fixed
+}
+
+// filenameCheck returns the FILENAME_ENCODING note for the file at p, or ""
+// if its path is fine. The path under root is checked, or for S3 the key,
+// and the note suggests a sanitized one. URLs aren't synced, so aren't
+// checked.
+func filenameCheck(root, p string) string {
+	var rel string
+	switch {
+	case isURL(p):
+		return ""
+	case isObject(p):
+		_, _, rel, _ = parseObjectURI(p)
+	default:
+		var err error
+		rel, err = filepath.Rel(root, p)
+		if root == "" || err != nil || strings.HasPrefix(rel, "..") {
+			rel = filepath.Base(p)
+		}
+		rel = filepath.ToSlash(rel)
+	}
+	var problems []string
+	seen := map[string]bool{}
+	elements := strings.Split(rel, "/")
+	for i, name := range elements {
+		for _, pr := range nameProblems(name) {
+			if !seen[pr] {
+				seen[pr] = true
+				problems = append(problems, pr)
+			}
+		}
+		elements[i] = sanitizeName(name)
+	}
+	if len(problems) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s: %s, suggest %s", filenameWarning, strings.Join(problems, ", "), strings.Join(elements, "/"))
+}
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestSanitizeName(t *testing.T) {
+	values := []struct {
+		name     string
+		problems []string
+		want     string
+	}{
+		{"jsc2015e012345.jpg", nil, "jsc2015e012345.jpg"},
+		{"Bogotá.jpg", nil, "Bogotá.jpg"},
+		// Bogotá in Latin-1.
+		{"Bogot\xe1.jpg", []string{"invalid UTF-8"}, "Bogotá.jpg"},
+		{`launch: day 1?.jpg`, []string{"illegal characters"}, "launch_ day 1_.jpg"},
+		{"tab\there.jpg", []string{"illegal characters"}, "tab_here.jpg"},
+		{"final. ", []string{"ends in a dot or space"}, "final"},
+		{"con.jpg", []string{"reserved name"}, "con_.jpg"},
+		{"Aux", []string{"reserved name"}, "Aux_"},
+		{"console.jpg", nil, "console.jpg"},
+	}
+	for _, v := range values {
+		equals(t, nameProblems(v.name), v.problems)
+		equals(t, sanitizeName(v.name), v.want)
+	}
+}
+
+func TestFilenameCheck(t *testing.T) {
+	values := []struct {
+		root, p, want string
+	}{
+		{"/archive", "/archive/2015/jsc2015e012345.jpg", ""},
+		{"/archive", "/archive/Mission: Apollo/a?.jpg", "FILENAME_ENCODING: illegal characters, suggest Mission_ Apollo/a_.jpg"},
+		{"/archive", "/archive/S\xe3o Paulo/CON.tif", "FILENAME_ENCODING: invalid UTF-8, reserved name, suggest São Paulo/CON_.tif"},
+		// Only the path under root is checked.
+		{"/archive: old", "/archive: old/a.jpg", ""},
+		{"", "/elsewhere/a?.jpg", "FILENAME_ENCODING: illegal characters, suggest a_.jpg"},
+		{"s3://media/ksc", "s3://media/ksc/a|b.jpg", "FILENAME_ENCODING: illegal characters, suggest ksc/a_b.jpg"},
+		{"", "https://example.com/a%3F.jpg?sig=1", ""},
+	}
+	for _, v := range values {
+		equals(t, filenameCheck(v.root, v.p), v.want)
+	}
+}
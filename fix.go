@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// fixField is a field -fix can write: the correction CSV column it comes
+// from, how to tell if the file already has it, and the tags to write it to.
+type fixField struct {
+	column string
+	has    func(exif) bool
+	args   func(v string) ([]string, error)
+}
+
+// assign returns a function making exiftool assignments of a value to tags.
+// If list is true the value is split on commas, like our Keywords column, and
+// each item assigned separately.
+func assign(list bool, tags ...string) func(string) ([]string, error) {
+	return func(v string) ([]string, error) {
+		vals := []string{v}
+		if list {
+			vals = nil
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					vals = append(vals, item)
+				}
+			}
+		}
+		var args []string
+		for _, t := range tags {
+			for _, val := range vals {
+				args = append(args, "-"+t+"="+val)
+			}
+		}
+		return args, nil
+	}
+}
+
+// assignDate writes a date, in any format parseDate understands, to the
+// IPTC, Exif and XMP date created tags in the format each expects.
+func assignDate(v string) ([]string, error) {
+	d, err := parseDate(v)
+	if err != nil {
+		return nil, err
+	}
+	return []string{
+		"-IPTC:DateCreated=" + d.Format(exifDateOnly),
+		"-IPTC:TimeCreated=" + d.Format("15:04:05-07:00"),
+		"-EXIF:DateTimeOriginal=" + d.Format(exifDate),
+		"-XMP-photoshop:DateCreated=" + d.Format(exifDateZone),
+	}, nil
+}
+
+// fixFields are the fields -fix can write, in the order they are written.
+var fixFields = []fixField{
+	{"Title", exif.HasTitle, assign(false, "IPTC:ObjectName", "XMP-dc:Title")},
+	{"Description", exif.HasDescription, assign(false, "IPTC:Caption-Abstract", "EXIF:ImageDescription", "XMP-dc:Description")},
+	{"Date Created", exif.HasDateCreated, assignDate},
+	{"Keywords", exif.HasKeywords, assign(true, "IPTC:Keywords", "XMP-dc:Subject")},
+	{"Photographer", exif.HasPhotographer, assign(false, "IPTC:By-line", "EXIF:Artist", "XMP-dc:Creator")},
+	{"City", exif.HasLocation, assign(false, "IPTC:City", "XMP-photoshop:City")},
+	{"State", exif.HasLocation, assign(false, "IPTC:Province-State", "XMP-photoshop:State")},
+	{"Country", exif.HasLocation, assign(false, "IPTC:Country-PrimaryLocationName", "XMP-photoshop:Country")},
+}
+
+// corrections are the rows of a -fix CSV, keyed on Path and NASA ID. A row
+// may have either or both.
+type corrections struct {
+	byPath  map[string]map[string]string
+	byID    map[string]map[string]string
+	sidecar bool
+}
+
+// readCorrections reads a CSV of corrections. The header names the columns
+// which are Path and/or NASA ID, plus any of the fixFields columns.
+func readCorrections(p string, sidecar bool) (*corrections, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("no header row")
+	}
+	header := records[0]
+	keyed := false
+	for _, h := range header {
+		keyed = keyed || h == "Path" || h == "NASA ID"
+	}
+	if !keyed {
+		return nil, errors.New("needs a Path or NASA ID column")
+	}
+	c := &corrections{
+		byPath:  map[string]map[string]string{},
+		byID:    map[string]map[string]string{},
+		sidecar: sidecar,
+	}
+	for _, rec := range records[1:] {
+		row := map[string]string{}
+		for i, h := range header {
+			if i < len(rec) && rec[i] != "" {
+				row[h] = rec[i]
+			}
+		}
+		if row["Path"] != "" {
+			c.byPath[filepath.Clean(row["Path"])] = row
+		}
+		if row["NASA ID"] != "" {
+			c.byID[row["NASA ID"]] = row
+		}
+	}
+	return c, nil
+}
+
+// lookup finds the correction for the file at p, by path then NASA ID.
+func (c *corrections) lookup(p string, e exif) map[string]string {
+	if row, ok := c.byPath[filepath.Clean(p)]; ok {
+		return row
+	}
+	return c.byID[e.NasaID()]
+}
+
+// args returns the exiftool assignments for the fields e is missing that the
+// correction has, and the names of those fields.
+func (c *corrections) args(row map[string]string, e exif) ([]string, []string, error) {
+	var args, fields []string
+	for _, f := range fixFields {
+		v := row[f.column]
+		if v == "" || f.has(e) {
+			continue
+		}
+		a, err := f.args(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %s", f.column, err)
+		}
+		for _, arg := range a {
+			if !c.sidecar || strings.HasPrefix(arg, "-XMP") {
+				args = append(args, arg)
+			}
+		}
+		fields = append(fields, f.column)
+	}
+	return args, fields, nil
+}
+
+// apply writes any corrections for the file at p that e is missing, then
+// re-extracts it. It returns the new exif and the fields written. Files in
+// archives can't be written to, so they're left alone.
+func (c *corrections) apply(p string, e exif, extract func(string) (exif, error)) (exif, []string, error) {
+	if isArchiveMember(p) {
+		return e, nil, nil
+	}
+	row := c.lookup(p, e)
+	if row == nil {
+		return e, nil, nil
+	}
+	args, fields, err := c.args(row, e)
+	if err != nil || len(args) == 0 {
+		return e, nil, err
+	}
+	if err = c.write(p, args); err != nil {
+		return e, nil, err
+	}
+	fe, err := extract(p)
+	if err != nil {
+		return e, nil, err
+	}
+	return fe, fields, nil
+}
+
+// write runs exiftool to make the assignments in the file at p, or in its
+// sidecar. exiftool keeps a copy of the unmodified file as p_original.
+func (c *corrections) write(p string, args []string) error {
+	target := p
+	if c.sidecar {
+		side := sidecarPath(p)
+		if _, err := os.Stat(side); err == nil {
+			target = side
+		} else {
+			args = append(args, "-o", side)
+		}
+	}
+	out, err := detach(exec.Command("exiftool", append(args, exiftoolPath(target))...)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("exiftool: %s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// sidecarPath returns the XMP sidecar for p, which is p with its extension
+// replaced by .xmp.
+func sidecarPath(p string) string {
+	return strings.TrimSuffix(p, filepath.Ext(p)) + ".xmp"
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestReadCorrections(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "fixes.csv")
+	csv := "Path,NASA ID,Title,Keywords\n./a/img.jpg,,A Title,\n,KSC-01,,\"moon, launch\"\n"
+	equals(t, ioutil.WriteFile(p, []byte(csv), 0644), nil)
+
+	c, err := readCorrections(p, false)
+	equals(t, err, nil)
+	e := newExif()
+	e.Data["FileName"] = "img.jpg"
+	equals(t, c.lookup("a/img.jpg", e), map[string]string{"Path": "./a/img.jpg", "Title": "A Title"})
+	e.IPTC["JobID"] = "KSC-01"
+	equals(t, c.lookup("b/other.jpg", e), map[string]string{"NASA ID": "KSC-01", "Keywords": "moon, launch"})
+	e.IPTC["JobID"] = "KSC-02"
+	equals(t, c.lookup("b/other.jpg", e) == nil, true)
+
+	equals(t, ioutil.WriteFile(p, []byte("Title\nA Title\n"), 0644), nil)
+	_, err = readCorrections(p, false)
+	equals(t, err.Error(), "needs a Path or NASA ID column")
+}
+
+func TestCorrectionArgs(t *testing.T) {
+	row := map[string]string{
+		"Title":        "A Title",
+		"Keywords":     "moon, launch",
+		"Date Created": "2015:01:09 01:32:16+00:00",
+		"Photographer": "Bill Ingalls",
+	}
+	e := newExif()
+	e.IPTC["By-line"] = "Someone Else"
+	c := &corrections{}
+	args, fields, err := c.args(row, e)
+	equals(t, err, nil)
+	equals(t, fields, []string{"Title", "Date Created", "Keywords"})
+	equals(t, args, []string{
+		"-IPTC:ObjectName=A Title",
+		"-XMP-dc:Title=A Title",
+		"-IPTC:DateCreated=2015:01:09",
+		"-IPTC:TimeCreated=01:32:16+00:00",
+		"-EXIF:DateTimeOriginal=2015:01:09 01:32:16",
+		"-XMP-photoshop:DateCreated=2015:01:09 01:32:16+00:00",
+		"-IPTC:Keywords=moon",
+		"-IPTC:Keywords=launch",
+		"-XMP-dc:Subject=moon",
+		"-XMP-dc:Subject=launch",
+	})
+
+	c.sidecar = true
+	args, _, err = c.args(map[string]string{"Title": "A Title"}, e)
+	equals(t, err, nil)
+	equals(t, args, []string{"-XMP-dc:Title=A Title"})
+
+	_, _, err = c.args(map[string]string{"Date Created": "yesterday"}, e)
+	equals(t, err.Error(), `Date Created: parsing time "yesterday" as "2006:01:02": cannot parse "yesterday" as "2006"`)
+}
+
+func TestSidecarPath(t *testing.T) {
+	equals(t, sidecarPath("a/img.CR2"), "a/img.xmp")
+	equals(t, sidecarPath("img"), "img.xmp")
+}
+
+// TestFixInherits checks a file -fix writes to keeps what it inherits from
+// metadata.yaml.
+func TestFixInherits(t *testing.T) {
+	if !haveExiftool() {
+		t.Skip("exiftool isn't installed")
+	}
+	root, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(root)
+	b, err := ioutil.ReadFile("nomd.jpg")
+	equals(t, err, nil)
+	p := filepath.Join(root, "nomd.jpg")
+	equals(t, ioutil.WriteFile(p, b, 0644), nil)
+	equals(t, ioutil.WriteFile(filepath.Join(root, folderMetadataFile),
+		[]byte("center: Kennedy Space Center\nphotographer: Bill Ingalls\n"), 0644), nil)
+	fixes := filepath.Join(root, "fixes.csv")
+	equals(t, ioutil.WriteFile(fixes, []byte("NASA ID,Title\nnomd,A Title\n"), 0644), nil)
+
+	r := newTestRunner(t, "")
+	r.roots = dirList{root}
+	r.inherited = newFolders(root)
+	r.fixes, err = readCorrections(fixes, false)
+	equals(t, err, nil)
+	files := make(chan string, 1)
+	files <- p
+	close(files)
+	rows := make(chan []string, 1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	r.processFiles(context.Background(), files, rows, &statistics{}, &wg)
+	row := <-rows
+	equals(t, row[column("Title")], "A Title")
+	equals(t, row[column("Center")], "Kennedy Space Center")
+	equals(t, row[column("Photographer")], "Bill Ingalls")
+}
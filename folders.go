@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v1"
+)
+
+const (
+	// folderMetadataFile is the name of the optional per directory
+	// metadata, and folderDefaultsFile the hidden one, which sets what it
+	// has over it in the same directory.
+	folderMetadataFile = "metadata.yaml"
+	folderDefaultsFile = ".chkmd.yaml"
+	// folderGroup is the provenance of what's inherited from them.
+	folderGroup = "directory-default"
+)
+
+// folderMetadata is the contents of a metadata.yaml or .chkmd.yaml. Its
+// values are inherited by every file in the directory and below, unless a
+// closer one sets them, and are only used when the file doesn't have the
+// field embedded.
+type folderMetadata struct {
+	Center       string   `yaml:"center"`
+	Album        string   `yaml:"album"`
+	Credit       string   `yaml:"credit"`
+	Photographer string   `yaml:"photographer"`
+	Keywords     []string `yaml:"keywords"`
+}
+
+// values returns the metadata keyed like the exif maps, less those it
+// doesn't set.
+func (fm *folderMetadata) values() map[string]string {
+	md := map[string]string{}
+	for k, v := range map[string]string{
+		"Center":       fm.Center,
+		"Album":        fm.Album,
+		"Credit":       fm.Credit,
+		"Photographer": fm.Photographer,
+		"Keywords":     strings.Join(fm.Keywords, ", "),
+	} {
+		if v != "" {
+			md[k] = v
+		}
+	}
+	return md
+}
+
+// folders reads and caches the metadata.yaml and .chkmd.yaml of each
+// directory. It is safe for use by multiple processFiles goroutines.
+type folders struct {
+	sync.Mutex
+	roots dirList
+	read  map[string]map[string]string
+}
+
+// newFolders returns a folders for the trees at roots.
+func newFolders(roots ...string) *folders {
+	return &folders{roots: roots, read: map[string]map[string]string{}}
+}
+
+// load returns the metadata set in dir, by its metadata.yaml and then its
+// .chkmd.yaml, which is empty if it has neither.
+func (f *folders) load(dir string) (map[string]string, error) {
+	f.Lock()
+	defer f.Unlock()
+	if md, ok := f.read[dir]; ok {
+		return md, nil
+	}
+	md := map[string]string{}
+	for _, name := range []string{folderMetadataFile, folderDefaultsFile} {
+		p := filepath.Join(dir, name)
+		b, err := ioutil.ReadFile(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		fm := &folderMetadata{}
+		if err = yaml.Unmarshal(b, fm); err != nil {
+			return nil, fmt.Errorf("%s: %s", p, err)
+		}
+		for k, v := range fm.values() {
+			md[k] = v
+		}
+	}
+	f.read[dir] = md
+	return md, nil
+}
+
+// inherit returns the metadata the file at p inherits from the directories
+// between its root and it, keyed like the exif maps.
+func (f *folders) inherit(p string) (map[string]string, error) {
+	root := filepath.Clean(f.roots.rootOf(p))
+	dir := filepath.Dir(filepath.Clean(p))
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return map[string]string{}, err
+	}
+	dirs := []string{root}
+	if rel != "." {
+		for _, part := range strings.Split(rel, string(filepath.Separator)) {
+			dirs = append(dirs, filepath.Join(dirs[len(dirs)-1], part))
+		}
+	}
+	md := map[string]string{}
+	for _, d := range dirs {
+		set, err := f.load(d)
+		if err != nil {
+			return md, err
+		}
+		for k, v := range set {
+			md[k] = v
+		}
+	}
+	return md, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFoldersInherit(t *testing.T) {
+	root, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(root)
+	sub := filepath.Join(root, "ksc", "sts-1")
+	equals(t, os.MkdirAll(sub, 0755), nil)
+	equals(t, ioutil.WriteFile(filepath.Join(root, folderMetadataFile),
+		[]byte("center: Kennedy Space Center\ncredit: NASA\nkeywords: [shuttle, launch]\n"), 0644), nil)
+	equals(t, ioutil.WriteFile(filepath.Join(sub, folderMetadataFile),
+		[]byte("album: STS-1\ncredit: NASA/Bill Ingalls\n"), 0644), nil)
+
+	f := newFolders(root)
+	md, err := f.inherit(filepath.Join(sub, "a.jpg"))
+	equals(t, err, nil)
+	equals(t, md, map[string]string{
+		"Center":   "Kennedy Space Center",
+		"Album":    "STS-1",
+		"Credit":   "NASA/Bill Ingalls",
+		"Keywords": "shuttle, launch",
+	})
+	md, err = f.inherit(filepath.Join(root, "ksc", "b.jpg"))
+	equals(t, err, nil)
+	equals(t, md["Album"], "")
+	equals(t, md["Credit"], "NASA")
+
+	e := newExif()
+	e.Folder = md
+	equals(t, e.Keywords(), "shuttle, launch")
+	equals(t, e.Center(), "Kennedy Space Center")
+	e.IPTC["Keywords"] = "embedded"
+	equals(t, e.Keywords(), "embedded")
+}
+
+func TestFolderDefaults(t *testing.T) {
+	root, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(root)
+	sub := filepath.Join(root, "jsc")
+	equals(t, os.MkdirAll(sub, 0755), nil)
+	equals(t, ioutil.WriteFile(filepath.Join(root, folderDefaultsFile),
+		[]byte("center: Johnson Space Center\nphotographer: Bill Ingalls\n"), 0644), nil)
+	// .chkmd.yaml sets what it has over metadata.yaml in the same directory.
+	equals(t, ioutil.WriteFile(filepath.Join(sub, folderMetadataFile),
+		[]byte("album: Expedition 42\nphotographer: Robert Markowitz\n"), 0644), nil)
+	equals(t, ioutil.WriteFile(filepath.Join(sub, folderDefaultsFile),
+		[]byte("photographer: Norah Moran\n"), 0644), nil)
+
+	md, err := newFolders(root).inherit(filepath.Join(sub, "a.jpg"))
+	equals(t, err, nil)
+	equals(t, md, map[string]string{
+		"Center":       "Johnson Space Center",
+		"Album":        "Expedition 42",
+		"Photographer": "Norah Moran",
+	})
+
+	e := newExif()
+	e.Folder = md
+	equals(t, e.Photographer(), "Norah Moran")
+	dr := newDetails(ioutil.Discard, rules{}).detail(make([]string, len(csvHeader)), e)
+	equals(t, dr.Provenance["Photographer"].Used, "directory-default:Photographer")
+	equals(t, dr.Provenance["Center"].Used, "directory-default:Center")
+	e.IPTC["By-line"] = "Embedded"
+	equals(t, e.Photographer(), "Embedded")
+
+	equals(t, ioutil.WriteFile(filepath.Join(sub, folderDefaultsFile), []byte("keywords: {"), 0644), nil)
+	_, err = newFolders(root).inherit(filepath.Join(sub, "a.jpg"))
+	equals(t, err != nil, true)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gcsScope is the OAuth scope for reading from Google Cloud Storage.
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_only"
+
+// gcsClient reads from Google Cloud Storage with the JSON API, as the
+// service account in GOOGLE_APPLICATION_CREDENTIALS. It's safe for use by
+// multiple processFiles goroutines.
+type gcsClient struct {
+	client   *http.Client
+	account  serviceAccount
+	endpoint string
+
+	sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// newGCSClient returns a gcsClient configured from the environment. Set
+// STORAGE_EMULATOR_HOST to use an emulator, which needs no credentials.
+func newGCSClient() (*gcsClient, error) {
+	c := &gcsClient{client: &http.Client{}, endpoint: "https://storage.googleapis.com"}
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+		c.endpoint = strings.TrimRight(host, "/")
+		return c, nil
+	}
+	keyfile := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if keyfile == "" {
+		return nil, fmt.Errorf("GOOGLE_APPLICATION_CREDENTIALS must be set to read from Google Cloud Storage")
+	}
+	var err error
+	if c.account, err = readServiceAccount(keyfile); err != nil {
+		return nil, fmt.Errorf("reading %s: %s", keyfile, err)
+	}
+	return c, nil
+}
+
+// get makes an authorized GET request, returning the response if it was a
+// 200. The access token is fetched, or refreshed if it's about to expire,
+// first. Without a service account, for an emulator, there's no token.
+func (c *gcsClient) get(u string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.account.ClientEmail != "" {
+		c.Lock()
+		if c.token == "" || time.Now().Add(time.Minute).After(c.expires) {
+			c.token, c.expires, err = c.account.token(c.client, gcsScope)
+		}
+		token := c.token
+		c.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s: %s", u, resp.Status, strings.TrimSpace(string(b)))
+	}
+	return resp, nil
+}
+
+// gcsList is the part of an objects list response we use. Sizes are
+// strings, as they're 64 bit.
+type gcsList struct {
+	NextPageToken string `json:"nextPageToken"`
+	Items         []struct {
+		Name string `json:"name"`
+		Size string `json:"size"`
+	} `json:"items"`
+}
+
+// list calls fn with each object under prefix in bucket.
+func (c *gcsClient) list(bucket, prefix string, fn func(key string, size int64) error) error {
+	token := ""
+	for {
+		q := url.Values{"prefix": {prefix}, "fields": {"nextPageToken,items(name,size)"}}
+		if token != "" {
+			q.Set("pageToken", token)
+		}
+		resp, err := c.get(c.endpoint + "/storage/v1/b/" + url.PathEscape(bucket) + "/o?" + q.Encode())
+		if err != nil {
+			return err
+		}
+		var l gcsList
+		err = json.NewDecoder(resp.Body).Decode(&l)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		for _, o := range l.Items {
+			size, _ := strconv.ParseInt(o.Size, 10, 64)
+			if err = fn(o.Name, size); err != nil {
+				return err
+			}
+		}
+		if l.NextPageToken == "" {
+			return nil
+		}
+		token = l.NextPageToken
+	}
+}
+
+// download copies the object to w.
+func (c *gcsClient) download(bucket, key string, w io.Writer) error {
+	resp, err := c.get(c.endpoint + "/storage/v1/b/" + url.PathEscape(bucket) + "/o/" + url.PathEscape(key) + "?alt=media")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// coordinateNumber matches the numbers in exiftool's coordinates, which look
+// like 29 deg 33' 47.00" N, or are decimal with -n.
+var coordinateNumber = regexp.MustCompile(`[-+]?[0-9]+(\.[0-9]+)?`)
+
+// parseCoordinate parses a latitude or longitude from exiftool into decimal
+// degrees. ref is the Exif GPSLatitudeRef or GPSLongitudeRef, for values
+// without their own N, S, E or W.
+func parseCoordinate(v, ref string) (float64, bool) {
+	v = strings.TrimSpace(v)
+	nums := coordinateNumber.FindAllString(v, 3)
+	if len(nums) == 0 {
+		return 0, false
+	}
+	var deg float64
+	for i, n := range nums {
+		f, err := strconv.ParseFloat(n, 64)
+		if err != nil {
+			return 0, false
+		}
+		deg += math.Abs(f) / math.Pow(60, float64(i))
+	}
+	hemi := strings.ToUpper(strings.TrimSpace(ref))
+	if last := strings.ToUpper(v[len(v)-1:]); strings.Contains("NSEW", last) {
+		hemi = last
+	}
+	if strings.HasPrefix(nums[0], "-") || strings.HasPrefix(hemi, "S") || strings.HasPrefix(hemi, "W") {
+		deg = -deg
+	}
+	return deg, true
+}
+
+// GPS returns the latitude and longitude in decimal degrees from the Exif
+// GPS tags, or XMP's exif:GPSLatitude and exif:GPSLongitude, or exiftool's
+// Composite tags.
+func (e exif) GPS() (lat, lon float64, ok bool) {
+	tags := []struct {
+		lat, latRef, lon, lonRef string
+	}{
+		{e.Exif["GPSLatitude"], e.Exif["GPSLatitudeRef"], e.Exif["GPSLongitude"], e.Exif["GPSLongitudeRef"]},
+		{e.XMP["GPSLatitude"], "", e.XMP["GPSLongitude"], ""},
+		{e.Data["GPSLatitude"], "", e.Data["GPSLongitude"], ""},
+	}
+	for _, t := range tags {
+		if t.lat == "" || t.lon == "" {
+			continue
+		}
+		lat, latOK := parseCoordinate(t.lat, t.latRef)
+		lon, lonOK := parseCoordinate(t.lon, t.lonRef)
+		if latOK && lonOK && math.Abs(lat) <= 90 && math.Abs(lon) <= 180 {
+			return lat, lon, true
+		}
+	}
+	return 0, 0, false
+}
+
+// GPSLocation returns a Location for the GPS coordinates: the nearest place
+// in the gazetteer, or the coordinates themselves if none is near.
+func (e exif) GPSLocation() string {
+	lat, lon, ok := e.GPS()
+	if !ok {
+		return ""
+	}
+	if p := nearestPlace(lat, lon); p != "" {
+		return p
+	}
+	return fmt.Sprintf("%.4f, %.4f", lat, lon)
+}
+
+// place is a gazetteer entry.
+type place struct {
+	name     string
+	lat, lon float64
+}
+
+// placeRadius is how near, in km, coordinates must be to a place to be named
+// for it.
+const placeRadius = 25
+
+// gazetteer is where most of our assets are shot: the NASA centers and
+// facilities, named as we would in the Location column.
+var gazetteer = []place{
+	{"Kennedy Space Center, Florida, United States", 28.5729, -80.6490},
+	{"Houston, Texas, United States", 29.5593, -95.0900},
+	{"Huntsville, Alabama, United States", 34.6466, -86.6743},
+	{"Greenbelt, Maryland, United States", 38.9917, -76.8526},
+	{"Hampton, Virginia, United States", 37.0864, -76.3806},
+	{"Cleveland, Ohio, United States", 41.4150, -81.8614},
+	{"Moffett Field, California, United States", 37.4143, -122.0547},
+	{"Pasadena, California, United States", 34.2011, -118.1712},
+	{"Edwards, California, United States", 34.9586, -117.8848},
+	{"Hancock County, Mississippi, United States", 30.3625, -89.6003},
+	{"Wallops Island, Virginia, United States", 37.9402, -75.4664},
+	{"Washington, District of Columbia, United States", 38.8830, -77.0163},
+	{"New Orleans, Louisiana, United States", 30.0275, -89.9160},
+	{"Las Cruces, New Mexico, United States", 32.5007, -106.6086},
+}
+
+// distance returns the great circle distance in km between two coordinates.
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371
+	rad := math.Pi / 180
+	dlat, dlon := (lat2-lat1)*rad, (lon2-lon1)*rad
+	a := math.Sin(dlat/2)*math.Sin(dlat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dlon/2)*math.Sin(dlon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// nearestPlace returns the name of the nearest gazetteer place within
+// placeRadius of the coordinates, or "".
+func nearestPlace(lat, lon float64) string {
+	name, best := "", float64(placeRadius)
+	for _, p := range gazetteer {
+		if d := distance(lat, lon, p.lat, p.lon); d <= best {
+			name, best = p.name, d
+		}
+	}
+	return name
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseCoordinate(t *testing.T) {
+	values := []struct {
+		v, ref string
+		want   float64
+		ok     bool
+	}{
+		{`29 deg 33' 36.00" N`, "", 29.56, true},
+		{`95 deg 5' 24.00" W`, "", -95.09, true},
+		{`95 deg 5' 24.00"`, "West", -95.09, true},
+		{`28 deg 34' 12.00"`, "North", 28.57, true},
+		{"-80.649", "", -80.649, true},
+		{"", "", 0, false},
+	}
+	for _, v := range values {
+		got, ok := parseCoordinate(v.v, v.ref)
+		equals(t, ok, v.ok)
+		equals(t, math.Abs(got-v.want) < 0.001, true)
+	}
+}
+
+func TestGPSLocation(t *testing.T) {
+	e := newExif()
+	equals(t, e.Location(), "")
+
+	e.Exif["GPSLatitude"], e.Exif["GPSLatitudeRef"] = `29 deg 33' 36.00"`, "North"
+	e.Exif["GPSLongitude"], e.Exif["GPSLongitudeRef"] = `95 deg 5' 24.00"`, "West"
+	equals(t, e.Location(), "Houston, Texas, United States")
+
+	e = newExif()
+	e.XMP["GPSLatitude"], e.XMP["GPSLongitude"] = `0 deg 0' 0.00" N`, `10 deg 30' 0.00" E`
+	equals(t, e.Location(), "0.0000, 10.5000")
+
+	// Text location tags win over GPS.
+	e.IPTC["City"] = "Nowhere"
+	equals(t, e.Location(), "Nowhere")
+
+	e = newExif()
+	e.Data["GPSLatitude"], e.Data["GPSLongitude"] = `91 deg 0' 0.00" N`, `10 deg 30' 0.00" E`
+	equals(t, e.Location(), "")
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/v-studios/chkmd/chkmdpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// grpcDrain is how long calls in progress have to finish once -serve is
+// interrupted.
+const grpcDrain = 30 * time.Second
+
+// grpcServer answers the gRPC calls in chkmd.proto, so services can check
+// files over the network rather than running chkmd. Its processes check the
+// files of every call, taking them from files in turn, and their rows are
+// passed back to the calls waiting on them by Path.
+type grpcServer struct {
+	chkmdpb.UnimplementedChkmdServer
+	r     *runner
+	files chan string
+
+	sync.Mutex
+	waiting map[string][]chan []string
+}
+
+// newGRPCServer returns a grpcServer checking files with procs processes.
+func (r *runner) newGRPCServer(procs int) *grpcServer {
+	s := &grpcServer{r: r, files: make(chan string), waiting: map[string][]chan []string{}}
+	results := make(chan []string, procs)
+	stats := &statistics{}
+	var wg sync.WaitGroup
+	for i := 0; i < procs; i++ {
+		wg.Add(1)
+		go r.processFiles(context.Background(), s.files, results, stats, &wg)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	go func() {
+		for row := range results {
+			s.Lock()
+			p := row[column("Path")]
+			waiting := s.waiting[p]
+			if len(waiting) == 0 {
+				s.Unlock()
+				continue
+			}
+			if s.waiting[p] = waiting[1:]; len(s.waiting[p]) == 0 {
+				delete(s.waiting, p)
+			}
+			s.Unlock()
+			waiting[0] <- row
+		}
+	}()
+	return s
+}
+
+// serve answers gRPC calls on addr, over TLS with the cert and key files if
+// they're given and in plaintext if not, until ctx is done.
+func (r *runner) serve(ctx context.Context, addr, cert, key string, procs int) error {
+	var opts []grpc.ServerOption
+	if cert != "" {
+		creds, err := credentials.NewServerTLSFromFile(cert, key)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return r.serveListener(ctx, ln, procs, opts...)
+}
+
+// serveListener answers gRPC calls on ln until ctx is done, giving the calls
+// in progress grpcDrain to finish.
+func (r *runner) serveListener(ctx context.Context, ln net.Listener, procs int, opts ...grpc.ServerOption) error {
+	srv := grpc.NewServer(opts...)
+	chkmdpb.RegisterChkmdServer(srv, r.newGRPCServer(procs))
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		stopped := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(grpcDrain):
+			srv.Stop()
+		}
+	}()
+	if err := srv.Serve(ln); err != nil {
+		return err
+	}
+	<-drained
+	return nil
+}
+
+// CheckFile checks one file, as -object does.
+func (s *grpcServer) CheckFile(ctx context.Context, req *chkmdpb.CheckFileRequest) (*chkmdpb.CheckResult, error) {
+	rows, err := s.check(ctx, []string{req.GetPath()}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return checkResult(rows[0]), nil
+}
+
+// CheckBatch checks the files, returning their results in the order asked.
+func (s *grpcServer) CheckBatch(ctx context.Context, req *chkmdpb.CheckBatchRequest) (*chkmdpb.CheckBatchResponse, error) {
+	rows, err := s.check(ctx, req.GetPaths(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp := &chkmdpb.CheckBatchResponse{}
+	for _, row := range rows {
+		resp.Results = append(resp.Results, checkResult(row))
+	}
+	return resp, nil
+}
+
+// StreamResults checks the files, sending each result as it's checked.
+func (s *grpcServer) StreamResults(req *chkmdpb.CheckBatchRequest, stream chkmdpb.Chkmd_StreamResultsServer) error {
+	_, err := s.check(stream.Context(), req.GetPaths(), func(row []string) error {
+		return stream.Send(checkResult(row))
+	})
+	return err
+}
+
+// check checks the files at paths, calling each, if it's given, with each
+// row as it's made, and returns the rows in the order of the paths.
+func (s *grpcServer) check(ctx context.Context, paths []string, each func([]string) error) ([][]string, error) {
+	for i, p := range paths {
+		var err error
+		if paths[i], err = s.r.servedPath(p); err != nil {
+			return nil, err
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Rows made after the call's ended are buffered and dropped with it.
+	rows := make(chan []string, len(paths))
+	go func() {
+		for _, p := range paths {
+			s.Lock()
+			s.waiting[p] = append(s.waiting[p], rows)
+			s.Unlock()
+			select {
+			case s.files <- p:
+			case <-ctx.Done():
+				s.forget(p, rows)
+				return
+			}
+		}
+	}()
+
+	checked := make([][]string, len(paths))
+	for range paths {
+		var row []string
+		select {
+		case row = <-rows:
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		if each != nil {
+			if err := each(row); err != nil {
+				return nil, err
+			}
+		}
+		for i, p := range paths {
+			if p == row[column("Path")] && checked[i] == nil {
+				checked[i] = row
+				break
+			}
+		}
+	}
+	return checked, nil
+}
+
+// forget stops waiting on a row for p that won't be made.
+func (s *grpcServer) forget(p string, rows chan []string) {
+	s.Lock()
+	defer s.Unlock()
+	waiting := s.waiting[p]
+	for i := len(waiting) - 1; i >= 0; i-- {
+		if waiting[i] == rows {
+			s.waiting[p] = append(waiting[:i], waiting[i+1:]...)
+			break
+		}
+	}
+	if len(s.waiting[p]) == 0 {
+		delete(s.waiting, p)
+	}
+}
+
+// servedPath returns p as the path of a file under -d, a relative one
+// being under the first, or an error if it's not under any, so calls can
+// check no other files on the host.
+func (r *runner) servedPath(p string) (string, error) {
+	if p == "" {
+		return "", status.Error(codes.InvalidArgument, "a path is empty")
+	}
+	if len(r.roots) > 0 && !isObject(p) && !isURL(p) && !filepath.IsAbs(p) {
+		if isObject(r.roots[0]) {
+			p = strings.TrimSuffix(r.roots[0], "/") + "/" + p
+		} else {
+			p = filepath.Join(r.roots[0], p)
+		}
+	}
+	for _, d := range r.roots {
+		if isObject(d) == isObject(p) && under(d, p) {
+			return p, nil
+		}
+	}
+	return "", status.Errorf(codes.InvalidArgument, "%s isn't under -d", p)
+}
+
+// checkResult returns a row as a CheckResult.
+func checkResult(row []string) *chkmdpb.CheckResult {
+	result := &chkmdpb.CheckResult{
+		Path:   row[column("Path")],
+		Status: row[column("Status")],
+		Reason: row[column("Reason")],
+		Fields: map[string]string{},
+	}
+	for i, h := range csvHeader {
+		result.Fields[h] = row[i]
+	}
+	return result
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/v-studios/chkmd/chkmdpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// resultStream is a Chkmd_StreamResultsServer keeping the results sent.
+type resultStream struct {
+	grpc.ServerStream
+	results []*chkmdpb.CheckResult
+}
+
+func (rs *resultStream) Context() context.Context {
+	return context.Background()
+}
+
+func (rs *resultStream) Send(result *chkmdpb.CheckResult) error {
+	rs.results = append(rs.results, result)
+	return nil
+}
+
+func TestGRPCServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	r := newTestRunner(t, "test-config.yaml")
+	r.replay = dir
+	r.roots = dirList{dir}
+	s := r.newGRPCServer(2)
+	ctx := context.Background()
+
+	a, b := filepath.Join(dir, "a.jpg"), filepath.Join(dir, "sub", "b.jpg")
+	result, err := s.CheckFile(ctx, &chkmdpb.CheckFileRequest{Path: "a.jpg"})
+	equals(t, err, nil)
+	equals(t, result.Path, a)
+	equals(t, result.Status, "Rejected")
+	equals(t, result.Fields["Path"], a)
+	equals(t, result.Fields["Status"], "Rejected")
+
+	batch := &chkmdpb.CheckBatchRequest{Paths: []string{b, "a.jpg", "sub/b.jpg"}}
+	resp, err := s.CheckBatch(ctx, batch)
+	equals(t, err, nil)
+	var paths []string
+	for _, result := range resp.Results {
+		paths = append(paths, result.Path)
+	}
+	equals(t, paths, []string{b, a, b})
+
+	stream := &resultStream{}
+	equals(t, s.StreamResults(&chkmdpb.CheckBatchRequest{Paths: []string{b, "a.jpg", "sub/b.jpg"}}, stream), nil)
+	streamed := map[string]int{}
+	for _, result := range stream.results {
+		streamed[result.Path]++
+	}
+	equals(t, streamed, map[string]int{a: 1, b: 2})
+
+	_, err = s.CheckFile(ctx, &chkmdpb.CheckFileRequest{Path: "../passwd"})
+	equals(t, status.Code(err), codes.InvalidArgument)
+	equals(t, status.Convert(err).Message(), filepath.Join(filepath.Dir(dir), "passwd")+" isn't under -d")
+	_, err = s.CheckFile(ctx, &chkmdpb.CheckFileRequest{Path: "https://example.com/a.jpg"})
+	equals(t, status.Code(err), codes.InvalidArgument)
+	_, err = s.CheckFile(ctx, &chkmdpb.CheckFileRequest{})
+	equals(t, status.Code(err), codes.InvalidArgument)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = s.CheckBatch(cancelled, &chkmdpb.CheckBatchRequest{Paths: []string{"a.jpg"}})
+	equals(t, status.Code(err), codes.Canceled)
+	// The call stops waiting on the row after it's returned.
+	for i := 0; ; i++ {
+		s.Lock()
+		n := len(s.waiting)
+		s.Unlock()
+		if n == 0 || i == 100 {
+			equals(t, n, 0)
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestGRPCServe calls the service over a connection, in plaintext.
+func TestGRPCServe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	r := newTestRunner(t, "test-config.yaml")
+	r.replay = dir
+	r.roots = dirList{dir}
+	ln := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() {
+		served <- r.serveListener(ctx, ln, 2)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	equals(t, err, nil)
+	defer conn.Close()
+	client := chkmdpb.NewChkmdClient(conn)
+
+	a := filepath.Join(dir, "a.jpg")
+	result, err := client.CheckFile(ctx, &chkmdpb.CheckFileRequest{Path: "a.jpg"})
+	equals(t, err, nil)
+	equals(t, result.Path, a)
+	equals(t, result.Fields["Status"], "Rejected")
+
+	stream, err := client.StreamResults(ctx, &chkmdpb.CheckBatchRequest{Paths: []string{"a.jpg", "b.jpg"}})
+	equals(t, err, nil)
+	n := 0
+	for {
+		if _, err = stream.Recv(); err != nil {
+			break
+		}
+		n++
+	}
+	equals(t, err, io.EOF)
+	equals(t, n, 2)
+
+	_, err = client.CheckFile(ctx, &chkmdpb.CheckFileRequest{Path: "../passwd"})
+	equals(t, status.Code(err), codes.InvalidArgument)
+
+	cancel()
+	equals(t, <-served, nil)
+}
+
+func TestServedPath(t *testing.T) {
+	r := &runner{roots: dirList{"s3://bucket/media"}}
+	p, err := r.servedPath("2015/a.jpg")
+	equals(t, err, nil)
+	equals(t, p, "s3://bucket/media/2015/a.jpg")
+	_, err = r.servedPath("s3://bucket/other/a.jpg")
+	equals(t, status.Code(err), codes.InvalidArgument)
+	_, err = r.servedPath("")
+	equals(t, status.Code(err), codes.InvalidArgument)
+}
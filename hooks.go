@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// defaultHookTimeout is how long a hook may take on a file, unless the
+// config says.
+const defaultHookTimeout = 30 * time.Second
+
+// hookConfig is a hook of the hooks section of the config: a command run
+// for each file whose metadata was read, once it's been checked, for rules
+// too particular to a mission to be chkmd's own, e.g.
+//
+//	hooks:
+//	  - name: JSC rules
+//	    command: [/opt/jsc/chkmd-hook, --strict]
+//	    timeout_ms: 5000
+//
+// It's given the file's webhookPayload as JSON on stdin and writes a
+// hookResult as JSON to stdout, or nothing to leave the file as it is.
+type hookConfig struct {
+	Name      string   `yaml:"name"`
+	Command   []string `yaml:"command"`
+	TimeoutMS int      `yaml:"timeout_ms"`
+}
+
+// hookResult is what a hook says of a file: the Fields to set, by column,
+// the Status to give it instead, and Reasons to add to its Reason.
+type hookResult struct {
+	Fields  map[string]string `json:"fields"`
+	Status  string            `json:"status"`
+	Reasons []string          `json:"reasons"`
+}
+
+// hooks are the config's hooks, run in turn.
+type hooks []hookConfig
+
+// validate checks each hook has a name and a command.
+func (hs hooks) validate() error {
+	for i, h := range hs {
+		switch {
+		case h.Name == "":
+			return fmt.Errorf("hooks: hook %d has no name", i+1)
+		case len(h.Command) == 0 || h.Command[0] == "":
+			return fmt.Errorf("hooks: %s has no command", h.Name)
+		case h.TimeoutMS < 0:
+			return fmt.Errorf("hooks: %s's timeout_ms is negative", h.Name)
+		}
+	}
+	return nil
+}
+
+// apply runs the hooks on the row made from e, each given the row as the
+// ones before left it, and changes it as they say, returning the reasons
+// they gave to count. Their reasons are prefixed with their names, as
+// checks' are, and a hook that fails, or says what can't be done, is a
+// reason too, making an Accepted file Incomplete. The hooks are stopped
+// with ctx.
+func (hs hooks) apply(ctx context.Context, row []string, e exif) []string {
+	var counted []string
+	note := func(h hookConfig, reason string) {
+		row[column("Reason")] = joinReason(row[column("Reason")], h.Name+": "+reason)
+		counted = append(counted, "hook "+h.Name)
+	}
+	for _, h := range hs {
+		res, err := h.run(ctx, newPayload(row, e))
+		if err == nil {
+			err = res.validate()
+		}
+		if err != nil {
+			note(h, err.Error())
+			if accepted(row[column("Status")]) {
+				row[column("Status")] = "Incomplete"
+			}
+			continue
+		}
+		for c, v := range res.Fields {
+			row[column(c)] = v
+		}
+		if res.Status != "" {
+			row[column("Status")] = res.Status
+		}
+		for _, reason := range res.Reasons {
+			note(h, reason)
+		}
+	}
+	// What the hooks set is output like the rest.
+	sanitizeRow(row, e.maxLengths())
+	return counted
+}
+
+// run runs the hook with the payload, stopping it if ctx is done or it
+// takes too long.
+func (h hookConfig) run(ctx context.Context, pl webhookPayload) (hookResult, error) {
+	var res hookResult
+	in, err := json.Marshal(pl)
+	if err != nil {
+		return res, err
+	}
+	timeout := defaultHookTimeout
+	if h.TimeoutMS > 0 {
+		timeout = time.Duration(h.TimeoutMS) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := detach(exec.CommandContext(ctx, h.Command[0], h.Command[1:]...))
+	var out, stderr bytes.Buffer
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(in), &out, &stderr
+	if err = cmd.Run(); err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return res, fmt.Errorf("took more than %s", timeout)
+		case context.Canceled:
+			return res, ctx.Err()
+		}
+		// The hook's first line of stderr says why, if it says.
+		if msg := strings.SplitN(strings.TrimSpace(stderr.String()), "\n", 2)[0]; msg != "" {
+			return res, errors.New(msg)
+		}
+		return res, err
+	}
+	if len(bytes.TrimSpace(out.Bytes())) == 0 {
+		return res, nil
+	}
+	if err = json.Unmarshal(out.Bytes(), &res); err != nil {
+		return res, fmt.Errorf("wrote what isn't a result: %s", err)
+	}
+	return res, nil
+}
+
+// validate checks the result only sets columns a hook may, and a Status
+// we have.
+func (res hookResult) validate() error {
+	for c := range res.Fields {
+		if column(c) < 0 || c == "Path" || c == "Status" || c == "Reason" {
+			return fmt.Errorf("can't set %q", c)
+		}
+	}
+	switch res.Status {
+	case "", "Accepted", statusWarned, "Incomplete", "Rejected", statusBelowQuality:
+		return nil
+	}
+	return fmt.Errorf("no status %q", res.Status)
+}
+
+// recount moves a file whose Status a hook changed between the Accept and
+// Reject counts, and into or out of the AcceptedWarnings and BelowQuality
+// ones.
+func recount(stats *statistics, before, after string) {
+	switch {
+	case accepted(before) == accepted(after):
+	case accepted(before):
+		atomic.AddInt32(&stats.Accept, -1)
+		atomic.AddInt32(&stats.Reject, 1)
+	default:
+		atomic.AddInt32(&stats.Reject, -1)
+		atomic.AddInt32(&stats.Accept, 1)
+	}
+	for status, count := range map[string]*int32{
+		statusWarned:       &stats.AcceptedWarnings,
+		statusBelowQuality: &stats.BelowQuality,
+	} {
+		switch {
+		case before == after:
+		case before == status:
+			atomic.AddInt32(count, -1)
+		case after == status:
+			atomic.AddInt32(count, 1)
+		}
+	}
+}
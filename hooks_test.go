@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestHookProcess isn't a test, but the hook the other tests run, as the
+// test binary, doing what its last argument says.
+func TestHookProcess(t *testing.T) {
+	if os.Getenv("CHKMD_TEST_HOOK") != "1" {
+		return
+	}
+	var pl webhookPayload
+	if err := json.NewDecoder(os.Stdin).Decode(&pl); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	switch os.Args[len(os.Args)-1] {
+	case "set":
+		if pl.Fields["Center"] == "" {
+			fmt.Printf(`{"fields": {"Center": "JSC", "Album": "Artemis  II"}, "status": "Incomplete", "reasons": ["%s has no Center"]}`, pl.Fields["Title"])
+		}
+	case "accept":
+		fmt.Print(`{"status": "Accepted"}`)
+	case "fail":
+		fmt.Fprintln(os.Stderr, "no mission for this file\nmore detail")
+		os.Exit(1)
+	case "bad":
+		fmt.Print(`{"fields": {"Path": "/elsewhere.jpg"}}`)
+	case "slow":
+		time.Sleep(5 * time.Second)
+	}
+	os.Exit(0)
+}
+
+// testHook returns a hook running TestHookProcess to do what.
+func testHook(name, what string) hookConfig {
+	return hookConfig{Name: name, Command: []string{os.Args[0], "-test.run=^TestHookProcess$", "--", what}}
+}
+
+func TestHooks(t *testing.T) {
+	os.Setenv("CHKMD_TEST_HOOK", "1")
+	defer os.Unsetenv("CHKMD_TEST_HOOK")
+	makeRow := func(status string) []string {
+		e := newExif()
+		e.XMP["Title"] = "Artemis II crew"
+		rows := make(chan []string, 1)
+		e.MakeRow(rows, "/media/a.jpg", status, "", "")
+		return <-rows
+	}
+
+	row := makeRow("Accepted")
+	equals(t, hooks{testHook("JSC", "set"), testHook("Quiet", "quiet")}.apply(context.Background(), row, newExif()), []string{"hook JSC"})
+	equals(t, row[column("Status")], "Incomplete")
+	equals(t, row[column("Reason")], "JSC: Artemis II crew has no Center")
+	equals(t, row[column("Center")], "JSC")
+	equals(t, row[column("Album")], "Artemis II")
+
+	// The second sees what the first did, and has the last word.
+	row = makeRow("Accepted")
+	equals(t, hooks{testHook("JSC", "set"), testHook("Accept", "accept")}.apply(context.Background(), row, newExif()), []string{"hook JSC"})
+	equals(t, row[column("Status")], "Accepted")
+
+	row = makeRow("Accepted")
+	slow := testHook("Slow", "slow")
+	slow.TimeoutMS = 100
+	equals(t, hooks{testHook("Failing", "fail"), testHook("Bad", "bad"), slow}.apply(context.Background(), row, newExif()), []string{"hook Failing", "hook Bad", "hook Slow"})
+	equals(t, row[column("Status")], "Incomplete")
+	equals(t, row[column("Path")], "/media/a.jpg")
+	equals(t, row[column("Reason")], `Failing: no mission for this file; Bad: can't set "Path"; Slow: took more than 100ms`)
+
+	// Stopping the run stops the hooks.
+	row = makeRow("Accepted")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	equals(t, hooks{testHook("Slow", "slow")}.apply(ctx, row, newExif()), []string{"hook Slow"})
+	equals(t, time.Since(start) < 5*time.Second, true)
+	equals(t, row[column("Reason")], "Slow: context canceled")
+
+	stats := &statistics{Accept: 1}
+	recount(stats, "Accepted", "Incomplete")
+	equals(t, [2]int32{stats.Accept, stats.Reject}, [2]int32{0, 1})
+	recount(stats, "Rejected", "Accepted")
+	equals(t, [2]int32{stats.Accept, stats.Reject}, [2]int32{1, 0})
+	recount(stats, "Accepted", statusBelowQuality)
+	equals(t, [3]int32{stats.Accept, stats.Reject, stats.BelowQuality}, [3]int32{0, 1, 1})
+	recount(stats, statusBelowQuality, "Incomplete")
+	equals(t, [3]int32{stats.Accept, stats.Reject, stats.BelowQuality}, [3]int32{0, 1, 0})
+}
+
+func TestHooksValidate(t *testing.T) {
+	equals(t, hooks{{Name: "JSC", Command: []string{"jsc-hook"}}}.validate(), nil)
+	equals(t, hooks{{Command: []string{"jsc-hook"}}}.validate().Error(), "hooks: hook 1 has no name")
+	equals(t, hooks{{Name: "JSC"}}.validate().Error(), "hooks: JSC has no command")
+	equals(t, hookResult{Status: "Done"}.validate().Error(), `no status "Done"`)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// inferredFields are the fields a file name may fill in.
+var inferredFields = map[string]bool{"NasaID": true, "Center": true, "DateCreated": true}
+
+// inferenceConfig is the filename_patterns section of the config. Each
+// pattern is tried in order on the file name, without its extension, and
+// fills in the fields it captures that an earlier one didn't, e.g.
+//
+//	filename_patterns:
+//	  - '^(?P<Center>[A-Z]{2,4})-\d{4}-\d+_(?P<DateCreated>\d{8})$'
+//	  - '{NasaID}_{}'
+//
+// A pattern is a regexp with groups named for inferredFields, or a template
+// where {Field} captures a field and {} skips anything up to the text after
+// it. A Date Created like 20140607 or 2014-06-07 is read as that day. The
+// fields are used last, after any directory defaults, with the provenance
+// filename.
+type inferenceConfig []string
+
+// validate checks the patterns compile.
+func (ic inferenceConfig) validate() error {
+	_, err := ic.compile()
+	return err
+}
+
+// compile returns the patterns as regexps.
+func (ic inferenceConfig) compile() (filenamePatterns, error) {
+	var patterns filenamePatterns
+	for _, p := range ic {
+		expr := p
+		if !strings.Contains(p, "(?P<") {
+			expr = templateRegexp(p)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("filename_patterns: %q: %s", p, err)
+		}
+		var fields int
+		for _, name := range re.SubexpNames()[1:] {
+			if name == "" {
+				continue
+			}
+			if !inferredFields[name] {
+				return nil, fmt.Errorf("filename_patterns: %q can't infer %s, only NasaID, Center and DateCreated", p, name)
+			}
+			fields++
+		}
+		if fields == 0 {
+			return nil, fmt.Errorf("filename_patterns: %q captures no fields", p)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+// templateVars are the {Field} and {} of a filename_patterns template.
+var templateVars = regexp.MustCompile(`\{(\w*)\}`)
+
+// templateRegexp returns the regexp matching the whole name for a template.
+func templateRegexp(t string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	for _, m := range templateVars.FindAllStringSubmatchIndex(t, -1) {
+		b.WriteString(regexp.QuoteMeta(t[last:m[0]]))
+		if name := t[m[2]:m[3]]; name != "" {
+			fmt.Fprintf(&b, "(?P<%s>.+?)", name)
+		} else {
+			b.WriteString(".+?")
+		}
+		last = m[1]
+	}
+	b.WriteString(regexp.QuoteMeta(t[last:]))
+	b.WriteString("$")
+	return b.String()
+}
+
+// filenamePatterns are the compiled filename_patterns.
+type filenamePatterns []*regexp.Regexp
+
+// datePattern is a Date Created in a file name, with or without separators.
+var datePattern = regexp.MustCompile(`^(\d{4})[-_.:]?(\d{2})[-_.:]?(\d{2})$`)
+
+// infer returns the fields the patterns capture from the name of the file
+// at p, keyed like the exif maps.
+func (fp filenamePatterns) infer(p string) map[string]string {
+	fields := map[string]string{}
+	if len(fp) == 0 {
+		return fields
+	}
+	name := filepath.Base(p)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	for _, re := range fp {
+		m := re.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		for i, field := range re.SubexpNames() {
+			if field == "" || m[i] == "" || fields[field] != "" {
+				continue
+			}
+			v := m[i]
+			if field == "DateCreated" {
+				if d := datePattern.FindStringSubmatch(v); d != nil {
+					v = d[1] + ":" + d[2] + ":" + d[3]
+				}
+			}
+			fields[field] = v
+		}
+	}
+	return fields
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestFilenamePatterns(t *testing.T) {
+	fp, err := inferenceConfig{
+		`^(?P<Center>[A-Z]{2,4})-\d{4}-\d+_(?P<DateCreated>\d{8})$`,
+		`{NasaID}_{}`,
+	}.compile()
+	equals(t, err, nil)
+	equals(t, fp.infer("/media/KSC-2014-1234_20140607.jpg"), map[string]string{
+		"Center": "KSC", "DateCreated": "2014:06:07", "NasaID": "KSC-2014-1234",
+	})
+	equals(t, fp.infer("/media/jsc2019e001234.jpg"), map[string]string{})
+	equals(t, filenamePatterns(nil).infer("/media/KSC-1.jpg"), map[string]string{})
+
+	e := newExif()
+	e.Data["FileName"] = "KSC-2014-1234_20140607.jpg"
+	e.Filename = fp.infer(e.Data["FileName"])
+	equals(t, e.NasaID(), "KSC-2014-1234")
+	equals(t, e.Center(), "KSC")
+	equals(t, e.dateCreatedText(), "2014:06:07")
+	e.IPTC["Credit"], e.Folder["Center"] = "", "JSC"
+	equals(t, e.Center(), "JSC")
+	e.Exif["DateTimeOriginal"] = "2014:06:08 10:00:00"
+	equals(t, e.dateCreatedText(), "2014:06:08 10:00:00")
+}
+
+func TestTemplateRegexp(t *testing.T) {
+	equals(t, templateRegexp("{Center}-{}_{DateCreated}"), `^(?P<Center>.+?)-.+?_(?P<DateCreated>.+?)$`)
+	equals(t, templateRegexp("img.{NasaID}"), `^img\.(?P<NasaID>.+?)$`)
+}
+
+func TestInferenceConfigValidate(t *testing.T) {
+	equals(t, inferenceConfig(nil).validate(), nil)
+	equals(t, inferenceConfig{"{Title}_{}"}.validate().Error(),
+		`filename_patterns: "{Title}_{}" can't infer Title, only NasaID, Center and DateCreated`)
+	equals(t, inferenceConfig{"KSC-{}"}.validate().Error(), `filename_patterns: "KSC-{}" captures no fields`)
+	equals(t, inferenceConfig{"(?P<NasaID>["}.validate() != nil, true)
+}
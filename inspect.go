@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// inspectUsage is printed for chkmd inspect -h.
+const inspectUsage = `Usage: chkmd inspect [-c config.yaml] [-profile name] path ...
+
+Checks each file, s3:// object or URL, and prints its Status and Reason,
+where each field's value came from, and every tag exiftool found, by group,
+marking those the fields came from, to see why a file wasn't Accepted.
+`
+
+// inspectCommand runs chkmd inspect with args, returning the exit status.
+func inspectCommand(args []string) int {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	cfgfile := fs.String("c", "", "The config file to read from.")
+	profile := fs.String("profile", "", "The profile in the config file to check with.")
+	lang := fs.String("lang", "", "The language to read XMP titles and descriptions in, as -lang does.")
+	replay := fs.String("replay", "", "A directory of recorded exiftool output to read instead of running exiftool.")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, inspectUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := readConfig(*cfgfile)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	if cfg, err = cfg.withProfile(*profile); err != nil {
+		log.Printf("Error in -profile: %s\n", err)
+		return 1
+	}
+	status := 0
+	for i, p := range fs.Args() {
+		r, err := newRunner(options{cfgfile: *cfgfile, lang: *lang, replay: *replay}, cfg)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		r.inspect = func(row []string, e exif) {
+			fmt.Print(inspect(row, e))
+		}
+		if _, err = r.checkOne(p); err != nil {
+			log.Printf("Error opening %s: %s\n", p, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// inspect describes a row and the exif it was made from: its Status, Reason
+// and Warnings, the source each field's value came from, and every tag by
+// group, with the fields that came from it.
+func inspect(row []string, e exif) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n  Status: %s\n", row[column("Path")], row[column("Status")])
+	if reason := row[column("Reason")]; reason != "" {
+		fmt.Fprintf(&b, "  Reason: %s\n", reason)
+	}
+	if warnings := row[column(warningsColumn)]; warnings != "" {
+		fmt.Fprintf(&b, "  Warnings: %s\n", warnings)
+	}
+
+	var fields []string
+	for f := range fieldSources {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	from := map[string][]string{}
+	b.WriteString("\nFields:\n")
+	for _, f := range fields {
+		used := false
+		for _, s := range e.sources(f) {
+			if v := s.get(e); v != "" {
+				fmt.Fprintf(&b, "  %-13s %q from %s\n", f, v, s.name)
+				for _, tag := range inspectTags(s) {
+					from[tag] = append(from[tag], f)
+				}
+				used = true
+				break
+			}
+		}
+		if !used {
+			fmt.Fprintf(&b, "  %-13s (empty)\n", f)
+		}
+	}
+
+	b.WriteString("\nTags:\n")
+	groups := []struct {
+		name string
+		tags map[string]string
+	}{
+		{"File", e.Data}, {"Exif", e.Exif}, {"IPTC", e.IPTC}, {"XMP", e.XMP},
+		{"ID3", e.ID3}, {"RIFF", e.RIFF}, {"PDF", e.PDF}, {"Model", e.Model},
+		{folderGroup, e.Folder}, {"filename", e.Filename},
+	}
+	found := false
+	for _, g := range groups {
+		if len(g.tags) == 0 {
+			continue
+		}
+		found = true
+		fmt.Fprintf(&b, "  %s:\n", g.name)
+		var tags []string
+		for tag := range g.tags {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			fmt.Fprintf(&b, "    %s = %q", tag, g.tags[tag])
+			if f := from[g.name+":"+tag]; len(f) > 0 {
+				fmt.Fprintf(&b, "  -> %s", strings.Join(f, ", "))
+			}
+			b.WriteString("\n")
+		}
+	}
+	if !found {
+		b.WriteString("  none\n")
+	}
+	return b.String()
+}
+
+// inspectTags returns the group:tag names of the tags a source reads, as
+// inspect lists them, or none for a script.
+func inspectTags(s source) []string {
+	i := strings.Index(s.name, ":")
+	if i <= 0 || strings.HasPrefix(s.name, scriptPrefix) {
+		return nil
+	}
+	group := s.name[:i]
+	switch group {
+	case "Composite":
+		// exiftool's Composite tags are with its File ones.
+		group = "File"
+	case "metadata.yaml":
+		group = folderGroup
+	}
+	var tags []string
+	for _, tag := range strings.Fields(s.name[i+1:]) {
+		tags = append(tags, group+":"+strings.TrimSuffix(tag, langSuffix))
+	}
+	return tags
+}
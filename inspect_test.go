@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	e := newExif()
+	e.Data["FileName"] = "KSC-2015-001.jpg"
+	e.IPTC["Keywords"] = "Launch, Pad 39A"
+	e.IPTC["Credit"] = "KSC"
+	e.XMP["Title"] = "Launch"
+	e.XMP["Subject"] = "Launch"
+	e.Folder = map[string]string{"Album": "Artemis"}
+	rows := make(chan []string, 1)
+	e.MakeRow(rows, "/media/KSC-2015-001.jpg", "Incomplete", "Missing: DateCreated", "")
+	got := inspect(<-rows, e)
+
+	for _, want := range []string{
+		"/media/KSC-2015-001.jpg\n  Status: Incomplete\n  Reason: Missing: DateCreated\n\nFields:\n",
+		"  Album         \"Artemis\" from directory-default:Album\n",
+		"  Center        \"KSC\" from IPTC:Credit\n",
+		"  DateCreated   (empty)\n",
+		"  NasaID        \"KSC-2015-001\" from File:FileName\n",
+		"\nTags:\n  File:\n    FileName = \"KSC-2015-001.jpg\"  -> NasaID\n",
+		"  IPTC:\n    Credit = \"KSC\"  -> Center, Credit\n    Keywords = \"Launch, Pad 39A\"  -> Keywords\n",
+		"  XMP:\n    Subject = \"Launch\"\n    Title = \"Launch\"  -> Title\n",
+		"  directory-default:\n    Album = \"Artemis\"  -> Album\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("inspect is missing %q in:\n%s", want, got)
+		}
+	}
+	equals(t, inspectTags(source{name: "IPTC:DateCreated TimeCreated"}), []string{"IPTC:DateCreated", "IPTC:TimeCreated"})
+	equals(t, inspectTags(source{name: "Composite:GPSLatitude GPSLongitude"}), []string{"File:GPSLatitude", "File:GPSLongitude"})
+	equals(t, inspectTags(source{name: "XMP:Title" + langSuffix}), []string{"XMP:Title"})
+	equals(t, inspectTags(source{name: "script:nasa_id"}), []string(nil))
+}
+
+func TestInspectFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	r := newTestRunner(t, "test-config.yaml")
+	r.replay = dir
+	var got string
+	r.inspect = func(row []string, e exif) {
+		got = inspect(row, e)
+	}
+	p := filepath.Join(dir, "a.jpg")
+	_, err = r.checkOne(p)
+	equals(t, err, nil)
+	if !strings.HasPrefix(got, p+"\n  Status: Rejected\n  Reason: ") || !strings.HasSuffix(got, "\nTags:\n  none\n") {
+		t.Fatalf("unexpected inspect of a file that can't be read:\n%s", got)
+	}
+}
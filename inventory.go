@@ -0,0 +1,164 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+// inventoryManifest is the part of an S3 Inventory manifest.json we use. See
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/storage-inventory-location.html
+type inventoryManifest struct {
+	SourceBucket      string `json:"sourceBucket"`
+	DestinationBucket string `json:"destinationBucket"`
+	FileFormat        string `json:"fileFormat"`
+	FileSchema        string `json:"fileSchema"`
+	Files             []struct {
+		Key string `json:"key"`
+	} `json:"files"`
+}
+
+// readManifest reads an inventory manifest from S3 or a local file.
+func readManifest(c *s3Client, p string) (inventoryManifest, error) {
+	var m inventoryManifest
+	var b []byte
+	var err error
+	if bucket, key, ok := parseS3URI(p); ok {
+		var buf strings.Builder
+		err = c.download(bucket, key, &buf)
+		b = []byte(buf.String())
+	} else {
+		b, err = ioutil.ReadFile(p)
+	}
+	if err != nil {
+		return m, err
+	}
+	if err = json.Unmarshal(b, &m); err != nil {
+		return m, err
+	}
+	if !strings.EqualFold(m.FileFormat, "CSV") {
+		return m, fmt.Errorf("inventory format is %s, only CSV is supported", m.FileFormat)
+	}
+	return m, nil
+}
+
+// inventoryColumns returns the indexes of the Bucket and Key columns in the
+// manifest's fileSchema.
+func inventoryColumns(schema string) (bucket, key int, err error) {
+	bucket, key = -1, -1
+	for i, name := range strings.Split(schema, ",") {
+		switch strings.TrimSpace(name) {
+		case "Bucket":
+			bucket = i
+		case "Key":
+			key = i
+		}
+	}
+	if bucket < 0 || key < 0 {
+		return 0, 0, fmt.Errorf("inventory schema %q has no Bucket or Key", schema)
+	}
+	return bucket, key, nil
+}
+
+// readInventory calls fn with the bucket and key of each object in a gzipped
+// inventory CSV. Keys in inventories are URL encoded.
+func readInventory(r io.Reader, bucketCol, keyCol int, fn func(bucket, key string) error) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	cr := csv.NewReader(gz)
+	cr.FieldsPerRecord = -1
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if bucketCol >= len(row) || keyCol >= len(row) {
+			continue
+		}
+		key, err := url.QueryUnescape(row[keyCol])
+		if err != nil {
+			return fmt.Errorf("bad key %q: %s", row[keyCol], err)
+		}
+		if err = fn(row[bucketCol], key); err != nil {
+			return err
+		}
+	}
+}
+
+// walkInventory is walkS3 driven by an S3 Inventory rather than listing the
+// bucket. It sends the objects in the shard under the s3:// uri with relevant
+// extensions to the files channel, so nothing is fetched but the inventory itself until
+// the files are processed.
+func walkInventory(ctx context.Context, c *s3Client, manifest, uri string, sh shard, files chan string, stats *statistics, types map[string]bool) error {
+	bucket, prefix, ok := parseS3URI(uri)
+	if !ok {
+		return fmt.Errorf("not an s3:// URI: %s", uri)
+	}
+	m, err := readManifest(c, manifest)
+	if err != nil {
+		return err
+	}
+	bucketCol, keyCol, err := inventoryColumns(m.FileSchema)
+	if err != nil {
+		return err
+	}
+	dest := strings.TrimPrefix(m.DestinationBucket, "arn:aws:s3:::")
+	for _, f := range m.Files {
+		err = readInventoryFile(c, dest, f.Key, func(r io.Reader) error {
+			return readInventory(r, bucketCol, keyCol, func(b, key string) error {
+				if b != bucket || !strings.HasPrefix(key, prefix) || strings.HasSuffix(key, "/") || !sh.mine("s3://"+b+"/"+key) {
+					return nil
+				}
+				atomic.AddInt32(&stats.Total, 1)
+				if types[mime.TypeByExtension(path.Ext(key))] {
+					if err := sendFile(ctx, files, "s3://"+b+"/"+key); err != nil {
+						return err
+					}
+					atomic.AddInt32(&stats.Relevant, 1)
+				}
+				return nil
+			})
+		})
+		if err != nil && err == ctx.Err() {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("reading inventory %s: %s", f.Key, err)
+		}
+	}
+	return nil
+}
+
+// readInventoryFile downloads an inventory data file to a temporary file,
+// which may be large, and calls fn to read it.
+func readInventoryFile(c *s3Client, bucket, key string, fn func(io.Reader) error) error {
+	f, err := ioutil.TempFile("", "chkmd-inventory-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if err = c.download(bucket, key, f); err != nil {
+		return err
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return fn(f)
+}
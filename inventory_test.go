@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func gzipped(s string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(s))
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestWalkInventory(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/logs/inventory/manifest.json":
+			fmt.Fprint(w, `{"sourceBucket": "media", "destinationBucket": "arn:aws:s3:::logs",
+				"fileFormat": "CSV", "fileSchema": "Bucket, Key, Size",
+				"files": [{"key": "inventory/data/1.csv.gz"}, {"key": "inventory/data/2.csv.gz"}]}`)
+		case "/logs/inventory/data/1.csv.gz":
+			w.Write(gzipped("\"media\",\"ksc/a.jpg\",\"10\"\n\"media\",\"ksc/notes.txt\",\"10\"\n\"media\",\"jsc/c.jpg\",\"10\"\n"))
+		case "/logs/inventory/data/2.csv.gz":
+			w.Write(gzipped("\"media\",\"ksc/b+b.mp4\",\"10\"\n\"media\",\"ksc/\",\"0\"\n"))
+		default:
+			// Nothing should be listed or fetched but the inventory.
+			t.Errorf("unexpected request for %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	c := &s3Client{client: ts.Client(), region: "us-east-1", key: "key", secret: "secret", endpoint: ts.URL, now: time.Now}
+
+	files := make(chan string, 10)
+	stats := &statistics{}
+	readConfig("")
+	equals(t, walkInventory(context.Background(), c, "s3://logs/inventory/manifest.json", "s3://media/ksc/", shard{}, files, stats, defaultTypeSet), nil)
+	close(files)
+	var got []string
+	for f := range files {
+		got = append(got, f)
+	}
+	equals(t, got, []string{"s3://media/ksc/a.jpg", "s3://media/ksc/b b.mp4"})
+	equals(t, stats.Total, int32(3))
+	equals(t, stats.Relevant, int32(2))
+}
+
+func TestInventoryColumns(t *testing.T) {
+	b, k, err := inventoryColumns("Bucket, Key, Size, LastModifiedDate")
+	equals(t, []interface{}{b, k, err}, []interface{}{0, 1, nil})
+	_, _, err = inventoryColumns("Size")
+	equals(t, err.Error(), `inventory schema "Size" has no Bucket or Key`)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// unknownKeywordsColumn lists the Keywords not in the vocabulary, only in
+// the CSV output with a keywords vocabulary or when the config's columns
+// list it.
+const unknownKeywordsColumn = "Unknown Keywords"
+
+// keywordConfig is the keywords section of the config. With Normalize the
+// Keywords column is the keywords trimmed, de-duplicated and separated by
+// "; ", lowercased too with Lowercase. Vocabulary is a file of the allowed
+// terms, one a line, and those not in it, ignoring case, are listed in the
+// unknownKeywordsColumn.
+type keywordConfig struct {
+	Normalize  bool   `yaml:"normalize"`
+	Lowercase  bool   `yaml:"lowercase"`
+	Vocabulary string `yaml:"vocabulary"`
+}
+
+// validate checks the Vocabulary can be read.
+func (kc keywordConfig) validate() error {
+	_, err := kc.compile()
+	return err
+}
+
+// compile returns the keywordPolicy for the config, reading the Vocabulary.
+func (kc keywordConfig) compile() (*keywordPolicy, error) {
+	kp := &keywordPolicy{normalize: kc.Normalize, lowercase: kc.Lowercase}
+	if kc.Vocabulary == "" {
+		return kp, nil
+	}
+	var err error
+	if kp.vocabulary, err = readVocabulary(kc.Vocabulary); err != nil {
+		return nil, fmt.Errorf("keywords: vocabulary: %s", err)
+	}
+	return kp, nil
+}
+
+// readVocabulary returns the lowercased terms in the file at p, one a line.
+// Blank lines and those starting with # are skipped.
+func readVocabulary(p string) (map[string]bool, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	vocabulary := map[string]bool{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		term := strings.TrimSpace(s.Text())
+		if term != "" && !strings.HasPrefix(term, "#") {
+			vocabulary[strings.ToLower(term)] = true
+		}
+	}
+	return vocabulary, s.Err()
+}
+
+// keywordPolicy is how the Keywords are written and checked, see
+// keywordConfig.
+type keywordPolicy struct {
+	normalize  bool
+	lowercase  bool
+	vocabulary map[string]bool
+}
+
+// splitKeywords returns the comma or semicolon separated keywords, trimmed,
+// without empty ones.
+func splitKeywords(s string) []string {
+	var kws []string
+	for _, kw := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			kws = append(kws, kw)
+		}
+	}
+	return kws
+}
+
+// dedupeKeywords returns kws without repeats, ignoring case, keeping the
+// first of each.
+func dedupeKeywords(kws []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, kw := range kws {
+		if !seen[strings.ToLower(kw)] {
+			seen[strings.ToLower(kw)] = true
+			out = append(out, kw)
+		}
+	}
+	return out
+}
+
+// format returns e's Keywords as they go in the Keywords column: as they are
+// without normalize.
+func (kp *keywordPolicy) format(e exif) string {
+	if kp == nil || !kp.normalize {
+		return e.Keywords()
+	}
+	kws := e.KeywordList()
+	if kp.lowercase {
+		for i, kw := range kws {
+			kws[i] = strings.ToLower(kw)
+		}
+	}
+	return strings.Join(dedupeKeywords(kws), "; ")
+}
+
+// unknown returns e's keywords not in the vocabulary, separated by "; ", or
+// "" if there's no vocabulary.
+func (kp *keywordPolicy) unknown(e exif) string {
+	if kp == nil || kp.vocabulary == nil {
+		return ""
+	}
+	var unknown []string
+	for _, kw := range dedupeKeywords(e.KeywordList()) {
+		if !kp.vocabulary[strings.ToLower(kw)] {
+			unknown = append(unknown, kw)
+		}
+	}
+	return strings.Join(unknown, "; ")
+}
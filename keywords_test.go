@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeywordPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	vocab := filepath.Join(dir, "vocabulary.txt")
+	equals(t, ioutil.WriteFile(vocab, []byte("# NASA thesaurus\nLaunch\nSpace Shuttle\n\nISS\n"), 0644), nil)
+
+	kw := func(keywords string) exif {
+		e := newExif()
+		e.IPTC["Keywords"] = keywords
+		return e
+	}
+	kp, err := keywordConfig{Normalize: true, Vocabulary: vocab}.compile()
+	equals(t, err, nil)
+	equals(t, kp.format(kw(" Launch, space shuttle;launch,, Pad 39A ")), "Launch; space shuttle; Pad 39A")
+	equals(t, kp.unknown(kw(" Launch, space shuttle;launch,, Pad 39A ")), "Pad 39A")
+	equals(t, kp.unknown(kw("iss")), "")
+
+	kp, err = keywordConfig{Normalize: true, Lowercase: true}.compile()
+	equals(t, err, nil)
+	equals(t, kp.format(kw("Launch, ISS, launch")), "launch; iss")
+	equals(t, kp.unknown(kw("Launch")), "")
+
+	kp, err = keywordConfig{}.compile()
+	equals(t, err, nil)
+	equals(t, kp.format(kw("Launch, ISS, launch")), "Launch, ISS, launch")
+
+	var none *keywordPolicy
+	equals(t, none.format(kw("Launch, ISS")), "Launch, ISS")
+
+	_, err = keywordConfig{Vocabulary: filepath.Join(dir, "missing.txt")}.compile()
+	equals(t, err != nil, true)
+}
+
+func TestMakeRowKeywords(t *testing.T) {
+	e := newExif()
+	e.Data["FileName"] = "a.jpg"
+	e.IPTC["Keywords"] = "Launch, Pad 39A, launch"
+	e.keywords = &keywordPolicy{normalize: true, vocabulary: map[string]bool{"launch": true}}
+	rows := make(chan []string, 1)
+	e.MakeRow(rows, "a.jpg", "Accepted", "", "")
+	row := <-rows
+	equals(t, row[column("Keywords")], "Launch; Pad 39A")
+	equals(t, row[column(unknownKeywordsColumn)], "Pad 39A")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// languagesColumn lists the languages the file's text is in, only in the CSV
+// output with -languages or when the config's columns list it.
+const languagesColumn = "Languages"
+
+// langDefault is the language of a language alternative's default value,
+// which exiftool gives the tag's plain name.
+const langDefault = "x-default"
+
+// langAltTags are the XMP language alternatives chkmd reads, which may have
+// a value per language. exiftool gives the other languages' values the tag's
+// name and the language, like Title-de.
+var langAltTags = []string{"Title", "Description", "AltTextAccessibility", "ExtDescrAccessibility"}
+
+// langSuffix marks a source as the -lang value of a language alternative.
+const langSuffix = "-lang"
+
+// langAltSource is a source reading the -lang value of an XMP language
+// alternative, which the accessors prefer to any other.
+func langAltSource(tag string) source {
+	return source{"XMP:" + tag + langSuffix, func(e exif) string {
+		return e.langAlt(tag)
+	}}
+}
+
+// langAlt returns the XMP tag's value in the -lang language, or "" if there
+// isn't one or no -lang. A value in a more specific language, like en-US for
+// en, will do if there's none in the language itself.
+func (e exif) langAlt(tag string) string {
+	if e.lang == "" || strings.EqualFold(e.lang, langDefault) {
+		return ""
+	}
+	if v := e.XMP[tag+"-"+e.lang]; v != "" {
+		return v
+	}
+	var keys []string
+	for k := range e.XMP {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if lang := strings.TrimPrefix(k, tag+"-"); lang != k && e.XMP[k] != "" &&
+			(strings.EqualFold(lang, e.lang) || hasPrefixFold(lang, e.lang+"-")) {
+			return e.XMP[k]
+		}
+	}
+	return ""
+}
+
+// hasPrefixFold is strings.HasPrefix ignoring case.
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// xmp returns the XMP tag's value, in the -lang language if it has one.
+func (e exif) xmp(tag string) string {
+	if v := e.langAlt(tag); v != "" {
+		return v
+	}
+	return e.XMP[tag]
+}
+
+// Languages returns the languages the language alternatives have values
+// in, x-default first.
+func (e exif) Languages() []string {
+	seen := map[string]bool{}
+	var langs []string
+	for k, v := range e.XMP {
+		if v == "" {
+			continue
+		}
+		for _, tag := range langAltTags {
+			lang := langDefault
+			if k != tag {
+				if lang = strings.TrimPrefix(k, tag+"-"); lang == k {
+					continue
+				}
+			}
+			if !seen[lang] {
+				seen[lang] = true
+				langs = append(langs, lang)
+			}
+		}
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		if langs[i] == langDefault || langs[j] == langDefault {
+			return langs[i] == langDefault
+		}
+		return langs[i] < langs[j]
+	})
+	return langs
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestLangAlt(t *testing.T) {
+	e := newExif()
+	e.IPTC["ObjectName"] = "Launch"
+	e.XMP["Title"] = "Launch"
+	e.XMP["Title-de"] = "Start"
+	e.XMP["Title-fr-CA"] = "Lancement"
+	e.XMP["Description"] = "The launch"
+
+	for _, v := range []struct {
+		lang, title, description string
+	}{
+		{"", "Launch", "The launch"},
+		{"x-default", "Launch", "The launch"},
+		{"de", "Start", "The launch"},
+		{"DE", "Start", "The launch"},
+		{"fr", "Lancement", "The launch"},
+		{"fr-FR", "Launch", "The launch"},
+		{"es", "Launch", "The launch"},
+	} {
+		e.lang = v.lang
+		equals(t, e.Title(), v.title)
+		equals(t, e.Description(), v.description)
+		equals(t, fieldSources["Title"][0].get(e), e.langAlt("Title"))
+	}
+	equals(t, e.Languages(), []string{"x-default", "de", "fr-CA"})
+
+	// A mapped XMP source is read in the language too.
+	e.lang = "de"
+	e.fields = map[string][]source{"Title": {tagSource("XMP", "Title")}}
+	equals(t, e.Title(), "Start")
+}
+
+func TestLangSources(t *testing.T) {
+	e := newExif()
+	equals(t, len(e.sources("Title")), len(fieldSources["Title"])-1)
+	e.lang = "de"
+	equals(t, len(e.sources("Title")), len(fieldSources["Title"]))
+	equals(t, sourceTags(langAltSource("Title")), []string{"XMP:Title"})
+}
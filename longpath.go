@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+)
+
+// Windows limits paths to maxPath characters, MAX_PATH less the NUL, unless
+// they're extended-length paths, starting with extendedPrefix, or
+// extendedUNCPrefix in place of the \\ of a share's.
+const (
+	maxPath           = 259
+	extendedPrefix    = `\\?\`
+	extendedUNCPrefix = `\\?\UNC\`
+)
+
+// extendedLength returns the absolute Windows path abs as an extended-length
+// path if it's too long for MAX_PATH, with its slashes made backslashes as
+// they must be. Shorter paths and those already extended are left alone.
+func extendedLength(abs string) string {
+	if strings.HasPrefix(abs, extendedPrefix) || len(abs) <= maxPath {
+		return abs
+	}
+	abs = strings.Replace(abs, "/", `\`, -1)
+	if strings.HasPrefix(abs, `\\`) {
+		return extendedUNCPrefix + abs[2:]
+	}
+	return extendedPrefix + abs
+}
+
+// plainWindowsPath returns the extended-length path p as an ordinary one, a
+// share's starting \\ again, so it can be compared with and made relative to
+// the paths the walk finds. Other paths are left alone.
+func plainWindowsPath(p string) string {
+	switch {
+	case strings.HasPrefix(p, extendedUNCPrefix):
+		return `\\` + p[len(extendedUNCPrefix):]
+	case strings.HasPrefix(p, extendedPrefix):
+		return p[len(extendedPrefix):]
+	}
+	return p
+}
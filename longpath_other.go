@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+// exiftoolPath returns p, which exiftool can open however long it is where
+// there's no MAX_PATH.
+func exiftoolPath(p string) string {
+	return p
+}
+
+// plainPath returns d, as there are no extended-length paths.
+func plainPath(d string) string {
+	return d
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtendedLength(t *testing.T) {
+	long := strings.Repeat("delivery-2019-08-apollo-anniversary-", 8)
+	equals(t, extendedLength(`C:\archive\a.jpg`), `C:\archive\a.jpg`)
+	equals(t, extendedLength(`C:\archive\`+long+`\a.jpg`), `\\?\C:\archive\`+long+`\a.jpg`)
+	equals(t, extendedLength(`C:/archive/`+long+`/a.jpg`), `\\?\C:\archive\`+long+`\a.jpg`)
+	equals(t, extendedLength(`\\files\media\`+long+`\a.jpg`), `\\?\UNC\files\media\`+long+`\a.jpg`)
+	equals(t, extendedLength(`\\?\UNC\files\media\`+long+`\a.jpg`), `\\?\UNC\files\media\`+long+`\a.jpg`)
+}
+
+func TestPlainWindowsPath(t *testing.T) {
+	equals(t, plainWindowsPath(`\\?\UNC\files\media\deliveries`), `\\files\media\deliveries`)
+	equals(t, plainWindowsPath(`\\?\D:\deliveries`), `D:\deliveries`)
+	equals(t, plainWindowsPath(`\\files\media`), `\\files\media`)
+	equals(t, plainWindowsPath(`/media/deliveries`), `/media/deliveries`)
+}
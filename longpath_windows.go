@@ -0,0 +1,25 @@
+package main
+
+import (
+	"path/filepath"
+)
+
+// exiftoolPath returns the path to give exiftool for the file at p, as an
+// extended-length path if it's longer than MAX_PATH, which exiftool can't
+// open otherwise. Go does this for itself.
+func exiftoolPath(p string) string {
+	if len(p) <= maxPath && filepath.IsAbs(p) {
+		return p
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	return extendedLength(abs)
+}
+
+// plainPath returns a -d given as an extended-length path, like
+// \\?\UNC\server\share, as an ordinary one.
+func plainPath(d string) string {
+	return plainWindowsPath(d)
+}
@@ -36,14 +36,17 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/csv"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"mime"
 	"os"
 	"os/exec"
-	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -60,20 +63,193 @@ const (
 	exifNanoDate     = "2006:01:02 15:04:05.00"
 	exifDateZone     = "2006:01:02 15:04:05-07:00"
 	exifNanoDateZone = "2006:01:02 15:04:05.00-07:00"
-	na               = "N/A"
 )
 
+// options are the command line flags.
+type options struct {
+	cfgfile string
+	dirs    dirList
+	output  string
+	procs   procCount
+	walkers int
+	timeout time.Duration
+	verbose bool
+
+	sheetID    string
+	sheetRange string
+	sheetKey   string
+	tickets    bool
+	fix        string
+	fixSidecar bool
+	clusterOut string
+	traceField string
+	object     string
+	webhookURL string
+	watch      bool
+	watchEvery time.Duration
+	quiet      time.Duration
+	urls       string
+	inventory  string
+	exportDir  string
+	exportTmpl string
+	resume     string
+	verify     string
+	driftOut   string
+	dupsOut    string
+	record     string
+	replay     string
+	egress     byteSize
+	dumpOut    string
+	since      string
+	changesOut string
+	writeID    string
+	termsOut   string
+	detailsOut string
+	summaryOut string
+	thumbsDir  string
+	cacheDir   string
+	// deliveriesDir is for -deliveries.
+	deliveriesDir string
+	// webhookBatch is how many results -webhook posts at a time.
+	webhookBatch int
+	// baseline is a results CSV from an earlier run to compare -o to.
+	baseline string
+	// baselineOut is where the -baseline changes go, stderr if "".
+	baselineOut string
+	// archives is how many levels of archives -d is walked into.
+	archives int
+	// rollupOut is for -rollup, grouping by rollupBy.
+	rollupOut string
+	rollupBy  string
+	// runID identifies the run in the -o database.
+	runID string
+	// manifest is where to write the runManifest, if not beside -o.
+	manifest string
+	// documents checks the documentTypes too.
+	documents bool
+	// notify emails the summary when the run's done, per the config.
+	notify bool
+	// sniff finds relevant files by their content too.
+	sniff bool
+	// profile is the config's profile to check with.
+	profile string
+	// exportXMP is the -export-xmp directory.
+	exportXMP string
+	// technical adds the technicalColumns.
+	technical bool
+	// timings adds the timingColumns.
+	timings bool
+	// serve is the address to answer gRPC calls on, over TLS with
+	// serveCert and serveKey if they're given, instead of checking -d once.
+	serve     string
+	serveCert string
+	serveKey  string
+	// encoding is the -o CSV's, from -bom and -utf16.
+	encoding outputEncoding
+	// append carries on writing -o, and splitSize splits it into files of
+	// that many rows.
+	append    bool
+	splitSize int
+	// lang is the language to read titles and descriptions in, and
+	// languages adds the languagesColumn.
+	lang      string
+	languages bool
+
+	failOnReject  bool
+	maxRejectRate percent
+	workingSet    string
+	rights        bool
+	from          string
+	shard         shard
+	pathMode      pathMode
+}
+
+// newOptions defines the flags on fs, to be filled in by fs.Parse.
+func newOptions(fs *flag.FlagSet) *options {
+	o := &options{maxRejectRate: -1}
+	fs.StringVar(&o.cfgfile, "c", "", "The config file to read from.")
+	fs.StringVar(&o.profile, "profile", "", "The profile in the config file to check with, like images-strict.")
+	fs.Var(&o.dirs, "d", "The directory, or s3://, gs:// or az://bucket/prefix, to process, recursively. Repeat it, or separate them with commas, for several.")
+	fs.StringVar(&o.output, "o", "", "A file, or a postgres:// database, to output to.")
+	fs.StringVar(&o.manifest, "manifest", "", "A file to write the run's versions, config hash, args and times to as JSON, instead of beside -o as out.manifest.json.")
+	fs.StringVar(&o.runID, "run-id", "", "The ID of the run in the -o database, by default when it started and some random hex.")
+	o.procs = procCount{n: runtime.NumCPU()}
+	fs.Var(&o.procs, "p", "The number of processes to run, or auto to tune it as the run goes.")
+	fs.IntVar(&o.walkers, "walkers", 8, "The number of directories to read at once when walking -d.")
+	fs.DurationVar(&o.timeout, "timeout", time.Minute, "How long exiftool may take on a file before it's Rejected. 0 waits forever.")
+	fs.BoolVar(&o.verbose, "v", false, "Be noisy while processing. Really, just print errors.")
+	fs.BoolVar(&o.failOnReject, "fail-on-reject", false, "Exit with status 2 if any file is Rejected or Incomplete.")
+	fs.Var(&o.maxRejectRate, "max-reject-rate", "Exit with status 2 if more than this many files, e.g. 5%, are Rejected or Incomplete.")
+
+	fs.StringVar(&o.sheetID, "sheet", "", "A Google Sheet ID to append results to.")
+	fs.StringVar(&o.sheetRange, "sheet-range", "Sheet1", "The sheet (tab) name to append results to.")
+	fs.StringVar(&o.sheetKey, "sheet-key", os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"), "The service account key file for -sheet.")
+	fs.BoolVar(&o.tickets, "tickets", false, "Open or update a ticket per delivery folder with rejects, per the config.")
+	fs.StringVar(&o.fix, "fix", "", "A CSV of corrections to write to Incomplete files, keyed on Path or NASA ID.")
+	fs.BoolVar(&o.fixSidecar, "fix-sidecar", false, "Write -fix corrections to an XMP sidecar instead of the file.")
+	fs.BoolVar(&o.rights, "rights", false, "Add the Copyright and Usage Terms columns to the output, unless the config lists its columns.")
+	fs.StringVar(&o.clusterOut, "clusters", "", "A file to write the files sharing a Description to.")
+	fs.StringVar(&o.termsOut, "terms", "", "A file to write every Keyword and Photographer to, with how many files have it.")
+	fs.StringVar(&o.traceField, "trace-field", "", "Log how this field, e.g. Description, was resolved for each file.")
+	fs.StringVar(&o.object, "object", "", "Check just this file or s3:// object, writing the result as JSON.")
+	fs.StringVar(&o.webhookURL, "webhook", "", "A URL to POST each file's result and metadata to as JSON as it's checked, and the summary when done.")
+	fs.StringVar(&o.lang, "lang", "", "The language, e.g. de, to read XMP titles and descriptions in, if they have it, rather than x-default.")
+	fs.BoolVar(&o.languages, "languages", false, "Add the Languages column, the languages each file's titles and descriptions are in.")
+	fs.IntVar(&o.webhookBatch, "webhook-batch", 0, "Post -webhook results this many at a time instead of one by one.")
+	fs.StringVar(&o.baseline, "baseline", "", "A results CSV from an earlier run to report the changes in -o from, as chkmd diff does.")
+	fs.StringVar(&o.baselineOut, "baseline-changes", "", "A file to write the -baseline changes to, instead of stderr.")
+	fs.BoolVar(&o.append, "append", false, "Add the rows to the end of -o if it's there, without another header.")
+	fs.IntVar(&o.splitSize, "split-size", 0, "Split -o into files of this many rows, like out-001.csv, out-002.csv, each with the header.")
+	fs.BoolVar(&o.technical, "technical", false, "Add the Image Width, Image Height, Orientation, Color Profile and Bit Depth columns to the output, unless the config lists its columns.")
+	fs.BoolVar(&o.encoding.bom, "bom", false, "Start the CSV output with a UTF-8 byte order mark, so Excel reads it as UTF-8.")
+	fs.BoolVar(&o.encoding.utf16, "utf16", false, "Write the CSV output as UTF-16LE, with a byte order mark, for older Excel.")
+	fs.BoolVar(&o.timings, "timings", false, "Add the Bytes and Extraction ms columns to the output, to find the files slow to check, unless the config lists its columns.")
+	fs.StringVar(&o.serve, "serve", "", "An address, e.g. :8443, to answer gRPC calls to check files under -d on, per chkmd.proto, until interrupted.")
+	fs.StringVar(&o.serveCert, "serve-cert", "", "The TLS certificate file for -serve, which is plaintext without it.")
+	fs.StringVar(&o.serveKey, "serve-key", "", "The TLS key file for -serve.")
+	fs.IntVar(&o.archives, "archives", 0, "Check the files in .zip, .tar and .tar.gz archives under -d, and in archives in them this many levels deep.")
+	fs.BoolVar(&o.watch, "watch", false, "Keep checking -d for new and changed files until interrupted.")
+	fs.DurationVar(&o.watchEvery, "watch-interval", 2*time.Second, "How often -watch scans -d.")
+	fs.DurationVar(&o.quiet, "quiet", 5*time.Second, "How long a file must be unchanged before -watch checks it.")
+	fs.StringVar(&o.urls, "urls", "", "A file of http(s) URLs, e.g. pre-signed, to check instead of -d. - reads stdin.")
+	fs.StringVar(&o.inventory, "inventory", "", "An S3 Inventory manifest.json to read the objects in -d s3:// from, instead of listing them.")
+	fs.StringVar(&o.exportDir, "export", "", "A directory to copy accepted files to, laid out by -export-layout.")
+	fs.BoolVar(&o.documents, "documents", false, "Check PDFs too, with the Media Type document.")
+	fs.BoolVar(&o.notify, "notify", false, "Email the summary, with the report, when done, per the config.")
+	fs.BoolVar(&o.sniff, "sniff", false, "Check files in -d whose extensions aren't relevant if their content is, e.g. TIFFs named .dat.")
+	fs.StringVar(&o.exportXMP, "export-xmp", "", "A directory to write an XMP sidecar of each checked file's resolved Title, Description, Date Created, Keywords and Photographer to.")
+	fs.StringVar(&o.exportTmpl, "export-layout", defaultExportLayout, "The text/template for where -export copies each file to.")
+	fs.StringVar(&o.resume, "resume", "", "A journal of processed files; rerun with it to skip them and append to -o.")
+	fs.StringVar(&o.verify, "verify", "", "An AVAIL export (CSV or JSON) to compare metadata to by NASA ID.")
+	fs.StringVar(&o.driftOut, "drift", "", "A file to write the -verify differences to, instead of stderr.")
+	fs.StringVar(&o.record, "record", "", "A directory to record each file's exiftool output to, for -replay.")
+	fs.StringVar(&o.replay, "replay", "", "A directory of recorded exiftool output to read instead of running exiftool.")
+	fs.Var(&o.egress, "egress-budget", "The most to download from S3 or URLs, e.g. 50GB, before stopping early. 0 is no limit.")
+	fs.StringVar(&o.summaryOut, "summary", "", "A file to write the summary to as JSON, with counts by reason, media type and extension.")
+	fs.StringVar(&o.detailsOut, "details", "", "A file to write each file's rule outcomes and field sources to, as JSON lines.")
+	fs.StringVar(&o.dumpOut, "dump", "", "A file to write each file's embedded metadata to, as JSON lines, for -since.")
+	fs.StringVar(&o.since, "since", "", "A previous -dump; only files changed since are checked, and their metadata changes reported.")
+	fs.StringVar(&o.changesOut, "changes", "", "A file to write the -since metadata changes to, instead of stderr.")
+	fs.StringVar(&o.thumbsDir, "thumbnails", "", "A directory to copy each checked file's embedded preview to, for reviewing the output.")
+	fs.StringVar(&o.writeID, "write-id", "", "A tag, e.g. XMP-dc:Identifier, to write each Accepted file's NASA ID to if it isn't there.")
+	fs.StringVar(&o.workingSet, "working-set", "", "Only list the relevant files in -d, with sizes and access hints, as JSON lines to this file.")
+	fs.StringVar(&o.from, "from", "", "A -working-set, or a path per line, of the files to check instead of walking -d.")
+	fs.Var(&o.pathMode, "path-mode", "Write the Path column relative to -d, absolute, or as a file:// uri, instead of as found.")
+	fs.Var(&o.shard, "shard", "Check only this shard, e.g. 3/8, of the files, to split a scan across machines. See chkmd merge.")
+	fs.StringVar(&o.deliveriesDir, "deliveries", "", "A directory to write a report and summary per delivery folder to, with a rollup of them all.")
+	fs.StringVar(&o.cacheDir, "cache", "", "A directory to cache each local file's metadata in, so later runs only extract files that changed.")
+	fs.StringVar(&o.rollupOut, "rollup", "", "A file to write the counts of each Status and how complete each field is, per top level directory or Center, to.")
+	fs.StringVar(&o.rollupBy, "rollup-by", rollupByDirectory, "Group -rollup by directory or center.")
+	fs.StringVar(&o.dupsOut, "duplicates", "", "A file to write the files sharing content or a NASA ID to.")
+	return o
+}
+
 var (
-	cfgfile = flag.String("c", "", "The config file to read from.")
-	dir     = flag.String("d", "", "The directory to process, recursively.")
-	output  = flag.String("o", "", "A file to output to.")
-	procs   = flag.Int("p", runtime.NumCPU(), "The number of processes to run.")
-	verbose = flag.Bool("v", false, "Be noisy while processing. Really, just print errors.")
-
-	mimeTypes = make(map[string]bool)
-	ingroup   sync.WaitGroup
-	outgroup  sync.WaitGroup
-	csvHeader = []string{
+	// defaultTypeSet is defaultTypes as a set.
+	defaultTypeSet = config{MimeTypes: defaultTypes}.mimeTypeSet()
+	// defaultMediaTypeSet is defaultMediaTypes as a set.
+	defaultMediaTypeSet = config{}.mediaTypeSet()
+	csvHeader           = []string{
 		"Path",
 		"Status",
 		"Reason",
@@ -90,37 +266,207 @@ var (
 		"Secondary Creator Credit",
 		"Photographer",
 		"Album",
+		"Extraction Warnings",
+		"Copyright",
+		"Usage Terms",
+		romanizedColumn,
+		unknownKeywordsColumn,
+		languagesColumn,
+		"Image Width",
+		"Image Height",
+		"Orientation",
+		"Color Profile",
+		"Bit Depth",
+		"Bytes",
+		"Extraction ms",
+		warningsColumn,
 	}
+	// rightsColumns are only in the CSV output with -rights.
+	rightsColumns = []string{"Copyright", "Usage Terms"}
 )
 
 // statistics tracks our statistics.
 type statistics struct {
-	Total    int32
-	Relevant int32
-	Reject   int32
-	Accept   int32
+	Total     int32
+	Relevant  int32
+	Reject    int32
+	Accept    int32
+	Similar   int32
+	Fixed     int32
+	Skipped   int32
+	Derived   int32
+	Modified  int32
+	TimedOut  int32
+	Warned    int32
+	Pool      poolStats
+	Unchanged int32
+	WroteID   int32
+	Previews  int32
+	BadNames  int32
+	Cached    int32
+	BadDates  int32
+	// BelowQuality counts the files below the thresholds, which are in
+	// Reject too.
+	BelowQuality int32
+	// AcceptedWarnings counts the files Accepted with warnings, which are in
+	// Accept too.
+	AcceptedWarnings int32
+	// Retried counts the files extracted again after a transient error.
+	Retried int32
+	// Sniffed counts the files found relevant by their content, with -sniff,
+	// which are in Relevant too.
+	Sniffed int32
+	// XMPExported counts the -export-xmp sidecars written.
+	XMPExported int32
+	// PermissionDenied, Empty and NotMedia count the files that couldn't
+	// be checked for those reasons, see preflight, which are in Reject too.
+	PermissionDenied int32
+	Empty            int32
+	NotMedia         int32
+	Quality          *scorecard
+	// Reasons counts why files were Incomplete.
+	Reasons *reasonCounts
 }
 
 // config holds the config.
 type config struct {
 	MimeTypes []string `yaml:"mime_types"`
+	// MediaTypes are the top level MIME types, like image, a Media Type may
+	// be. Without them it's defaultMediaTypes.
+	MediaTypes []string      `yaml:"media_types"`
+	Tickets    ticketConfig  `yaml:"tickets"`
+	Notify     notifyConfig  `yaml:"notify"`
+	Rules      rules         `yaml:"rules"`
+	Quality    qualityConfig `yaml:"quality"`
+	// TitleSimilarity is the edit distance, as a fraction of length, under
+	// which a Description is flagged as a copy of the Title. 0 uses
+	// defaultTitleSimilarity and a negative number turns the check off.
+	TitleSimilarity float64 `yaml:"title_similarity"`
+	// DescriptionClusters is how many files must share a Description to be
+	// reported. 0 uses defaultClusterSize.
+	DescriptionClusters int `yaml:"description_clusters"`
+	// TopTerms is how many of the most frequent Keywords and Photographers
+	// the summary lists. 0 uses defaultTopTerms and a negative number
+	// leaves them out.
+	TopTerms int         `yaml:"top_terms"`
+	Albums   albumConfig `yaml:"albums"`
+	// Derivatives are skipped, see derivativeConfig.
+	Derivatives derivativeConfig `yaml:"derivatives"`
+	// SidecarConflicts is which wins when a file's XMP and its sidecar
+	// disagree: embedded-wins (the default), sidecar-wins or
+	// conflict-warning, which keeps the embedded value and notes it.
+	SidecarConflicts string `yaml:"sidecar_conflicts"`
+	// SidecarPrecedence lists where XMP is read from, highest precedence
+	// first, see sidecarPrecedence.
+	SidecarPrecedence []string `yaml:"sidecar_precedence"`
+	// Centers maps Center values to the names we use.
+	Centers []centerName `yaml:"centers"`
+	// Exiftool sets when each worker's exiftool is recycled.
+	Exiftool poolConfig `yaml:"exiftool"`
+	// Duplicates sets the content hash for -duplicates.
+	Duplicates duplicateConfig `yaml:"duplicates"`
+	// Fields replaces the sources of any field, see fieldMapping.
+	Fields fieldMapping `yaml:"fields"`
+	// Columns are the columns of the CSV output, in order, instead of
+	// csvHeader, less the rightsColumns without -rights and romanizedColumn
+	// unless it's asked for. ColumnHeaders renames columns in its header.
+	Columns       []string          `yaml:"columns"`
+	ColumnHeaders map[string]string `yaml:"column_headers"`
+	// RomanizeLocation is how to romanize Location, if at all, see
+	// romanizeReplace and romanizeColumn.
+	RomanizeLocation string `yaml:"romanize_location"`
+	// Scripts defines the functions fields and checks may use, see scripts.
+	Scripts string `yaml:"scripts"`
+	// Checks are functions in the Scripts files must pass, besides the
+	// Rules, to be Accepted, see scriptCheck.
+	Checks []scriptCheck `yaml:"checks"`
+	// Hooks are commands run on each file once it's checked, which may
+	// change its row, see hookConfig.
+	Hooks hooks `yaml:"hooks"`
+	// Dates bounds a plausible Date Created, see dateConfig.
+	Dates dateConfig `yaml:"dates"`
+	// Keywords is how Keywords are written and checked, see keywordConfig.
+	Keywords keywordConfig `yaml:"keywords"`
+	// Thresholds are the technical minimums files must meet, see
+	// thresholdConfig.
+	Thresholds thresholdConfig `yaml:"thresholds"`
+	// MaxLengths are the most characters a column may have, on top of
+	// defaultMaxLengths.
+	MaxLengths maxLengthConfig `yaml:"max_lengths"`
+	// FilenamePatterns infer fields missing from the metadata from file
+	// names, see inferenceConfig.
+	FilenamePatterns inferenceConfig `yaml:"filename_patterns"`
+	// Version is the schema of the config, see configVersion, and Profiles
+	// the settings -profile may choose, see profile.
+	Version  int                `yaml:"version"`
+	Profiles map[string]profile `yaml:"profiles"`
 }
 
-// Exif is our Exif data structure.
+// Exif is our Exif data structure. Folder holds what the file inherits from
+// metadata.yaml and .chkmd.yaml files, which is used after any embedded
+// metadata, and Filename what the config's filename_patterns infer from its
+// name, which is used last of all. Conflicts lists the XMP tags its sidecar
+// set differently. ID3 holds an MP3's ID3v2 frames and RIFF a WAV's
+// Broadcast WAV bext chunk and INFO list, which are used after the image
+// standards for audio. Model holds a 3D model's own metadata, see
+// modelExtract. Lists holds the items of exiftool's list tags, like
+// Keywords, keyed by group:tag, which the maps have separated by commas. PDF
+// holds a PDF's Info dictionary, used after the standards for documents.
 type exif struct {
-	Data map[string]string
-	Exif map[string]string
-	IPTC map[string]string
-	XMP  map[string]string
+	Data      map[string]string
+	Exif      map[string]string
+	IPTC      map[string]string
+	XMP       map[string]string
+	ID3       map[string]string
+	RIFF      map[string]string
+	Model     map[string]string
+	PDF       map[string]string
+	Folder    map[string]string
+	Filename  map[string]string
+	Lists     map[string][]string
+	Conflicts []string
+	// Hash is the content hash, when looking for -duplicates.
+	Hash string
+	// types, media, centers, fields, romanize, dates, keywords and lang are
+	// from the config and options, see runner.configure. Without them it's
+	// the default MIME and media types, Center isn't normalized, the fields
+	// come from fieldSources, Location isn't romanized, any date that parses
+	// will do, Keywords are as they are and there's no -lang.
+	types    map[string]bool
+	media    map[string]bool
+	centers  []centerName
+	fields   map[string][]source
+	romanize string
+	// dates is the config's plausible Date Created window, see DateProblem.
+	dates *dateConfig
+	// keywords is how the Keywords column is written and checked.
+	keywords *keywordPolicy
+	// lang is the -lang language language alternatives are read in.
+	lang string
+	// size is the file's size, if it's sized, and took how long extracting
+	// its metadata did, see timings.go.
+	size  int64
+	sized bool
+	took  time.Duration
+	// lengths are the most characters each column may have, see
+	// maxLengths.
+	lengths map[string]int
 }
 
 // newExif is an Exif constructor.
 func newExif() exif {
 	return exif{
-		Data: map[string]string{},
-		Exif: map[string]string{},
-		IPTC: map[string]string{},
-		XMP:  map[string]string{},
+		Data:     map[string]string{},
+		Exif:     map[string]string{},
+		IPTC:     map[string]string{},
+		XMP:      map[string]string{},
+		ID3:      map[string]string{},
+		RIFF:     map[string]string{},
+		Model:    map[string]string{},
+		PDF:      map[string]string{},
+		Folder:   map[string]string{},
+		Filename: map[string]string{},
+		Lists:    map[string][]string{},
 	}
 }
 
@@ -133,59 +479,53 @@ func newExif() exif {
 // them both and concatenating them. Trimming space will give us just the date.
 // Just the time should fail as we don't have a format for them. This failure
 // is just as well since a time is pretty pointless without the Year/Month/Day.
-// Next we try Exif.DateTimeOriginal and finally XMP.DateCreated.  It appears
+// Next we try Exif.DateTimeOriginal and XMP.DateCreated.  It appears
 // that XMP:CreateDate is when the representation of the resource is created
 // and Photoshop:DateCreated is when the copyrightable intellectual property
-// was created
+// was created. Audio has the ID3 recording time, the bext origination date
+// and time, or failing those the RIFF INFO creation date. Documents have
+// their xmp:CreateDate or PDF CreationDate.
 //
 // This field is available in our import template as 'Date Created'.
 func (e exif) DateCreated() (time.Time, error) {
-	var d string
-	// IPTC 3.1 p.1
-	// IPTC 6 pp. 34-35
-	// IPTC 7 p. 14                            - photoshop:DateCreated
-	// IPTC 7 p. 14                            - photoshop:TimeCreated
-	d = strings.TrimSpace(e.IPTC["DateCreated"] + " " + e.IPTC["TimeCreated"])
-	if d == "" {
-		// Exif 1 p.30 (36 in PDF)             - DateTimeOriginal
-		// Exif 3 p.9 (13 in PDF)              - exif:DateTimeOriginal
-		d = e.Exif["DateTimeOriginal"]
-	}
-	if d == "" {
-		// XMP 1 p.27 (35 in PDF)              - xmp:CreateDate ??? The digital or original.
-		// XMP 2 p.32                          - photoshop:DateCreated
-		d = e.XMP["DateCreated"]
-	}
+	return parseDate(e.dateCreatedText())
+}
 
-	return parseDate(d)
+// dateCreatedText returns the Date Created as written, see DateCreated.
+func (e exif) dateCreatedText() string {
+	return strings.TrimSpace(e.field("DateCreated"))
 }
 
-// HasDateCreated returns if DateCreated returns a value.
+// HasDateCreated returns if DateCreated returns a value, and it's plausible,
+// see DateProblem.
 func (e exif) HasDateCreated() bool {
 	_, err := e.DateCreated()
 	if err != nil {
 		return false
 	}
-	return true
+	return e.DateProblem() == ""
 }
 
 // Keywords returns the IPTC keywords value, or the XMP:Subject field. AFAICT
-// there is no Exif tag for this.
+// there is no Exif tag for this. Failing those we use any default keywords
+// from metadata.yaml.
 //
 // This field is available in our import template as 'Keywords'.
 func (e exif) Keywords() string {
-	var kw string
-	// IPTC 3.1 p.2                            - Keywords
-	// IPTC 6 p.31 (32 in PDF)                 - Keywords
-	// IPTC 7 p.12                             - dc:subject
-	kw = e.IPTC["Keywords"]
-	// Exif 1 p.28 (34 in PDF) says USerComment may be used for Keywords, but
-	// keywords isn't in Exif 3
-	if kw == "" {
-		// XMP 1 p.26 (34 in PDF)              - dc:subject
-		kw = e.XMP["Subject"]
+	return e.field("Keywords")
+}
+
+// KeywordList returns the Keywords one by one. Those from a list tag are
+// whole even if they have a comma in them, others are split on commas and
+// semicolons.
+func (e exif) KeywordList() []string {
+	kw := e.Keywords()
+	for _, tag := range [][2]string{{"IPTC", "Keywords"}, {"XMP", "Subject"}} {
+		if items, ok := e.list(tag[0], tag[1]); ok && strings.Join(items, ", ") == kw {
+			return append([]string{}, items...)
+		}
 	}
-	return kw
+	return splitKeywords(kw)
 }
 
 // HasKeywords returns true if Keywords is non-empty.
@@ -195,25 +535,13 @@ func (e exif) HasKeywords() bool {
 
 // Description returns the Description. Description has been mapped to
 // IPTC.Caption-Abstract tag, the Exif.ImageDescription tag and also
-// XMP.Description. So we try them in that order.
+// XMP.Description. So we try them in that order. Audio has the ID3 comment,
+// the bext description or the RIFF INFO comment, and documents the PDF
+// Subject. An XMP Description in the -lang language comes before them all.
 //
 // This field is available in our import template as 'Description'.
 func (e exif) Description() string {
-	var d string
-	// IPTC 3.1 p.2                            - Description
-	// IPTC 6 p.39 (40 in PDF)                 - Caption/Abstract (/ not valid in field so -?)
-	// IPTC 7 p.18                             - dc:description
-	d = e.IPTC["Caption-Abstract"]
-	if d == "" {
-		// Exif 1 p.22 (28)                    - ImageDescription
-		// Exif 3 p.6 (10)                     - dc:description
-		d = e.Exif["ImageDescription"]
-	}
-	if d == "" {
-		// XMP 1 p.25 (33)                     - dc:description
-		d = e.XMP["Description"]
-	}
-	return d
+	return e.field("Description")
 }
 
 // Has Description returns true if Description is non-empty.
@@ -234,38 +562,7 @@ func (e exif) HasDescription() bool {
 //
 // This field is available in our import template as 'NASA ID'.
 func (e exif) NasaID() string {
-	var id string
-	// IPTC 3.1 p.2 contains a field 'Title' that may be used for this AFAICT.
-	// IPTC 6 p.38 (39)                        - OriginalTransmissionReference
-	// IPTC 7 p.17                             - photoshop:TransmissionReference
-	id = e.IPTC["OriginalTransmissionReference"]
-	if id == "" {
-		// IPTC 1 p.7                          - Job Identifier
-		// IPTC 3.1 p.2                        - Job ID
-		// IPTC 5 p.15                         - JobID
-		id = e.IPTC["JobID"]
-
-	}
-	if id == "" {
-		// Exif 1 p. 45 (54)                   - ImageUniqueID
-		// Exif 3 p.17 (21)                    - exif:ImageUniqueID
-		id = e.Exif["ImageUniqueID"]
-	}
-	if id == "" {
-		// XMP 1 p.27 (35)                     - xmp:Identifier
-		// XMP 1 p.26 (34)                     - dc:identifier
-		id = e.XMP["Identifier"]
-	}
-	if id == "" {
-		// XMP 2 p.33                          - photoshop:TransmissionReference
-		id = e.XMP["TransmissionReference"]
-	}
-	if id == "" {
-		name := e.Data["FileName"]
-		ext := filepath.Ext(name)
-		id = name[0 : len(name)-len(ext)]
-	}
-	return id
+	return e.field("NasaID")
 }
 
 // HasNasaID returns if exif.NasaId() returns a non empty string.
@@ -275,27 +572,13 @@ func (e exif) HasNasaID() bool {
 
 // Title tries to return a valid title for the asset. This has been mapped to
 // IPTC.ObjectName or IPTC.Headline, but can also be XMP.Title. So we try
-// them in that order. I don't see an equivalent in Exif.
+// them in that order. I don't see an equivalent in Exif. Audio has the ID3
+// title or the RIFF INFO name, and documents the PDF Title. An XMP Title in
+// the -lang language comes before them all.
 //
 // This field is availale in out ingestion template as 'Title'.
 func (e exif) Title() string {
-	var t string
-	// IPTC 3.1 p.2 - Says Title is usually used for file name or id.
-	// IPTC 6 p.26 (27)                         - ObjectName
-	// IPTC 7 p.10                              - dc:title
-	t = e.IPTC["ObjectName"]
-	if t == "" {
-		// IPTC 6 p.39 (40)                     - Headline
-		// IPTC 7 p.17                          - photoshop:Headline
-		t = e.IPTC["Headline"]
-	}
-	// No such Exif???
-	if t == "" {
-		// XMP 1 p.27                           - dc:title
-		// XMP 2 p.32                           - photoshop:Headline
-		t = e.XMP["Title"]
-	}
-	return t
+	return e.field("Title")
 }
 
 // HasTitle returns  if exif.Title() returns a non empty string.
@@ -303,6 +586,21 @@ func (e exif) HasTitle() bool {
 	return e.Title() != ""
 }
 
+// AltText returns the IPTC 2021 Alt Text (Accessibility), or if that's
+// empty the Extended Description (Accessibility), which is for images that
+// need more than a short alt text. Either in the -lang language comes
+// first.
+//
+// This tag is available in our ingestion template as '508 Description'.
+func (e exif) AltText() string {
+	return e.field("AltText")
+}
+
+// HasAltText returns if exif.AltText() returns a non-empty string.
+func (e exif) HasAltText() bool {
+	return e.AltText() != ""
+}
+
 // Location appears particularly difficult to map cleanly.
 // IPTC:
 // IPTCCore [IPTC 3.1 p.1] says the Location fields:
@@ -311,41 +609,19 @@ func (e exif) HasTitle() bool {
 // are also fields we might be able to use in XMP. It appears that what I see
 // now in XMP are Creator City, Creator ..., But I am not sure that means the
 // subject matter would share the info. So we are only doing IPTC here at this
-// point. Exif appears not to support these tags but does have some GPS tags.
+// point. Exif appears not to support these tags but does have some GPS tags,
+// which we fall back to when there's no City, State or Country.
 //
 // These tags are collectively available in our ingestion template as 'Location'.
 func (e exif) Location() string {
-	var city, region, country string
 	var addr []string
-	// IPTC 6 p.37 (38)                        - City
-	// IPTC 7 p.16                             - photoshop:City
-	city = e.IPTC["City"]
-	if city == "" {
-		// XMP 2 p.32                          - photoshop:City
-		city = e.XMP["City"]
-	}
-	if city != "" {
-		addr = append(addr, city)
-	}
-	// IPTC 6 p.37 (38)                        - Province-State
-	// IPTC 7 p.16                             - photoshop:State
-	region = e.IPTC["Province-State"]
-	if region == "" {
-		// XMP 2 p.32                          - photoshop:State
-		region = e.XMP["State"]
-	}
-	if region != "" {
-		addr = append(addr, region)
-	}
-	// IPTC 6 p.38 (39)                        - Country-PrimaryLocationName
-	// IPTC 7 p.17                             - photoshop:Country
-	country = e.IPTC["Country-PrimaryLocationName"]
-	if country == "" {
-		// XMP 2 p.32                          - photoshop:Country
-		country = e.XMP["Country"]
-	}
-	if country != "" {
-		addr = append(addr, country)
+	for _, f := range []string{"City", "State", "Country"} {
+		if v := e.field(f); v != "" {
+			addr = append(addr, v)
+		}
+	}
+	if len(addr) == 0 {
+		return e.GPSLocation()
 	}
 	return strings.Join(addr, ", ")
 }
@@ -362,16 +638,10 @@ func (e exif) HasLocation() bool {
 //
 // This tag is available in our ingestion template as 'Media Type'.
 func (e exif) MediaType() string {
-	var t string
-	// XMP 1 p.26 (35)                         - dc:format
-	t = e.XMP["Format"]
-	if t == "" {
-		// This just pulls from exiftool fileinfo.
-		t = e.Data["MIMEType"]
-	}
-	if mimeTypes[t] {
-		t = strings.Split(t, "/")[0]
-		if t == "audio" || t == "image" || t == "video" {
+	t := e.rawType(e.field("MediaType"))
+	if e.mimeType(t) {
+		t = e.mediaTypeOf(t)
+		if e.mediaType(t) {
 			return t
 		}
 	}
@@ -384,43 +654,45 @@ func (e exif) HasMediaType() bool {
 }
 
 // FileFormat returns the exiftool file format if the MIME type is in
-// mimeTypes.  There is no 'file format' in the metadata standards that I can
+// the MIME types we check.  There is no 'file format' in the metadata standards that I can
 // see. AFAICT the only place it is is the MIMEType (dc:format) above.
 //
 // This tag is available in our ingestion template as 'File Format'
 func (e exif) FileFormat() string {
-	if mimeTypes[e.Data["MIMEType"]] {
-		// We pull this from the file data provided by exiftool
-		return e.Data["FileType"]
+	if e.mimeType(e.Data["MIMEType"]) {
+		return e.field("FileFormat")
 	}
 	return ""
 }
 
+// mimeType returns if t is one of the MIME types we check.
+func (e exif) mimeType(t string) bool {
+	if e.types == nil {
+		return defaultTypeSet[t]
+	}
+	return e.types[t]
+}
+
+// mediaType returns if t is one of the top level types a Media Type may be.
+func (e exif) mediaType(t string) bool {
+	if e.media == nil {
+		return defaultMediaTypeSet[t]
+	}
+	return e.media[t]
+}
+
 // HasFileFormat returns if exif.FileType() returns a non-empty value.
 func (e exif) HasFileFormat() bool {
 	return e.FileFormat() != ""
 }
 
 // Photographer returns the IPTC By-line. It that fails it falls back to XMP
-// Creator, then Exif Artist.
+// Creator, then Exif Artist, and last the directory default. Several
+// by-lines, or creators, are all credited, separated by commas, each once.
 //
 // This tag is available in our ingestion template as 'Photographer'.
 func (e exif) Photographer() string {
-	var p string
-	// IPTC 6 p.36 (37)                        - By-line
-	// IPTC 7 p.15                             - dc:creator
-	p = e.IPTC["By-line"]
-	if p == "" {
-		// Exif 1 p.23 (29) 				   - Artist
-		// Exif 2 p.40 (45) 				   - Artist
-		// Exif 3 p.6  (10)  				   - dc:creator
-		p = e.Exif["Artist"]
-	}
-	if p == "" {
-		// XMP 1 p.25  (33)					   - dc:creator
-		p = e.XMP["Artist"]
-	}
-	return p
+	return e.field("Photographer")
 }
 
 // HasPhotographer returns if exif.Photographer returns a non-empty value.
@@ -428,6 +700,82 @@ func (e exif) HasPhotographer() bool {
 	return e.Photographer() != ""
 }
 
+// Center returns the NASA center, which our photographers put in the IPTC
+// Credit or Source, or XMP photoshop:Credit. If they didn't, it's inherited
+// from metadata.yaml. The centers in the config normalize the name.
+//
+// This tag is available in our ingestion template as 'Center'.
+func (e exif) Center() string {
+	return normalizeCenter(e.centers, e.field("Center"))
+}
+
+// HasCenter returns if exif.Center returns a non-empty value.
+func (e exif) HasCenter() bool {
+	return e.Center() != ""
+}
+
+// Credit returns the secondary creator credit from the IPTC Writer-Editor.
+// If that fails it falls back to the IPTC Credit, then XMP CaptionWriter, and
+// last what's inherited from metadata.yaml.
+//
+// This tag is available in our ingestion template as 'Secondary Creator
+// Credit'.
+func (e exif) Credit() string {
+	return e.field("Credit")
+}
+
+// HasCredit returns if exif.Credit returns a non-empty value.
+func (e exif) HasCredit() bool {
+	return e.Credit() != ""
+}
+
+// Album returns the album inherited from metadata.yaml.
+//
+// This tag is available in our ingestion template as 'Album'.
+func (e exif) Album() string {
+	return e.field("Album")
+}
+
+// HasAlbum returns if exif.Album returns a non-empty value.
+func (e exif) HasAlbum() bool {
+	return e.Album() != ""
+}
+
+// Copyright returns the copyright notice from the IPTC CopyrightNotice,
+// then the Exif Copyright and XMP dc:rights.
+//
+// This tag is in our output as 'Copyright' with -rights.
+func (e exif) Copyright() string {
+	return e.field("Copyright")
+}
+
+// HasCopyright returns if exif.Copyright returns a non-empty value.
+func (e exif) HasCopyright() bool {
+	return e.Copyright() != ""
+}
+
+// UsageTerms returns the XMP xmpRights:UsageTerms, the instructions on how
+// the asset may be used. There's no IPTC IIM or Exif equivalent.
+//
+// This tag is in our output as 'Usage Terms' with -rights.
+func (e exif) UsageTerms() string {
+	return e.field("UsageTerms")
+}
+
+// HasUsageTerms returns if exif.UsageTerms returns a non-empty value.
+func (e exif) HasUsageTerms() bool {
+	return e.UsageTerms() != ""
+}
+
+// Warnings returns the warnings exiftool had about the file, e.g. "Bad IPTC
+// data". parseExifOutput keeps them all in Data, a line each.
+func (e exif) Warnings() []string {
+	if w := e.Data["Warning"]; w != "" {
+		return strings.Split(w, "\n")
+	}
+	return nil
+}
+
 // MakeErrorRow creates a sequence suitable for the CSV output when an error
 // has occured.
 func (e exif) MakeErrorRow(c chan []string, p string, err error) {
@@ -435,41 +783,56 @@ func (e exif) MakeErrorRow(c chan []string, p string, err error) {
 	for _ = range csvHeader[3:] {
 		erow = append(erow, "")
 	}
+	// How big and slow the file was is most of interest when it failed.
+	erow[column("Bytes")], erow[column("Extraction ms")] = e.Bytes(), e.ExtractionMs()
+	sanitizeRow(erow, nil)
 	c <- erow
 }
 
 // MakeRow makes a row suitable for CSV output with the data from an individual
-// file.
-func (e exif) MakeRow(c chan []string, p, status, reason string) {
+// file. If the DateCreated doesn't parse it's left out.
+func (e exif) MakeRow(c chan []string, p, status, reason, warnings string) {
 	var dc string
-	if e.HasDateCreated() {
-		dto, err := e.DateCreated()
-		if err != nil {
-			e.MakeErrorRow(c, p, err)
-			if *verbose {
-				log.Printf("Error getting DateCreated for %s: %s", p, err.Error())
-			}
-			return
-		}
+	if dto, err := e.DateCreated(); err == nil {
+		// Even if it's implausible, so it can be seen.
 		dc = dto.Format(time.RFC3339)
 	}
+	location := e.Location()
+	if e.romanize == romanizeReplace {
+		location = romanize(location)
+	}
 	row := []string{p,
 		status,
 		reason,
 		e.NasaID(),
 		e.Title(),
-		na,
+		e.AltText(),
 		e.Description(),
 		dc,
-		e.Location(),
-		e.Keywords(),
+		location,
+		e.keywords.format(e),
 		e.MediaType(),
 		e.FileFormat(),
-		na,
-		na,
+		e.Center(),
+		e.Credit(),
 		e.Photographer(),
-		na,
+		e.Album(),
+		strings.Join(e.Warnings(), "; "),
+		e.Copyright(),
+		e.UsageTerms(),
+		romanize(e.Location()),
+		e.keywords.unknown(e),
+		strings.Join(e.Languages(), "; "),
+		e.ImageWidth(),
+		e.ImageHeight(),
+		e.Orientation(),
+		e.ColorProfile(),
+		e.BitDepth(),
+		e.Bytes(),
+		e.ExtractionMs(),
+		warnings,
 	}
+	sanitizeRow(row, e.maxLengths())
 	c <- row
 }
 
@@ -505,78 +868,343 @@ func parseDate(d string) (time.Time, error) {
 
 }
 
-// getExifData gets the output of `exiftool p[ath]` and loads it into an exif struct.
-func getExifData(p string) (exif, error) {
-	exif := newExif()
+// getExifData gets the output of `exiftool args p[ath]` and loads it into an exif struct.
+// It gives up with errTimeout after timeout, unless that's 0.
+func getExifData(p string, args []string, timeout time.Duration) (exif, error) {
+	out, err := runExiftool(p, args, timeout)
+	if err != nil {
+		return newExif(), err
+	}
+	return parseExifOutput(out)
+}
 
-	cmd := exec.Command("exiftool", "-G", "-s", "-a", p)
+// runExiftool returns the output of `exiftool args p[ath]`, giving up with
+// errTimeout after timeout, unless that's 0.
+func runExiftool(p string, args []string, timeout time.Duration) (string, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	args = append(append([]string{}, args...), exiftoolPath(p))
+	cmd := detach(exec.CommandContext(ctx, "exiftool", args...))
 
-	var out bytes.Buffer
+	var out, stderr bytes.Buffer
 	cmd.Stdout = &out
+	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return exif, err
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", errTimeout
+		}
+		// exiftool says why, which retryExtract needs to know.
+		for _, line := range strings.Split(stderr.String(), "\n") {
+			if strings.HasPrefix(line, "Error") {
+				return "", errors.New(strings.TrimSpace(line))
+			}
+		}
+		return "", err
 	}
+	return out.String(), nil
+}
 
-	cmdOut := strings.Trim(out.String(), " \r\n")
+// parseExifOutput loads exiftool's output into an exif struct, with the IPTC
+// decoded to UTF-8. It's the JSON of `exiftool -j -G -struct -a`, or for
+// fixtures recorded before we asked for that, the text of `exiftool -G -s -a`.
+func parseExifOutput(s string) (exif, error) {
+	var e exif
+	if strings.HasPrefix(strings.TrimSpace(s), "[{") {
+		var err error
+		if e, err = parseExifJSON(s); err != nil {
+			return newExif(), err
+		}
+	} else {
+		e = parseExifText(s)
+	}
+	decodeIPTC(e.IPTC)
+	return e, nil
+}
+
+// parseExifText loads the output of `exiftool -G -s -a` into an exif struct.
+// The tag is sliced from fixed columns, so long tag names and values over
+// several lines don't come out right.
+func parseExifText(s string) exif {
+	exif := newExif()
+
+	cmdOut := strings.Trim(s, " \r\n")
 	lines := strings.Split(cmdOut, "\n")
 
 	for _, line := range lines {
+		if len(line) < 50 {
+			continue
+		}
 		tk := strings.TrimSpace(line[0:48])
 		t := strings.TrimSpace(tk[0:15])
 		k := strings.TrimSpace(tk[16:])
 		v := strings.TrimSpace(line[50:])
+		exif.set(strings.Trim(t, "[]"), k, v)
+	}
 
-		switch {
-		case t == "[EXIF]":
-			exif.Exif[k] = v
-		case t == "[IPTC]":
-			exif.IPTC[k] = v
-		case t == "[XMP]":
-			exif.XMP[k] = v
-		default:
-			exif.Data[k] = v
+	return exif
+}
+
+// group returns the map for exiftool's group, and whether it's one of the
+// metadata standards rather than Data.
+func (e exif) group(name string) (map[string]string, bool) {
+	switch name {
+	case "EXIF":
+		return e.Exif, true
+	case "IPTC":
+		return e.IPTC, true
+	case "XMP":
+		return e.XMP, true
+	case "ID3":
+		return e.ID3, true
+	case "RIFF":
+		return e.RIFF, true
+	case "PDF":
+		return e.PDF, true
+	}
+	return e.Data, false
+}
+
+// set sets the tag in exiftool's group to the values, more than one for a
+// list, in the map for the group. In the metadata standards a tag that's
+// listed again, as -a does when it's in the file more than once, keeps
+// every distinct value, in Lists and separated by commas in the map, rather
+// than the last. In Data the last wins, but for warnings, a line each.
+func (e *exif) set(group, tag string, values ...string) {
+	m, standard := e.group(group)
+	v := strings.Join(values, ", ")
+	old, seen := m[tag]
+	switch {
+	case !standard && tag == "Warning" && old != "":
+		// -a lists every warning, not just the first.
+		m[tag] = old + "\n" + v
+	case !standard || !seen && len(values) == 1:
+		m[tag] = v
+	default:
+		key := group + ":" + tag
+		items, listed := e.Lists[key]
+		if seen && !listed {
+			items = []string{old}
 		}
+		for _, v := range values {
+			if !containsString(items, v) {
+				items = append(items, v)
+			}
+		}
+		e.Lists[key] = items
+		m[tag] = strings.Join(items, ", ")
 	}
+}
 
-	return exif, nil
+// containsString is whether ss has s.
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
 }
 
-// readConfig, uh, reads the config, and makes the values available.
-func readConfig(p string) {
-	var mtypes []string
-	switch {
-	case p != "":
-		b, err := ioutil.ReadFile(p)
-		if err != nil {
-			log.Fatalf("Couldn't open config file: %s. Error: %s", p, err)
+// list returns the items of the tag in exiftool's group if it's a list or
+// was in the file more than once. Lists only count while the map still has
+// them, not if a sidecar or a correction has replaced them since.
+func (e exif) list(group, tag string) ([]string, bool) {
+	m, _ := e.group(group)
+	items, ok := e.Lists[group+":"+tag]
+	if !ok || strings.Join(items, ", ") != m[tag] {
+		return nil, false
+	}
+	return items, true
+}
+
+// Values returns the tag's values in exiftool's group, like IPTC: each item
+// if it's a list, else the one value, if any. Scripts can use it, as
+// file.values, to count by-lines, say.
+func (e exif) Values(group, tag string) []string {
+	if items, ok := e.list(group, tag); ok {
+		return items
+	}
+	m, _ := e.group(group)
+	if v := m[tag]; v != "" {
+		return []string{v}
+	}
+	return nil
+}
+
+// titleSimilarity returns the TitleSimilarity threshold to use.
+func (c config) titleSimilarity() float64 {
+	if c.TitleSimilarity == 0 {
+		return defaultTitleSimilarity
+	}
+	return c.TitleSimilarity
+}
+
+// clusterSize returns the DescriptionClusters size to use.
+func (c config) clusterSize() int {
+	if c.DescriptionClusters == 0 {
+		return defaultClusterSize
+	}
+	return c.DescriptionClusters
+}
+
+// topTerms returns the TopTerms count to use.
+func (c config) topTerms() int {
+	if c.TopTerms == 0 {
+		return defaultTopTerms
+	}
+	return c.TopTerms
+}
+
+// readConfig, uh, reads the config. With no config file it's the defaults.
+func readConfig(p string) (config, error) {
+	if p == "" {
+		return config{MimeTypes: defaultTypes}, nil
+	}
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return config{}, fmt.Errorf("Couldn't open config file: %s. Error: %s", p, err)
+	}
+	conf := config{}
+	err = yaml.Unmarshal(b, &conf)
+	if err != nil {
+		return config{}, fmt.Errorf("Error parsing file %s: %s", p, err)
+	}
+	sc, err := compileScripts(conf.Scripts)
+	if err != nil {
+		return config{}, configError(p, b, []string{"scripts"}, err)
+	}
+	if keys, err := conf.validateProfiles(sc); err != nil {
+		return config{}, configError(p, b, keys, err)
+	}
+	for _, check := range []configCheck{
+		{"rules", conf.Rules.validate},
+		{"quality", conf.Quality.validate},
+		{"notify", conf.Notify.validate},
+		{"sidecar_conflicts", func() error { return validSidecarPolicy(conf.SidecarConflicts) }},
+		{"sidecar_precedence", func() error { return validSidecarPrecedence(conf.SidecarPrecedence) }},
+		{"centers", func() error { return compileCenters(conf.Centers) }},
+		{"duplicates", conf.Duplicates.validate},
+		{"fields", func() error { return conf.Fields.validate(sc) }},
+		{"dates", conf.Dates.validate},
+		{"keywords", conf.Keywords.validate},
+		{"thresholds", conf.Thresholds.validate},
+		{"filename_patterns", conf.FilenamePatterns.validate},
+		{"max_lengths", conf.MaxLengths.validate},
+		{"media_types", func() error { return validMediaTypes(conf.MediaTypes) }},
+		{"columns", func() error { return validColumns(conf.Columns, conf.ColumnHeaders) }},
+		{"romanize_location", func() error { return validRomanize(conf.RomanizeLocation) }},
+		{"exiftool", func() error { return validTags(conf.Exiftool.Tags) }},
+		{"checks", func() error {
+			_, err := compileChecks(conf.Checks, sc)
+			return err
+		}},
+		{"hooks", conf.Hooks.validate},
+	} {
+		if err = check.validate(); err != nil {
+			return config{}, configError(p, b, []string{check.key}, err)
 		}
-		conf := config{}
-		err = yaml.Unmarshal(b, &conf)
-		if err != nil {
-			log.Fatalf("Error parsing file %s: %s", p, err)
+	}
+	return conf, nil
+}
+
+// configError is the error in the config file p, with contents b, at the
+// keys, giving the line they're on if they're there.
+func configError(p string, b []byte, keys []string, err error) error {
+	if line := configLine(b, keys...); line > 0 {
+		return fmt.Errorf("Error in config file %s, line %d: %s", p, line, err)
+	}
+	return fmt.Errorf("Error in config file %s: %s", p, err)
+}
+
+// mimeTypeSet returns the MimeTypes to check as a set.
+func (c config) mimeTypeSet() map[string]bool {
+	types := map[string]bool{}
+	for _, t := range c.MimeTypes {
+		types[t] = true
+	}
+	return types
+}
+
+// mediaTypeSet returns the MediaTypes, or defaultMediaTypes, as a set.
+func (c config) mediaTypeSet() map[string]bool {
+	types := c.MediaTypes
+	if len(types) == 0 {
+		types = defaultMediaTypes
+	}
+	set := map[string]bool{}
+	for _, t := range types {
+		set[t] = true
+	}
+	return set
+}
+
+// validMediaTypes checks the MediaTypes are top level types, not full MIME
+// types.
+func validMediaTypes(types []string) error {
+	for _, t := range types {
+		if t == "" || strings.Contains(t, "/") {
+			return fmt.Errorf("media_types: %q isn't a top level type like image", t)
+		}
+	}
+	return nil
+}
+
+// column returns the index of the named column in csvHeader, or -1.
+func column(name string) int {
+	for i, h := range csvHeader {
+		if h == name {
+			return i
 		}
-		mtypes = conf.MimeTypes
+	}
+	return -1
+}
 
-	case p == "":
-		mtypes = defaultTypes
+// deliveryOf returns the delivery folder of p, which is the first directory
+// below root. Files directly in root belong to the "." delivery.
+func deliveryOf(root, p string) string {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return "."
 	}
-	for _, t := range mtypes {
-		mimeTypes[t] = true
+	parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+	if len(parts) < 2 {
+		return "."
 	}
+	return parts[0]
 }
 
-// makeWalker returns a function suitable for filepath.Walk. It walks the
-// directory recursively and finds files that have relevant extensions. Which
-// sends to the files channel.
-func makeWalker(files chan string, stats *statistics, types map[string]bool) func(string, os.FileInfo, error) error {
+// makeWalker returns a function suitable for filepath.Walk or walkParallel.
+// It walks the directory recursively and finds files in the shard that have
+// relevant extensions, or with -sniff relevant content. Which sends to the files channel, until ctx is done.
+// With -archives the files in archives are walked too, see walkArchive.
+func (r *runner) makeWalker(ctx context.Context, sh shard, files chan string, stats *statistics) func(string, os.FileInfo, error) error {
 	return func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
 		if fi.IsDir() {
 			return nil
 		}
+		if r.archives > 0 && isArchive(p) {
+			return r.walkArchiveFile(ctx, p, r.archives, sh, files, stats)
+		}
+		if !sh.mine(p) {
+			return nil
+		}
 		atomic.AddInt32(&stats.Total, 1)
-		if types[mime.TypeByExtension(path.Ext(p))] {
-			files <- p
+		if r.types[mime.TypeByExtension(filepath.Ext(p))] || r.sniffed(p, stats) {
+			if r.derivs.derivative(p) {
+				atomic.AddInt32(&stats.Derived, 1)
+				return nil
+			}
+			if err := sendFile(ctx, files, p); err != nil {
+				return err
+			}
 			atomic.AddInt32(&stats.Relevant, 1)
 			return nil
 		}
@@ -584,39 +1212,300 @@ func makeWalker(files chan string, stats *statistics, types map[string]bool) fun
 	}
 }
 
+// sendFile sends p to the files channel, or returns ctx's error if it's done
+// first.
+func sendFile(ctx context.Context, files chan string, p string) error {
+	select {
+	case files <- p:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // processFiles receives filepaths on the files channel. It then processes each
 // file to extract metadata and make a 'row' for output. The main function
-// launches one of these for each core the system is running on has.
-func processFiles(files chan string, results chan []string, stats *statistics, wg *sync.WaitGroup) {
+// launches one of these for each core the system is running on has. Each one
+// keeps its own exiftool running to extract with. When ctx is done it finishes
+// the file it's on and returns.
+func (r *runner) processFiles(ctx context.Context, files chan string, results chan []string, stats *statistics, wg *sync.WaitGroup) {
 	defer wg.Done()
+	var extract func(string) (exif, error)
+	switch {
+	case r.replay != "":
+		extract = replayExtract(r.replay)
+	case r.record != "":
+		extract = recordExtract(r.record, r.args, r.timeout)
+	default:
+		extract = func(p string) (exif, error) {
+			return getExifData(p, r.args, r.timeout)
+		}
+		et, err := newExiftool(r.args, r.timeout, r.cfg.Exiftool, &stats.Pool)
+		if err != nil {
+			log.Printf("Error starting exiftool, running it per file instead: %s", err)
+			break
+		}
+		defer func() {
+			if err := et.Close(); err != nil {
+				log.Printf("Error stopping exiftool: %s", err)
+			}
+		}()
+		extract = et.Extract
+	}
+	if r.replay == "" {
+		// Replays don't need the files.
+		extract = preflightExtract(r.types, extract)
+	}
+	extract = retryExtract(r.cfg.Exiftool.withDefaults(), &stats.Retried, extract)
+	extract = modelExtract(extract)
+	extract = sizeExtract(extract)
+	if r.dups != nil {
+		extract = hashExtract(r.cfg.Duplicates, extract)
+	}
+	extract = sidecarExtract(r.cfg.sidecarPrecedence(), r.cfg.sidecarPolicy(), extract)
+	if len(r.stores) > 0 {
+		extract = objectExtract(r.stores, r.egress, extract)
+	}
+	extract = urlExtract(r.egress, extract)
+	extract = archiveExtract(r.unpacked, extract)
+	if r.cache != nil {
+		extract = cacheExtract(r.cache, &stats.Cached, extract)
+	}
+	extract = r.configure(extract)
+	rows := results
+	if r.hook != nil || r.details != nil || len(r.cfg.Hooks) > 0 || r.inspect != nil {
+		// Catch each row to hook, post, detail or inspect it with the
+		// metadata it came from.
+		rows = make(chan []string, 1)
+	}
 	var status, reason string
-	for p := range files {
-		e, err := getExifData(p)
+	for {
+		p, ok := r.next(files)
+		if !ok {
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		shown := displayPath(p)
+		if r.resumed.skip(shown) {
+			atomic.AddInt32(&stats.Skipped, 1)
+			r.unpacked.release(p)
+			continue
+		}
+		if r.audit.unchanged(p) {
+			atomic.AddInt32(&stats.Unchanged, 1)
+			r.unpacked.release(p)
+			continue
+		}
+		start := time.Now()
+		e, err := extract(p)
+		e.timed(p, start)
+		extracted := err == nil
 		switch {
 		case err != nil:
 			atomic.AddInt32(&stats.Reject, 1)
-			e.MakeErrorRow(results, p, err)
-			if *verbose {
-				log.Printf("Error processing %s: %s\n", p, err)
+			if err == errTimeout {
+				atomic.AddInt32(&stats.TimedOut, 1)
+			}
+			countUnreadable(stats, err)
+			e.MakeErrorRow(rows, shown, err)
+			if r.verbose {
+				log.Printf("Error processing %s: %s\n", shown, err)
+			}
+			if stats.Quality != nil {
+				stats.Quality.add(deliveryOf(r.roots.rootOf(p), p), nil)
 			}
 		default:
-			if e.HasDateCreated() && (e.HasKeywords() || e.HasDescription()) {
+			var fixed []string
+			if r.fixes != nil && !r.cfg.Rules.accepts(e) {
+				e, fixed, err = r.fixes.apply(p, e, extract)
+				if err != nil {
+					log.Printf("Error fixing %s: %s\n", shown, err)
+				}
+			}
+			missing, failed := r.cfg.Rules.missing(e), r.checks.failures(e)
+			below := r.cfg.Thresholds.below(e)
+			// Warnings don't keep a file from being Accepted.
+			var warnings string
+			if warn := r.cfg.Rules.warnings(e); len(warn) > 0 {
+				warnings = missingReason(warn)
+			}
+			if note := filenameCheck(r.roots.rootOf(p), p); note != "" {
+				atomic.AddInt32(&stats.BadNames, 1)
+				warnings = joinReason(warnings, note)
+			}
+			if e.DescriptionLikeTitle(r.cfg.titleSimilarity()) {
+				atomic.AddInt32(&stats.Similar, 1)
+				warnings = joinReason(warnings, similarReason)
+			}
+			if note := e.DateProblem(); note != "" {
+				atomic.AddInt32(&stats.BadDates, 1)
+				warnings = joinReason(warnings, note)
+			}
+			if len(missing) == 0 && len(failed) == 0 && len(below) == 0 {
 				atomic.AddInt32(&stats.Accept, 1)
 				status = "Accepted"
 				reason = ""
+				if warnings != "" {
+					atomic.AddInt32(&stats.AcceptedWarnings, 1)
+					status = statusWarned
+				}
 			} else {
 				atomic.AddInt32(&stats.Reject, 1)
 				status = "Incomplete"
-				reason = "Minimum metadata not provided"
+				reason = ""
+				if len(missing) > 0 {
+					reason = missingReason(missing)
+					stats.Reasons.add(missing...)
+				}
+				for _, f := range failed {
+					reason = joinReason(reason, f.String())
+					stats.Reasons.add("check " + f.name)
+				}
+				if len(below) > 0 {
+					// The metadata can be fixed, but not the file.
+					atomic.AddInt32(&stats.BelowQuality, 1)
+					status = statusBelowQuality
+					reason = joinReason(strings.Join(below, ", "), reason)
+				}
+			}
+			if r.traceField != "" {
+				var correction map[string]string
+				if r.fixes != nil {
+					correction = r.fixes.lookup(p, e)
+				}
+				log.Print(trace(p, e, r.traceField, correction))
+			}
+			if len(fixed) > 0 {
+				atomic.AddInt32(&stats.Fixed, 1)
+				reason = joinReason(reason, "Fixed "+strings.Join(fixed, ", "))
+			}
+			if r.ids != nil && accepted(status) {
+				wrote, err := r.ids.write(p, e)
+				if err != nil {
+					log.Printf("Error writing NASA ID to %s: %s\n", shown, err)
+				}
+				if wrote {
+					atomic.AddInt32(&stats.WroteID, 1)
+					reason = joinReason(reason, "Wrote NASA ID to "+r.ids.tag)
+				}
+			}
+			if r.thumbs != nil {
+				extracted, err := r.thumbs.extract(p, e)
+				if err != nil {
+					log.Printf("Error extracting the preview of %s: %s\n", shown, err)
+				}
+				if extracted {
+					atomic.AddInt32(&stats.Previews, 1)
+				}
+			}
+			if r.xmp != nil {
+				if err := r.xmp.write(p, e); err != nil {
+					log.Printf("Error writing the XMP sidecar of %s: %s\n", shown, err)
+				} else {
+					atomic.AddInt32(&stats.XMPExported, 1)
+				}
+			}
+			if len(e.Conflicts) > 0 {
+				reason = joinReason(reason, sidecarReason(e.Conflicts))
+			}
+			if len(e.Warnings()) > 0 {
+				atomic.AddInt32(&stats.Warned, 1)
+			}
+			if e.IPTCModified() {
+				atomic.AddInt32(&stats.Modified, 1)
+				reason = joinReason(reason, e.iptcModified())
+			}
+			if stats.Quality != nil {
+				stats.Quality.add(deliveryOf(r.roots.rootOf(p), p), &e)
+			}
+			if r.dups != nil {
+				r.dups.add(shown, e.NasaID(), e.Hash)
+			}
+			r.audit.record(shown, p, e)
+			e.MakeRow(rows, shown, status, reason, warnings)
+		}
+		r.unpacked.release(p)
+		if r.hook != nil || r.details != nil || len(r.cfg.Hooks) > 0 || r.inspect != nil {
+			row := <-rows
+			if extracted && len(r.cfg.Hooks) > 0 {
+				status := row[column("Status")]
+				stats.Reasons.add(r.cfg.Hooks.apply(ctx, row, e)...)
+				recount(stats, status, row[column("Status")])
+			}
+			if r.hook != nil {
+				if err := r.hook.send(row, e); err != nil {
+					log.Printf("Error posting %s to webhook: %s\n", shown, err)
+				}
+			}
+			if r.details != nil {
+				r.details.record(row, e)
+			}
+			if r.inspect != nil {
+				r.inspect(row, e)
 			}
-			e.MakeRow(results, p, status, reason)
+			results <- row
 		}
 	}
 }
 
-// make output receives rows on the c channel and writes them to the csv
+// joinReason adds another reason to reason.
+func joinReason(reason, another string) string {
+	if reason == "" {
+		return another
+	}
+	return reason + "; " + another
+}
+
+// rowWriter is something we can write output rows to. A *csv.Writer is one.
+type rowWriter interface {
+	Write(row []string) error
+}
+
+// multiWriter writes each row to all of its writers.
+type multiWriter []rowWriter
+
+// Write writes the row to each writer, returning the first error.
+func (mw multiWriter) Write(row []string) error {
+	var first error
+	for _, w := range mw {
+		if err := w.Write(row); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// dropColumns is a rowWriter writing rows to w without some columns.
+type dropColumns struct {
+	w    rowWriter
+	drop map[int]bool
+}
+
+// newDropColumns returns a dropColumns dropping the named columns.
+func newDropColumns(w rowWriter, names ...string) dropColumns {
+	d := dropColumns{w, map[int]bool{}}
+	for _, n := range names {
+		d.drop[column(n)] = true
+	}
+	return d
+}
+
+// Write writes the row without the dropped columns.
+func (d dropColumns) Write(row []string) error {
+	kept := make([]string, 0, len(row))
+	for i, v := range row {
+		if !d.drop[i] {
+			kept = append(kept, v)
+		}
+	}
+	return d.w.Write(kept)
+}
+
+// make output receives rows on the c channel and writes them to the out
 // writer.
-func makeOutput(c chan []string, out *csv.Writer, wg *sync.WaitGroup) {
+func makeOutput(c chan []string, out rowWriter, wg *sync.WaitGroup) {
 	for result := range c {
 		err := out.Write(result)
 		if err != nil {
@@ -627,69 +1516,640 @@ func makeOutput(c chan []string, out *csv.Writer, wg *sync.WaitGroup) {
 }
 
 func main() {
-	flag.Parse()
-	if *dir == "" {
-		flag.PrintDefaults()
-		os.Exit(1)
+	if len(os.Args) > 1 && os.Args[1] == "rename" {
+		os.Exit(renameCommand(os.Args[2:]))
 	}
+	if len(os.Args) > 1 && os.Args[1] == "merge" {
+		os.Exit(mergeCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(diffCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "inspect" {
+		os.Exit(inspectCommand(os.Args[2:]))
+	}
+	os.Exit(run(os.Args[1:]))
+}
 
-	readConfig(*cfgfile)
-	files := make(chan string, 64)
-	stats := &statistics{}
+// run checks the files the command line args say to and returns the exit
+// status.
+func run(args []string) int {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	o := newOptions(fs)
+	fs.Parse(args)
+	if len(o.dirs) == 0 && o.object == "" && o.urls == "" && o.from == "" {
+		fs.PrintDefaults()
+		return exitStopped
+	}
 
-	_, err := os.Stat(*dir)
+	cfg, err := readConfig(o.cfgfile)
 	if err != nil {
-		log.Fatalf("Error opening %s: %s\n", *dir, err)
+		log.Fatalln(err)
 	}
-	go func() {
-		err := filepath.Walk(*dir, makeWalker(files, stats, mimeTypes))
+	if cfg, err = cfg.withProfile(o.profile); err != nil {
+		log.Fatalf("Error in -profile: %s\n", err)
+	}
+	r, err := newRunner(*o, cfg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if o.object != "" {
+		return r.checkObject(o.object, os.Stdout)
+	}
+	if isPostgres(o.output) && (o.append || o.splitSize > 0 || o.resume != "" || o.baseline != "") {
+		log.Fatalln("-o postgres:// can't be used with -append, -split-size, -resume or -baseline")
+	}
+	if o.notify && cfg.Notify.Host == "" {
+		log.Fatalln("-notify needs the notify host, from and to in the config")
+	}
+	if o.baseline != "" && (o.output == "" || o.splitSize > 0) {
+		log.Fatalln("-baseline needs -o to compare to it, without -split-size")
+	}
+	if (o.append || o.splitSize > 0) && o.output == "" {
+		log.Fatalln("-append and -split-size need -o")
+	}
+	if o.resume != "" {
+		if o.output == "" {
+			log.Fatalln("-resume needs -o to append the output to")
+		}
+		r.resumed, err = openJournal(o.resume)
 		if err != nil {
-			log.Fatalf("Error opening %s: %s\n", *dir, err)
+			log.Fatalf("Error opening journal %s: %s\n", o.resume, err)
+		}
+		if r.resumed.resuming() {
+			log.Printf("Resuming, skipping %d files already in %s\n", len(r.resumed.done), o.output)
+		}
+	}
+	var dump *os.File
+	if o.dumpOut != "" || o.since != "" {
+		var previous map[string]dumpEntry
+		if o.since != "" {
+			previous, err = readDumpFile(o.since)
+			if err != nil {
+				log.Fatalf("Error reading dump %s: %s\n", o.since, err)
+			}
+		}
+		var w io.Writer
+		if o.dumpOut != "" {
+			dump, err = os.Create(o.dumpOut)
+			if err != nil {
+				log.Fatalf("Error creating dump %s: %s\n", o.dumpOut, err)
+			}
+			w = dump
+		}
+		r.audit = newAudit(previous, w)
+	}
+	started := time.Now()
+	files := make(chan string, 64)
+	stats := &statistics{Quality: newScorecard(cfg.Quality), Reasons: newReasonCounts()}
+
+	switch n := o.dirs.objects(); {
+	case len(o.dirs) == 0:
+		// Only checking -urls or -from.
+	case n == len(o.dirs):
+		if r.stores, err = newObjectStores(o.dirs...); err != nil {
+			log.Fatalf("Error opening %s: %s\n", o.dirs, err)
+		}
+	case n > 0:
+		log.Fatalln("-d can't mix object store URIs and local directories")
+	default:
+		for _, d := range o.dirs {
+			if _, err = os.Stat(d); err != nil {
+				log.Fatalf("Error opening %s: %s\n", d, err)
+			}
+		}
+		r.inherited = newFolders(o.dirs...)
+	}
+	if o.inventory != "" && (r.stores.s3() == nil || len(r.stores) > 1) {
+		log.Fatalln("-inventory needs -d to be s3:// URIs")
+	}
+	if o.watch && (len(o.dirs) != 1 || len(r.stores) > 0) {
+		log.Fatalln("-watch needs -d to be a single local directory")
+	}
+	if o.workingSet != "" && (len(o.dirs) == 0 || len(r.stores) > 0 || o.watch) {
+		log.Fatalln("-working-set needs -d to be a local directory, without -watch")
+	}
+	if o.archives > 0 && o.workingSet != "" {
+		log.Fatalln("-archives can't be used with -working-set")
+	}
+	if o.deliveriesDir != "" && (len(o.dirs) == 0 || o.watch) {
+		log.Fatalln("-deliveries needs -d, without -watch")
+	}
+	if o.shard.count > 0 && o.watch {
+		log.Fatalln("-shard can't be used with -watch")
+	}
+	if (o.serveCert == "") != (o.serveKey == "") {
+		log.Fatalln("-serve-cert and -serve-key must be given together")
+	}
+	if o.serve != "" && (o.watch || o.resume != "" || o.since != "") {
+		log.Fatalln("-serve can't be used with -watch, -resume or -since")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go cancelOnSignal(cancel)
+	r.egress = newEgress(o.egress, cancel)
+	if o.serve != "" {
+		log.Printf("Serving gRPC on %s\n", o.serve)
+		if err = r.serve(ctx, o.serve, o.serveCert, o.serveKey, o.procs.n); err != nil {
+			log.Fatalf("Error serving %s: %s\n", o.serve, err)
+		}
+		return exitOK
+	}
+	go func() {
+		var err error
+		src := o.dirs.String()
+		switch {
+		case o.watch:
+			err = watchDir(ctx, o.dirs[0], o.watchEvery, newWatcher(o.quiet), files, stats, r.types)
+		case o.from != "":
+			src, err = o.from, readFileList(ctx, o.from, o.shard, files, stats, r.types)
+		case o.urls != "":
+			err = readURLs(ctx, o.urls, o.shard, files, stats, r.types)
+		default:
+			// Each -d is walked in turn, into the one report.
+			for _, d := range o.dirs {
+				src = d
+				switch {
+				case o.inventory != "":
+					err = walkInventory(ctx, r.stores.s3(), o.inventory, d, o.shard, files, stats, r.types)
+				case len(r.stores) > 0:
+					scheme, _, _, _ := parseObjectURI(d)
+					err = walkObjects(ctx, r.stores[scheme], d, o.shard, files, stats, r.types)
+				default:
+					err = walkParallel(ctx, d, o.walkers, r.makeWalker(ctx, o.shard, files, stats))
+				}
+				if err != nil {
+					break
+				}
+			}
+		}
+		if err != nil && err != ctx.Err() {
+			log.Fatalf("Error opening %s: %s\n", src, err)
 		}
 		close(files)
 	}()
+	if o.workingSet != "" {
+		ws, err := writeWorkingSet(o.workingSet, files)
+		if err != nil {
+			log.Fatalf("Error writing working set %s: %s\n", o.workingSet, err)
+		}
+		log.Printf("Working set: %s\n", ws)
+		if ctx.Err() != nil {
+			return exitStopped
+		}
+		return exitOK
+	}
 
+	var detailsFile *os.File
+	if o.detailsOut != "" {
+		detailsFile, err = os.Create(o.detailsOut)
+		if err != nil {
+			log.Fatalf("Error creating details %s: %s\n", o.detailsOut, err)
+		}
+		r.details = newDetails(detailsFile, cfg.Rules)
+	}
 	results := make(chan []string, 64)
 
-	for i := 0; i < *procs; i++ {
+	var ingroup, outgroup sync.WaitGroup
+	var tuned *tuner
+	if o.procs.auto {
+		tuned = newTuner(o.procs.n)
 		ingroup.Add(1)
-		go processFiles(files, results, stats, &ingroup)
+		go r.autoTune(ctx, tuned, files, results, stats, &ingroup, tuneInterval)
+	} else {
+		for i := 0; i < o.procs.n; i++ {
+			ingroup.Add(1)
+			go r.processFiles(ctx, files, results, stats, &ingroup)
+		}
+	}
+
+	var drop []string
+	if !o.rights {
+		drop = append(drop, rightsColumns...)
 	}
+	if cfg.RomanizeLocation != romanizeColumn {
+		drop = append(drop, romanizedColumn)
+	}
+	if cfg.Keywords.Vocabulary == "" {
+		drop = append(drop, unknownKeywordsColumn)
+	}
+	if !o.languages {
+		drop = append(drop, languagesColumn)
+	}
+	if !o.technical {
+		drop = append(drop, technicalColumns...)
+	}
+	if !o.timings {
+		drop = append(drop, timingColumns...)
+	}
+	// shape gives w the columns the config and flags ask for.
+	shape := func(w rowWriter) rowWriter {
+		switch {
+		case len(cfg.Columns) > 0:
+			return newSelectColumns(w, cfg.Columns)
+		case len(drop) > 0:
+			return newDropColumns(w, drop...)
+		}
+		return w
+	}
+	header := renameColumns(csvHeader, cfg.ColumnHeaders)
+	var shaped []string
+	shape(rowFunc(func(row []string) error {
+		shaped = row
+		return nil
+	})).Write(header)
 
-	var out *csv.Writer
+	// Carry on with the output of an earlier run, if there is one, when
+	// resuming or with -append.
+	appending := r.resumed.resuming()
+	var out csvWriter
 	var f *os.File
-	if *output != "" {
-		f, err = os.Create(*output)
+	var split *splitWriter
+	var db *postgresWriter
+	switch {
+	case isPostgres(o.output):
+		runID := o.runID
+		if runID == "" {
+			runID = newRunID(started)
+		}
+		db, err = newPostgresWriter(o.output, runID, shaped)
+		if err != nil {
+			log.Fatalln("Error opening output database: ", err)
+		}
+		log.Printf("Writing run %s to the database\n", runID)
+		appending, out = true, db
+	case o.splitSize > 0:
+		split, err = newSplitWriter(o.output, o.splitSize, shaped, appending || o.append, o.encoding)
 		if err != nil {
 			log.Fatalln("Error opening output file: ", err)
 		}
-		out = csv.NewWriter(f)
-	} else {
-		out = csv.NewWriter(os.Stdout)
+		appending, out = true, split
+	case o.output != "":
+		if fi, err := os.Stat(o.output); o.append && err == nil && fi.Size() > 0 {
+			if _, err = countRows(o.output, shaped); err != nil {
+				log.Fatalln("Can't -append:", err)
+			}
+			appending = true
+		}
+		if appending {
+			f, err = os.OpenFile(o.output, os.O_WRONLY|os.O_APPEND, 0644)
+		} else {
+			f, err = os.Create(o.output)
+		}
+		if err != nil {
+			log.Fatalln("Error opening output file: ", err)
+		}
+		enc, err := o.encoding.writer(f, !appending)
+		if err != nil {
+			log.Fatalln("Error writing output file: ", err)
+		}
+		out = csv.NewWriter(enc)
+	default:
+		enc, err := o.encoding.writer(os.Stdout, true)
+		if err != nil {
+			log.Fatalln("Error writing output: ", err)
+		}
+		out = csv.NewWriter(enc)
 	}
-	err = out.Write(csvHeader)
-	if err != nil {
-		log.Printf("Error writing csvHeader: %s", err)
+	var ow rowWriter = out
+	if o.watch {
+		ow = flushWriter{out}
+	}
+	ow = shape(ow)
+	if !appending {
+		err = ow.Write(header)
+		if err != nil {
+			log.Printf("Error writing csvHeader: %s", err)
+		}
 	}
 	out.Flush()
+	ow = newPathRewriter(ow, o.pathMode, o.dirs)
+
+	w := multiWriter{ow}
+	if r.resumed != nil {
+		r.resumed.flush = func() error {
+			out.Flush()
+			return out.Error()
+		}
+		w = append(w, r.resumed)
+	}
+	var sheet *sheetWriter
+	if o.sheetID != "" {
+		sheet, err = newSheetWriter(o.sheetID, o.sheetRange, o.sheetKey)
+		if err != nil {
+			log.Fatalf("Error opening sheet %s: %s\n", o.sheetID, err)
+		}
+		w = append(w, newPathRewriter(sheet, o.pathMode, o.dirs))
+	}
+	descriptions := newDescriptionClusters()
+	w = append(w, descriptions)
+	terms := newTermCounts()
+	w = append(w, terms)
+	breakdown := newStatusBreakdown()
+	w = append(w, breakdown)
+	var albums *albumCheck
+	if cfg.Albums.Required {
+		albums = newAlbumCheck(cfg.Albums)
+		w = append(w, albums)
+	}
+	var drifts *driftCheck
+	if o.verify != "" {
+		published, err := readExport(o.verify)
+		if err != nil {
+			log.Fatalf("Error reading AVAIL export %s: %s\n", o.verify, err)
+		}
+		drifts = newDriftCheck(published)
+		w = append(w, drifts)
+	}
+	var exported *exporter
+	if o.exportDir != "" {
+		exported, err = newExporter(o.exportDir, o.exportTmpl, o.dirs...)
+		if err != nil {
+			log.Fatalf("Error in -export-layout: %s\n", err)
+		}
+		if len(r.stores) > 0 {
+			exported.download = func(p string, w io.Writer) error {
+				return r.stores.download(p, r.egress.meter(w))
+			}
+		}
+		w = append(w, exported)
+	}
+	var rejects *rejectCollector
+	if o.tickets {
+		rejects = newRejectCollector(o.dirs...)
+		w = append(w, rejects)
+	}
+	var rolled *rollup
+	if o.rollupOut != "" {
+		if rolled, err = newRollup(o.rollupBy, o.dirs); err != nil {
+			log.Fatalln(err)
+		}
+		w = append(w, rolled)
+	}
+	var reports *deliveryReports
+	if o.deliveriesDir != "" {
+		reports, err = newDeliveryReports(o.deliveriesDir, o.dirs, func(out *csv.Writer) (rowWriter, error) {
+			if err := shape(out).Write(renameColumns(csvHeader, cfg.ColumnHeaders)); err != nil {
+				return nil, err
+			}
+			return newPathRewriter(shape(out), o.pathMode, o.dirs), nil
+		})
+		if err != nil {
+			log.Fatalf("Error making -deliveries directory: %s\n", err)
+		}
+		w = append(w, reports)
+	}
 
+	var sink rowWriter = w
+	if o.watch {
+		sink = newDedupRows(w)
+	}
 	outgroup.Add(1)
 	go func() {
-		makeOutput(results, out, &outgroup)
+		makeOutput(results, sink, &outgroup)
 	}()
 
 	ingroup.Wait()
+	r.unpacked.removeAll()
 	close(results)
 	outgroup.Wait()
 	out.Flush()
+	interrupted := ctx.Err() != nil
+	if reports != nil {
+		if err = reports.Close(); err != nil {
+			log.Printf("Error writing delivery reports: %s", err)
+		}
+	}
+	if sheet != nil {
+		err = sheet.Flush()
+		if err != nil {
+			log.Printf("Error appending to sheet %s: %s", o.sheetID, err)
+		}
+	}
+	var drifted int
+	if drifts != nil {
+		results := drifts.results()
+		drifted = len(results)
+		err = writeDrift(o.driftOut, results)
+		if err != nil {
+			log.Printf("Error writing drift report: %s", err)
+		}
+	}
+	clusters := descriptions.clusters(cfg.clusterSize())
+	if o.clusterOut != "" {
+		err = writeClusters(o.clusterOut, clusters)
+		if err != nil {
+			log.Printf("Error writing clusters to %s: %s", o.clusterOut, err)
+		}
+	}
+	if rolled != nil {
+		if err = writeRollup(o.rollupOut, rolled); err != nil {
+			log.Printf("Error writing rollup to %s: %s", o.rollupOut, err)
+		}
+	}
+	if o.termsOut != "" {
+		if err = writeTerms(o.termsOut, terms); err != nil {
+			log.Printf("Error writing terms to %s: %s", o.termsOut, err)
+		}
+	}
+	if r.details != nil {
+		if err = r.details.Err(); err != nil {
+			log.Printf("Error writing details %s: %s", o.detailsOut, err)
+		}
+		if err = detailsFile.Close(); err != nil {
+			log.Printf("Error closing details %s: %s", o.detailsOut, err)
+		}
+	}
+	var changes []metadataChange
+	if r.audit != nil {
+		if err = r.audit.Err(); err != nil {
+			log.Printf("Error writing dump %s: %s", o.dumpOut, err)
+		}
+		if dump != nil {
+			if err = dump.Close(); err != nil {
+				log.Printf("Error closing dump %s: %s", o.dumpOut, err)
+			}
+		}
+		if o.since != "" && !interrupted {
+			changes = r.audit.changes()
+			if err = writeChanges(o.changesOut, changes); err != nil {
+				log.Printf("Error writing metadata changes: %s", err)
+			}
+		}
+	}
+	var dups []duplicate
+	if r.dups != nil {
+		dups = r.dups.found()
+		err = writeDuplicates(o.dupsOut, dups)
+		if err != nil {
+			log.Printf("Error writing duplicates to %s: %s", o.dupsOut, err)
+		}
+	}
+	if rejects != nil && !interrupted {
+		err = openTickets(cfg.Tickets, rejects)
+		if err != nil {
+			log.Printf("Error opening tickets: %s", err)
+		}
+	}
 
-	if *output != "" {
+	switch {
+	case db != nil:
+		if err = db.Close(); err != nil {
+			log.Printf("Error writing to the database: %s", err)
+		}
+	case split != nil:
+		if err = split.Close(); err != nil {
+			log.Printf("Error closing file %s: %s", split.f.Name(), err)
+		}
+	case o.output != "":
 		err = f.Close()
 		if err != nil {
 			log.Printf("Error closing file %s: %s", f.Name(), err)
 		}
 	}
+	var baselined *diffCounts
+	if o.baseline != "" {
+		if counts, err := writeBaselineChanges(o.baseline, o.output, o.baselineOut); err != nil {
+			log.Printf("Error comparing %s to %s: %s", o.output, o.baseline, err)
+		} else {
+			baselined = &counts
+		}
+	}
+	if r.resumed != nil && !interrupted {
+		// The run finished, so there's nothing to resume next time.
+		r.resumed.Close()
+		if err = os.Remove(o.resume); err != nil {
+			log.Printf("Error removing journal %s: %s", o.resume, err)
+		}
+	}
 
-	log.Printf("\nTotal Found: %d\nRelevant Files: %d\nRejected Files: %d\nAccepted Files: %d\n",
-		stats.Total, stats.Relevant, stats.Reject, stats.Accept)
+	switch {
+	case r.egress.exceeded():
+		log.Printf("\nEgress budget used, these are only the files checked so far.")
+	case interrupted:
+		log.Printf("\nInterrupted, these are only the files checked so far.")
+	}
+	if o.shard.count > 0 {
+		log.Printf("\nShard %s only, chkmd merge the shards' output for the whole.", o.shard)
+	}
+	s := newRunSummary(started, stats, breakdown)
+	s.Interrupted, s.Shard = interrupted, o.shard.String()
+	if o.since != "" {
+		cs := r.audit.cache()
+		s.Cache = &cs
+	}
+	if o.summaryOut != "" {
+		if err = writeSummary(o.summaryOut, s); err != nil {
+			log.Printf("Error writing summary %s: %s", o.summaryOut, err)
+		}
+	}
+	if r.hook != nil {
+		if err = r.hook.finish(s); err != nil {
+			log.Printf("Error posting the summary to webhook: %s\n", err)
+		}
+	}
+	var outputs []string
+	switch {
+	case split != nil:
+		for part := 1; part <= split.part; part++ {
+			outputs = append(outputs, splitName(o.output, part))
+		}
+	case o.output != "" && db == nil:
+		outputs = []string{o.output}
+	}
+	if p := o.manifestPath(); p != "" {
+		if err = writeJSON(p, newRunManifest(args, *o, started, outputs, interrupted)); err != nil {
+			log.Printf("Error writing manifest %s: %s", p, err)
+		}
+	}
+	if o.notify && !interrupted {
+		if err = notify(cfg.Notify, s, stats.Reasons.summary(), o.dirs, outputs); err != nil {
+			log.Printf("Error emailing the summary: %s\n", err)
+		}
+	}
+	log.Printf("\nTotal Found: %d\nRelevant Files: %d\nRejected Files: %d\nAccepted Files: %d\nAccepted with Warnings: %d\n",
+		stats.Total, stats.Relevant, stats.Reject, stats.Accept, stats.AcceptedWarnings)
+	if s := stats.Reasons.summary(); s != "" {
+		log.Printf("Incomplete Reasons:\n%s", s)
+	}
+	log.Printf("Derivatives Skipped: %d\n", stats.Derived)
+	log.Printf("Descriptions like their Title: %d\n", stats.Similar)
+	log.Printf("IPTC modified after XMP: %d\n", stats.Modified)
+	log.Printf("Paths with %s: %d\n", filenameWarning, stats.BadNames)
+	log.Printf("Implausible Dates Created: %d\n", stats.BadDates)
+	log.Printf("Below Quality Threshold: %d\n", stats.BelowQuality)
+	log.Printf("Extraction Timeouts: %d\n", stats.TimedOut)
+	log.Printf("Unreadable Files: %d permission denied, %d empty, %d not media\n", stats.PermissionDenied, stats.Empty, stats.NotMedia)
+	log.Printf("Extraction Retries: %d\n", stats.Retried)
+	log.Printf("Files with Extraction Warnings: %d\n", stats.Warned)
+	log.Printf("exiftool processes: %s\n", &stats.Pool)
+	if tuned != nil {
+		log.Printf("Workers: %s\n", tuned)
+	}
+	if len(r.stores) > 0 || o.urls != "" {
+		log.Printf("Downloaded: %s\n", r.egress)
+	}
+	if r.fixes != nil {
+		log.Printf("Fixed Files: %d\n", stats.Fixed)
+	}
+	if r.ids != nil {
+		log.Printf("NASA IDs Written: %d\n", stats.WroteID)
+	}
+	if r.thumbs != nil {
+		log.Printf("Previews Extracted: %d\n", stats.Previews)
+	}
+	if r.sniff {
+		log.Printf("Relevant by Content: %d\n", stats.Sniffed)
+	}
+	if r.xmp != nil {
+		log.Printf("XMP Sidecars Exported: %d\n", stats.XMPExported)
+	}
+	if r.cache != nil {
+		log.Printf("Metadata from Cache: %d\n", stats.Cached)
+	}
+	if reports != nil {
+		log.Printf("Delivery Reports: %d in %s\n", len(reports.reports), o.deliveriesDir)
+	}
+	if exported != nil {
+		log.Printf("Exported Files: %d\nFailed Exports: %d\n", exported.exported, exported.failed)
+	}
+	if r.resumed.resuming() {
+		log.Printf("Skipped Files, from an earlier run: %d\n", stats.Skipped)
+	}
+	if drifts != nil {
+		log.Printf("Differences from AVAIL: %d\n", drifted)
+	}
+	if baselined != nil {
+		logDiffCounts(o.baseline, *baselined)
+	}
+	if o.since != "" {
+		log.Printf("Unchanged since %s: %d\nMetadata changes: %d\n", o.since, stats.Unchanged, len(changes))
+		log.Printf("Cache hit rate: %s\n", r.audit.cache())
+	}
+	if r.dups != nil {
+		log.Printf("Files with Duplicate Content: %d\nFiles with Duplicate NASA IDs: %d\n",
+			countDuplicates(dups, "Content"), countDuplicates(dups, "NASA ID"))
+	}
+	log.Printf("\nQuality score per delivery:\n%s", stats.Quality)
+	if len(clusters) > 0 {
+		log.Printf("\nDescriptions shared by %d or more files:\n%s", cfg.clusterSize(), summarizeClusters(clusters))
+	}
+	if n := cfg.topTerms(); n > 0 {
+		if s := terms.summarize(n); s != "" {
+			log.Print(s)
+		}
+	}
+	if albums != nil {
+		if missing := albums.missing(); len(missing) > 0 {
+			log.Printf("\nFolders without album metadata:\n%s\n", strings.Join(missing, "\n"))
+		}
+	}
+	if stoppedEarly(interrupted, o.watch, r.egress.exceeded()) {
+		return exitStopped
+	}
+	if why := tooManyRejects(o.failOnReject, o.maxRejectRate, stats); why != "" {
+		log.Printf("\nFailing: %s.\n", why)
+		return exitRejects
+	}
+	return exitOK
 }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// version is chkmd's version, set by make build from git describe, e.g.
+// with -ldflags "-X main.version=0.7.0".
+var version = "devel"
+
+// runManifest says how a run was made, written beside its output, so which
+// chkmd, exiftool, config and options made a report can be told long after.
+type runManifest struct {
+	Version         string    `json:"version"`
+	GoVersion       string    `json:"go_version"`
+	ExiftoolVersion string    `json:"exiftool_version,omitempty"`
+	Config          string    `json:"config,omitempty"`
+	ConfigSHA256    string    `json:"config_sha256,omitempty"`
+	Profile         string    `json:"profile,omitempty"`
+	Args            []string  `json:"args"`
+	Host            string    `json:"host"`
+	Started         time.Time `json:"started"`
+	Finished        time.Time `json:"finished"`
+	Roots           []string  `json:"roots"`
+	Outputs         []string  `json:"outputs,omitempty"`
+	Interrupted     bool      `json:"interrupted"`
+}
+
+// manifestPath returns where to write the run manifest: -manifest, or
+// beside an -o file, as out.manifest.json for out.csv, or "" for none.
+func (o options) manifestPath() string {
+	switch {
+	case o.manifest != "":
+		return o.manifest
+	case o.output == "" || isPostgres(o.output):
+		return ""
+	}
+	return strings.TrimSuffix(o.output, filepath.Ext(o.output)) + ".manifest.json"
+}
+
+// newRunManifest returns the manifest of the run with args and options,
+// which wrote outputs. Its config is unhashed if it can't be read.
+func newRunManifest(args []string, o options, started time.Time, outputs []string, interrupted bool) runManifest {
+	m := runManifest{
+		Version:         version,
+		GoVersion:       runtime.Version(),
+		ExiftoolVersion: exiftoolVersion(),
+		Config:          o.cfgfile,
+		Profile:         o.profile,
+		Started:         started.UTC(),
+		Finished:        time.Now().UTC(),
+		Roots:           o.dirs,
+		Outputs:         outputs,
+		Interrupted:     interrupted,
+	}
+	m.Host, _ = os.Hostname()
+	if o.cfgfile != "" {
+		if abs, err := filepath.Abs(o.cfgfile); err == nil {
+			m.Config = abs
+		}
+		if b, err := ioutil.ReadFile(o.cfgfile); err == nil {
+			sum := sha256.Sum256(b)
+			m.ConfigSHA256 = hex.EncodeToString(sum[:])
+		}
+	}
+	for _, arg := range args {
+		m.Args = append(m.Args, redactArg(arg))
+	}
+	return m
+}
+
+// exiftoolVersion returns the version of the exiftool on the PATH, or "" if
+// it can't be run.
+func exiftoolVersion() string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := detach(exec.CommandContext(ctx, "exiftool", "-ver")).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// redactArg returns a command line arg without the password or query string
+// of a URL in it, like a postgres:// -o's or a -webhook's token, so the
+// manifest can be shared.
+func redactArg(arg string) string {
+	prefix, v := "", arg
+	if strings.HasPrefix(arg, "-") {
+		if i := strings.Index(arg, "="); i >= 0 {
+			prefix, v = arg[:i+1], arg[i+1:]
+		}
+	}
+	if !strings.Contains(v, "://") {
+		return arg
+	}
+	u, err := url.Parse(v)
+	if err != nil {
+		return prefix + "xxxxx"
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = "xxxxx"
+	}
+	return prefix + u.String()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManifestPath(t *testing.T) {
+	for _, v := range []struct{ manifest, output, want string }{
+		{"", "", ""},
+		{"", "out.csv", "out.manifest.json"},
+		{"", "reports/run.json", "reports/run.manifest.json"},
+		{"", "postgres://chkmd@localhost/chkmd", ""},
+		{"audit.json", "postgres://chkmd@localhost/chkmd", "audit.json"},
+	} {
+		equals(t, options{manifest: v.manifest, output: v.output}.manifestPath(), v.want)
+	}
+}
+
+func TestRedactArg(t *testing.T) {
+	for arg, want := range map[string]string{
+		"-d":                                     "-d",
+		"/data/photos":                           "/data/photos",
+		"postgres://chkmd:secret@db/chkmd":       "postgres://chkmd:xxxxx@db/chkmd",
+		"-o=postgres://chkmd:secret@db/chkmd":    "-o=postgres://chkmd:xxxxx@db/chkmd",
+		"https://hooks.example.com/run?token=t0": "https://hooks.example.com/run?xxxxx",
+		"-config=chkmd.yaml":                     "-config=chkmd.yaml",
+	} {
+		equals(t, redactArg(arg), want)
+	}
+}
+
+func TestNewRunManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	cfg := filepath.Join(dir, "chkmd.yaml")
+	equals(t, ioutil.WriteFile(cfg, []byte("fields: [Title]\n"), 0644), nil)
+	sum := sha256.Sum256([]byte("fields: [Title]\n"))
+
+	started := time.Now().Add(-time.Minute)
+	o := options{cfgfile: cfg, profile: "nasa", dirs: []string{"/data/photos"}, output: "out.csv"}
+	m := newRunManifest([]string{"-c", cfg, "-o", "postgres://chkmd:secret@db/chkmd"}, o, started, []string{"out.csv"}, true)
+	equals(t, m.Version, version)
+	equals(t, m.Config, cfg)
+	equals(t, m.ConfigSHA256, hex.EncodeToString(sum[:]))
+	equals(t, m.Profile, "nasa")
+	equals(t, m.Args, []string{"-c", cfg, "-o", "postgres://chkmd:xxxxx@db/chkmd"})
+	equals(t, m.Roots, []string{"/data/photos"})
+	equals(t, m.Outputs, []string{"out.csv"})
+	equals(t, m.Interrupted, true)
+	equals(t, m.Started, started.UTC())
+	equals(t, m.Finished.Before(started), false)
+	equals(t, m.GoVersion != "" && m.Host != "", true)
+}
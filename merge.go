@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// mergeUsage is printed for chkmd merge -h.
+const mergeUsage = `Usage: chkmd merge [-o all.csv] shard1.csv shard2.csv ...
+
+Merges the output of a -shard run on each machine into one results CSV,
+sorted by Path, and prints the summary for the whole.
+`
+
+// mergeCounts is the summary of merged results.
+type mergeCounts struct {
+	Relevant int
+	Reject   int
+	Accept   int
+}
+
+// mergeResults reads the results CSVs, named by names, and writes their rows
+// to w under one header, sorted by Path. The CSVs must have the same columns,
+// i.e. all with -rights or none, and no file can be in two of them, as it
+// would be if the shards overlapped.
+func mergeResults(names []string, parts []io.Reader, w *csv.Writer) (mergeCounts, error) {
+	var counts mergeCounts
+	var header []string
+	var headerFrom string
+	var rows [][]string
+	seen := map[string]string{}
+	p, status := -1, -1
+	for i, part := range parts {
+		all, err := csv.NewReader(textReader(part)).ReadAll()
+		if err != nil {
+			return counts, fmt.Errorf("%s: %s", names[i], err)
+		}
+		if len(all) == 0 {
+			continue
+		}
+		if header == nil {
+			header, headerFrom = all[0], names[i]
+			// The config may choose the columns, but we need these.
+			for j, h := range header {
+				switch h {
+				case "Path":
+					p = j
+				case "Status":
+					status = j
+				}
+			}
+			if p < 0 || status < 0 {
+				return counts, fmt.Errorf("%s: no Path and Status columns", names[i])
+			}
+		} else if strings.Join(all[0], ",") != strings.Join(header, ",") {
+			return counts, fmt.Errorf("%s: columns differ from %s", names[i], headerFrom)
+		}
+		for _, row := range all[1:] {
+			if other, ok := seen[row[p]]; ok {
+				return counts, fmt.Errorf("%s: %s is also in %s", names[i], row[p], other)
+			}
+			seen[row[p]] = names[i]
+			rows = append(rows, row)
+		}
+	}
+	if header == nil {
+		return counts, nil
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i][p] < rows[j][p] })
+	for _, row := range rows {
+		counts.Relevant++
+		if accepted(row[status]) {
+			counts.Accept++
+		} else {
+			counts.Reject++
+		}
+	}
+	if err := w.Write(header); err != nil {
+		return counts, err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return counts, err
+	}
+	return counts, nil
+}
+
+// mergeCommand is chkmd merge.
+func mergeCommand(args []string) int {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	output := fs.String("o", "", "A file to output to, instead of stdout.")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, mergeUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var parts []io.Reader
+	for _, name := range fs.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Printf("Error opening %s: %s", name, err)
+			return 1
+		}
+		defer f.Close()
+		parts = append(parts, f)
+	}
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Printf("Error opening output file: %s", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	counts, err := mergeResults(fs.Args(), parts, csv.NewWriter(out))
+	if err != nil {
+		log.Printf("Error merging: %s", err)
+		return 1
+	}
+	log.Printf("\nShards Merged: %d\nRelevant Files: %d\nRejected Files: %d\nAccepted Files: %d\n",
+		fs.NArg(), counts.Relevant, counts.Reject, counts.Accept)
+	return 0
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMergeResults(t *testing.T) {
+	header := "Path,Status,Reason\n"
+	one := header + "b.jpg,Accepted,\nd.jpg,Rejected,exiftool failed\n"
+	two := header + "a.jpg,Incomplete,Minimum metadata not provided\nc.jpg,Accepted,\n"
+	merge := func(parts ...string) (string, mergeCounts, error) {
+		var names []string
+		var rs []io.Reader
+		for i, p := range parts {
+			names = append(names, string(rune('1'+i))+".csv")
+			rs = append(rs, strings.NewReader(p))
+		}
+		var b bytes.Buffer
+		counts, err := mergeResults(names, rs, csv.NewWriter(&b))
+		return b.String(), counts, err
+	}
+
+	got, counts, err := merge(one, two, header)
+	equals(t, err, nil)
+	equals(t, got, header+"a.jpg,Incomplete,Minimum metadata not provided\nb.jpg,Accepted,\nc.jpg,Accepted,\nd.jpg,Rejected,exiftool failed\n")
+	equals(t, counts, mergeCounts{Relevant: 4, Reject: 2, Accept: 2})
+
+	_, _, err = merge(one, "Path,Status,Reason,Copyright\n")
+	equals(t, err.Error(), "2.csv: columns differ from 1.csv")
+
+	// The config chose the columns.
+	got, _, err = merge("Status,Path\nAccepted,b.jpg\n", "Status,Path\nRejected,a.jpg\n")
+	equals(t, err, nil)
+	equals(t, got, "Status,Path\nRejected,a.jpg\nAccepted,b.jpg\n")
+	_, _, err = merge("NASA ID,Title\n")
+	equals(t, err.Error(), "1.csv: no Path and Status columns")
+
+	// Overlapping shards.
+	_, _, err = merge(one, header+"b.jpg,Accepted,\n")
+	equals(t, err.Error(), "2.csv: b.jpg is also in 1.csv")
+
+	got, counts, err = merge("")
+	equals(t, err, nil)
+	equals(t, got, "")
+	equals(t, counts, mergeCounts{})
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// modelFormats are the 3D model formats we read ourselves, since exiftool
+// doesn't, by extension: their MIME type and exiftool style FileType.
+var modelFormats = map[string]struct {
+	mimeType string
+	fileType string
+}{
+	".gltf": {"model/gltf+json", "GLTF"},
+	".glb":  {"model/gltf-binary", "GLB"},
+	".obj":  {"model/obj", "OBJ"},
+	".usdz": {"model/vnd.usdz+zip", "USDZ"},
+}
+
+func init() {
+	// So the walkers find models by extension like everything else.
+	for ext, f := range modelFormats {
+		mime.AddExtensionType(ext, f.mimeType)
+	}
+}
+
+// modelMetadata is what a model says about itself, in its glTF asset extras
+// or its JSON sidecar, which is the model's name with .json in place of its
+// extension, e.g. rover.json for rover.glb.
+type modelMetadata struct {
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Author      string      `json:"author"`
+	Keywords    interface{} `json:"keywords"`
+	DateCreated string      `json:"date_created"`
+	NasaID      string      `json:"nasa_id"`
+}
+
+// tags returns the metadata as exif.Model tags. Keywords may be a list or a
+// comma separated string.
+func (md modelMetadata) tags() map[string]string {
+	tags := map[string]string{}
+	add := func(tag, v string) {
+		if v = strings.TrimSpace(v); v != "" {
+			tags[tag] = v
+		}
+	}
+	add("Title", md.Title)
+	add("Description", md.Description)
+	add("Author", md.Author)
+	add("DateCreated", md.DateCreated)
+	add("NasaID", md.NasaID)
+	switch kw := md.Keywords.(type) {
+	case string:
+		add("Keywords", kw)
+	case []interface{}:
+		var list []string
+		for _, k := range kw {
+			if s, ok := k.(string); ok && strings.TrimSpace(s) != "" {
+				list = append(list, strings.TrimSpace(s))
+			}
+		}
+		add("Keywords", strings.Join(list, ", "))
+	}
+	return tags
+}
+
+// gltfAsset is the asset block of a glTF file.
+type gltfAsset struct {
+	Asset struct {
+		Copyright string        `json:"copyright"`
+		Generator string        `json:"generator"`
+		Version   string        `json:"version"`
+		Extras    modelMetadata `json:"extras"`
+	} `json:"asset"`
+}
+
+// readGLTF returns the tags in a glTF file's asset block.
+func readGLTF(b []byte) (map[string]string, error) {
+	var g gltfAsset
+	if err := json.Unmarshal(b, &g); err != nil {
+		return nil, fmt.Errorf("reading glTF: %s", err)
+	}
+	if g.Asset.Version == "" {
+		return nil, errors.New("reading glTF: no asset version")
+	}
+	tags := g.Asset.Extras.tags()
+	for tag, v := range map[string]string{
+		"Copyright": g.Asset.Copyright,
+		"Generator": g.Asset.Generator,
+		"Version":   g.Asset.Version,
+	} {
+		if v != "" {
+			tags[tag] = v
+		}
+	}
+	return tags, nil
+}
+
+// glbJSON returns the JSON chunk of a binary glTF, which comes first after
+// the 12 byte header.
+func glbJSON(b []byte) ([]byte, error) {
+	if len(b) < 20 || string(b[:4]) != "glTF" {
+		return nil, errors.New("reading GLB: not a binary glTF")
+	}
+	n := binary.LittleEndian.Uint32(b[12:16])
+	if string(b[16:20]) != "JSON" || uint64(n) > uint64(len(b)-20) {
+		return nil, errors.New("reading GLB: no JSON chunk")
+	}
+	return b[20 : 20+n], nil
+}
+
+// usdaDoc matches the doc string in a USDA layer's metadata.
+var usdaDoc = regexp.MustCompile(`(?m)^\s*doc\s*=\s*"((?:[^"\\]|\\.)*)"`)
+
+// readUSDZ returns the Description from the doc of a USDZ's root layer, if
+// it's a text .usda layer. Binary .usdc layers have nothing we read.
+func readUSDZ(p string) (map[string]string, error) {
+	z, err := zip.OpenReader(p)
+	if err != nil {
+		return nil, fmt.Errorf("reading USDZ: %s", err)
+	}
+	defer z.Close()
+	tags := map[string]string{}
+	if len(z.File) == 0 || filepath.Ext(z.File[0].Name) != ".usda" {
+		return tags, nil
+	}
+	f, err := z.File[0].Open()
+	if err != nil {
+		return nil, fmt.Errorf("reading USDZ: %s", err)
+	}
+	defer f.Close()
+	// The layer metadata is at the top, before any prims.
+	head, err := ioutil.ReadAll(io.LimitReader(f, 64*1024))
+	if err != nil {
+		return nil, fmt.Errorf("reading USDZ: %s", err)
+	}
+	if m := usdaDoc.FindSubmatch(head); m != nil {
+		tags["Description"] = strings.Replace(string(m[1]), `\"`, `"`, -1)
+	}
+	return tags, nil
+}
+
+// readOBJ checks the file looks like an OBJ. OBJs have no metadata of their
+// own beyond comments, so it all comes from the sidecar.
+func readOBJ(p string) (map[string]string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.IndexAny(line, " \t"); i > 0 {
+			switch line[:i] {
+			case "v", "vt", "vn", "f", "o", "g", "mtllib", "usemtl", "s":
+				return map[string]string{}, nil
+			}
+		}
+		break
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("reading OBJ: no vertices or faces")
+}
+
+// readModel returns the tags embedded in the model at p.
+func readModel(p string) (map[string]string, error) {
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".gltf":
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		return readGLTF(b)
+	case ".glb":
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		j, err := glbJSON(b)
+		if err != nil {
+			return nil, err
+		}
+		return readGLTF(j)
+	case ".usdz":
+		return readUSDZ(p)
+	}
+	return readOBJ(p)
+}
+
+// modelSidecarPath returns the JSON sidecar for the model at p.
+func modelSidecarPath(p string) string {
+	return strings.TrimSuffix(p, filepath.Ext(p)) + ".json"
+}
+
+// readModelSidecar returns the tags in the model's JSON sidecar, or none if
+// it hasn't one.
+func readModelSidecar(p string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(modelSidecarPath(p))
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var md modelMetadata
+	if err = json.Unmarshal(bytes.TrimSpace(b), &md); err != nil {
+		return nil, fmt.Errorf("reading sidecar %s: %s", modelSidecarPath(p), err)
+	}
+	return md.tags(), nil
+}
+
+// modelExtract wraps extract so 3D models are read here instead, since
+// exiftool can't. The File tags are filled in as exiftool would, and the
+// model's own metadata goes in Model, with its JSON sidecar filling in what
+// the model doesn't have.
+func modelExtract(extract func(string) (exif, error)) func(string) (exif, error) {
+	return func(p string) (exif, error) {
+		format, ok := modelFormats[strings.ToLower(filepath.Ext(p))]
+		if !ok {
+			return extract(p)
+		}
+		e := newExif()
+		fi, err := os.Stat(p)
+		if err != nil {
+			return e, err
+		}
+		e.Data["FileName"] = filepath.Base(p)
+		e.Data["Directory"] = filepath.Dir(p)
+		e.Data["FileSize"] = fmt.Sprint(fi.Size())
+		e.Data["FileType"] = format.fileType
+		e.Data["MIMEType"] = format.mimeType
+		if e.Model, err = readModel(p); err != nil {
+			return e, err
+		}
+		side, err := readModelSidecar(p)
+		if err != nil {
+			return e, err
+		}
+		for tag, v := range side {
+			if e.Model[tag] == "" {
+				e.Model[tag] = v
+			}
+		}
+		return e, nil
+	}
+}
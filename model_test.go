@@ -0,0 +1,102 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testGLTF = `{"asset": {"version": "2.0", "generator": "Blender", "copyright": "Public domain",
+	"extras": {"title": "Perseverance Rover", "author": "NASA/JPL-Caltech", "keywords": ["Mars", "rover"]}}}`
+
+// glb wraps the glTF JSON in a binary glTF.
+func glb(j string) []byte {
+	for len(j)%4 != 0 {
+		j += " "
+	}
+	var b bytes.Buffer
+	b.WriteString("glTF")
+	binary.Write(&b, binary.LittleEndian, []uint32{2, uint32(20 + len(j)), uint32(len(j))})
+	b.WriteString("JSON")
+	b.WriteString(j)
+	return b.Bytes()
+}
+
+func TestModelExtract(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	write := func(name string, b []byte) string {
+		p := filepath.Join(dir, name)
+		equals(t, ioutil.WriteFile(p, b, 0644), nil)
+		return p
+	}
+	var usdz bytes.Buffer
+	zw := zip.NewWriter(&usdz)
+	f, err := zw.Create("ingenuity.usda")
+	equals(t, err, nil)
+	f.Write([]byte("#usda 1.0\n(\n    doc = \"The \\\"Ingenuity\\\" helicopter\"\n)\n"))
+	equals(t, zw.Close(), nil)
+
+	gltf := write("rover.gltf", []byte(testGLTF))
+	glbPath := write("rover.glb", glb(testGLTF))
+	obj := write("habitat.obj", []byte("# Habitat\nmtllib habitat.mtl\nv 0 0 0\n"))
+	write("habitat.json", []byte(`{"title": "Lunar Habitat", "keywords": "Artemis, Moon", "date_created": "2021:04:19 07:34:00"}`))
+	usd := write("ingenuity.usdz", usdz.Bytes())
+	bad := write("broken.obj", []byte("not a model\n"))
+
+	notCalled := func(p string) (exif, error) {
+		t.Fatalf("exiftool called for %s", p)
+		return newExif(), nil
+	}
+	extract := modelExtract(notCalled)
+	for _, p := range []string{gltf, glbPath} {
+		e, err := extract(p)
+		equals(t, err, nil)
+		equals(t, e.Title(), "Perseverance Rover")
+		equals(t, e.Photographer(), "NASA/JPL-Caltech")
+		equals(t, e.Keywords(), "Mars, rover")
+		equals(t, e.Model["Copyright"], "Public domain")
+		equals(t, e.MediaType(), "model")
+		equals(t, e.NasaID(), "rover")
+	}
+	e, err := extract(glbPath)
+	equals(t, err, nil)
+	equals(t, e.FileFormat(), "GLB")
+
+	e, err = extract(obj)
+	equals(t, err, nil)
+	equals(t, e.Title(), "Lunar Habitat")
+	equals(t, e.Keywords(), "Artemis, Moon")
+	equals(t, e.HasDateCreated(), true)
+	equals(t, e.FileFormat(), "OBJ")
+
+	e, err = extract(usd)
+	equals(t, err, nil)
+	equals(t, e.Description(), `The "Ingenuity" helicopter`)
+
+	_, err = extract(bad)
+	equals(t, err.Error(), "reading OBJ: no vertices or faces")
+
+	// Anything else goes to exiftool.
+	called := false
+	_, err = modelExtract(func(p string) (exif, error) {
+		called = true
+		return newExif(), nil
+	})(filepath.Join(dir, "a.jpg"))
+	equals(t, err, nil)
+	equals(t, called, true)
+}
+
+func TestGLBJSON(t *testing.T) {
+	j, err := glbJSON(glb(`{"asset":{"version":"2.0"}}`))
+	equals(t, err, nil)
+	equals(t, string(bytes.TrimSpace(j)), `{"asset":{"version":"2.0"}}`)
+
+	_, err = glbJSON([]byte("not binary glTF at all"))
+	equals(t, err.Error(), "reading GLB: not a binary glTF")
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	defaultNotifyPort    = 587
+	defaultAttachmentMB  = 10
+	defaultNotifySubject = "chkmd: {{.Accepted}} of {{.Relevant}} accepted in {{.Dirs}}"
+	defaultNotifyBody    = `chkmd checked {{.Dirs}} on {{.Host}}, starting {{.Started.Format "2006-01-02 15:04 MST"}}, in {{printf "%.0f" .Seconds}} seconds.
+
+Total Found: {{.Total}}
+Relevant Files: {{.Relevant}}
+Rejected Files: {{.Rejected}}
+Accepted Files: {{.Accepted}}
+{{if .ReasonLines}}
+Incomplete Reasons:
+{{.ReasonLines}}{{end}}{{if .CountLines}}
+{{.CountLines}}{{end}}{{range .Links}}
+Report: {{.}}{{end}}{{if .Attached}}
+The report is attached.{{end}}
+`
+)
+
+// notifyConfig configures -notify, the email of the summary. Subject and
+// Body are text/template strings executed with a notifyData. The password
+// is read from the environment variable named by PasswordEnv so it doesn't
+// end up in the config file. Reports up to MaxAttachmentMB are attached if
+// Attach is set, and linked to under ReportURL if it is.
+type notifyConfig struct {
+	Host            string   `yaml:"host"`
+	Port            int      `yaml:"port"`
+	User            string   `yaml:"user"`
+	PasswordEnv     string   `yaml:"password_env"`
+	From            string   `yaml:"from"`
+	To              []string `yaml:"to"`
+	Subject         string   `yaml:"subject"`
+	Body            string   `yaml:"body"`
+	Attach          bool     `yaml:"attach"`
+	MaxAttachmentMB int      `yaml:"max_attachment_mb"`
+	ReportURL       string   `yaml:"report_url"`
+}
+
+// notifyData is what the notify templates are executed with.
+type notifyData struct {
+	runSummary
+	Dirs string
+	Host string
+	// ReasonLines and CountLines list the Incomplete reasons and the
+	// counts that aren't 0, a line each.
+	ReasonLines string
+	CountLines  string
+	// Links are the reports under ReportURL, and Attached whether they're
+	// attached.
+	Links    []string
+	Attached bool
+}
+
+// validate checks the addresses parse and the templates do, if there's a
+// host to send to.
+func (nc notifyConfig) validate() error {
+	if nc.Host == "" {
+		return nil
+	}
+	if _, err := mail.ParseAddress(nc.From); err != nil {
+		return fmt.Errorf("notify: from %q: %s", nc.From, err)
+	}
+	if nc.MaxAttachmentMB < 0 {
+		return fmt.Errorf("notify: max_attachment_mb is %d, expected 0 or more", nc.MaxAttachmentMB)
+	}
+	if len(nc.To) == 0 {
+		return fmt.Errorf("notify: no one to send to")
+	}
+	for _, to := range nc.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("notify: to %q: %s", to, err)
+		}
+	}
+	if _, _, err := nc.templates(); err != nil {
+		return fmt.Errorf("notify: %s", err)
+	}
+	return nil
+}
+
+// templates returns the subject and body templates, or the defaults.
+func (nc notifyConfig) templates() (subject, body *template.Template, err error) {
+	if nc.Subject == "" {
+		nc.Subject = defaultNotifySubject
+	}
+	if nc.Body == "" {
+		nc.Body = defaultNotifyBody
+	}
+	if subject, err = template.New("subject").Parse(nc.Subject); err != nil {
+		return nil, nil, err
+	}
+	body, err = template.New("body").Parse(nc.Body)
+	return subject, body, err
+}
+
+// notify emails the summary of a run of the dirs, with reports, the files
+// it was written to, attached or linked to as configured.
+func notify(nc notifyConfig, s runSummary, reasons string, dirs, reports []string) error {
+	msg, err := notifyMessage(nc, s, reasons, dirs, reports, time.Now())
+	if err != nil {
+		return err
+	}
+	return sendMail(nc, msg)
+}
+
+// notifyMessage returns the email of the summary, sent at now.
+func notifyMessage(nc notifyConfig, s runSummary, reasons string, dirs, reports []string, now time.Time) ([]byte, error) {
+	subject, body, err := nc.templates()
+	if err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	data := notifyData{runSummary: s, Dirs: strings.Join(dirs, ", "), Host: host, ReasonLines: reasons}
+	var counts []string
+	for k, n := range s.Counts {
+		if n != 0 {
+			counts = append(counts, fmt.Sprintf("%s: %d\n", k, n))
+		}
+	}
+	sort.Strings(counts)
+	data.CountLines = strings.Join(counts, "")
+
+	limit := int64(nc.MaxAttachmentMB)
+	if limit == 0 {
+		limit = defaultAttachmentMB
+	}
+	attach := nc.Attach
+	for _, p := range reports {
+		if nc.ReportURL != "" {
+			data.Links = append(data.Links, strings.TrimRight(nc.ReportURL, "/")+"/"+filepath.Base(p))
+		}
+		if fi, err := os.Stat(p); err != nil || fi.Size() > limit<<20 {
+			attach = false
+		}
+	}
+	data.Attached = attach && len(reports) > 0
+
+	var subj, text bytes.Buffer
+	if err = subject.Execute(&subj, data); err != nil {
+		return nil, err
+	}
+	if err = body.Execute(&text, data); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\n",
+		nc.From, strings.Join(nc.To, ", "), mime.QEncoding.Encode("utf-8", subj.String()), now.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+	pw, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return nil, err
+	}
+	pw.Write([]byte(strings.Replace(text.String(), "\n", "\r\n", -1)))
+	if data.Attached {
+		for _, p := range reports {
+			if err = attachFile(mw, p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err = mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// attachFile adds the file at p to mw, base64 encoded.
+func attachFile(mw *multipart.Writer, p string) error {
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	typ := mime.TypeByExtension(filepath.Ext(p))
+	if typ == "" {
+		typ = "application/octet-stream"
+	}
+	pw, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {typ},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(p)})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return err
+	}
+	enc := base64.StdEncoding.EncodeToString(b)
+	for len(enc) > 76 {
+		fmt.Fprintf(pw, "%s\r\n", enc[:76])
+		enc = enc[76:]
+	}
+	_, err = fmt.Fprintf(pw, "%s\r\n", enc)
+	return err
+}
+
+// sendMail sends msg to the configured SMTP server, with TLS from the start
+// on port 465 and otherwise STARTTLS if the server offers it, logging in if
+// there's a user.
+func sendMail(nc notifyConfig, msg []byte) error {
+	port := nc.Port
+	if port == 0 {
+		port = defaultNotifyPort
+	}
+	addr := net.JoinHostPort(nc.Host, strconv.Itoa(port))
+	tc := &tls.Config{ServerName: nc.Host}
+	var c *smtp.Client
+	if port == 465 {
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: time.Minute}, "tcp", addr, tc)
+		if err != nil {
+			return err
+		}
+		if c, err = smtp.NewClient(conn, nc.Host); err != nil {
+			conn.Close()
+			return err
+		}
+	} else {
+		conn, err := net.DialTimeout("tcp", addr, time.Minute)
+		if err != nil {
+			return err
+		}
+		if c, err = smtp.NewClient(conn, nc.Host); err != nil {
+			conn.Close()
+			return err
+		}
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err = c.StartTLS(tc); err != nil {
+				c.Close()
+				return err
+			}
+		}
+	}
+	defer c.Close()
+	if nc.User != "" {
+		if err := c.Auth(smtp.PlainAuth("", nc.User, os.Getenv(nc.PasswordEnv), nc.Host)); err != nil {
+			return err
+		}
+	}
+	from, _ := mail.ParseAddress(nc.From)
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range nc.To {
+		a, _ := mail.ParseAddress(to)
+		if err := c.Rcpt(a.Address); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
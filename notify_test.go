@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotifyConfig(t *testing.T) {
+	nc := notifyConfig{Host: "smtp.example.com", From: "chkmd <chkmd@example.com>", To: []string{"curation@example.com"}}
+	equals(t, nc.validate(), nil)
+	equals(t, notifyConfig{}.validate(), nil)
+	for _, bad := range []notifyConfig{
+		{Host: "smtp.example.com", From: "nobody", To: nc.To},
+		{Host: "smtp.example.com", From: nc.From},
+		{Host: "smtp.example.com", From: nc.From, To: nc.To, Subject: "{{.Total"},
+		{Host: "smtp.example.com", From: nc.From, To: nc.To, MaxAttachmentMB: -1},
+	} {
+		equals(t, bad.validate() != nil, true)
+	}
+}
+
+func TestNotifyMessage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	report := filepath.Join(dir, "weekly.csv")
+	equals(t, ioutil.WriteFile(report, []byte("Path,Status\n/media/a.jpg,Accepted\n"), 0644), nil)
+
+	nc := notifyConfig{
+		From:      "chkmd@example.com",
+		To:        []string{"curation@example.com", "lead@example.com"},
+		Attach:    true,
+		ReportURL: "https://reports.example.com/chkmd/",
+	}
+	s := runSummary{
+		Started:  time.Date(2015, 6, 1, 9, 0, 0, 0, time.UTC),
+		Seconds:  90,
+		Total:    12,
+		Relevant: 10,
+		Accepted: 7,
+		Rejected: 3,
+		Counts:   map[string]int32{"bad_dates": 2, "similar": 0},
+	}
+	send := func(nc notifyConfig) (*mail.Message, string, []string) {
+		b, err := notifyMessage(nc, s, "  Missing Title: 3\n", []string{"/media"}, []string{report}, s.Started)
+		equals(t, err, nil)
+		m, err := mail.ReadMessage(bytes.NewReader(b))
+		equals(t, err, nil)
+		_, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+		equals(t, err, nil)
+		mr := multipart.NewReader(m.Body, params["boundary"])
+		p, err := mr.NextPart()
+		equals(t, err, nil)
+		body, _ := ioutil.ReadAll(p)
+		var attached []string
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			attached = append(attached, p.FileName())
+		}
+		return m, strings.Replace(string(body), "\r\n", "\n", -1), attached
+	}
+
+	m, body, attached := send(nc)
+	equals(t, m.Header.Get("Subject"), "chkmd: 7 of 10 accepted in /media")
+	equals(t, m.Header.Get("To"), "curation@example.com, lead@example.com")
+	equals(t, attached, []string{"weekly.csv"})
+	for _, want := range []string{
+		"starting 2015-06-01 09:00 UTC, in 90 seconds.",
+		"Relevant Files: 10\nRejected Files: 3\nAccepted Files: 7\n",
+		"Incomplete Reasons:\n  Missing Title: 3\n",
+		"bad_dates: 2\n",
+		"Report: https://reports.example.com/chkmd/weekly.csv\n",
+		"The report is attached.",
+	} {
+		equals(t, []interface{}{want, strings.Contains(body, want)}, []interface{}{want, true})
+	}
+	equals(t, strings.Contains(body, "similar"), false)
+
+	// Too big to attach, and with our own templates.
+	nc.MaxAttachmentMB, nc.Subject, nc.Body = 1, "Weekly audit: {{.Rejected}} rejected", "{{range .Links}}{{.}}{{end}} {{.Attached}}"
+	equals(t, ioutil.WriteFile(report, make([]byte, 2<<20), 0644), nil)
+	m, body, attached = send(nc)
+	equals(t, m.Header.Get("Subject"), "Weekly audit: 3 rejected")
+	equals(t, body, "https://reports.example.com/chkmd/weekly.csv false")
+	equals(t, attached, []string(nil))
+}
+
+func TestSendMail(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	equals(t, err, nil)
+	defer ln.Close()
+	got := make(chan []string, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		r := bufio.NewReader(c)
+		var lines []string
+		c.Write([]byte("220 localhost ESMTP\r\n"))
+		data := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch {
+			case data && line == ".":
+				data = false
+				c.Write([]byte("250 queued\r\n"))
+			case data:
+			case strings.HasPrefix(line, "EHLO"):
+				c.Write([]byte("250 localhost\r\n"))
+			case line == "DATA":
+				data = true
+				c.Write([]byte("354 go ahead\r\n"))
+			case line == "QUIT":
+				c.Write([]byte("221 bye\r\n"))
+				got <- lines
+				return
+			default:
+				c.Write([]byte("250 ok\r\n"))
+			}
+		}
+		got <- lines
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	nc := notifyConfig{Host: host, From: "chkmd <chkmd@example.com>", To: []string{"Curation <curation@example.com>"}}
+	nc.Port, _ = strconv.Atoi(port)
+	equals(t, sendMail(nc, []byte("Subject: hi\r\n\r\nHello\r\n")), nil)
+	lines := <-got
+	equals(t, lines[1:], []string{
+		"MAIL FROM:<chkmd@example.com>",
+		"RCPT TO:<curation@example.com>",
+		"DATA",
+		"Subject: hi",
+		"",
+		"Hello",
+		".",
+		"QUIT",
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+)
+
+// Exit statuses of -object.
+const (
+	objectAccepted   = 0
+	objectIncomplete = 1
+	objectRejected   = 2
+	objectError      = 3
+)
+
+// checkObject checks the single file, object or URL at p, as for an S3
+// event in a Lambda function: there's no walk or summary, just the row as a
+// JSON object on w. It returns the exit status for its Status.
+func (r *runner) checkObject(p string, w io.Writer) int {
+	row, err := r.checkOne(p)
+	if err != nil {
+		log.Printf("Error opening %s: %s\n", p, err)
+		return objectError
+	}
+
+	result := map[string]string{}
+	for i, h := range csvHeader {
+		result[h] = row[i]
+	}
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error writing result: %s\n", err)
+		return objectError
+	}
+	return objectStatus(result["Status"])
+}
+
+// checkOne checks the single file, object or URL at p, returning its row.
+// A local file inherits the folder metadata of the directories from its
+// -d, or its own directory, down.
+func (r *runner) checkOne(p string) ([]string, error) {
+	var err error
+	if isObject(p) {
+		if r.stores, err = newObjectStores(p); err != nil {
+			return nil, err
+		}
+	} else if !isURL(p) {
+		root := r.roots.rootOf(p)
+		if root == "" {
+			root = filepath.Dir(p)
+		}
+		r.inherited = newFolders(root)
+	}
+
+	files := make(chan string, 1)
+	results := make(chan []string, 1)
+	files <- p
+	close(files)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	r.processFiles(context.Background(), files, results, &statistics{}, &wg)
+	wg.Wait()
+	return <-results, nil
+}
+
+// objectStatus returns the -object exit status for a row's Status.
+func objectStatus(status string) int {
+	switch status {
+	case "Accepted", statusWarned:
+		return objectAccepted
+	case "Incomplete":
+		return objectIncomplete
+	}
+	return objectRejected
+}
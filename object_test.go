@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestObjectStatus(t *testing.T) {
+	equals(t, objectStatus("Accepted"), objectAccepted)
+	equals(t, objectStatus("Incomplete"), objectIncomplete)
+	equals(t, objectStatus("Rejected"), objectRejected)
+}
+
+func TestCheckObject(t *testing.T) {
+	values := []struct {
+		p, status string
+		want      int
+	}{
+		{"image.jpg", statusWarned, objectAccepted},
+		{"nomd.jpg", "Incomplete", objectIncomplete},
+	}
+	for _, v := range values {
+		var buf bytes.Buffer
+		r := newTestRunner(t, "")
+		if !haveExiftool() {
+			r.replay = fixtures
+		}
+		equals(t, r.checkObject(v.p, &buf), v.want)
+		var result map[string]string
+		equals(t, json.Unmarshal(buf.Bytes(), &result), nil)
+		equals(t, result["Path"], v.p)
+		equals(t, result["Status"], v.status)
+	}
+}
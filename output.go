@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// csvWriter is where the CSV output goes: a *csv.Writer, or a splitWriter
+// for -split-size.
+type csvWriter interface {
+	rowWriter
+	Flush()
+	Error() error
+}
+
+// rowFunc is a func as a rowWriter.
+type rowFunc func(row []string) error
+
+// Write calls f.
+func (f rowFunc) Write(row []string) error {
+	return f(row)
+}
+
+// splitName returns the name of the part of the -o file base, like
+// out-001.csv for out.csv.
+func splitName(base string, part int) string {
+	ext := filepath.Ext(base)
+	return fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(base, ext), part, ext)
+}
+
+// splitWriter is a csvWriter to files named for the -o file by splitName,
+// each with the header and at most size rows, which is as many as Excel
+// opens comfortably.
+type splitWriter struct {
+	*csv.Writer
+	base   string
+	size   int
+	header []string
+	part   int
+	rows   int
+	f      *os.File
+	enc    outputEncoding
+}
+
+// newSplitWriter returns a splitWriter to the parts of base with the header.
+// With appending it carries on with the last part there is, which is an
+// error if its header isn't the same. Otherwise it starts on the first,
+// removing any others left from an earlier run.
+func newSplitWriter(base string, size int, header []string, appending bool, enc outputEncoding) (*splitWriter, error) {
+	sw := &splitWriter{base: base, size: size, header: header, enc: enc}
+	last := 0
+	for {
+		if _, err := os.Stat(splitName(base, last+1)); err != nil {
+			break
+		}
+		last++
+	}
+	if appending && last > 0 {
+		p := splitName(base, last)
+		rows, err := countRows(p, header)
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		w, err := enc.writer(f, false)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		sw.part, sw.rows, sw.f, sw.Writer = last, rows, f, csv.NewWriter(w)
+		return sw, nil
+	}
+	for part := 2; part <= last; part++ {
+		if err := os.Remove(splitName(base, part)); err != nil {
+			return nil, err
+		}
+	}
+	return sw, sw.next()
+}
+
+// next closes the part being written, if there is one, and starts the next.
+func (sw *splitWriter) next() error {
+	if sw.f != nil {
+		if err := sw.Close(); err != nil {
+			return err
+		}
+	}
+	sw.part++
+	f, err := os.Create(splitName(sw.base, sw.part))
+	if err != nil {
+		return err
+	}
+	w, err := sw.enc.writer(f, true)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	sw.f, sw.rows, sw.Writer = f, 0, csv.NewWriter(w)
+	return sw.Writer.Write(sw.header)
+}
+
+// Write writes the row to the part, starting the next if it's full.
+func (sw *splitWriter) Write(row []string) error {
+	if sw.rows >= sw.size {
+		if err := sw.next(); err != nil {
+			return err
+		}
+	}
+	sw.rows++
+	return sw.Writer.Write(row)
+}
+
+// Close flushes and closes the part being written.
+func (sw *splitWriter) Close() error {
+	sw.Flush()
+	err := sw.Error()
+	if cerr := sw.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// countRows returns the rows in the CSV at p after its header, which must
+// be header, as the rows appended to it will have those columns.
+func countRows(p string, header []string) (int, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	r := csv.NewReader(textReader(f))
+	r.FieldsPerRecord = -1
+	rows := -1
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%s: %s", p, err)
+		}
+		if rows < 0 && strings.Join(row, ",") != strings.Join(header, ",") {
+			return 0, fmt.Errorf("%s has different columns", p)
+		}
+		rows++
+	}
+	if rows < 0 {
+		return 0, fmt.Errorf("%s has no header", p)
+	}
+	return rows, nil
+}
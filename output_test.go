@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "out.csv")
+	equals(t, splitName(base, 2), filepath.Join(dir, "out-002.csv"))
+	read := func(part int) string {
+		b, _ := ioutil.ReadFile(splitName(base, part))
+		return string(b)
+	}
+	write := func(appending bool, rows ...string) {
+		sw, err := newSplitWriter(base, 2, []string{"Path", "Status"}, appending, outputEncoding{})
+		equals(t, err, nil)
+		for _, row := range rows {
+			equals(t, sw.Write([]string{row, "Accepted"}), nil)
+		}
+		equals(t, sw.Close(), nil)
+	}
+
+	write(false, "a.jpg", "b.jpg", "c.jpg", "d.jpg", "e.jpg")
+	equals(t, read(1), "Path,Status\na.jpg,Accepted\nb.jpg,Accepted\n")
+	equals(t, read(3), "Path,Status\ne.jpg,Accepted\n")
+	write(true, "f.jpg", "g.jpg")
+	equals(t, read(3), "Path,Status\ne.jpg,Accepted\nf.jpg,Accepted\n")
+	equals(t, read(4), "Path,Status\ng.jpg,Accepted\n")
+
+	// Starting again leaves no parts from before.
+	write(false, "h.jpg")
+	equals(t, read(1), "Path,Status\nh.jpg,Accepted\n")
+	_, err = os.Stat(splitName(base, 2))
+	equals(t, os.IsNotExist(err), true)
+
+	_, err = newSplitWriter(base, 2, []string{"Path", "Status", "Reason"}, true, outputEncoding{})
+	equals(t, err.Error(), splitName(base, 1)+" has different columns")
+}
+
+func TestAppendAndSplitRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	media, fixtures := filepath.Join(dir, "media"), filepath.Join(dir, "fixtures")
+	equals(t, os.Mkdir(media, 0755), nil)
+	equals(t, os.Mkdir(fixtures, 0755), nil)
+	for _, name := range []string{"KSC-1.jpg", "KSC-2.jpg", "KSC-3.jpg"} {
+		equals(t, ioutil.WriteFile(filepath.Join(media, name), nil, 0644), nil)
+	}
+	output := filepath.Join(dir, "out.csv")
+	rows := func(p string) int {
+		f, err := os.Open(p)
+		equals(t, err, nil)
+		defer f.Close()
+		results, err := readResults(f)
+		equals(t, err, nil)
+		return len(results)
+	}
+
+	args := []string{"-d", media, "-replay", fixtures, "-o", output, "-p", "1"}
+	equals(t, run(args), 0)
+	equals(t, run(append(args, "-append")), 0)
+	equals(t, rows(output), 6)
+
+	args = append(args, "-split-size", "2")
+	equals(t, run(args), 0)
+	equals(t, run(append(args, "-append")), 0)
+	equals(t, []int{rows(splitName(output, 1)), rows(splitName(output, 2)), rows(splitName(output, 3))}, []int{2, 2, 2})
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// How -path-mode writes the Path column.
+const (
+	// pathRelative is relative to the -d the file is under.
+	pathRelative = "relative"
+	// pathAbsolute is the absolute path.
+	pathAbsolute = "absolute"
+	// pathURI is a file:// URI.
+	pathURI = "uri"
+)
+
+// pathMode is a flag.Value for -path-mode. Empty writes paths as they were
+// found, relative to the working directory if -d was.
+type pathMode string
+
+func (pm *pathMode) Set(s string) error {
+	switch s {
+	case pathRelative, pathAbsolute, pathURI:
+		*pm = pathMode(s)
+		return nil
+	}
+	return fmt.Errorf("expected %s, %s or %s", pathRelative, pathAbsolute, pathURI)
+}
+
+func (pm pathMode) String() string {
+	return string(pm)
+}
+
+// rewrite returns p as the mode writes it, roots being the -d. S3 objects
+// and URLs are only made relative, when they're under a -d, and paths that
+// are in none of roots aren't.
+func (pm pathMode) rewrite(p string, roots dirList) string {
+	if isURL(p) || isObject(p) {
+		root := roots.rootOf(p)
+		if pm == pathRelative && under(root, p) {
+			return strings.TrimPrefix(p, strings.TrimSuffix(root, "/")+"/")
+		}
+		return p
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	switch pm {
+	case pathRelative:
+		if len(roots) == 0 {
+			return p
+		}
+		root, err := filepath.Abs(roots.rootOf(p))
+		if err != nil || !under(root, abs) {
+			return p
+		}
+		rel, _ := filepath.Rel(root, abs)
+		return rel
+	case pathAbsolute:
+		return abs
+	case pathURI:
+		abs = filepath.ToSlash(abs)
+		if !strings.HasPrefix(abs, "/") {
+			// A Windows drive, C:/...
+			abs = "/" + abs
+		}
+		return (&url.URL{Scheme: "file", Path: abs}).String()
+	}
+	return p
+}
+
+// pathRewriter is a rowWriter writing rows to w with their Path as mode
+// writes it.
+type pathRewriter struct {
+	w     rowWriter
+	mode  pathMode
+	roots dirList
+}
+
+// newPathRewriter returns w wrapped to rewrite the Path column, or w itself
+// if mode leaves paths as they are.
+func newPathRewriter(w rowWriter, mode pathMode, roots dirList) rowWriter {
+	if mode == "" {
+		return w
+	}
+	return pathRewriter{w, mode, roots}
+}
+
+// Write writes the row with its Path rewritten. The row itself is left alone
+// for the other writers.
+func (pr pathRewriter) Write(row []string) error {
+	rewritten := append([]string{}, row...)
+	c := column("Path")
+	rewritten[c] = pr.mode.rewrite(row[c], pr.roots)
+	return pr.w.Write(rewritten)
+}
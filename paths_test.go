@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPathMode(t *testing.T) {
+	var pm pathMode
+	equals(t, pm.Set("uri"), nil)
+	equals(t, pm, pathMode(pathURI))
+	equals(t, pm.Set("url").Error(), "expected relative, absolute or uri")
+
+	wd, err := os.Getwd()
+	equals(t, err, nil)
+	roots := dirList{"media", "s3://bucket/delivery"}
+	for _, v := range []struct {
+		mode pathMode
+		p    string
+		want string
+	}{
+		{"", "media/a/1.jpg", "media/a/1.jpg"},
+		{pathRelative, "media/a/1.jpg", filepath.Join("a", "1.jpg")},
+		{pathRelative, "elsewhere/1.jpg", "elsewhere/1.jpg"},
+		{pathRelative, "s3://bucket/delivery/a/1.jpg", "a/1.jpg"},
+		{pathRelative, "https://example.com/1.jpg", "https://example.com/1.jpg"},
+		{pathAbsolute, "media/a/1.jpg", filepath.Join(wd, "media", "a", "1.jpg")},
+		{pathAbsolute, "s3://bucket/delivery/a/1.jpg", "s3://bucket/delivery/a/1.jpg"},
+		{pathURI, "/media/a b/1.jpg", "file:///media/a%20b/1.jpg"},
+	} {
+		equals(t, v.mode.rewrite(v.p, roots), v.want)
+	}
+	equals(t, pathMode(pathRelative).rewrite("media/1.jpg", nil), "media/1.jpg")
+}
+
+func TestPathRewriter(t *testing.T) {
+	var b bytes.Buffer
+	out := csv.NewWriter(&b)
+	equals(t, newPathRewriter(out, "", nil), rowWriter(out))
+
+	row := testRow("/media/a/1.jpg", "Accepted", "")
+	pr := newPathRewriter(newSelectColumns(out, []string{"Path", "Status"}), pathRelative, dirList{"/media"})
+	equals(t, pr.Write(row), nil)
+	out.Flush()
+	equals(t, b.String(), filepath.Join("a", "1.jpg")+",Accepted\n")
+	equals(t, row[column("Path")], "/media/a/1.jpg")
+}
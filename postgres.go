@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// pgBatch is how many rows a postgresWriter copies in at a time.
+const pgBatch = 500
+
+// pgTimeout is how long connecting, and each statement, may take.
+const pgTimeout = 30 * time.Second
+
+// isPostgres is whether the -o is a postgres:// URL rather than a file.
+func isPostgres(p string) bool {
+	return strings.HasPrefix(p, "postgres://") || strings.HasPrefix(p, "postgresql://")
+}
+
+// pgColumn returns the column for a CSV column, like 508_description for
+// 508 Description.
+func pgColumn(column string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(column) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "_"):
+			b.WriteByte('_')
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
+
+// postgresWriter is a csvWriter copying the rows into the chkmd_results
+// table, with the run they're from in chkmd_runs, for -o postgres://, so the
+// history of runs can be queried. It makes the tables, and adds any columns
+// they don't have yet, as it starts. Rows are copied in pgBatch at a time,
+// and when flushed.
+type postgresWriter struct {
+	conn    *pgx.Conn
+	runID   string
+	columns []string
+	rows    [][]interface{}
+	seq     int
+	err     error
+}
+
+// newPostgresWriter connects to the database at rawURL and starts the run,
+// whose rows have the columns. The URL is libpq's, so the user and password
+// may come from PGUSER and PGPASSWORD, and its sslmode is prefer by default.
+func newPostgresWriter(rawURL, runID string, columns []string) (*postgresWriter, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pgTimeout)
+	defer cancel()
+	conn, err := pgx.Connect(ctx, rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: %s", err)
+	}
+	pw := &postgresWriter{conn: conn, runID: runID}
+	for _, c := range columns {
+		pw.columns = append(pw.columns, pgColumn(c))
+	}
+	for _, stmt := range pw.migration() {
+		if err = pw.exec(stmt); err != nil {
+			conn.Close(context.Background())
+			return nil, err
+		}
+	}
+	host, _ := os.Hostname()
+	if err = pw.exec("INSERT INTO chkmd_runs (run_id, started, host) VALUES ($1, now(), $2)", runID, host); err != nil {
+		conn.Close(context.Background())
+		return nil, err
+	}
+	return pw, nil
+}
+
+// migration returns the statements making the tables, and adding the
+// columns, that aren't there yet.
+func (pw *postgresWriter) migration() []string {
+	stmts := []string{
+		"CREATE TABLE IF NOT EXISTS chkmd_runs (run_id text PRIMARY KEY, started timestamptz NOT NULL, finished timestamptz, host text)",
+		"CREATE TABLE IF NOT EXISTS chkmd_results (run_id text NOT NULL REFERENCES chkmd_runs, seq integer NOT NULL, PRIMARY KEY (run_id, seq))",
+	}
+	for _, c := range pw.columns {
+		stmts = append(stmts, "ALTER TABLE chkmd_results ADD COLUMN IF NOT EXISTS "+pgx.Identifier{c}.Sanitize()+" text")
+	}
+	return stmts
+}
+
+// exec runs the statement with its args, giving up after pgTimeout.
+func (pw *postgresWriter) exec(sql string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pgTimeout)
+	defer cancel()
+	if _, err := pw.conn.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("postgres: %s", err)
+	}
+	return nil
+}
+
+// Write adds the row to the batch, copying it in once it's full.
+func (pw *postgresWriter) Write(row []string) error {
+	if pw.err != nil {
+		return pw.err
+	}
+	pw.seq++
+	values := []interface{}{pw.runID, pw.seq}
+	for _, v := range row {
+		// Text can't have NULs.
+		values = append(values, strings.Replace(v, "\x00", "", -1))
+	}
+	pw.rows = append(pw.rows, values)
+	if len(pw.rows) >= pgBatch {
+		pw.Flush()
+	}
+	return pw.err
+}
+
+// Flush copies the batch in.
+func (pw *postgresWriter) Flush() {
+	if len(pw.rows) == 0 || pw.err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pgTimeout)
+	defer cancel()
+	columns := append([]string{"run_id", "seq"}, pw.columns...)
+	_, err := pw.conn.CopyFrom(ctx, pgx.Identifier{"chkmd_results"}, columns, pgx.CopyFromRows(pw.rows))
+	if err != nil {
+		pw.err = fmt.Errorf("postgres: %s", err)
+	}
+	pw.rows = nil
+}
+
+// Error returns the error copying in, if there was one.
+func (pw *postgresWriter) Error() error {
+	return pw.err
+}
+
+// Close copies in what's left, marks the run finished and disconnects.
+func (pw *postgresWriter) Close() error {
+	pw.Flush()
+	err := pw.err
+	if err == nil {
+		err = pw.exec("UPDATE chkmd_runs SET finished = now() WHERE run_id = $1", pw.runID)
+	}
+	if cerr := pw.conn.Close(context.Background()); err == nil && cerr != nil {
+		err = fmt.Errorf("postgres: %s", cerr)
+	}
+	return err
+}
+
+// newRunID returns an ID for this run, the time it started and some random
+// hex, like 20140607T101112Z-1a2b3c.
+func newRunID(now time.Time) string {
+	b := make([]byte, 3)
+	rand.Read(b)
+	return now.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,9 @@
+package main
+
+import "os/exec"
+
+// detach is a no-op on Windows, where Ctrl-C is only sent to the console's
+// processes and exiftool is already finished by then.
+func detach(cmd *exec.Cmd) *exec.Cmd {
+	return cmd
+}
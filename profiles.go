@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// configVersion is the newest config schema we read. Version 2 added
+// profiles; a config without a version is version 1.
+const configVersion = 2
+
+// profile is a named set of the settings checking a kind of collection
+// needs, chosen with -profile, e.g.
+//
+//	version: 2
+//	profiles:
+//	  images-strict:
+//	    mime_types: [image/jpeg, image/tiff]
+//	    rules:
+//	      required: [DateCreated, Description, Keywords]
+//	    thresholds:
+//	      min_megapixels: 4
+//	  video-lenient:
+//	    mime_types: [video/mp4, video/quicktime]
+//	    rules:
+//	      required: [Title]
+//
+// Whatever a profile sets replaces the config's own setting, except fields,
+// which it maps on top of the config's.
+type profile struct {
+	MimeTypes  []string        `yaml:"mime_types"`
+	MediaTypes []string        `yaml:"media_types"`
+	Rules      rules           `yaml:"rules"`
+	Fields     fieldMapping    `yaml:"fields"`
+	Thresholds thresholdConfig `yaml:"thresholds"`
+}
+
+// validators returns the checks of each of the profile's settings, keyed by
+// its name in the config. Its fields may use the config's scripts, sc.
+func (pr profile) validators(sc scripts) []configCheck {
+	return []configCheck{
+		{"media_types", func() error { return validMediaTypes(pr.MediaTypes) }},
+		{"rules", pr.Rules.validate},
+		{"fields", func() error { return pr.Fields.validate(sc) }},
+		{"thresholds", pr.Thresholds.validate},
+	}
+}
+
+// configCheck validates the setting at key in the config.
+type configCheck struct {
+	key      string
+	validate func() error
+}
+
+// validateProfiles checks the config's version is one we read, that
+// profiles are only in version 2 or later, and every profile's settings,
+// returning the keys of the setting in error too.
+func (c config) validateProfiles(sc scripts) ([]string, error) {
+	if c.Version < 0 || c.Version > configVersion {
+		return []string{"version"}, fmt.Errorf("version %d isn't one we read, the newest is %d", c.Version, configVersion)
+	}
+	if len(c.Profiles) > 0 && c.Version < 2 {
+		return []string{"profiles"}, fmt.Errorf("profiles need version: 2")
+	}
+	for _, name := range c.profileNames() {
+		for _, check := range c.Profiles[name].validators(sc) {
+			if err := check.validate(); err != nil {
+				return []string{"profiles", name, check.key}, fmt.Errorf("profile %s: %s", name, err)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// profileNames returns the names of the config's profiles in order.
+func (c config) profileNames() []string {
+	var names []string
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// withProfile returns the config with the named profile's settings in place
+// of its own. No name is the config as it is.
+func (c config) withProfile(name string) (config, error) {
+	if name == "" {
+		return c, nil
+	}
+	pr, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return c, fmt.Errorf("unknown profile %q, the config has none", name)
+		}
+		return c, fmt.Errorf("unknown profile %q, expected one of %s", name, strings.Join(c.profileNames(), ", "))
+	}
+	if len(pr.MimeTypes) > 0 {
+		c.MimeTypes = pr.MimeTypes
+	}
+	if len(pr.MediaTypes) > 0 {
+		c.MediaTypes = pr.MediaTypes
+	}
+	if pr.Rules.Required != nil || pr.Rules.AnyOf != nil || pr.Rules.Warn != nil || pr.Rules.MediaTypes != nil {
+		c.Rules = pr.Rules
+	}
+	if len(pr.Fields) > 0 {
+		fields := fieldMapping{}
+		for f, sources := range c.Fields {
+			fields[f] = sources
+		}
+		for f, sources := range pr.Fields {
+			fields[f] = sources
+		}
+		c.Fields = fields
+	}
+	if pr.Thresholds != (thresholdConfig{}) {
+		c.Thresholds = pr.Thresholds
+	}
+	return c, nil
+}
+
+// configLine returns the line of the YAML config b that the keys, each
+// nested in the one before, are on, or 0 if it isn't there.
+func configLine(b []byte, keys ...string) int {
+	s := bufio.NewScanner(bytes.NewReader(b))
+	// indent is that of the key found last, and child that of its keys.
+	line, found, indent, child := 0, 0, -1, 0
+	for s.Scan() {
+		line++
+		text := s.Text()
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "-") {
+			continue
+		}
+		in := len(text) - len(trimmed)
+		if in <= indent {
+			// Out of the block of the key found last.
+			return 0
+		}
+		if child < 0 {
+			child = in
+		}
+		if in == child && strings.HasPrefix(trimmed, keys[found]+":") {
+			found++
+			if found == len(keys) {
+				return line
+			}
+			indent, child = in, -1
+		}
+	}
+	return 0
+}
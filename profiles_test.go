@@ -0,0 +1,96 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const profilesConfig = `---
+version: 2
+mime_types: [image/jpeg, video/mp4]
+fields:
+  Title: [XMP:Title]
+profiles:
+  images-strict:
+    mime_types: [image/jpeg]
+    rules:
+      required: [DateCreated, Description]
+    fields:
+      NasaID: [IPTC:JobID]
+    thresholds:
+      min_megapixels: 4
+  video-lenient:
+    rules:
+      required: [Title]
+  warn-only:
+    rules:
+      warn: [Location]
+`
+
+// writeConfig writes the config yml into dir, returning its path.
+func writeConfig(t *testing.T, dir, yml string) string {
+	p := filepath.Join(dir, "config.yaml")
+	equals(t, ioutil.WriteFile(p, []byte(yml), 0644), nil)
+	return p
+}
+
+func TestWithProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	cfg, err := readConfig(writeConfig(t, dir, profilesConfig))
+	equals(t, err, nil)
+	strict, err := cfg.withProfile("images-strict")
+	equals(t, err, nil)
+	equals(t, strict.MimeTypes, []string{"image/jpeg"})
+	equals(t, strict.Rules.Required, []string{"DateCreated", "Description"})
+	equals(t, strict.Fields, fieldMapping{"Title": {"XMP:Title"}, "NasaID": {"IPTC:JobID"}})
+	equals(t, strict.Thresholds, thresholdConfig{MinMegapixels: 4})
+	equals(t, cfg.Fields, fieldMapping{"Title": {"XMP:Title"}})
+
+	lenient, err := cfg.withProfile("video-lenient")
+	equals(t, err, nil)
+	equals(t, lenient.MimeTypes, []string{"image/jpeg", "video/mp4"})
+	equals(t, lenient.Rules.Required, []string{"Title"})
+	warn, err := cfg.withProfile("warn-only")
+	equals(t, err, nil)
+	equals(t, warn.Rules.Warn, []string{"Location"})
+
+	same, err := cfg.withProfile("")
+	equals(t, err, nil)
+	equals(t, same.Rules, cfg.Rules)
+	_, err = cfg.withProfile("audio")
+	equals(t, err.Error(), `unknown profile "audio", expected one of images-strict, video-lenient, warn-only`)
+	_, err = config{}.withProfile("audio")
+	equals(t, err.Error(), `unknown profile "audio", the config has none`)
+}
+
+func TestConfigErrorLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	for _, tc := range []struct {
+		yml, want string
+	}{
+		{"version: 3\n", "line 1: version 3 isn't one we read, the newest is 2"},
+		{"mime_types: [image/jpeg]\nprofiles:\n  a:\n    rules:\n      required: [Title]\n", "line 2: profiles need version: 2"},
+		{"version: 2\nprofiles:\n  a:\n    rules:\n      required: [Title]\n  b:\n    rules:\n      required: [Nope]\n",
+			`line 7: profile b: unknown field "Nope" in rules`},
+		{"mime_types: [image/jpeg]\n\n# Rules\nrules:\n  required: [Nope]\n", `line 4: unknown field "Nope" in rules`},
+	} {
+		p := writeConfig(t, dir, tc.yml)
+		_, err := readConfig(p)
+		equals(t, err.Error(), "Error in config file "+p+", "+tc.want)
+	}
+}
+
+func TestConfigLine(t *testing.T) {
+	b := []byte(profilesConfig)
+	equals(t, configLine(b, "fields"), 4)
+	equals(t, configLine(b, "profiles", "images-strict", "fields"), 11)
+	equals(t, configLine(b, "profiles", "video-lenient", "rules"), 16)
+	equals(t, configLine(b, "profiles", "video-lenient", "fields"), 0)
+	equals(t, configLine(b, "rules"), 0)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// qualityConfig weights the metadata quality score. Weights are per field
+// name as in fieldChecks, and WarningPenalty is taken off a file's score for
+// each warning. It's a pointer so warning_penalty: 0 can turn it off, where
+// leaving it out uses defaultWarningPenalty.
+type qualityConfig struct {
+	Weights        map[string]float64 `yaml:"weights"`
+	WarningPenalty *float64           `yaml:"warning_penalty"`
+}
+
+// defaultWarningPenalty is the WarningPenalty when the config has none.
+const defaultWarningPenalty = 5
+
+// defaultQuality is used for anything not set in the config.
+var defaultQuality = qualityConfig{
+	Weights: map[string]float64{
+		"DateCreated":  3,
+		"Description":  3,
+		"Keywords":     2,
+		"Title":        2,
+		"NasaID":       1,
+		"Location":     1,
+		"Photographer": 1,
+	},
+}
+
+// score returns the 0-100 quality score of e. It is the weighted percentage
+// of fields present less the penalty for each warning.
+func (qc qualityConfig) score(e exif) float64 {
+	var got, total float64
+	for f, w := range qc.Weights {
+		total += w
+		if fieldChecks[f](e) {
+			got += w
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	s := 100*got/total - qc.warningPenalty()*float64(len(e.Warnings()))
+	if s < 0 {
+		return 0
+	}
+	return s
+}
+
+// warningPenalty returns WarningPenalty, or defaultWarningPenalty if it's
+// not set.
+func (qc qualityConfig) warningPenalty() float64 {
+	if qc.WarningPenalty == nil {
+		return defaultWarningPenalty
+	}
+	return *qc.WarningPenalty
+}
+
+// validate checks that the weights only name fields we can check.
+func (qc qualityConfig) validate() error {
+	for f := range qc.Weights {
+		if fieldChecks[f] == nil {
+			return fmt.Errorf("unknown field %q in quality weights", f)
+		}
+	}
+	return nil
+}
+
+// quality is the running score of one delivery.
+type quality struct {
+	Files int
+	Total float64
+}
+
+// Score is the delivery's average file score.
+func (q quality) Score() float64 {
+	if q.Files == 0 {
+		return 0
+	}
+	return q.Total / float64(q.Files)
+}
+
+// scorecard keeps the quality score of each delivery. It is safe for use by
+// multiple processFiles goroutines.
+type scorecard struct {
+	sync.Mutex
+	config     qualityConfig
+	Deliveries map[string]*quality
+}
+
+// newScorecard returns a scorecard using qc, with defaults for anything
+// qc doesn't set.
+func newScorecard(qc qualityConfig) *scorecard {
+	if qc.Weights == nil {
+		qc.Weights = defaultQuality.Weights
+	}
+	return &scorecard{config: qc, Deliveries: map[string]*quality{}}
+}
+
+// add scores e against its delivery. A nil e, e.g. when exiftool failed,
+// scores 0.
+func (sc *scorecard) add(delivery string, e *exif) {
+	var s float64
+	if e != nil {
+		s = sc.config.score(*e)
+	}
+	sc.Lock()
+	defer sc.Unlock()
+	q := sc.Deliveries[delivery]
+	if q == nil {
+		q = &quality{}
+		sc.Deliveries[delivery] = q
+	}
+	q.Files++
+	q.Total += s
+}
+
+// qualitySummary is a delivery's score in the -summary file.
+type qualitySummary struct {
+	Score float64 `json:"score"`
+	Files int     `json:"files"`
+}
+
+// summary returns the score of each delivery, keyed by delivery.
+func (sc *scorecard) summary() map[string]qualitySummary {
+	scores := map[string]qualitySummary{}
+	if sc == nil {
+		return scores
+	}
+	sc.Lock()
+	defer sc.Unlock()
+	for name, q := range sc.Deliveries {
+		scores[name] = qualitySummary{Score: q.Score(), Files: q.Files}
+	}
+	return scores
+}
+
+// String summarizes the scores, one delivery per line.
+func (sc *scorecard) String() string {
+	sc.Lock()
+	defer sc.Unlock()
+	var names []string
+	for name := range sc.Deliveries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		q := sc.Deliveries[name]
+		fmt.Fprintf(&b, "%s: %.1f (%d files)\n", name, q.Score(), q.Files)
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	yaml "gopkg.in/yaml.v1"
+)
+
+func TestQualityScore(t *testing.T) {
+	penalty := 10.0
+	qc := qualityConfig{
+		Weights:        map[string]float64{"DateCreated": 3, "Keywords": 1},
+		WarningPenalty: &penalty,
+	}
+	values := []struct {
+		date, kw, warning string
+		want              float64
+	}{
+		{"2015:01:09", "moon", "", 100},
+		{"2015:01:09", "", "", 75},
+		{"", "moon", "", 25},
+		{"2015:01:09", "moon", "Bad IPTC data", 90},
+		{"", "moon", "Bad IPTC data", 15},
+		{"", "", "Bad IPTC data", 0},
+	}
+	for _, v := range values {
+		e := newExif()
+		e.IPTC["DateCreated"] = v.date
+		e.IPTC["Keywords"] = v.kw
+		e.Data["Warning"] = v.warning
+		equals(t, qc.score(e), v.want)
+	}
+}
+
+func TestScorecard(t *testing.T) {
+	sc := newScorecard(qualityConfig{Weights: map[string]float64{"Keywords": 1}})
+	equals(t, sc.config.warningPenalty(), 5.0)
+	e := newExif()
+	e.IPTC["Keywords"] = "moon"
+	sc.add("ksc", &e)
+	sc.add("ksc", nil)
+	sc.add("jsc", &e)
+	equals(t, sc.Deliveries["ksc"].Score(), 50.0)
+	equals(t, sc.Deliveries["jsc"].Score(), 100.0)
+	equals(t, sc.String(), "jsc: 100.0 (1 files)\nksc: 50.0 (2 files)\n")
+}
+
+func TestQualityWarningPenalty(t *testing.T) {
+	var cfg config
+	equals(t, yaml.Unmarshal([]byte("quality:\n  warning_penalty: 0\n"), &cfg), nil)
+	sc := newScorecard(cfg.Quality)
+	equals(t, sc.config.warningPenalty(), 0.0)
+	e := newExif()
+	e.IPTC["DateCreated"] = "2015:01:09"
+	e.Data["Warning"] = "Bad IPTC data"
+	sc.add("ksc", &e)
+	equals(t, sc.Deliveries["ksc"].Score() > 0, true)
+
+	cfg = config{}
+	equals(t, yaml.Unmarshal([]byte("quality:\n  weights: {Keywords: 1}\n"), &cfg), nil)
+	equals(t, cfg.Quality.warningPenalty(), 5.0)
+}
+
+func TestQualityValidate(t *testing.T) {
+	equals(t, defaultQuality.validate(), nil)
+	err := qualityConfig{Weights: map[string]float64{"Caption": 1}}.validate()
+	equals(t, err.Error(), `unknown field "Caption" in quality weights`)
+}
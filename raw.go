@@ -0,0 +1,42 @@
+package main
+
+import "mime"
+
+// rawTypes are the MIME types exiftool gives camera RAW files, which
+// photographers deliver as originals, by extension.
+var rawTypes = map[string]string{
+	".arw": "image/x-sony-arw",
+	".cr2": "image/x-canon-cr2",
+	".cr3": "image/x-canon-cr3",
+	".dng": "image/x-adobe-dng",
+	".nef": "image/x-nikon-nef",
+}
+
+// rawAliases are what else RAW files' XMP dc:format may call their types:
+// Adobe's tools write image/dng for DNGs, and some image/x-raw for any RAW,
+// which is whatever exiftool says the file is.
+var rawAliases = map[string]string{
+	"image/dng":   "image/x-adobe-dng",
+	"image/x-raw": "",
+}
+
+func init() {
+	// Systems' MIME tables know few of them, and by other names than
+	// exiftool's.
+	for ext, t := range rawTypes {
+		mime.AddExtensionType(ext, t)
+	}
+}
+
+// rawType returns t as exiftool names it, if it's another name for a RAW
+// type, or otherwise t. A generic RAW type is the file's own MIMEType.
+func (e exif) rawType(t string) string {
+	raw, ok := rawAliases[t]
+	switch {
+	case !ok:
+		return t
+	case raw == "":
+		return e.Data["MIMEType"]
+	}
+	return raw
+}
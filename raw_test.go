@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/ioutil"
+	"mime"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRawTypes(t *testing.T) {
+	for ext, want := range map[string]string{
+		".nef": "image/x-nikon-nef",
+		".NEF": "image/x-nikon-nef",
+		".cr2": "image/x-canon-cr2",
+		".CR3": "image/x-canon-cr3",
+		".arw": "image/x-sony-arw",
+		".dng": "image/x-adobe-dng",
+	} {
+		equals(t, []string{ext, mime.TypeByExtension(ext)}, []string{ext, want})
+		equals(t, defaultTypeSet[want], true)
+	}
+
+	equals(t, sniffType([]byte("II*\x00\x10\x00\x00\x00CR\x02\x00")), "image/x-canon-cr2")
+	equals(t, sniffType([]byte("MM\x00*\x00\x00\x00\x08")), "image/tiff")
+	equals(t, sniffType([]byte("\x00\x00\x00\x18ftypcrx \x00\x00\x00\x01")), "image/x-canon-cr3")
+
+	for _, v := range []struct{ format, mimeType, fileType string }{
+		{"", "image/x-nikon-nef", "NEF"},
+		{"image/x-canon-cr2", "image/x-canon-cr2", "CR2"},
+		{"image/dng", "image/x-adobe-dng", "DNG"},
+		{"image/x-raw", "image/x-sony-arw", "ARW"},
+	} {
+		e := newExif()
+		e.XMP["Format"], e.Data["MIMEType"], e.Data["FileType"] = v.format, v.mimeType, v.fileType
+		equals(t, []string{v.format, e.MediaType(), e.FileFormat()}, []string{v.format, "image", v.fileType})
+	}
+}
+
+func TestRawSidecars(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	nef := filepath.Join(dir, "KSC-2015-001.nef")
+	equals(t, ioutil.WriteFile(nef, []byte("MM\x00*\x00\x00\x00\x08raw"), 0644), nil)
+	xpacket := "<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n<x:xmpmeta xmlns:x=\"adobe:ns:meta/\"></x:xmpmeta>\n"
+	equals(t, ioutil.WriteFile(nef+".xmp", []byte(xpacket), 0644), nil)
+	equals(t, ioutil.WriteFile(sidecarPath(nef), []byte(xpacket), 0644), nil)
+
+	// RAW files seldom have IPTC of their own; what's written about them is
+	// their sidecars' XMP, from Lightroom or Bridge.
+	extract := preflightExtract(defaultTypeSet, func(p string) (exif, error) {
+		e := newExif()
+		switch p {
+		case nef:
+			e.Data["MIMEType"], e.Data["FileType"] = "image/x-nikon-nef", "NEF"
+			e.Exif["DateTimeOriginal"] = "2015:06:01 10:00:00"
+		case nef + ".xmp":
+			e.XMP["Title"] = "Launch"
+			e.XMP["Subject"] = "Launch, Pad 39A"
+		case sidecarPath(nef):
+			e.XMP["Title"] = "Older title"
+			e.XMP["Description"] = "The launch from Pad 39A."
+		}
+		return e, nil
+	})
+	e, err := sidecarExtract(config{}.sidecarPrecedence(), embeddedWins, extract)(nef)
+	equals(t, err, nil)
+	e.types, e.media = defaultTypeSet, defaultMediaTypeSet
+	equals(t, e.Title(), "Launch")
+	equals(t, e.Description(), "The launch from Pad 39A.")
+	equals(t, e.KeywordList(), []string{"Launch", "Pad 39A"})
+	equals(t, e.MediaType(), "image")
+	equals(t, e.HasDateCreated(), true)
+	equals(t, rules{}.accepts(e), true)
+}
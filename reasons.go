@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// missingReason is the Reason for an Incomplete file missing what the rules
+// require, e.g. "Missing: DateCreated, Keywords or Description".
+func missingReason(missing []string) string {
+	return "Missing: " + strings.Join(missing, ", ")
+}
+
+// reasonCounts counts why files were Incomplete, so remediation can target
+// the commonest: each field or any_of group missing, and each check failed.
+type reasonCounts struct {
+	sync.Mutex
+	counts map[string]int
+}
+
+// newReasonCounts returns an empty reasonCounts.
+func newReasonCounts() *reasonCounts {
+	return &reasonCounts{counts: map[string]int{}}
+}
+
+// add counts a file for each of the reasons.
+func (rc *reasonCounts) add(reasons ...string) {
+	if rc == nil {
+		return
+	}
+	rc.Lock()
+	defer rc.Unlock()
+	for _, r := range reasons {
+		rc.counts[r]++
+	}
+}
+
+// counted returns a copy of the counts.
+func (rc *reasonCounts) counted() map[string]int {
+	counts := map[string]int{}
+	if rc == nil {
+		return counts
+	}
+	rc.Lock()
+	defer rc.Unlock()
+	for r, n := range rc.counts {
+		counts[r] = n
+	}
+	return counts
+}
+
+// summary lists the reasons, commonest first, a line each.
+func (rc *reasonCounts) summary() string {
+	if rc == nil {
+		return ""
+	}
+	rc.Lock()
+	defer rc.Unlock()
+	var reasons []string
+	for r := range rc.counts {
+		reasons = append(reasons, r)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if rc.counts[reasons[i]] != rc.counts[reasons[j]] {
+			return rc.counts[reasons[i]] > rc.counts[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	var b strings.Builder
+	for _, r := range reasons {
+		fmt.Fprintf(&b, "  %s: %d\n", r, rc.counts[r])
+	}
+	return b.String()
+}
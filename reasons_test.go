@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestReasonCounts(t *testing.T) {
+	rc := newReasonCounts()
+	equals(t, rc.summary(), "")
+	rc.add("DateCreated", "Keywords or Description")
+	rc.add("Keywords or Description")
+	rc.add("check KSC IDs")
+	equals(t, rc.summary(), "  Keywords or Description: 2\n  DateCreated: 1\n  check KSC IDs: 1\n")
+
+	// Statistics made without one don't count.
+	var none *reasonCounts
+	none.add("DateCreated")
+	equals(t, none.summary(), "")
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// renameUsage is printed for chkmd rename -h.
+const renameUsage = `Usage: chkmd rename [-n] [-d dir] [-log rename.csv] results.csv
+       chkmd rename -undo rename.csv
+
+Renames the Accepted files in a chkmd results CSV to <NASA ID>.<ext>, with
+their sidecars, logging each rename so it can be undone.
+`
+
+// renamePlan is a file to rename, and its Sidecars, renamed with it.
+type renamePlan struct {
+	From, To string
+	Sidecars []renamePlan
+}
+
+// readResults reads a chkmd results CSV, returning its rows as maps of column
+// name to value.
+func readResults(r io.Reader) ([]map[string]string, error) {
+	rows, err := csv.NewReader(textReader(r)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	var results []map[string]string
+	for _, row := range rows[1:] {
+		m := map[string]string{}
+		for i, h := range rows[0] {
+			if i < len(row) {
+				m[h] = row[i]
+			}
+		}
+		results = append(results, m)
+	}
+	return results, nil
+}
+
+// renamePath returns the local file a results Path names: as it's written,
+// joined to dir if it's relative and dir is given, as -path-mode relative
+// writes it, or the file of a -path-mode uri file:// URI.
+func renamePath(p, dir string) (string, error) {
+	switch {
+	case isObject(p):
+		return "", errors.New("can't rename objects in cloud storage")
+	case isURL(p):
+		return "", errors.New("can't rename URLs")
+	case isArchiveMember(p):
+		return "", errors.New("can't rename files in archives")
+	case strings.HasPrefix(p, "file://"):
+		u, err := url.Parse(p)
+		if err != nil || u.Host != "" && u.Host != "localhost" {
+			return "", errors.New("not a local file:// URI")
+		}
+		path := u.Path
+		if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+			// A Windows drive, /C:/...
+			path = path[1:]
+		}
+		return filepath.FromSlash(path), nil
+	case dir != "" && !filepath.IsAbs(p):
+		return filepath.Join(dir, p), nil
+	}
+	return p, nil
+}
+
+// sidecarRenames returns the renames of the sidecars the file at p has for
+// its rename to to: its XMP sidecars, see sidecarPaths, and a 3D model's
+// JSON one.
+func sidecarRenames(p, to string, exists func(string) bool) []renamePlan {
+	from, next := sidecarPaths(p), sidecarPaths(to)
+	var renames []renamePlan
+	for _, source := range []string{fromExtSidecar, fromSidecar} {
+		renames = append(renames, renamePlan{From: from[source], To: next[source]})
+	}
+	if _, ok := modelFormats[strings.ToLower(filepath.Ext(p))]; ok {
+		renames = append(renames, renamePlan{From: modelSidecarPath(p), To: modelSidecarPath(to)})
+	}
+	var sidecars []renamePlan
+	for _, s := range renames {
+		if s.From != s.To && exists(s.From) {
+			sidecars = append(sidecars, s)
+		}
+	}
+	return sidecars
+}
+
+// planRenames works out the renames for the Accepted results, with their
+// sidecars, dir being where relative paths are, see renamePath. Files
+// already named for their ID are left alone. A rename is skipped, with a
+// problem reported, if the file can't be found or renamed, the ID can't be
+// a file name or the new name of the file or a sidecar is taken, either by
+// an existing file or another rename.
+func planRenames(results []map[string]string, dir string, exists func(string) bool) (plans []renamePlan, problems []string) {
+	taken := map[string]string{}
+	moved := map[string]string{}
+	for _, r := range results {
+		id := strings.TrimSpace(r["NASA ID"])
+		if !accepted(r["Status"]) || r["Path"] == "" {
+			continue
+		}
+		p, err := renamePath(r["Path"], dir)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %s", r["Path"], err))
+			continue
+		case id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == "..":
+			problems = append(problems, fmt.Sprintf("%s: NASA ID %q can't be a file name", p, id))
+			continue
+		case !exists(p):
+			problems = append(problems, fmt.Sprintf("%s: not found", p))
+			continue
+		}
+		to := filepath.Join(filepath.Dir(p), id+strings.ToLower(filepath.Ext(p)))
+		if to == p {
+			continue
+		}
+		pl := renamePlan{From: p, To: to}
+		problem := ""
+		for _, s := range append([]renamePlan{{From: p, To: to}}, sidecarRenames(p, to, exists)...) {
+			if next, ok := moved[s.From]; ok {
+				if next != s.To {
+					problem = fmt.Sprintf("%s: %s is already renamed to %s", p, s.From, next)
+					break
+				}
+				// A sidecar shared with a file renamed the same way.
+				continue
+			}
+			if other, ok := taken[s.To]; ok {
+				problem = fmt.Sprintf("%s: %s is also the new name for %s", p, s.To, other)
+				break
+			}
+			// Allow renaming only the case of the name on case-insensitive file systems.
+			if exists(s.To) && !strings.EqualFold(s.To, s.From) {
+				problem = fmt.Sprintf("%s: %s already exists", p, s.To)
+				break
+			}
+			if s.From != p {
+				pl.Sidecars = append(pl.Sidecars, s)
+			}
+		}
+		if problem != "" {
+			problems = append(problems, problem)
+			continue
+		}
+		for _, s := range append([]renamePlan{{From: p, To: to}}, pl.Sidecars...) {
+			taken[s.To], moved[s.From] = p, s.To
+		}
+		plans = append(plans, pl)
+	}
+	return plans, problems
+}
+
+// applyRenames renames the files and their sidecars, writing each one done
+// to the log so they can be undone. It returns how many files it renamed.
+func applyRenames(plans []renamePlan, logw *csv.Writer) (int, error) {
+	done := 0
+	for _, pl := range plans {
+		for _, r := range append([]renamePlan{{From: pl.From, To: pl.To}}, pl.Sidecars...) {
+			if err := os.Rename(r.From, r.To); err != nil {
+				return done, err
+			}
+			if logw != nil {
+				logw.Write([]string{r.From, r.To})
+				logw.Flush()
+				if err := logw.Error(); err != nil {
+					return done, err
+				}
+			}
+		}
+		done++
+	}
+	return done, nil
+}
+
+// undoRenames reverses the renames in the log, last first.
+func undoRenames(r io.Reader) (int, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return 0, err
+	}
+	done := 0
+	for i := len(rows) - 1; i >= 0; i-- {
+		if len(rows[i]) != 2 || rows[i][0] == "From" {
+			continue
+		}
+		from, to := rows[i][0], rows[i][1]
+		if _, err := os.Stat(from); err == nil && !strings.EqualFold(from, to) {
+			return done, fmt.Errorf("can't undo %s, %s exists again", to, from)
+		}
+		if err := os.Rename(to, from); err != nil {
+			return done, err
+		}
+		done++
+	}
+	return done, nil
+}
+
+// renameCommand is chkmd rename, returning the exit status.
+func renameCommand(args []string) int {
+	fs := flag.NewFlagSet("rename", flag.ExitOnError)
+	dryRun := fs.Bool("n", false, "Print the renames without doing them.")
+	dir := fs.String("d", "", "The directory relative paths in the results are in, the -d of a -path-mode relative run.")
+	logPath := fs.String("log", "rename.csv", "The file to log renames to, for -undo.")
+	undo := fs.String("undo", "", "Undo the renames logged in this file.")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, renameUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *undo != "" {
+		f, err := os.Open(*undo)
+		if err != nil {
+			log.Printf("Error opening %s: %s", *undo, err)
+			return 1
+		}
+		defer f.Close()
+		n, err := undoRenames(f)
+		log.Printf("Undid %d renames", n)
+		if err != nil {
+			log.Printf("Error undoing renames: %s", err)
+			return 1
+		}
+		return 0
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		log.Printf("Error opening %s: %s", fs.Arg(0), err)
+		return 1
+	}
+	results, err := readResults(f)
+	f.Close()
+	if err != nil {
+		log.Printf("Error reading %s: %s", fs.Arg(0), err)
+		return 1
+	}
+	plans, problems := planRenames(results, *dir, func(p string) bool {
+		_, err := os.Stat(p)
+		return err == nil
+	})
+	for _, p := range problems {
+		log.Printf("Skipping %s", p)
+	}
+	if *dryRun {
+		for _, pl := range plans {
+			fmt.Printf("%s -> %s\n", pl.From, pl.To)
+			for _, s := range pl.Sidecars {
+				fmt.Printf("  %s -> %s\n", s.From, s.To)
+			}
+		}
+		log.Printf("Would rename %d files, skipping %d", len(plans), len(problems))
+		return 0
+	}
+
+	lf, err := os.OpenFile(*logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		log.Printf("Error opening log %s: %s", *logPath, err)
+		return 1
+	}
+	defer lf.Close()
+	n, err := applyRenames(plans, csv.NewWriter(lf))
+	log.Printf("Renamed %d files, skipping %d; undo with chkmd rename -undo %s", n, len(problems), *logPath)
+	if err != nil {
+		log.Printf("Error renaming: %s", err)
+		return 1
+	}
+	return 0
+}
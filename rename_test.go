@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadResults(t *testing.T) {
+	got, err := readResults(strings.NewReader("Path,Status,NASA ID\n/a/1.jpg,Accepted,KSC-1\n"))
+	equals(t, err, nil)
+	equals(t, got, []map[string]string{{"Path": "/a/1.jpg", "Status": "Accepted", "NASA ID": "KSC-1"}})
+}
+
+func TestPlanRenames(t *testing.T) {
+	result := func(p, status, id string) map[string]string {
+		return map[string]string{"Path": p, "Status": status, "NASA ID": id}
+	}
+	results := []map[string]string{
+		result("/a/IMG_1.JPG", "Accepted", "KSC-1"),
+		result("/a/IMG_2.jpg", "Incomplete", "KSC-2"),
+		result("/a/KSC-3.jpg", "Accepted", "KSC-3"),
+		result("/a/IMG_4.jpg", "Accepted", "KSC-4"),
+		result("/a/IMG_5.jpg", "Accepted", "KSC-1"),
+		result("/a/IMG_6.jpg", "Accepted", "../x"),
+		result("s3://b/IMG_7.jpg", "Accepted", "KSC-7"),
+		result("/a/b.zip!/IMG_8.jpg", "Accepted", "KSC-8"),
+		result("/a/IMG_9.jpg", "Accepted", "KSC-9"),
+		result("https://example.com/IMG_10.jpg", "Accepted", "KSC-10"),
+	}
+	files := map[string]bool{
+		"/a/IMG_1.JPG": true, "/a/KSC-3.jpg": true, "/a/IMG_4.jpg": true,
+		"/a/IMG_5.jpg": true, "/a/IMG_6.jpg": true, "/a/KSC-4.jpg": true,
+	}
+	exists := func(p string) bool { return files[p] }
+	plans, problems := planRenames(results, "", exists)
+	equals(t, plans, []renamePlan{{From: "/a/IMG_1.JPG", To: "/a/KSC-1.jpg"}})
+	equals(t, problems, []string{
+		"/a/IMG_4.jpg: /a/KSC-4.jpg already exists",
+		"/a/IMG_5.jpg: /a/KSC-1.jpg is also the new name for /a/IMG_1.JPG",
+		`/a/IMG_6.jpg: NASA ID "../x" can't be a file name`,
+		"s3://b/IMG_7.jpg: can't rename objects in cloud storage",
+		"/a/b.zip!/IMG_8.jpg: can't rename files in archives",
+		"/a/IMG_9.jpg: not found",
+		"https://example.com/IMG_10.jpg: can't rename URLs",
+	})
+}
+
+func TestPlanRenamesSidecars(t *testing.T) {
+	result := func(p, id string) map[string]string {
+		return map[string]string{"Path": p, "Status": "Accepted", "NASA ID": id}
+	}
+	files := map[string]bool{
+		"/a/IMG_1.jpg": true, "/a/IMG_1.jpg.xmp": true, "/a/IMG_1.xmp": true,
+		"/a/IMG_1.cr2": true,
+		"/a/rover.glb": true, "/a/rover.json": true,
+		"/a/IMG_2.jpg": true, "/a/IMG_2.xmp": true, "/a/KSC-2.xmp": true,
+	}
+	exists := func(p string) bool { return files[p] }
+	plans, problems := planRenames([]map[string]string{
+		result("/a/IMG_1.jpg", "KSC-1"),
+		// IMG_1.xmp is its sidecar too, and already goes to KSC-1.xmp.
+		result("/a/IMG_1.cr2", "KSC-1"),
+		result("/a/rover.glb", "JPL-1"),
+		result("/a/IMG_2.jpg", "KSC-2"),
+	}, "", exists)
+	equals(t, plans, []renamePlan{
+		{"/a/IMG_1.jpg", "/a/KSC-1.jpg", []renamePlan{
+			{From: "/a/IMG_1.jpg.xmp", To: "/a/KSC-1.jpg.xmp"},
+			{From: "/a/IMG_1.xmp", To: "/a/KSC-1.xmp"},
+		}},
+		{From: "/a/IMG_1.cr2", To: "/a/KSC-1.cr2"},
+		{"/a/rover.glb", "/a/JPL-1.glb", []renamePlan{{From: "/a/rover.json", To: "/a/JPL-1.json"}}},
+	})
+	equals(t, problems, []string{"/a/IMG_2.jpg: /a/KSC-2.xmp already exists"})
+
+	// A shared sidecar can't go two ways.
+	_, problems = planRenames([]map[string]string{
+		result("/a/IMG_1.jpg", "KSC-1"),
+		result("/a/IMG_1.cr2", "KSC-5"),
+	}, "", exists)
+	equals(t, problems, []string{"/a/IMG_1.cr2: /a/IMG_1.xmp is already renamed to /a/KSC-1.xmp"})
+}
+
+func TestRenamePath(t *testing.T) {
+	values := []struct {
+		p, dir, want string
+	}{
+		{"/a/IMG_1.jpg", "", "/a/IMG_1.jpg"},
+		{"/a/IMG_1.jpg", "/b", "/a/IMG_1.jpg"},
+		{"ksc/IMG_1.jpg", "", "ksc/IMG_1.jpg"},
+		{"ksc/IMG_1.jpg", "/media", "/media/ksc/IMG_1.jpg"},
+		{"file:///media/ksc/IMG%201.jpg", "", "/media/ksc/IMG 1.jpg"},
+	}
+	for _, v := range values {
+		p, err := renamePath(v.p, v.dir)
+		equals(t, err, nil)
+		equals(t, p, filepath.FromSlash(v.want))
+	}
+	_, err := renamePath("file://host/media/IMG_1.jpg", "")
+	equals(t, err.Error(), "not a local file:// URI")
+}
+
+func TestRenameAndUndo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	from := filepath.Join(dir, "IMG_1.jpg")
+	to := filepath.Join(dir, "KSC-1.jpg")
+	ioutil.WriteFile(from, []byte("jpeg"), 0644)
+	ioutil.WriteFile(from+".xmp", []byte("xmp"), 0644)
+
+	var buf bytes.Buffer
+	sidecar := renamePlan{From: from + ".xmp", To: to + ".xmp"}
+	n, err := applyRenames([]renamePlan{{from, to, []renamePlan{sidecar}}}, csv.NewWriter(&buf))
+	equals(t, err, nil)
+	equals(t, n, 1)
+	_, err = os.Stat(to)
+	equals(t, err, nil)
+	_, err = os.Stat(to + ".xmp")
+	equals(t, err, nil)
+
+	n, err = undoRenames(&buf)
+	equals(t, err, nil)
+	equals(t, n, 2)
+	_, err = os.Stat(from)
+	equals(t, err, nil)
+	_, err = os.Stat(from + ".xmp")
+	equals(t, err, nil)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fixtureExt is added to a file's name for its recorded exiftool output.
+const fixtureExt = ".exiftool"
+
+// fixturePath returns where the exiftool output for the file at p is
+// recorded in dir. Fixtures are keyed on the file's name alone, so a
+// directory of them can be replayed wherever the files are checked out.
+func fixturePath(dir, p string) string {
+	return filepath.Join(dir, filepath.Base(p)+fixtureExt)
+}
+
+// recordExtract returns an extract that runs exiftool on each file, writing
+// its output to dir for replayExtract. exiftool is run with the options args.
+// Files exiftool fails on aren't recorded, so they fail when replayed too.
+func recordExtract(dir string, args []string, timeout time.Duration) func(string) (exif, error) {
+	return func(p string) (exif, error) {
+		out, err := runExiftool(p, args, timeout)
+		if err != nil {
+			return newExif(), err
+		}
+		if err = ioutil.WriteFile(fixturePath(dir, p), []byte(out), 0644); err != nil {
+			return newExif(), err
+		}
+		return parseExifOutput(out)
+	}
+}
+
+// replayExtract returns an extract that reads each file's exiftool output
+// from the fixtures in dir, instead of running exiftool, so the whole check
+// can be run where exiftool isn't installed, as in CI.
+func replayExtract(dir string) func(string) (exif, error) {
+	return func(p string) (exif, error) {
+		out, err := ioutil.ReadFile(fixturePath(dir, p))
+		if os.IsNotExist(err) {
+			return newExif(), fmt.Errorf("no recorded exiftool output for %s", filepath.Base(p))
+		}
+		if err != nil {
+			return newExif(), err
+		}
+		return parseExifOutput(string(out))
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// exiftoolLine formats a tag as `exiftool -G -s` prints it.
+func exiftoolLine(group, tag, v string) string {
+	return fmt.Sprintf("%-15s %-32s: %s\n", group, tag, v)
+}
+
+func TestReplayExtract(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	out := exiftoolLine("[File]", "FileType", "JPEG") + exiftoolLine("[IPTC]", "ObjectName", "A Title")
+	equals(t, ioutil.WriteFile(filepath.Join(dir, "a.jpg.exiftool"), []byte(out), 0644), nil)
+
+	extract := replayExtract(dir)
+	e, err := extract("/media/ksc/a.jpg")
+	equals(t, err, nil)
+	want, err := parseExifOutput(out)
+	equals(t, err, nil)
+	equals(t, e, want)
+	_, err = extract("/media/ksc/b.jpg")
+	equals(t, err.Error(), "no recorded exiftool output for b.jpg")
+}
+
+// TestReplayRun checks files end to end with recorded exiftool output, so it
+// runs without exiftool.
+func TestReplayRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	media, fixtures := filepath.Join(dir, "media"), filepath.Join(dir, "fixtures")
+	equals(t, os.Mkdir(media, 0755), nil)
+	equals(t, os.Mkdir(fixtures, 0755), nil)
+	for _, name := range []string{"KSC-1.jpg", "KSC-2.jpg", "KSC-3.jpg"} {
+		equals(t, ioutil.WriteFile(filepath.Join(media, name), nil, 0644), nil)
+	}
+	jpeg := exiftoolLine("[File]", "FileType", "JPEG") + exiftoolLine("[File]", "MIMEType", "image/jpeg")
+	fixture := map[string]string{
+		"KSC-1.jpg": jpeg + exiftoolLine("[IPTC]", "Keywords", "launch") +
+			exiftoolLine("[IPTC]", "DateCreated", "2015:05:26") + exiftoolLine("[IPTC]", "TimeCreated", "10:00:00+00:00"),
+		"KSC-2.jpg": jpeg,
+	}
+	for name, out := range fixture {
+		equals(t, ioutil.WriteFile(filepath.Join(fixtures, name+fixtureExt), []byte(out), 0644), nil)
+	}
+	output := filepath.Join(dir, "out.csv")
+
+	equals(t, run([]string{"-d", media, "-replay", fixtures, "-o", output, "-p", "1"}), 0)
+	f, err := os.Open(output)
+	equals(t, err, nil)
+	defer f.Close()
+	rows, err := readResults(f)
+	equals(t, err, nil)
+	status := map[string]string{}
+	for _, row := range rows {
+		status[filepath.Base(row["Path"])] = row["Status"] + ": " + row["Reason"]
+	}
+	equals(t, status, map[string]string{
+		"KSC-1.jpg": statusWarned + ": ",
+		"KSC-2.jpg": "Incomplete: Missing: DateCreated, Keywords or Description",
+		"KSC-3.jpg": "Rejected: no recorded exiftool output for KSC-3.jpg",
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// journal is a rowWriter recording the path of each row written to the
+// output, so an interrupted run can be resumed with -resume. It goes after
+// the output in a multiWriter, flushing it before recording the path, so a
+// path is only journaled once its row is in the output file.
+type journal struct {
+	f     *os.File
+	done  map[string]bool
+	flush func() error
+}
+
+// openJournal reads the paths already processed from the journal at p, if
+// there is one, and opens it to append more.
+func openJournal(p string) (*journal, error) {
+	j := &journal{done: map[string]bool{}}
+	f, err := os.Open(p)
+	if err == nil {
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			if line := strings.TrimSpace(s.Text()); line != "" {
+				j.done[line] = true
+			}
+		}
+		err = s.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	j.f, err = os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// resuming is whether there are already processed paths to skip.
+func (j *journal) resuming() bool {
+	return j != nil && len(j.done) > 0
+}
+
+// skip is whether p was processed by an earlier run.
+func (j *journal) skip(p string) bool {
+	return j != nil && j.done[p]
+}
+
+// Write records the row's path once the output has been flushed.
+func (j *journal) Write(row []string) error {
+	if j.flush != nil {
+		if err := j.flush(); err != nil {
+			return err
+		}
+	}
+	_, err := j.f.WriteString(row[0] + "\n")
+	return err
+}
+
+// Close closes the journal file.
+func (j *journal) Close() error {
+	return j.f.Close()
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "journal")
+
+	j, err := openJournal(p)
+	equals(t, err, nil)
+	equals(t, j.resuming(), false)
+	flushed := 0
+	j.flush = func() error { flushed++; return nil }
+	equals(t, j.Write(testRow("/media/a/1.jpg", "Accepted", "")), nil)
+	equals(t, j.Write(testRow("/media/a/2.jpg", "Incomplete", "")), nil)
+	equals(t, flushed, 2)
+	equals(t, j.Close(), nil)
+
+	j, err = openJournal(p)
+	equals(t, err, nil)
+	defer j.Close()
+	equals(t, j.resuming(), true)
+	equals(t, j.skip("/media/a/1.jpg"), true)
+	equals(t, j.skip("/media/a/3.jpg"), false)
+
+	var none *journal
+	equals(t, none.resuming(), false)
+	equals(t, none.skip("/media/a/1.jpg"), false)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// transientErrors are, lowercased, what the errors of reads that may well
+// work if tried again say: I/O errors and stale handles on network
+// filesystems, and exiftool failing to open or read the file because of
+// them.
+var transientErrors = []string{
+	"input/output error",
+	"stale file handle",
+	"stale nfs file handle",
+	"resource temporarily unavailable",
+	"error opening file",
+	"error reading file",
+}
+
+// transient is whether err, from extracting a file, is worth trying again,
+// rather than about the file itself, like it not being there or its
+// metadata being broken. exiftool dying on a file is, as it's restarted.
+// Taking longer than -timeout isn't, as it would likely do so again.
+func transient(err error) bool {
+	if err == nil || err == errTimeout {
+		return false
+	}
+	if err == errExited {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	if pe, ok := err.(*os.PathError); ok {
+		err = pe.Err
+	}
+	if errno, ok := err.(syscall.Errno); ok {
+		return errno == syscall.EIO || errno == syscall.EAGAIN || errno == syscall.ESTALE
+	}
+	for _, t := range transientErrors {
+		if strings.Contains(msg, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryExtract wraps extract so transient errors are tried again, up to
+// pc's Retries times, waiting RetryBackoffMS, then twice as long each time.
+// Each file that needed trying again is counted in retried.
+func retryExtract(pc poolConfig, retried *int32, extract func(string) (exif, error)) func(string) (exif, error) {
+	if pc.Retries <= 0 {
+		return extract
+	}
+	return func(p string) (exif, error) {
+		e, err := extract(p)
+		if !transient(err) {
+			return e, err
+		}
+		atomic.AddInt32(retried, 1)
+		var backoff time.Duration
+		if pc.RetryBackoffMS > 0 {
+			backoff = time.Duration(pc.RetryBackoffMS) * time.Millisecond
+		}
+		for i := 0; i < pc.Retries && transient(err); i++ {
+			log.Printf("Retrying %s in %s: %s\n", displayPath(p), backoff, err)
+			if backoff > 0 {
+				time.Sleep(backoff)
+			}
+			backoff *= 2
+			e, err = extract(p)
+		}
+		return e, err
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestTransient(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errTimeout, false},
+		{errExited, true},
+		{&os.PathError{Op: "open", Path: "a.jpg", Err: syscall.EIO}, true},
+		{&os.PathError{Op: "open", Path: "a.jpg", Err: syscall.ESTALE}, true},
+		{&os.PathError{Op: "open", Path: "a.jpg", Err: syscall.ENOENT}, false},
+		{errors.New("Error: Error opening file - /nfs/a.jpg"), true},
+		{errors.New("read /nfs/a.jpg: input/output error"), true},
+		{errors.New("Error: File not found - /nfs/a.jpg"), false},
+		{errors.New("Error: File format error - /nfs/a.jpg"), false},
+	} {
+		equals(t, transient(tc.err), tc.want)
+	}
+}
+
+func TestRetryExtract(t *testing.T) {
+	var calls int
+	var retried int32
+	fail := func(times int, err error) func(string) (exif, error) {
+		calls = 0
+		return func(p string) (exif, error) {
+			calls++
+			if calls <= times {
+				return newExif(), err
+			}
+			return newExif(), nil
+		}
+	}
+	pc := poolConfig{Retries: 2, RetryBackoffMS: -1}
+
+	_, err := retryExtract(pc, &retried, fail(2, errExited))("a.jpg")
+	equals(t, err, nil)
+	equals(t, calls, 3)
+	_, err = retryExtract(pc, &retried, fail(3, errExited))("a.jpg")
+	equals(t, err, errExited)
+	equals(t, calls, 3)
+	equals(t, retried, int32(2))
+
+	// Permanent errors aren't retried.
+	_, err = retryExtract(pc, &retried, fail(1, errTimeout))("a.jpg")
+	equals(t, err, errTimeout)
+	equals(t, calls, 1)
+	_, err = retryExtract(poolConfig{Retries: -1}, &retried, fail(1, errExited))("a.jpg")
+	equals(t, err, errExited)
+	equals(t, calls, 1)
+	equals(t, retried, int32(2))
+}
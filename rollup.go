@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// The -rollup-by groupings.
+const (
+	rollupByDirectory = "directory"
+	rollupByCenter    = "center"
+)
+
+// rollupStatuses are the Statuses -rollup counts.
+var rollupStatuses = []string{"Accepted", statusWarned, "Rejected", "Incomplete", statusBelowQuality}
+
+// rollupColumns are the columns whose completeness -rollup reports.
+var rollupColumns = []string{
+	"NASA ID",
+	"Title",
+	"508 Description",
+	"Description",
+	"Date Created",
+	"Location",
+	"Keywords",
+	"Center",
+	"Secondary Creator Credit",
+	"Photographer",
+	"Album",
+}
+
+// rollupGroup is the counts of one group of files.
+type rollupGroup struct {
+	files    int
+	statuses map[string]int
+	// filled counts the files with each of the rollupColumns.
+	filled map[string]int
+}
+
+// rollup is a rowWriter counting the files of each top level directory, or
+// Center, by Status and by which fields they have, so each center's team can
+// be told how their deliveries score.
+type rollup struct {
+	by     string
+	roots  dirList
+	groups map[string]*rollupGroup
+}
+
+// newRollup returns an empty rollup grouping by by, for the trees at roots.
+func newRollup(by string, roots dirList) (*rollup, error) {
+	switch by {
+	case "", rollupByDirectory:
+		by = rollupByDirectory
+	case rollupByCenter:
+	default:
+		return nil, fmt.Errorf("-rollup-by is %q, expected %s or %s", by, rollupByDirectory, rollupByCenter)
+	}
+	return &rollup{by: by, roots: roots, groups: map[string]*rollupGroup{}}, nil
+}
+
+// group returns the group of the row: its Center, or the directory under
+// its root it's in, with the root's name for files directly in it.
+func (ru *rollup) group(row []string) string {
+	if ru.by == rollupByCenter {
+		if c := row[column("Center")]; c != "" {
+			return c
+		}
+		return "none"
+	}
+	p := row[column("Path")]
+	root := ru.roots.rootOf(p)
+	name := deliveryOf(root, p)
+	if name == "." {
+		return filepath.Base(root)
+	}
+	if len(ru.roots) > 1 {
+		return filepath.Base(root) + "/" + name
+	}
+	return name
+}
+
+// Write counts the row.
+func (ru *rollup) Write(row []string) error {
+	name := ru.group(row)
+	g := ru.groups[name]
+	if g == nil {
+		g = &rollupGroup{statuses: map[string]int{}, filled: map[string]int{}}
+		ru.groups[name] = g
+	}
+	g.files++
+	g.statuses[row[column("Status")]]++
+	for _, c := range rollupColumns {
+		if row[column(c)] != "" {
+			g.filled[c]++
+		}
+	}
+	return nil
+}
+
+// header returns the rollup's columns.
+func (ru *rollup) header() []string {
+	group := "Directory"
+	if ru.by == rollupByCenter {
+		group = "Center"
+	}
+	h := []string{group, "Files"}
+	h = append(h, rollupStatuses...)
+	h = append(h, "Accepted %")
+	for _, c := range rollupColumns {
+		h = append(h, c+" %")
+	}
+	return h
+}
+
+// rows returns a row per group, in order of name.
+func (ru *rollup) rows() [][]string {
+	var names []string
+	for name := range ru.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	percent := func(n, of int) string {
+		return fmt.Sprintf("%.1f", 100*float64(n)/float64(of))
+	}
+	var rows [][]string
+	for _, name := range names {
+		g := ru.groups[name]
+		row := []string{name, fmt.Sprint(g.files)}
+		for _, status := range rollupStatuses {
+			row = append(row, fmt.Sprint(g.statuses[status]))
+		}
+		row = append(row, percent(g.statuses["Accepted"]+g.statuses[statusWarned], g.files))
+		for _, c := range rollupColumns {
+			row = append(row, percent(g.filled[c], g.files))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// writeRollup writes the rollup to a CSV at p.
+func writeRollup(p string, ru *rollup) error {
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	err = w.Write(ru.header())
+	for _, row := range ru.rows() {
+		if err == nil {
+			err = w.Write(row)
+		}
+	}
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRollup(t *testing.T) {
+	ru, err := newRollup("", dirList{"/media"})
+	equals(t, err, nil)
+	row := testRow("/media/ksc/1.jpg", "Accepted", "")
+	row[column("Title")], row[column("Center")] = "Launch", "KSC"
+	ru.Write(row)
+	ru.Write(testRow("/media/ksc/2.jpg", "Incomplete", "Minimum metadata not provided"))
+	row = testRow("/media/jsc/3.jpg", "Rejected", "exiftool: timed out")
+	ru.Write(row)
+	ru.Write(testRow("/media/4.jpg", "Accepted", ""))
+	ru.Write(testRow("/media/ksc/5.jpg", statusWarned, ""))
+	equals(t, ru.header()[:9], []string{"Directory", "Files", "Accepted", statusWarned, "Rejected", "Incomplete", statusBelowQuality, "Accepted %", "NASA ID %"})
+	rows := ru.rows()
+	equals(t, len(rows), 3)
+	equals(t, rows[0][:8], []string{"jsc", "1", "0", "0", "1", "0", "0", "0.0"})
+	equals(t, rows[1][:8], []string{"ksc", "3", "1", "1", "0", "1", "0", "66.7"})
+	equals(t, rows[1][8+indexOf(rollupColumns, "Title")], "33.3")
+	equals(t, rows[2][:3], []string{"media", "1", "1"})
+
+	ru, err = newRollup(rollupByCenter, dirList{"/media"})
+	equals(t, err, nil)
+	ru.Write(row)
+	row = testRow("/media/ksc/1.jpg", "Accepted", "")
+	row[column("Center")] = "KSC"
+	ru.Write(row)
+	equals(t, ru.header()[0], "Center")
+	rows = ru.rows()
+	equals(t, []string{rows[0][0], rows[1][0]}, []string{"KSC", "none"})
+	equals(t, rows[0][8+indexOf(rollupColumns, "Center")], "100.0")
+
+	_, err = newRollup("photographer", nil)
+	equals(t, err.Error(), `-rollup-by is "photographer", expected directory or center`)
+}
+
+func TestWriteRollup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	ru, _ := newRollup(rollupByDirectory, dirList{"/media", "/more"})
+	ru.Write(testRow("/more/ksc/1.jpg", "Accepted", ""))
+	p := filepath.Join(dir, "rollup.csv")
+	equals(t, writeRollup(p, ru), nil)
+	b, err := ioutil.ReadFile(p)
+	equals(t, err, nil)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	equals(t, len(lines), 2)
+	equals(t, strings.HasPrefix(lines[1], "more/ksc,1,1,0,0,0,0,100.0,0.0"), true)
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// How to romanize Location, for romanize_location in the config. Mixed
+// scripts break faceting on the site.
+const (
+	// romanizeReplace romanizes the Location column.
+	romanizeReplace = "replace"
+	// romanizeColumn keeps Location as it is and adds romanizedColumn.
+	romanizeColumn = "column"
+)
+
+// romanizedColumn is the romanized Location, only in the CSV output with
+// romanize_location: column or when the config's columns list it.
+const romanizedColumn = "Romanized Location"
+
+// validRomanize returns an error if policy isn't one we know.
+func validRomanize(policy string) error {
+	switch policy {
+	case "", romanizeReplace, romanizeColumn:
+		return nil
+	}
+	return fmt.Errorf("romanize_location is %q, expected %s or %s", policy, romanizeReplace, romanizeColumn)
+}
+
+// romanizations maps each letter in from to to. Accented Latin letters lose
+// their accents, and Greek and Cyrillic are transliterated, simply, letter by
+// letter.
+var romanizations = []struct {
+	from string
+	to   string
+}{
+	{"ÀÁÂÃÄÅĀĂĄ", "A"}, {"àáâãäåāăą", "a"}, {"ÇĆĈĊČ", "C"}, {"çćĉċč", "c"},
+	{"ÐĎĐ", "D"}, {"ðďđ", "d"}, {"ÈÉÊËĒĔĖĘĚ", "E"}, {"èéêëēĕėęě", "e"},
+	{"ĜĞĠĢ", "G"}, {"ĝğġģ", "g"}, {"ĤĦ", "H"}, {"ĥħ", "h"},
+	{"ÌÍÎÏĨĪĬĮİ", "I"}, {"ìíîïĩīĭįı", "i"}, {"Ĵ", "J"}, {"ĵ", "j"},
+	{"Ķ", "K"}, {"ķ", "k"}, {"ĹĻĽĿŁ", "L"}, {"ĺļľŀł", "l"},
+	{"ÑŃŅŇ", "N"}, {"ñńņň", "n"}, {"ÒÓÔÕÖØŌŎŐ", "O"}, {"òóôõöøōŏő", "o"},
+	{"ŔŖŘ", "R"}, {"ŕŗř", "r"}, {"ŚŜŞŠȘ", "S"}, {"śŝşšș", "s"},
+	{"ŢŤŦȚ", "T"}, {"ţťŧț", "t"}, {"ÙÚÛÜŨŪŬŮŰŲ", "U"}, {"ùúûüũūŭůűų", "u"},
+	{"Ŵ", "W"}, {"ŵ", "w"}, {"ÝŶŸ", "Y"}, {"ýÿŷ", "y"}, {"ŹŻŽ", "Z"}, {"źżž", "z"},
+	{"Æ", "AE"}, {"æ", "ae"}, {"Œ", "OE"}, {"œ", "oe"}, {"Þ", "Th"}, {"þ", "th"},
+	{"ß", "ss"},
+	// Greek.
+	{"ΑΆ", "A"}, {"αά", "a"}, {"Β", "V"}, {"β", "v"}, {"Γ", "G"}, {"γ", "g"},
+	{"Δ", "D"}, {"δ", "d"}, {"ΕΈ", "E"}, {"εέ", "e"}, {"Ζ", "Z"}, {"ζ", "z"},
+	{"ΗΉΙΊΪ", "I"}, {"ηήιίϊΐ", "i"}, {"Θ", "Th"}, {"θ", "th"}, {"Κ", "K"}, {"κ", "k"},
+	{"Λ", "L"}, {"λ", "l"}, {"Μ", "M"}, {"μ", "m"}, {"Ν", "N"}, {"ν", "n"},
+	{"Ξ", "X"}, {"ξ", "x"}, {"ΟΌΩΏ", "O"}, {"οόωώ", "o"}, {"Π", "P"}, {"π", "p"},
+	{"Ρ", "R"}, {"ρ", "r"}, {"Σ", "S"}, {"σς", "s"}, {"Τ", "T"}, {"τ", "t"},
+	{"ΥΎΫ", "Y"}, {"υύϋΰ", "y"}, {"Φ", "F"}, {"φ", "f"}, {"Χ", "Ch"}, {"χ", "ch"},
+	{"Ψ", "Ps"}, {"ψ", "ps"},
+	// Cyrillic.
+	{"А", "A"}, {"а", "a"}, {"Б", "B"}, {"б", "b"}, {"В", "V"}, {"в", "v"},
+	{"ГҐ", "G"}, {"гґ", "g"}, {"Д", "D"}, {"д", "d"}, {"ЕЭ", "E"}, {"еэ", "e"},
+	{"Ё", "Yo"}, {"ё", "yo"}, {"Є", "Ye"}, {"є", "ye"}, {"Ж", "Zh"}, {"ж", "zh"},
+	{"З", "Z"}, {"з", "z"}, {"ИІ", "I"}, {"иі", "i"}, {"Ї", "Yi"}, {"ї", "yi"},
+	{"ЙЫ", "Y"}, {"йы", "y"}, {"К", "K"}, {"к", "k"}, {"Л", "L"}, {"л", "l"},
+	{"М", "M"}, {"м", "m"}, {"Н", "N"}, {"н", "n"}, {"О", "O"}, {"о", "o"},
+	{"П", "P"}, {"п", "p"}, {"Р", "R"}, {"р", "r"}, {"С", "S"}, {"с", "s"},
+	{"Т", "T"}, {"т", "t"}, {"У", "U"}, {"у", "u"}, {"Ф", "F"}, {"ф", "f"},
+	{"Х", "Kh"}, {"х", "kh"}, {"Ц", "Ts"}, {"ц", "ts"}, {"Ч", "Ch"}, {"ч", "ch"},
+	{"Ш", "Sh"}, {"ш", "sh"}, {"Щ", "Shch"}, {"щ", "shch"}, {"ЪЬъь", ""},
+	{"Ю", "Yu"}, {"ю", "yu"}, {"Я", "Ya"}, {"я", "ya"},
+}
+
+// romanizer replaces the letters in romanizations.
+var romanizer = func() *strings.Replacer {
+	var pairs []string
+	for _, r := range romanizations {
+		for _, c := range r.from {
+			pairs = append(pairs, string(c), r.to)
+		}
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+// romanize returns s in Latin letters, as far as romanizations go. Other
+// scripts, like Chinese or Arabic, are left as they are.
+func romanize(s string) string {
+	return romanizer.Replace(s)
+}
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestRomanize(t *testing.T) {
+	values := []struct {
+		in, want string
+	}{
+		{"Houston, Texas", "Houston, Texas"},
+		{"Bogotá, Colombia", "Bogota, Colombia"},
+		{"Köln, Nordrhein-Westfalen, Deutschland", "Koln, Nordrhein-Westfalen, Deutschland"},
+		{"Байконур, Казахстан", "Baykonur, Kazakhstan"},
+		{"Звёздный городок", "Zvyozdnyy gorodok"},
+		{"Αθήνα, Ελλάδα", "Athina, Ellada"},
+		// Scripts we can't romanize letter by letter are left alone.
+		{"種子島, Japan", "種子島, Japan"},
+	}
+	for _, v := range values {
+		equals(t, romanize(v.in), v.want)
+	}
+}
+
+func TestRomanizeLocation(t *testing.T) {
+	for _, policy := range []string{"", romanizeReplace, romanizeColumn} {
+		equals(t, validRomanize(policy), nil)
+	}
+	equals(t, validRomanize("both").Error(), `romanize_location is "both", expected replace or column`)
+
+	values := []struct {
+		policy, location, romanized string
+	}{
+		{"", "Байконур, Казахстан", "Baykonur, Kazakhstan"},
+		{romanizeColumn, "Байконур, Казахстан", "Baykonur, Kazakhstan"},
+		{romanizeReplace, "Baykonur, Kazakhstan", "Baykonur, Kazakhstan"},
+	}
+	for _, v := range values {
+		e := newExif()
+		e.Data["FileName"] = "a.jpg"
+		e.IPTC["City"] = "Байконур"
+		e.IPTC["Country-PrimaryLocationName"] = "Казахстан"
+		e.romanize = v.policy
+		rows := make(chan []string, 1)
+		e.MakeRow(rows, "a.jpg", "Accepted", "", "")
+		row := <-rows
+		equals(t, row[column("Location")], v.location)
+		equals(t, row[column(romanizedColumn)], v.romanized)
+	}
+}
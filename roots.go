@@ -0,0 +1,61 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// dirList is the directories, or object store prefixes, to check, as a
+// flag.Value for -d that may be repeated or given a comma separated list, so
+// one run can cover several mount points. Windows extended-length paths are
+// made ordinary ones, see plainPath.
+type dirList []string
+
+func (dl *dirList) Set(s string) error {
+	for _, d := range strings.Split(s, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			*dl = append(*dl, plainPath(d))
+		}
+	}
+	return nil
+}
+
+func (dl dirList) String() string {
+	return strings.Join(dl, ",")
+}
+
+// objects returns how many of the directories are object store prefixes.
+func (dl dirList) objects() int {
+	n := 0
+	for _, d := range dl {
+		if isObject(d) {
+			n++
+		}
+	}
+	return n
+}
+
+// rootOf returns the directory p is in, the deepest if they're nested, for
+// delivery folders and the like. If it's in none it's the first, if there
+// is one.
+func (dl dirList) rootOf(p string) string {
+	root := ""
+	for _, d := range dl {
+		if len(d) > len(root) && under(d, p) {
+			root = d
+		}
+	}
+	if root == "" && len(dl) > 0 {
+		return dl[0]
+	}
+	return root
+}
+
+// under is whether p is in the directory, or object store prefix, d.
+func under(d, p string) bool {
+	if isObject(d) {
+		return strings.HasPrefix(p, strings.TrimSuffix(d, "/")+"/")
+	}
+	rel, err := filepath.Rel(d, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
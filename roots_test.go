@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestDirList(t *testing.T) {
+	var dl dirList
+	equals(t, dl.Set("/mnt/a, /mnt/b"), nil)
+	equals(t, dl.Set("/mnt/a/c"), nil)
+	equals(t, dl, dirList{"/mnt/a", "/mnt/b", "/mnt/a/c"})
+	equals(t, dl.String(), "/mnt/a,/mnt/b,/mnt/a/c")
+	equals(t, dl.objects(), 0)
+	equals(t, dirList{"s3://b/x", "gs://b/y", "/mnt/a"}.objects(), 2)
+
+	for _, v := range []struct {
+		p    string
+		want string
+	}{
+		{"/mnt/a/1.jpg", "/mnt/a"},
+		{"/mnt/b/d/2.jpg", "/mnt/b"},
+		{"/mnt/a/c/3.jpg", "/mnt/a/c"},
+		{"/mnt/ab/4.jpg", "/mnt/a"},
+		{"/elsewhere/5.jpg", "/mnt/a"},
+	} {
+		equals(t, dl.rootOf(v.p), v.want)
+	}
+	equals(t, dirList{"s3://b/x", "s3://b/y/"}.rootOf("s3://b/y/1.jpg"), "s3://b/y/")
+	equals(t, dirList{}.rootOf("/mnt/a/1.jpg"), "")
+}
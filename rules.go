@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fieldChecks maps the field names usable in rules to their Has methods.
+var fieldChecks = map[string]func(exif) bool{
+	"NasaID":       exif.HasNasaID,
+	"Title":        exif.HasTitle,
+	"AltText":      exif.HasAltText,
+	"Description":  exif.HasDescription,
+	"DateCreated":  exif.HasDateCreated,
+	"Location":     exif.HasLocation,
+	"Keywords":     exif.HasKeywords,
+	"MediaType":    exif.HasMediaType,
+	"FileFormat":   exif.HasFileFormat,
+	"Photographer": exif.HasPhotographer,
+	"Center":       exif.HasCenter,
+	"Credit":       exif.HasCredit,
+	"Album":        exif.HasAlbum,
+	"Copyright":    exif.HasCopyright,
+	"UsageTerms":   exif.HasUsageTerms,
+}
+
+// Rule is a set of acceptance checks. Every Required field must be present
+// and at least one field of each AnyOf group. A file without a Warn field is
+// Accepted with warnings. It's exported as yaml.v1 skips unexported fields,
+// even inlined ones, so the top level of rules would never be read.
+type Rule struct {
+	Required []string   `yaml:"required"`
+	AnyOf    [][]string `yaml:"any_of"`
+	Warn     []string   `yaml:"warn"`
+}
+
+// defaultRule is what we accepted before rules were configurable.
+var defaultRule = Rule{
+	Required: []string{"DateCreated"},
+	AnyOf:    [][]string{{"Keywords", "Description"}},
+}
+
+// defaultWarn is what a file is Accepted with warnings without if the rules
+// don't say, curators' usual second look.
+var defaultWarn = []string{"Title", "Location"}
+
+// rules holds the acceptance rules from the config. The top level rule
+// applies to everything unless there is a rule for the file's media type,
+// which has the top level's warn unless it has its own, e.g.:
+//
+//	rules:
+//	  required: [DateCreated]
+//	  any_of:
+//	    - [Keywords, Description]
+//	  warn: [Title, Location]
+//	  media_types:
+//	    video:
+//	      required: [DateCreated, Description]
+type rules struct {
+	Rule       `yaml:",inline"`
+	MediaTypes map[string]Rule `yaml:"media_types"`
+}
+
+// ruleFor returns the rule for the media type. Its warn is defaultWarn if
+// none is set, and none with warn: [].
+func (rs rules) ruleFor(mediaType string) Rule {
+	r, ok := rs.MediaTypes[mediaType]
+	switch {
+	case ok:
+	case rs.Required == nil && rs.AnyOf == nil:
+		r = defaultRule
+	default:
+		r = rs.Rule
+	}
+	if r.Warn == nil {
+		r.Warn = rs.Warn
+	}
+	if r.Warn == nil {
+		r.Warn = defaultWarn
+	}
+	return r
+}
+
+// ruleOutcome is how one check of a rule came out: a required field, an
+// any_of group of them, or a field to warn without.
+type ruleOutcome struct {
+	Check  string   `json:"check"`
+	Fields []string `json:"fields"`
+	Passed bool     `json:"passed"`
+}
+
+// outcomes returns how each check of the rule for e's media type came out.
+func (rs rules) outcomes(e exif) []ruleOutcome {
+	r := rs.ruleFor(e.MediaType())
+	var outs []ruleOutcome
+	for _, f := range r.Required {
+		outs = append(outs, ruleOutcome{"required", []string{f}, fieldChecks[f](e)})
+	}
+	for _, group := range r.AnyOf {
+		ok := false
+		for _, f := range group {
+			if fieldChecks[f](e) {
+				ok = true
+				break
+			}
+		}
+		outs = append(outs, ruleOutcome{"any_of", group, ok})
+	}
+	for _, f := range r.Warn {
+		outs = append(outs, ruleOutcome{"warn", []string{f}, fieldChecks[f](e)})
+	}
+	return outs
+}
+
+// missing returns what e is missing to pass the rule for its media type: each
+// required field and any_of group, as e.g. "Keywords or Description", that
+// it doesn't have.
+func (rs rules) missing(e exif) []string {
+	var missing []string
+	for _, o := range rs.outcomes(e) {
+		if !o.Passed && o.Check != "warn" {
+			missing = append(missing, strings.Join(o.Fields, " or "))
+		}
+	}
+	return missing
+}
+
+// warnings returns the warn fields of the rule for e's media type that it
+// doesn't have.
+func (rs rules) warnings(e exif) []string {
+	var missing []string
+	for _, o := range rs.outcomes(e) {
+		if !o.Passed && o.Check == "warn" {
+			missing = append(missing, o.Fields...)
+		}
+	}
+	return missing
+}
+
+// accepts returns if e passes the rule for its media type, warnings aside.
+func (rs rules) accepts(e exif) bool {
+	return len(rs.missing(e)) == 0
+}
+
+// validate checks that the rules only name fields we can check.
+func (rs rules) validate() error {
+	all := map[string]Rule{"": rs.Rule}
+	for t, r := range rs.MediaTypes {
+		all[t] = r
+	}
+	for t, r := range all {
+		fields := append(append([]string{}, r.Required...), r.Warn...)
+		for _, group := range r.AnyOf {
+			fields = append(fields, group...)
+		}
+		for _, f := range fields {
+			if fieldChecks[f] == nil {
+				if t != "" {
+					return fmt.Errorf("unknown field %q in %s rule", f, t)
+				}
+				return fmt.Errorf("unknown field %q in rules", f)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+
+	yaml "gopkg.in/yaml.v1"
+)
+
+func TestRulesAccepts(t *testing.T) {
+	video := rules{
+		MediaTypes: map[string]Rule{
+			"video": {Required: []string{"DateCreated", "Description"}},
+			"audio": {Required: []string{"DateCreated"}},
+		},
+	}
+	values := []struct {
+		rs                  rules
+		mime, date, kw, dsc string
+		want                bool
+	}{
+		{rules{}, "image/jpeg", "2015:01:09", "moon", "", true},
+		{rules{}, "image/jpeg", "2015:01:09", "", "", false},
+		{rules{}, "image/jpeg", "", "moon", "A moon", false},
+		{rules{Rule: Rule{Required: []string{"Keywords"}}}, "image/jpeg", "", "moon", "", true},
+		{video, "video/mp4", "2015:01:09", "moon", "", false},
+		{video, "video/mp4", "2015:01:09", "", "A moon", true},
+		{video, "audio/mpeg", "2015:01:09", "", "", true},
+		{video, "image/jpeg", "2015:01:09", "", "", false},
+	}
+	readConfig("")
+	for _, v := range values {
+		e := newExif()
+		e.Data["MIMEType"] = v.mime
+		e.IPTC["DateCreated"] = v.date
+		e.IPTC["Keywords"] = v.kw
+		e.IPTC["Caption-Abstract"] = v.dsc
+		equals(t, v.rs.accepts(e), v.want)
+	}
+}
+
+func TestRulesValidate(t *testing.T) {
+	equals(t, rules{}.validate(), nil)
+	equals(t, rules{Rule: defaultRule}.validate(), nil)
+	err := rules{Rule: Rule{AnyOf: [][]string{{"Keywords", "Caption"}}}}.validate()
+	equals(t, err.Error(), `unknown field "Caption" in rules`)
+	err = rules{MediaTypes: map[string]Rule{"video": {Required: []string{"Duration"}}}}.validate()
+	equals(t, err.Error(), `unknown field "Duration" in video rule`)
+}
+
+func TestRulesOutcomes(t *testing.T) {
+	e := newExif()
+	e.Data["MIMEType"] = "image/jpeg"
+	e.IPTC["DateCreated"] = "2015:01:09"
+	equals(t, rules{}.outcomes(e), []ruleOutcome{
+		{"required", []string{"DateCreated"}, true},
+		{"any_of", []string{"Keywords", "Description"}, false},
+		{"warn", []string{"Title"}, false},
+		{"warn", []string{"Location"}, false},
+	})
+}
+
+func TestRulesMissing(t *testing.T) {
+	rs := rules{Rule: Rule{Required: []string{"NasaID", "DateCreated"}, AnyOf: [][]string{{"Keywords", "Description"}}}}
+	e := newExif()
+	e.Data["FileName"] = "KSC-1.jpg"
+	equals(t, rs.missing(e), []string{"DateCreated", "Keywords or Description"})
+	equals(t, missingReason(rs.missing(e)), "Missing: DateCreated, Keywords or Description")
+	e.IPTC["DateCreated"] = "2015:01:09"
+	e.IPTC["Keywords"] = "moon"
+	equals(t, rs.missing(e), []string(nil))
+}
+
+func TestRulesWarnings(t *testing.T) {
+	rs := rules{Rule: Rule{Warn: []string{"Title", "Location"}}}
+	equals(t, rs.validate(), nil)
+	equals(t, rules{Rule: Rule{Warn: []string{"Caption"}}}.validate().Error(), `unknown field "Caption" in rules`)
+
+	e := newExif()
+	e.Data["MIMEType"] = "image/jpeg"
+	e.IPTC["DateCreated"] = "2015:01:09"
+	e.IPTC["Keywords"] = "moon"
+	e.IPTC["City"] = "Houston"
+	// Warnings alone still use the default rule.
+	equals(t, rs.accepts(e), true)
+	equals(t, rs.missing(e), []string(nil))
+	equals(t, rs.warnings(e), []string{"Title"})
+	equals(t, rs.outcomes(e)[2:], []ruleOutcome{
+		{"warn", []string{"Title"}, false},
+		{"warn", []string{"Location"}, true},
+	})
+	delete(e.IPTC, "DateCreated")
+	equals(t, rs.accepts(e), false)
+	equals(t, rs.missing(e), []string{"DateCreated"})
+
+	// A media type's rule has the top level's warn, or the default, unless
+	// it has its own, and warn: [] warns of nothing.
+	for _, tc := range []struct {
+		yml  string
+		want []string
+	}{
+		{"rules:\n  media_types:\n    image:\n      required: [DateCreated]\n", defaultWarn},
+		{"rules:\n  warn: [Location]\n  media_types:\n    image:\n      required: [DateCreated]\n", []string{"Location"}},
+		{"rules:\n  warn: [Location]\n  media_types:\n    image:\n      warn: [Title]\n", []string{"Title"}},
+		{"rules:\n  warn: []\n", []string{}},
+	} {
+		var cfg config
+		equals(t, yaml.Unmarshal([]byte(tc.yml), &cfg), nil)
+		equals(t, []interface{}{tc.yml, cfg.Rules.ruleFor("image").Warn}, []interface{}{tc.yml, tc.want})
+	}
+}
+
+func TestRulesYAML(t *testing.T) {
+	yml := "rules:\n  required: [NasaID]\n  any_of:\n    - [Keywords, Description]\n  media_types:\n    video:\n      required: [Description]\n"
+	var cfg config
+	equals(t, yaml.Unmarshal([]byte(yml), &cfg), nil)
+	equals(t, cfg.Rules.Required, []string{"NasaID"})
+	equals(t, cfg.Rules.AnyOf, [][]string{{"Keywords", "Description"}})
+	equals(t, cfg.Rules.ruleFor("image").Required, []string{"NasaID"})
+	equals(t, cfg.Rules.ruleFor("video").Required, []string{"Description"})
+}
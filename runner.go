@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// runner checks files. It holds everything checking depends on, from the
+// options and config, so separate runners share nothing and can check files
+// side by side, as the tests do.
+type runner struct {
+	cfg    config
+	types  map[string]bool
+	media  map[string]bool
+	fields map[string][]source
+	// keywords is the config's keywordPolicy.
+	keywords *keywordPolicy
+	// filenames are the config's filename_patterns.
+	filenames filenamePatterns
+	// lengths are the config's max_lengths.
+	lengths map[string]int
+	// retire stops a worker between files, for -p auto.
+	retire chan struct{}
+	// args are the options exiftool extracts with.
+	args       []string
+	cache      *metadataCache
+	roots      dirList
+	timeout    time.Duration
+	verbose    bool
+	traceField string
+	// lang is -lang.
+	lang string
+	// record and replay are directories of exiftool output fixtures.
+	record string
+	replay string
+	fixes  *corrections
+	ids    *idWriter
+	thumbs *thumbnails
+	xmp    *xmpExporter
+	derivs *derivatives
+	hook   *webhook
+	checks checks
+	// details is for -details.
+	details *details
+	// inspect is given each row with the exif it was made from, for chkmd
+	// inspect.
+	inspect func([]string, exif)
+	dups    *duplicates
+	// stores and inherited are set for where the files are: stores for
+	// object store ones and inherited for a local -d.
+	stores    objectStores
+	inherited *folders
+	// egress counts what's downloaded from stores and URLs.
+	egress *egress
+	// audit is for -dump and -since.
+	audit *audit
+	// resumed is the -resume journal, if there is one.
+	resumed *journal
+	// archives is -archives, and unpacked the copies of the files in them
+	// waiting to be checked.
+	archives int
+	unpacked *unpacked
+	// sniff is -sniff.
+	sniff bool
+}
+
+// newRunner returns a runner for the options and config.
+func newRunner(o options, cfg config) (*runner, error) {
+	r := &runner{
+		cfg:        cfg,
+		types:      cfg.mimeTypeSet(),
+		media:      cfg.mediaTypeSet(),
+		roots:      o.dirs,
+		timeout:    o.timeout,
+		verbose:    o.verbose,
+		traceField: o.traceField,
+		lang:       o.lang,
+		record:     o.record,
+		replay:     o.replay,
+		archives:   o.archives,
+		sniff:      o.sniff,
+	}
+	if o.archives > 0 {
+		r.unpacked = newUnpacked()
+	}
+	if o.documents {
+		for _, t := range documentTypes {
+			r.types[t] = true
+		}
+		r.media[documentMediaType] = true
+	}
+	if o.traceField != "" {
+		if _, err := traceFields(o.traceField); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if o.record != "" {
+		if err = os.MkdirAll(o.record, 0755); err != nil {
+			return nil, fmt.Errorf("Error making -record directory: %s", err)
+		}
+	}
+	if o.fix != "" {
+		r.fixes, err = readCorrections(o.fix, o.fixSidecar)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading corrections %s: %s", o.fix, err)
+		}
+	}
+	if o.writeID != "" {
+		if r.ids, err = newIDWriter(o.writeID); err != nil {
+			return nil, err
+		}
+	}
+	if o.thumbsDir != "" {
+		if r.thumbs, err = newThumbnails(o.thumbsDir, o.dirs...); err != nil {
+			return nil, fmt.Errorf("Error making -thumbnails directory: %s", err)
+		}
+	}
+	if o.exportXMP != "" {
+		if r.xmp, err = newXMPExporter(o.exportXMP, o.dirs...); err != nil {
+			return nil, fmt.Errorf("Error making -export-xmp directory: %s", err)
+		}
+	}
+	sc, err := compileScripts(cfg.Scripts)
+	if err != nil {
+		return nil, fmt.Errorf("Error in config file %s: %s", o.cfgfile, err)
+	}
+	if r.fields, err = cfg.Fields.sources(sc); err != nil {
+		return nil, fmt.Errorf("Error in config file %s: %s", o.cfgfile, err)
+	}
+	r.args = cfg.Exiftool.args(r.fields)
+	if o.cacheDir != "" {
+		// The metadata depends on these as well as the file.
+		r.cache, err = newMetadataCache(o.cacheDir, strings.Join(r.args, " "),
+			fmt.Sprint(o.dupsOut != "", cfg.Duplicates), fmt.Sprint(cfg.sidecarPrecedence(), cfg.sidecarPolicy()))
+		if err != nil {
+			return nil, fmt.Errorf("Error making -cache directory: %s", err)
+		}
+	}
+	if r.checks, err = compileChecks(cfg.Checks, sc); err != nil {
+		return nil, fmt.Errorf("Error in config file %s: %s", o.cfgfile, err)
+	}
+	r.derivs, err = newDerivatives(cfg.Derivatives)
+	if err != nil {
+		return nil, fmt.Errorf("Error in config file %s: %s", o.cfgfile, err)
+	}
+	r.lengths = cfg.MaxLengths.lengths()
+	if r.keywords, err = cfg.Keywords.compile(); err != nil {
+		return nil, fmt.Errorf("Error in config file %s: %s", o.cfgfile, err)
+	}
+	if r.filenames, err = cfg.FilenamePatterns.compile(); err != nil {
+		return nil, fmt.Errorf("Error in config file %s: %s", o.cfgfile, err)
+	}
+	if o.webhookURL != "" {
+		r.hook = newWebhook(o.webhookURL, o.webhookBatch)
+	}
+	if o.dupsOut != "" {
+		r.dups = newDuplicates()
+	}
+	return r, nil
+}
+
+// configure wraps extract so the exif has the config's MIME and media
+// types, centers, fields, how to romanize Location, plausible dates, how
+// to write and check Keywords, the -lang language, what its name says by
+// the filename_patterns and what it inherits from its directories' metadata.
+// Every extraction of the file, like -fix's after writing it, has them.
+func (r *runner) configure(extract func(string) (exif, error)) func(string) (exif, error) {
+	return func(p string) (exif, error) {
+		e, err := extract(p)
+		e.types, e.media, e.centers, e.fields = r.types, r.media, r.cfg.Centers, r.fields
+		e.romanize, e.dates, e.keywords = r.cfg.RomanizeLocation, &r.cfg.Dates, r.keywords
+		e.lang, e.lengths = r.lang, r.lengths
+		e.Filename = r.filenames.infer(p)
+		if err == nil && r.inherited != nil {
+			e.Folder, err = r.inherited.inherit(p)
+		}
+		return e, err
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewRunner(t *testing.T) {
+	r, err := newRunner(options{dirs: dirList{"/media"}, traceField: "Title"}, config{MimeTypes: []string{"image/png"}})
+	equals(t, err, nil)
+	equals(t, r.types, map[string]bool{"image/png": true})
+	equals(t, r.roots.rootOf("/media/a.jpg"), "/media")
+	equals(t, r.derivs != nil, true)
+
+	_, err = newRunner(options{traceField: "Caption"}, config{})
+	equals(t, err.Error(), `can't trace unknown field "Caption"`)
+}
+
+func TestRunnerConfigure(t *testing.T) {
+	// Two runners with different configs don't see each other's.
+	png, err := newRunner(options{}, config{MimeTypes: []string{"image/png"}})
+	equals(t, err, nil)
+	centers := []centerName{{Match: `(?i)\bKSC\b`, Center: "Kennedy Space Center"}}
+	equals(t, compileCenters(centers), nil)
+	ksc, err := newRunner(options{}, config{MimeTypes: []string{"image/jpeg"}, Centers: centers})
+	equals(t, err, nil)
+
+	extract := func(p string) (exif, error) {
+		e := newExif()
+		e.Data["MIMEType"] = "image/png"
+		e.Data["FileType"] = "PNG"
+		e.IPTC["Credit"] = "NASA/KSC"
+		return e, nil
+	}
+	e, err := png.configure(extract)("a.png")
+	equals(t, err, nil)
+	equals(t, e.FileFormat(), "PNG")
+	equals(t, e.Center(), "NASA/KSC")
+
+	e, err = ksc.configure(extract)("a.png")
+	equals(t, err, nil)
+	equals(t, e.FileFormat(), "")
+	equals(t, e.Center(), "Kennedy Space Center")
+}
+
+func TestRunnerConfigureInherits(t *testing.T) {
+	root, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(root)
+	equals(t, ioutil.WriteFile(filepath.Join(root, folderMetadataFile),
+		[]byte("center: Kennedy Space Center\nalbum: STS-1\n"), 0644), nil)
+	r, err := newRunner(options{dirs: dirList{root}}, config{})
+	equals(t, err, nil)
+	r.inherited = newFolders(root)
+
+	// Each extraction inherits, as -fix's after writing the file does.
+	extract := r.configure(func(p string) (exif, error) { return newExif(), nil })
+	for i := 0; i < 2; i++ {
+		e, err := extract(filepath.Join(root, "a.jpg"))
+		equals(t, err, nil)
+		equals(t, e.Center(), "Kennedy Space Center")
+		equals(t, e.Album(), "STS-1")
+	}
+}
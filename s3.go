@@ -0,0 +1,236 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// unsignedPayload is the x-amz-content-sha256 for requests whose body we
+// don't sign. We only GET from S3 so there's no body anyway.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// s3Client makes SigV4 signed requests to S3 with the credentials from the
+// usual AWS environment variables.
+type s3Client struct {
+	client   *http.Client
+	region   string
+	key      string
+	secret   string
+	token    string
+	endpoint string
+	now      func() time.Time
+}
+
+// newS3Client returns an s3Client configured from the environment. Set
+// AWS_ENDPOINT_URL_S3 to use an S3 compatible store with path style URLs.
+func newS3Client() (*s3Client, error) {
+	c := &s3Client{
+		client:   &http.Client{},
+		region:   os.Getenv("AWS_REGION"),
+		key:      os.Getenv("AWS_ACCESS_KEY_ID"),
+		secret:   os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		token:    os.Getenv("AWS_SESSION_TOKEN"),
+		endpoint: strings.TrimRight(os.Getenv("AWS_ENDPOINT_URL_S3"), "/"),
+		now:      time.Now,
+	}
+	if c.region == "" {
+		c.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if c.region == "" {
+		c.region = "us-east-1"
+	}
+	if c.key == "" || c.secret == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set to read from S3")
+	}
+	return c, nil
+}
+
+// parseS3URI splits s3://bucket/prefix into bucket and prefix.
+func parseS3URI(s string) (bucket, prefix string, ok bool) {
+	if !strings.HasPrefix(s, "s3://") {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(s, "s3://"), "/", 2)
+	if parts[0] == "" {
+		return "", "", false
+	}
+	if len(parts) == 2 {
+		prefix = parts[1]
+	}
+	return parts[0], prefix, true
+}
+
+// objectURL returns the URL of key in bucket, virtual host style on AWS and
+// path style on a custom endpoint.
+func (c *s3Client) objectURL(bucket, key string) string {
+	if c.endpoint != "" {
+		return c.endpoint + "/" + bucket + "/" + awsEscape(key, true)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, c.region, awsEscape(key, true))
+}
+
+// awsEscape URI encodes s the way SigV4 wants, which is everything but the
+// unreserved characters, and '/' if keepSlash is set.
+func awsEscape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sign adds the SigV4 Authorization header to req, signing the host and all
+// the headers already set. See
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (c *s3Client) sign(req *http.Request, payloadHash string) {
+	t := c.now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.token != "" {
+		req.Header.Set("X-Amz-Security-Token", c.token)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	var names []string
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	q := req.URL.Query()
+	var keys []string
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, k := range keys {
+		for _, v := range q[k] {
+			params = append(params, awsEscape(k, false)+"="+awsEscape(v, false))
+		}
+	}
+
+	uri := req.URL.EscapedPath()
+	if uri == "" {
+		uri = "/"
+	}
+	canon := strings.Join([]string{
+		req.Method, uri, strings.Join(params, "&"), canonHeaders.String(), signed, payloadHash,
+	}, "\n")
+	sum := sha256.Sum256([]byte(canon))
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	k := hmacSHA256([]byte("AWS4"+c.secret), date)
+	k = hmacSHA256(k, c.region)
+	k = hmacSHA256(k, "s3")
+	k = hmacSHA256(k, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(k, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.key, scope, signed, sig))
+}
+
+// get makes a signed GET request, returning the response if it was a 200.
+func (c *s3Client) get(u string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.sign(req, unsignedPayload)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s: %s", u, resp.Status, strings.TrimSpace(string(b)))
+	}
+	return resp, nil
+}
+
+// listResult is the part of a ListObjectsV2 response we use.
+type listResult struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key  string
+		Size int64
+	}
+}
+
+// list calls fn with each key under prefix in bucket.
+func (c *s3Client) list(bucket, prefix string, fn func(key string, size int64) error) error {
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u := c.objectURL(bucket, "") + "?" + q.Encode()
+		resp, err := c.get(u)
+		if err != nil {
+			return err
+		}
+		var lr listResult
+		err = xml.NewDecoder(resp.Body).Decode(&lr)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		for _, o := range lr.Contents {
+			if err = fn(o.Key, o.Size); err != nil {
+				return err
+			}
+		}
+		if !lr.IsTruncated {
+			return nil
+		}
+		token = lr.NextContinuationToken
+	}
+}
+
+// download copies the object to w.
+func (c *s3Client) download(bucket, key string, w io.Writer) error {
+	resp, err := c.get(c.objectURL(bucket, key))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
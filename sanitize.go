@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultMaxLengths are the most characters AVAIL takes in its fields. Longer
+// values are truncated in the output, with a note in the Reason.
+var defaultMaxLengths = map[string]int{
+	"NASA ID":                  100,
+	"Title":                    250,
+	"508 Description":          1000,
+	"Description":              10000,
+	"Location":                 250,
+	"Keywords":                 2000,
+	"Center":                   100,
+	"Secondary Creator Credit": 250,
+	"Photographer":             250,
+	"Album":                    250,
+}
+
+// maxLengthConfig is the config's max_lengths, the most characters a
+// column's values may have, by column, on top of the defaultMaxLengths. A
+// negative length is no limit.
+type maxLengthConfig map[string]int
+
+// validate checks the columns are ones we output, other than Path, which
+// isn't changed, and Reason, which truncations are noted in.
+func (mc maxLengthConfig) validate() error {
+	for c, n := range mc {
+		if column(c) < 0 || c == "Path" || c == "Reason" {
+			return fmt.Errorf("max_lengths: can't limit %q", c)
+		}
+		if n == 0 {
+			return fmt.Errorf("max_lengths: %q is 0, expected a length, or negative for none", c)
+		}
+	}
+	return nil
+}
+
+// maxLengths returns the most characters each column may have, or without
+// a config the defaultMaxLengths.
+func (e exif) maxLengths() map[string]int {
+	if e.lengths == nil {
+		return defaultMaxLengths
+	}
+	return e.lengths
+}
+
+// lengths returns the limits, the config's over the defaults.
+func (mc maxLengthConfig) lengths() map[string]int {
+	lengths := map[string]int{}
+	for c, n := range defaultMaxLengths {
+		lengths[c] = n
+	}
+	for c, n := range mc {
+		if n < 0 {
+			delete(lengths, c)
+			continue
+		}
+		lengths[c] = n
+	}
+	return lengths
+}
+
+// sanitize returns v for output: valid UTF-8, without control characters or
+// byte order marks, with newlines, tabs and runs of white space as a single
+// space, and trimmed, so it's on one line whatever reads it.
+func sanitize(v string) string {
+	v = validUTF8(v)
+	var b strings.Builder
+	space := false
+	for _, r := range v {
+		switch {
+		case unicode.IsSpace(r):
+			space = b.Len() > 0
+			continue
+		case unicode.IsControl(r), r == '\ufeff':
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// truncate returns v cut to max characters, ending with an ellipsis, and
+// whether it was.
+func truncate(v string, max int) (string, bool) {
+	if max <= 0 || utf8.RuneCountInString(v) <= max {
+		return v, false
+	}
+	runes := []rune(v)
+	return strings.TrimRightFunc(string(runes[:max-1]), unicode.IsSpace) + "…", true
+}
+
+// sanitizeRow sanitizes the values of the row, but for its Path, which
+// names the file, and truncates those longer than their lengths, noting
+// which in the Reason.
+func sanitizeRow(row []string, lengths map[string]int) {
+	var truncated []string
+	for i := 1; i < len(row); i++ {
+		row[i] = sanitize(row[i])
+		var cut bool
+		if row[i], cut = truncate(row[i], lengths[csvHeader[i]]); cut {
+			truncated = append(truncated, csvHeader[i])
+		}
+	}
+	if len(truncated) > 0 {
+		row[column("Reason")] = joinReason(row[column("Reason")], "Truncated "+strings.Join(truncated, ", "))
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	for v, want := range map[string]string{
+		"Apollo 11":                       "Apollo 11",
+		"  Apollo\t 11 \r\n\r\nlaunch  ":  "Apollo 11 launch",
+		"Apollo\x0011\x07":                "Apollo11",
+		"\ufeffApollo\u00a0 11\u2028":     "Apollo 11",
+		"caf\xe9\x9b":                     "caf��",
+		"Line one\nLine two\n\nLine four": "Line one Line two Line four",
+	} {
+		equals(t, []string{v, sanitize(v)}, []string{v, want})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	v, cut := truncate("Launch of Apollo 11", 10)
+	equals(t, v, "Launch of…")
+	equals(t, cut, true)
+	v, cut = truncate("東京の打ち上げ", 7)
+	equals(t, v, "東京の打ち上げ")
+	equals(t, cut, false)
+	v, _ = truncate("東京の打ち上げ", 4)
+	equals(t, v, "東京の…")
+	_, cut = truncate("Launch", 0)
+	equals(t, cut, false)
+}
+
+func TestMaxLengths(t *testing.T) {
+	equals(t, maxLengthConfig{"Title": 50, "Keywords": -1}.validate(), nil)
+	equals(t, maxLengthConfig{"Path": 50}.validate().Error(), `max_lengths: can't limit "Path"`)
+	equals(t, maxLengthConfig{"Caption": 50}.validate().Error(), `max_lengths: can't limit "Caption"`)
+	equals(t, maxLengthConfig{"Title": 0}.validate() != nil, true)
+
+	lengths := maxLengthConfig{"Title": 50, "Keywords": -1, "File Format": 4}.lengths()
+	equals(t, lengths["Title"], 50)
+	equals(t, lengths["Description"], defaultMaxLengths["Description"])
+	equals(t, lengths["File Format"], 4)
+	_, ok := lengths["Keywords"]
+	equals(t, ok, false)
+
+	e := newExif()
+	e.XMP["Title"] = "Apollo 11\nlaunch " + strings.Repeat("and more ", 40)
+	e.XMP["Description"] = "The\tSaturn V\r\nlifts off."
+	e.lengths = map[string]int{"Title": 20}
+	rows := make(chan []string, 1)
+	e.MakeRow(rows, "/media/a  b.jpg", "Accepted", "Fixed Title", "")
+	row := <-rows
+	equals(t, row[column("Title")], "Apollo 11 launch an…")
+	equals(t, row[column("Description")], "The Saturn V lifts off.")
+	equals(t, row[column("Reason")], "Fixed Title; Truncated Title")
+	equals(t, row[column("Path")], "/media/a  b.jpg")
+
+	e.MakeErrorRow(rows, "/media/c.jpg", errors.New("exiftool failed:\nbad file\n"))
+	equals(t, (<-rows)[column("Reason")], "exiftool failed: bad file")
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// scriptPrefix starts a fields source naming a function in the scripts.
+const scriptPrefix = "script:"
+
+// scriptSteps bounds how much a call into the scripts may do, so a script
+// stuck in a loop fails rather than holding up its file forever.
+const scriptSteps = 1000000
+
+// scriptFields are the fields scripts can read off a file, e.g. file.NasaID.
+var scriptFields = map[string]func(exif) string{
+	"NasaID":       exif.NasaID,
+	"Title":        exif.Title,
+	"AltText":      exif.AltText,
+	"Description":  exif.Description,
+	"DateCreated":  exif.dateCreatedText,
+	"Keywords":     exif.Keywords,
+	"Location":     exif.Location,
+	"Photographer": exif.Photographer,
+	"Center":       exif.Center,
+	"Credit":       exif.Credit,
+	"Album":        exif.Album,
+	"Copyright":    exif.Copyright,
+	"UsageTerms":   exif.UsageTerms,
+	"MediaType":    exif.MediaType,
+	"FileFormat":   exif.FileFormat,
+}
+
+// scriptGroups are the groups of tags scripts can read off a file, e.g.
+// file.IPTC["JobID"].
+var scriptGroups = map[string]func(exif) map[string]string{
+	"Data":   func(e exif) map[string]string { return e.Data },
+	"Exif":   func(e exif) map[string]string { return e.Exif },
+	"IPTC":   func(e exif) map[string]string { return e.IPTC },
+	"XMP":    func(e exif) map[string]string { return e.XMP },
+	"ID3":    func(e exif) map[string]string { return e.ID3 },
+	"RIFF":   func(e exif) map[string]string { return e.RIFF },
+	"Model":  func(e exif) map[string]string { return e.Model },
+	"PDF":    func(e exif) map[string]string { return e.PDF },
+	"Folder": func(e exif) map[string]string { return e.Folder },
+}
+
+// scripts are the functions of the scripts section of the config, a
+// Starlark (https://github.com/google/starlark-go) program defining the
+// functions fields sources and checks name, e.g.
+//
+//	scripts: |
+//	  def nasa_id(file):
+//	      if file.IPTC["JobID"]:
+//	          return "%s-%s" % (file.IPTC["JobID"], file.Exif["ImageUniqueID"])
+//	  def ksc_ids(file):
+//	      if not file.NasaID.startswith("KSC"):
+//	          return "NASA ID must start with KSC"
+//
+// Each function is called with the file, see scriptFile, and returns a
+// string, or None for nothing.
+type scripts starlark.StringDict
+
+// compileScripts runs the scripts section of the config, src, to define its
+// functions. Besides Starlark's builtins it has match, a regular expression.
+func compileScripts(src string) (scripts, error) {
+	if src == "" {
+		return nil, nil
+	}
+	thread := &starlark.Thread{Name: "scripts"}
+	thread.SetMaxExecutionSteps(scriptSteps)
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, "scripts", src,
+		starlark.StringDict{"match": starlark.NewBuiltin("match", scriptMatch)})
+	if err != nil {
+		return nil, fmt.Errorf("scripts: %s", err)
+	}
+	// Frozen, the functions may be called by every processFiles at once.
+	globals.Freeze()
+	return scripts(globals), nil
+}
+
+// function returns the function called name.
+func (sc scripts) function(name string) (*starlark.Function, error) {
+	fn, ok := sc[name].(*starlark.Function)
+	if !ok {
+		return nil, fmt.Errorf("there's no function %s in scripts", name)
+	}
+	return fn, nil
+}
+
+// callScript calls fn with e, returning the string it returns.
+func callScript(fn *starlark.Function, e exif) (string, error) {
+	thread := &starlark.Thread{Name: fn.Name()}
+	thread.SetMaxExecutionSteps(scriptSteps)
+	v, err := starlark.Call(thread, fn, starlark.Tuple{scriptFile{e}}, nil)
+	if err != nil {
+		return "", err
+	}
+	if v == starlark.None {
+		return "", nil
+	}
+	s, ok := starlark.AsString(v)
+	if !ok {
+		return "", fmt.Errorf("%s returned %s, not a string", fn.Name(), v.Type())
+	}
+	return strings.TrimSpace(s), nil
+}
+
+// scriptMatch is the scripts' match(pattern, s), whether the regular
+// expression pattern matches s.
+func scriptMatch(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern, s string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &pattern, &s); err != nil {
+		return nil, err
+	}
+	matched, err := regexp.MatchString(pattern, s)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.Bool(matched), nil
+}
+
+// scriptFile is a file as scripts see it: the fields, like file.NasaID, the
+// tags by group, like file.IPTC["JobID"], file.Data["FileName"] for
+// exiftool's File and Composite tags and file.Folder["Center"] for
+// metadata.yaml, where missing tags are empty, and file.values("IPTC",
+// "By-line"), a tag's values one by one.
+type scriptFile struct {
+	e exif
+}
+
+func (f scriptFile) String() string        { return "file" }
+func (f scriptFile) Type() string          { return "file" }
+func (f scriptFile) Freeze()               {}
+func (f scriptFile) Truth() starlark.Bool  { return starlark.True }
+func (f scriptFile) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable type: file") }
+
+// Attr returns the field, group or values called name.
+func (f scriptFile) Attr(name string) (starlark.Value, error) {
+	if get, ok := scriptFields[name]; ok {
+		return starlark.String(get(f.e)), nil
+	}
+	if name == "values" {
+		return starlark.NewBuiltin("values", f.values), nil
+	}
+	if get, ok := scriptGroups[name]; ok {
+		return scriptGroup(get(f.e)), nil
+	}
+	return nil, nil
+}
+
+// AttrNames returns the names Attr has.
+func (f scriptFile) AttrNames() []string {
+	names := []string{"values"}
+	for name := range scriptFields {
+		names = append(names, name)
+	}
+	for name := range scriptGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// values is file.values(group, tag), see exif.Values.
+func (f scriptFile) values(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var group, tag string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &group, &tag); err != nil {
+		return nil, err
+	}
+	var items []starlark.Value
+	for _, v := range f.e.Values(group, tag) {
+		items = append(items, starlark.String(v))
+	}
+	return starlark.NewList(items), nil
+}
+
+// scriptGroup is a group's tags, like file.IPTC, read-only and empty for
+// tags the file doesn't have.
+type scriptGroup map[string]string
+
+func (g scriptGroup) String() string        { return "group" }
+func (g scriptGroup) Type() string          { return "group" }
+func (g scriptGroup) Freeze()               {}
+func (g scriptGroup) Truth() starlark.Bool  { return len(g) > 0 }
+func (g scriptGroup) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable type: group") }
+
+// Get returns the tag k.
+func (g scriptGroup) Get(k starlark.Value) (starlark.Value, bool, error) {
+	tag, ok := starlark.AsString(k)
+	if !ok {
+		return nil, false, fmt.Errorf("a tag is a string, not a %s", k.Type())
+	}
+	return starlark.String(g[tag]), true, nil
+}
+
+// scriptSource is a fields source deriving the value from the file with the
+// function called name in the scripts, e.g. to make a NASA ID from several
+// tags. Fields in it are their usual values, without the config's mapping,
+// so a function for a field can use that field. A function that fails gives
+// nothing.
+func scriptSource(sc scripts, name string) (source, error) {
+	fn, err := sc.function(name)
+	if err != nil {
+		return source{}, err
+	}
+	return source{scriptPrefix + name, func(e exif) string {
+		e.fields = nil
+		v, err := callScript(fn, e)
+		if err != nil {
+			return ""
+		}
+		return v
+	}}, nil
+}
+
+// scriptCheck is a check of the checks section of the config: a function in
+// the scripts which returns nothing for a good file, and for a bad one why
+// it's bad, e.g.
+//
+//	checks:
+//	  - name: KSC IDs
+//	    script: ksc_ids
+//
+// Accepted files that fail a check are Incomplete.
+type scriptCheck struct {
+	Name   string `yaml:"name"`
+	Script string `yaml:"script"`
+}
+
+// compiledCheck is a scriptCheck ready to run.
+type compiledCheck struct {
+	name string
+	fn   *starlark.Function
+}
+
+// checks are the compiled checks of the config.
+type checks []compiledCheck
+
+// compileChecks finds the functions of the config's checks in sc.
+func compileChecks(scs []scriptCheck, sc scripts) (checks, error) {
+	var cs checks
+	for i, c := range scs {
+		if c.Name == "" {
+			return nil, fmt.Errorf("checks: check %d has no name", i+1)
+		}
+		fn, err := sc.function(c.Script)
+		if err != nil {
+			return nil, fmt.Errorf("checks: %s: %s", c.Name, err)
+		}
+		cs = append(cs, compiledCheck{c.Name, fn})
+	}
+	return cs, nil
+}
+
+// checkFailure is a check a file failed and why.
+type checkFailure struct {
+	name    string
+	message string
+}
+
+func (cf checkFailure) String() string {
+	return cf.name + ": " + cf.message
+}
+
+// failures returns why e fails each check it does. A check that can't be
+// run fails with its error.
+func (cs checks) failures(e exif) []checkFailure {
+	var failed []checkFailure
+	for _, c := range cs {
+		msg, err := callScript(c.fn, e)
+		if err != nil {
+			msg = err.Error()
+		}
+		if msg != "" {
+			failed = append(failed, checkFailure{c.name, msg})
+		}
+	}
+	return failed
+}
@@ -0,0 +1,102 @@
+package main
+
+import "testing"
+
+const testScripts = `
+def nasa_id(file):
+    if file.IPTC["JobID"]:
+        return "%s-%s" % (file.IPTC["JobID"], file.Exif["ImageUniqueID"])
+
+def title(file):
+    return file.Title.upper()
+
+def ksc_ids(file):
+    if not file.NasaID.startswith("KSC"):
+        return "NASA ID must start with KSC"
+
+def launch_keyword(file):
+    if not match("(?i)launch", file.Keywords):
+        return "no launch keyword"
+
+def one_photographer(file):
+    if len(file.values("IPTC", "By-line")) > 1:
+        return "one photographer only"
+
+def count(file):
+    return len(file.Title)
+
+def forever(file):
+    n = 0
+    for i in range(100000000):
+        n += i
+    return str(n)
+`
+
+func TestScriptSource(t *testing.T) {
+	sc, err := compileScripts(testScripts)
+	equals(t, err, nil)
+	fields, err := fieldMapping{
+		"NasaID": {"script:nasa_id", "File:FileName"},
+		"Title":  {"script:title"},
+	}.sources(sc)
+	equals(t, err, nil)
+
+	e := newExif()
+	e.fields = fields
+	e.Data["FileName"] = "jsc2015e012345.jpg"
+	e.XMP["Title"] = "Launch"
+	equals(t, e.NasaID(), "jsc2015e012345")
+	// A field's function gets its usual value.
+	equals(t, e.Title(), "LAUNCH")
+
+	e.IPTC["JobID"] = "JSC"
+	e.Exif["ImageUniqueID"] = "2015e012345"
+	equals(t, e.NasaID(), "JSC-2015e012345")
+
+	_, err = fieldMapping{"Title": {"script:headline"}}.sources(sc)
+	equals(t, err.Error(), "fields: Title: there's no function headline in scripts")
+	_, err = compileScripts("def title(file)\n    return file.Title\n")
+	equals(t, err != nil, true)
+}
+
+func TestChecks(t *testing.T) {
+	sc, err := compileScripts(testScripts)
+	equals(t, err, nil)
+	cs, err := compileChecks([]scriptCheck{
+		{"KSC IDs", "ksc_ids"},
+		{"Keywords", "launch_keyword"},
+		{"Photographer", "one_photographer"},
+	}, sc)
+	equals(t, err, nil)
+
+	e := newExif()
+	e.Data["FileName"] = "KSC-1.jpg"
+	e.IPTC["Keywords"] = "Launch, Pad 39A"
+	e.IPTC["By-line"] = "Bill Ingalls"
+	equals(t, cs.failures(e), []checkFailure(nil))
+
+	e.Data["FileName"] = "JSC-1.jpg"
+	e.IPTC["Keywords"] = "Moon"
+	e.IPTC["By-line"] = "Bill Ingalls, Joel Kowsky"
+	e.Lists["IPTC:By-line"] = []string{"Bill Ingalls", "Joel Kowsky"}
+	equals(t, cs.failures(e), []checkFailure{
+		{"KSC IDs", "NASA ID must start with KSC"},
+		{"Keywords", "no launch keyword"},
+		{"Photographer", "one photographer only"},
+	})
+	equals(t, cs.failures(e)[0].String(), "KSC IDs: NASA ID must start with KSC")
+
+	// A check that doesn't give a string, or runs too long, fails.
+	cs, err = compileChecks([]scriptCheck{{"Count", "count"}, {"Forever", "forever"}}, sc)
+	equals(t, err, nil)
+	failed := cs.failures(e)
+	equals(t, len(failed), 2)
+	equals(t, failed[0].message, "count returned int, not a string")
+
+	_, err = compileChecks([]scriptCheck{{Script: "ksc_ids"}}, sc)
+	equals(t, err.Error(), "checks: check 1 has no name")
+	_, err = compileChecks([]scriptCheck{{"Title", "title_check"}}, sc)
+	equals(t, err.Error(), "checks: Title: there's no function title_check in scripts")
+	_, err = compileChecks([]scriptCheck{{"Title", "title"}}, nil)
+	equals(t, err.Error(), "checks: Title: there's no function title in scripts")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// shard is which of a number of shards to check, as a flag.Value taking
+// e.g. 3/8, so one big scan can be split across machines. The zero shard is
+// everything.
+type shard struct {
+	index int
+	count int
+}
+
+func (sh *shard) Set(s string) error {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) == 2 {
+		i, ierr := strconv.Atoi(strings.TrimSpace(parts[0]))
+		n, nerr := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if ierr == nil && nerr == nil && n > 0 && i >= 1 && i <= n {
+			sh.index, sh.count = i, n
+			return nil
+		}
+	}
+	return fmt.Errorf("not a shard like 3/8: %q", s)
+}
+
+func (sh shard) String() string {
+	if sh.count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", sh.index, sh.count)
+}
+
+// mine is whether the file at p is in the shard. Files are sharded by a
+// hash of their path rather than their place in the list, so a list made
+// again, maybe in a different order, shards the same way.
+func (sh shard) mine(p string) bool {
+	if sh.count == 0 {
+		return true
+	}
+	h := fnv.New32a()
+	io.WriteString(h, p)
+	return int(h.Sum32()%uint32(sh.count)) == sh.index-1
+}
+
+// walkFileList is makeWalker for a list of files: a -working-set manifest,
+// or a path per line. It sends the relevant files in the shard to the files
+// channel.
+func walkFileList(ctx context.Context, r io.Reader, sh shard, files chan string, stats *statistics, types map[string]bool) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; s.Scan(); n++ {
+		p := strings.TrimSpace(s.Text())
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+		if strings.HasPrefix(p, "{") {
+			var we workingSetEntry
+			if err := json.Unmarshal([]byte(p), &we); err != nil {
+				return fmt.Errorf("line %d: %s", n, err)
+			}
+			p = we.Path
+		}
+		if !sh.mine(p) {
+			continue
+		}
+		atomic.AddInt32(&stats.Total, 1)
+		if types[mime.TypeByExtension(filepath.Ext(p))] {
+			if err := sendFile(ctx, files, p); err != nil {
+				return err
+			}
+			atomic.AddInt32(&stats.Relevant, 1)
+		}
+	}
+	return s.Err()
+}
+
+// readFileList walks the list of files at p.
+func readFileList(ctx context.Context, p string, sh shard, files chan string, stats *statistics, types map[string]bool) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return walkFileList(ctx, f, sh, files, stats, types)
+}
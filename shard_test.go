@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShard(t *testing.T) {
+	var sh shard
+	equals(t, sh.Set("3/8"), nil)
+	equals(t, sh, shard{3, 8})
+	equals(t, sh.String(), "3/8")
+	for _, bad := range []string{"0/8", "9/8", "3", "a/b", "1/0"} {
+		equals(t, sh.Set(bad).Error(), fmt.Sprintf("not a shard like 3/8: %q", bad))
+	}
+	equals(t, shard{}.String(), "")
+
+	// Every file is in exactly one shard.
+	for i := 0; i < 100; i++ {
+		p := fmt.Sprintf("/archive/%d.jpg", i)
+		n := 0
+		for s := 1; s <= 4; s++ {
+			if (shard{s, 4}).mine(p) {
+				n++
+			}
+		}
+		equals(t, n, 1)
+		equals(t, shard{}.mine(p), true)
+	}
+}
+
+func TestWalkFileList(t *testing.T) {
+	list := `{"path":"a.jpg","size":10,"modified":"2015-05-26T10:00:00Z"}
+b.jpg
+
+# Comments and blank lines are skipped.
+c.txt
+d.png
+`
+	walk := func(sh shard) ([]string, *statistics) {
+		files := make(chan string, 10)
+		stats := &statistics{}
+		equals(t, walkFileList(context.Background(), strings.NewReader(list), sh, files, stats, defaultTypeSet), nil)
+		close(files)
+		var got []string
+		for p := range files {
+			got = append(got, p)
+		}
+		return got, stats
+	}
+	got, stats := walk(shard{})
+	equals(t, got, []string{"a.jpg", "b.jpg", "d.png"})
+	equals(t, stats.Total, int32(4))
+	equals(t, stats.Relevant, int32(3))
+
+	var all []string
+	for s := 1; s <= 2; s++ {
+		got, _ = walk(shard{s, 2})
+		all = append(all, got...)
+	}
+	equals(t, len(all), 3)
+
+	err := walkFileList(context.Background(), strings.NewReader("{bad\n"), shard{}, make(chan string, 1), &statistics{}, defaultTypeSet)
+	equals(t, strings.HasPrefix(err.Error(), "line 1: "), true)
+}
+
+func TestMakeWalkerShard(t *testing.T) {
+	dir := testTree(t)
+	defer os.RemoveAll(dir)
+
+	r := newTestRunner(t, "test-config.yaml")
+	var all []string
+	for s := 1; s <= 3; s++ {
+		files := make(chan string, 10)
+		stats := &statistics{}
+		equals(t, filepath.Walk(dir, r.makeWalker(context.Background(), shard{s, 3}, files, stats)), nil)
+		close(files)
+		for p := range files {
+			all = append(all, p)
+		}
+		equals(t, stats.Total, stats.Relevant)
+	}
+	equals(t, len(all), 7)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	sheetsScope    = "https://www.googleapis.com/auth/spreadsheets"
+	sheetsEndpoint = "https://sheets.googleapis.com"
+	sheetsBatch    = 500
+)
+
+// serviceAccount holds the parts of a Google service account key file we
+// need to get an access token.
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// readServiceAccount reads the JSON key file downloaded from the Google
+// developer console.
+func readServiceAccount(p string) (serviceAccount, error) {
+	var sa serviceAccount
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return sa, err
+	}
+	if err = json.Unmarshal(b, &sa); err != nil {
+		return sa, err
+	}
+	if sa.TokenURI == "" {
+		sa.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return sa, nil
+}
+
+// signer parses the PEM encoded private key of the service account.
+func (sa serviceAccount) signer() (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return nil, errors.New("no PEM private key found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	rk, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return rk, nil
+}
+
+// token exchanges a signed JWT assertion for an access token as described in
+// https://developers.google.com/identity/protocols/OAuth2ServiceAccount. It
+// returns the token and the time it expires.
+func (sa serviceAccount) token(c *http.Client, scope string) (string, time.Time, error) {
+	key, err := sa.signer()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now()
+	hdr, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   sa.ClientEmail,
+		"scope": scope,
+		"aud":   sa.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(hdr) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	resp, err := c.PostForm(sa.TokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + enc.EncodeToString(sig)},
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return "", time.Time{}, fmt.Errorf("token request failed: %s: %s", resp.Status, b)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", time.Time{}, err
+	}
+	return tok.AccessToken, now.Add(time.Duration(tok.ExpiresIn) * time.Second), nil
+}
+
+// sheetWriter appends rows to a Google Sheet. Rows are buffered and sent in
+// batches since the Sheets API is rate limited per request, not per row.
+type sheetWriter struct {
+	client   *http.Client
+	account  serviceAccount
+	endpoint string
+	id       string
+	rng      string
+	token    string
+	expires  time.Time
+	rows     [][]string
+}
+
+// newSheetWriter returns a sheetWriter appending to rng (usually just the
+// sheet name) of the spreadsheet id. If the sheet is empty the csvHeader is
+// queued as the first row.
+func newSheetWriter(id, rng, keyfile string) (*sheetWriter, error) {
+	sa, err := readServiceAccount(keyfile)
+	if err != nil {
+		return nil, err
+	}
+	s := &sheetWriter{
+		client:   &http.Client{Timeout: time.Minute},
+		account:  sa,
+		endpoint: sheetsEndpoint,
+		id:       id,
+		rng:      rng,
+	}
+	return s, s.header()
+}
+
+// do makes an authorized request to the Sheets API, refreshing the access
+// token if it is about to expire.
+func (s *sheetWriter) do(method, u string, body interface{}, v interface{}) error {
+	if s.token == "" || time.Now().Add(time.Minute).After(s.expires) {
+		tok, exp, err := s.account.token(s.client, sheetsScope)
+		if err != nil {
+			return err
+		}
+		s.token, s.expires = tok, exp
+	}
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, u, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: %s: %s", method, u, resp.Status, strings.TrimSpace(string(b)))
+	}
+	if v != nil {
+		return json.NewDecoder(resp.Body).Decode(v)
+	}
+	return nil
+}
+
+// valuesURL builds the values endpoint for a range with an optional suffix
+// such as ":append".
+func (s *sheetWriter) valuesURL(rng, suffix string) string {
+	return fmt.Sprintf("%s/v4/spreadsheets/%s/values/%s%s",
+		s.endpoint, url.PathEscape(s.id), url.PathEscape(rng), suffix)
+}
+
+// empty reports whether the first cell of the sheet is empty.
+func (s *sheetWriter) empty() (bool, error) {
+	var vr struct {
+		Values [][]string `json:"values"`
+	}
+	if err := s.do("GET", s.valuesURL(s.rng+"!A1:A1", ""), nil, &vr); err != nil {
+		return false, err
+	}
+	return len(vr.Values) == 0, nil
+}
+
+// header queues the csvHeader if the sheet is empty.
+func (s *sheetWriter) header() error {
+	empty, err := s.empty()
+	if err == nil && empty {
+		s.rows = append(s.rows, csvHeader)
+	}
+	return err
+}
+
+// Write queues a row, sending the batch when it is full.
+func (s *sheetWriter) Write(row []string) error {
+	s.rows = append(s.rows, row)
+	if len(s.rows) >= sheetsBatch {
+		return s.Flush()
+	}
+	return nil
+}
+
+// Flush appends any queued rows to the sheet.
+func (s *sheetWriter) Flush() error {
+	if len(s.rows) == 0 {
+		return nil
+	}
+	u := s.valuesURL(s.rng, ":append") + "?valueInputOption=RAW&insertDataOption=INSERT_ROWS"
+	err := s.do("POST", u, map[string]interface{}{"values": s.rows}, nil)
+	s.rows = s.rows[:0]
+	return err
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testSheetServer fakes the token and Sheets values endpoints, recording
+// the rows appended to it.
+func testSheetServer(t *testing.T, existing [][]string) (*httptest.Server, *[][]string) {
+	var appended [][]string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		equals(t, r.FormValue("grant_type"), "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		equals(t, len(strings.Split(r.FormValue("assertion"), ".")), 3)
+		w.Write([]byte(`{"access_token": "tok", "expires_in": 3600}`))
+	})
+	mux.HandleFunc("/v4/spreadsheets/sid/values/", func(w http.ResponseWriter, r *http.Request) {
+		equals(t, r.Header.Get("Authorization"), "Bearer tok")
+		if r.Method == "GET" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"values": existing})
+			return
+		}
+		equals(t, strings.HasSuffix(r.URL.Path, "Sheet1:append"), true)
+		var body struct {
+			Values [][]string `json:"values"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		appended = append(appended, body.Values...)
+		w.Write([]byte(`{}`))
+	})
+	return httptest.NewServer(mux), &appended
+}
+
+func testServiceAccount(t *testing.T, tokenURI string) serviceAccount {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Error generating key: %s", err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	return serviceAccount{
+		ClientEmail: "chkmd@example.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    tokenURI,
+	}
+}
+
+func TestSheetWriter(t *testing.T) {
+	values := []struct {
+		existing [][]string
+		want     [][]string
+	}{
+		{nil, [][]string{csvHeader, {"a.jpg", "Accepted"}, {"b.jpg", "Incomplete"}}},
+		{[][]string{{"Path"}}, [][]string{{"a.jpg", "Accepted"}, {"b.jpg", "Incomplete"}}},
+	}
+	for _, v := range values {
+		ts, appended := testSheetServer(t, v.existing)
+		s := &sheetWriter{
+			client:   ts.Client(),
+			account:  testServiceAccount(t, ts.URL+"/token"),
+			endpoint: ts.URL,
+			id:       "sid",
+			rng:      "Sheet1",
+		}
+		equals(t, s.header(), nil)
+		equals(t, s.Write([]string{"a.jpg", "Accepted"}), nil)
+		equals(t, s.Write([]string{"b.jpg", "Incomplete"}), nil)
+		equals(t, len(*appended), 0)
+		equals(t, s.Flush(), nil)
+		equals(t, *appended, v.want)
+		ts.Close()
+	}
+}
+
+func TestMultiWriter(t *testing.T) {
+	var a, b rowRecorder
+	mw := multiWriter{&a, &b}
+	equals(t, mw.Write([]string{"one"}), nil)
+	equals(t, a.rows, [][]string{{"one"}})
+	equals(t, b.rows, [][]string{{"one"}})
+}
+
+// rowRecorder is a rowWriter that keeps everything written to it.
+type rowRecorder struct {
+	rows [][]string
+}
+
+func (r *rowRecorder) Write(row []string) error {
+	r.rows = append(r.rows, row)
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// How to resolve an XMP tag set differently in the file and its sidecar.
+const (
+	embeddedWins    = "embedded-wins"
+	sidecarWins     = "sidecar-wins"
+	conflictWarning = "conflict-warning"
+)
+
+// Where a file's XMP can come from, for sidecar_precedence in the config.
+// Some tools name a sidecar img.xmp and others img.jpg.xmp, which tells apart
+// img.jpg and img.cr2.
+const (
+	fromEmbedded   = "embedded"
+	fromExtSidecar = "file.ext.xmp"
+	fromSidecar    = "file.xmp"
+)
+
+// sidecarPolicy returns the SidecarConflicts policy to use.
+func (c config) sidecarPolicy() string {
+	if c.SidecarConflicts == "" {
+		return embeddedWins
+	}
+	return c.SidecarConflicts
+}
+
+// sidecarPrecedence returns where to read XMP from, highest precedence first:
+// SidecarPrecedence if it's set, or else the embedded XMP then the sidecars,
+// or for sidecarWins the sidecars first.
+func (c config) sidecarPrecedence() []string {
+	switch {
+	case len(c.SidecarPrecedence) > 0:
+		return c.SidecarPrecedence
+	case c.sidecarPolicy() == sidecarWins:
+		return []string{fromExtSidecar, fromSidecar, fromEmbedded}
+	}
+	return []string{fromEmbedded, fromExtSidecar, fromSidecar}
+}
+
+// validSidecarPrecedence returns an error if order has a source we don't know
+// or has one twice.
+func validSidecarPrecedence(order []string) error {
+	seen := map[string]bool{}
+	for _, from := range order {
+		switch {
+		case from != fromEmbedded && from != fromExtSidecar && from != fromSidecar:
+			return fmt.Errorf("sidecar_precedence has %q, expected %s, %s or %s", from, fromEmbedded, fromExtSidecar, fromSidecar)
+		case seen[from]:
+			return fmt.Errorf("sidecar_precedence has %s twice", from)
+		}
+		seen[from] = true
+	}
+	return nil
+}
+
+// validSidecarPolicy returns an error if policy isn't one we know.
+func validSidecarPolicy(policy string) error {
+	switch policy {
+	case "", embeddedWins, sidecarWins, conflictWarning:
+		return nil
+	}
+	return fmt.Errorf("sidecar_conflicts is %q, expected %s, %s or %s", policy, embeddedWins, sidecarWins, conflictWarning)
+}
+
+// mergeSidecar merges the XMP tags from a sidecar into e. Tags only in one
+// are used whichever it's in. Tags in both with different values are
+// resolved by policy: with conflictWarning e's value is kept and the tag is
+// added to e.Conflicts.
+func mergeSidecar(e exif, side map[string]string, policy string) exif {
+	for tag, v := range side {
+		embedded, ok := e.XMP[tag]
+		switch {
+		case !ok || embedded == "":
+			e.XMP[tag] = v
+		case v == "" || v == embedded:
+		case policy == sidecarWins:
+			e.XMP[tag] = v
+		case policy == conflictWarning:
+			e.Conflicts = append(e.Conflicts, tag)
+		}
+	}
+	// A tag may conflict with more than one sidecar.
+	sort.Strings(e.Conflicts)
+	conflicts := e.Conflicts[:0]
+	for i, tag := range e.Conflicts {
+		if i == 0 || tag != e.Conflicts[i-1] {
+			conflicts = append(conflicts, tag)
+		}
+	}
+	e.Conflicts = conflicts
+	return e
+}
+
+// sidecarReason is the Reason for conflicts kept by conflictWarning.
+func sidecarReason(conflicts []string) string {
+	return "Sidecar conflicts: " + strings.Join(conflicts, ", ")
+}
+
+// sidecarPaths returns where p's sidecars would be, keyed by their source.
+func sidecarPaths(p string) map[string]string {
+	return map[string]string{fromExtSidecar: p + ".xmp", fromSidecar: sidecarPath(p)}
+}
+
+// sidecarExtract wraps extract so a file's XMP sidecars, if it has any, are
+// extracted too and merged: each tag is taken from the first source in order
+// that has it, and conflicts between them are resolved by policy, as
+// mergeSidecar does. Sources not in order aren't read. The rest of the
+// metadata is always the file's own. S3 objects and sidecars themselves are
+// passed through.
+func sidecarExtract(order []string, policy string, extract func(string) (exif, error)) func(string) (exif, error) {
+	if policy != conflictWarning {
+		policy = embeddedWins
+	}
+	return func(p string) (exif, error) {
+		e, err := extract(p)
+		if err != nil || isObject(p) || strings.EqualFold(filepath.Ext(p), ".xmp") {
+			return e, err
+		}
+		paths := sidecarPaths(p)
+		var layers []map[string]string
+		for _, from := range order {
+			if from == fromEmbedded {
+				layers = append(layers, e.XMP)
+				continue
+			}
+			side := paths[from]
+			if _, serr := os.Stat(side); serr != nil {
+				continue
+			}
+			s, err := extract(side)
+			if err != nil {
+				return e, fmt.Errorf("reading sidecar %s: %s", side, err)
+			}
+			layers = append(layers, s.XMP)
+		}
+		if len(layers) == 0 {
+			e.XMP = map[string]string{}
+			return e, nil
+		}
+		e.XMP = layers[0]
+		for _, l := range layers[1:] {
+			e = mergeSidecar(e, l, policy)
+		}
+		return e, nil
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeSidecar(t *testing.T) {
+	embedded := func() exif {
+		e := newExif()
+		e.XMP["Title"] = "Embedded"
+		e.XMP["Description"] = "Same"
+		e.XMP["City"] = ""
+		return e
+	}
+	side := map[string]string{"Title": "Sidecar", "Description": "Same", "City": "Houston", "Subject": "Moon"}
+
+	e := mergeSidecar(embedded(), side, embeddedWins)
+	equals(t, e.XMP, map[string]string{"Title": "Embedded", "Description": "Same", "City": "Houston", "Subject": "Moon"})
+	equals(t, len(e.Conflicts), 0)
+
+	e = mergeSidecar(embedded(), side, sidecarWins)
+	equals(t, e.XMP["Title"], "Sidecar")
+	equals(t, len(e.Conflicts), 0)
+
+	e = mergeSidecar(embedded(), side, conflictWarning)
+	equals(t, e.XMP["Title"], "Embedded")
+	equals(t, e.Conflicts, []string{"Title"})
+	equals(t, sidecarReason(e.Conflicts), "Sidecar conflicts: Title")
+}
+
+func TestSidecarExtract(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	img := filepath.Join(dir, "a.jpg")
+	ioutil.WriteFile(img, []byte("jpeg"), 0644)
+
+	extract := func(p string) (exif, error) {
+		e := newExif()
+		switch p {
+		case img:
+			e.XMP["Title"] = "Embedded"
+		case sidecarPath(img):
+			e.XMP["Title"] = "Sidecar"
+		default:
+			return e, errors.New("unexpected " + p)
+		}
+		return e, nil
+	}
+	// No sidecar yet.
+	e, err := sidecarExtract(config{SidecarConflicts: sidecarWins}.sidecarPrecedence(), sidecarWins, extract)(img)
+	equals(t, err, nil)
+	equals(t, e.XMP["Title"], "Embedded")
+
+	ioutil.WriteFile(sidecarPath(img), []byte("xmp"), 0644)
+	e, err = sidecarExtract(config{SidecarConflicts: sidecarWins}.sidecarPrecedence(), sidecarWins, extract)(img)
+	equals(t, err, nil)
+	equals(t, e.XMP["Title"], "Sidecar")
+
+	// img.jpg.xmp comes before img.xmp, and the embedded XMP last.
+	ext := sidecarPaths(img)[fromExtSidecar]
+	ioutil.WriteFile(ext, []byte("xmp"), 0644)
+	layered := func(p string) (exif, error) {
+		e := newExif()
+		switch p {
+		case img:
+			e.XMP["Title"] = "Embedded"
+			e.XMP["City"] = "Houston"
+		case ext:
+			e.XMP["Title"] = "Ext sidecar"
+		case sidecarPath(img):
+			e.XMP["Title"] = "Sidecar"
+			e.XMP["Subject"] = "Moon"
+		}
+		return e, nil
+	}
+	e, err = sidecarExtract([]string{fromExtSidecar, fromSidecar, fromEmbedded}, conflictWarning, layered)(img)
+	equals(t, err, nil)
+	equals(t, e.XMP, map[string]string{"Title": "Ext sidecar", "City": "Houston", "Subject": "Moon"})
+	equals(t, e.Conflicts, []string{"Title"})
+
+	// Only what's listed is read.
+	e, err = sidecarExtract([]string{fromSidecar}, embeddedWins, layered)(img)
+	equals(t, err, nil)
+	equals(t, e.XMP, map[string]string{"Title": "Sidecar", "Subject": "Moon"})
+
+	// Sidecars don't have sidecars.
+	e, err = sidecarExtract([]string{fromSidecar}, embeddedWins, layered)(ext)
+	equals(t, err, nil)
+	equals(t, e.XMP["Title"], "Ext sidecar")
+}
+
+func TestSidecarPrecedence(t *testing.T) {
+	equals(t, config{}.sidecarPrecedence(), []string{fromEmbedded, fromExtSidecar, fromSidecar})
+	equals(t, config{SidecarConflicts: sidecarWins}.sidecarPrecedence(), []string{fromExtSidecar, fromSidecar, fromEmbedded})
+	equals(t, config{SidecarPrecedence: []string{fromSidecar, fromEmbedded}}.sidecarPrecedence(), []string{fromSidecar, fromEmbedded})
+
+	equals(t, validSidecarPrecedence(nil), nil)
+	equals(t, validSidecarPrecedence([]string{fromSidecar, fromEmbedded}), nil)
+	equals(t, validSidecarPrecedence([]string{"exif"}).Error(),
+		`sidecar_precedence has "exif", expected embedded, file.ext.xmp or file.xmp`)
+	equals(t, validSidecarPrecedence([]string{fromSidecar, fromSidecar}).Error(),
+		"sidecar_precedence has file.xmp twice")
+}
+
+func TestValidSidecarPolicy(t *testing.T) {
+	equals(t, validSidecarPolicy(""), nil)
+	equals(t, validSidecarPolicy(conflictWarning), nil)
+	equals(t, validSidecarPolicy("newest").Error(),
+		`sidecar_conflicts is "newest", expected embedded-wins, sidecar-wins or conflict-warning`)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// cancelOnSignal cancels the run on SIGINT or SIGTERM, so the files being
+// checked are finished and the output flushed before we exit. A second
+// signal exits straight away.
+func cancelOnSignal(cancel context.CancelFunc) {
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	s := <-sigs
+	log.Printf("Got %s, finishing the files in progress. Again to quit now.\n", s)
+	cancel()
+	<-sigs
+	os.Exit(1)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// defaultTitleSimilarity is the normalized edit distance under which a
+	// Description is considered a copy of the Title.
+	defaultTitleSimilarity = 0.2
+	similarReason          = "Description nearly identical to Title"
+)
+
+// normalizeText lowercases s and reduces punctuation and runs of spaces to
+// single spaces so they don't count as differences.
+func normalizeText(s string) []rune {
+	var out []rune
+	space := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			if space && len(out) > 0 {
+				out = append(out, ' ')
+			}
+			out = append(out, r)
+			space = false
+			continue
+		}
+		space = true
+	}
+	return out
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// textDistance is the levenshtein distance of the normalized strings divided
+// by the longer length, so 0 is identical and 1 is nothing in common.
+func textDistance(a, b string) float64 {
+	na, nb := normalizeText(a), normalizeText(b)
+	n := len(na)
+	if len(nb) > n {
+		n = len(nb)
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(levenshtein(na, nb)) / float64(n)
+}
+
+// DescriptionLikeTitle returns true if there is a Title and Description and
+// the Description is within threshold of being the Title, i.e. someone
+// pasted the title in as the description.
+func (e exif) DescriptionLikeTitle(threshold float64) bool {
+	t, d := e.Title(), e.Description()
+	if t == "" || d == "" {
+		return false
+	}
+	return textDistance(t, d) < threshold
+}
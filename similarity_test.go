@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestLevenshtein(t *testing.T) {
+	values := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"汉字", "漢字", 1},
+		{"moon", "", 4},
+	}
+	for _, v := range values {
+		equals(t, levenshtein([]rune(v.a), []rune(v.b)), v.want)
+	}
+}
+
+func TestDescriptionLikeTitle(t *testing.T) {
+	values := []struct {
+		title, desc string
+		want        bool
+	}{
+		{"Apollo 11 Launch", "Apollo 11 launch.", true},
+		{"Apollo 11 Launch", "  apollo-11   launch ", true},
+		{"Apollo 11 Launch", "Apollo 11 Launches", true},
+		{"Apollo 11 Launch", "The Saturn V carrying Apollo 11 lifts off from pad 39A.", false},
+		{"", "Apollo 11 launch", false},
+		{"Apollo 11 Launch", "", false},
+	}
+	for _, v := range values {
+		e := newExif()
+		e.IPTC["ObjectName"] = v.title
+		e.IPTC["Caption-Abstract"] = v.desc
+		equals(t, e.DescriptionLikeTitle(defaultTitleSimilarity), v.want)
+		equals(t, e.DescriptionLikeTitle(-1), false)
+	}
+}
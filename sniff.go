@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// sniffLen is how much of a file sniffType reads, as http.DetectContentType
+// considers no more.
+const sniffLen = 512
+
+// magic is the signature of a MIME type: the bytes at offset.
+type magic struct {
+	offset int
+	sig    string
+	typ    string
+}
+
+// magics are the signatures of the media types http.DetectContentType
+// doesn't know, or names differently than their extensions are known by,
+// checked in order. ftyp brands are checked apart, by ftypType.
+var magics = []magic{
+	// A CR2 is a TIFF too, so it's first.
+	{8, "CR\x02", "image/x-canon-cr2"},
+	{0, "II*\x00", "image/tiff"},
+	{0, "MM\x00*", "image/tiff"},
+	{0, "8BPS", "image/x-photoshop"},
+	{8, "WAVE", "audio/x-wav"},
+	{8, "AIFF", "audio/x-aiff"},
+	{8, "AIFC", "audio/x-aiff"},
+	{0, "fLaC", "audio/flac"},
+	{0, "ID3", "audio/mpeg"},
+	{0, "FLV\x01", "video/x-flv"},
+	{0, "#!AMR-WB\n", "audio/amr-wb"},
+	{0, "#!AMR\n", "audio/amr"},
+	{0, "\x1a\x45\xdf\xa3", "video/webm"},
+	// QuickTime movies from before ftyp start with one of these atoms.
+	{4, "moov", "video/quicktime"},
+	{4, "mdat", "video/quicktime"},
+	{4, "wide", "video/quicktime"},
+	{4, "pnot", "video/quicktime"},
+}
+
+// ftypType returns the type of an ISO base media file by its major brand:
+// QuickTime, a Canon CR3, audio only MPEG-4, or other MPEG-4. It's "" if b
+// isn't one.
+func ftypType(b []byte) string {
+	if len(b) < 12 || string(b[4:8]) != "ftyp" {
+		return ""
+	}
+	switch string(b[8:12]) {
+	case "qt  ":
+		return "video/quicktime"
+	case "crx ":
+		return "image/x-canon-cr3"
+	case "M4A ", "M4B ":
+		return "audio/mp4"
+	}
+	return "video/mp4"
+}
+
+// sniffType returns the MIME type of the first bytes of a file, b, by their
+// signature, like libmagic, rather than the file's name. It is
+// http.DetectContentType, less any parameters, for what magics don't list.
+func sniffType(b []byte) string {
+	if t := ftypType(b); t != "" {
+		return t
+	}
+	for _, m := range magics {
+		if len(b) >= m.offset+len(m.sig) && bytes.Equal(b[m.offset:m.offset+len(m.sig)], []byte(m.sig)) {
+			return m.typ
+		}
+	}
+	// An MPEG audio frame without ID3 tags.
+	if len(b) >= 2 && b[0] == 0xff && b[1]&0xe0 == 0xe0 && b[1]&0x06 != 0 {
+		return "audio/mpeg"
+	}
+	t := http.DetectContentType(b)
+	if i := strings.Index(t, ";"); i >= 0 {
+		t = t[:i]
+	}
+	return t
+}
+
+// sniffed is whether, with -sniff, the file at p is relevant by its content,
+// counting it if so.
+func (r *runner) sniffed(p string, stats *statistics) bool {
+	if !r.sniff {
+		return false
+	}
+	t, err := sniffFile(p)
+	if err != nil {
+		if r.verbose {
+			log.Printf("Error sniffing %s: %s\n", displayPath(p), err)
+		}
+		return false
+	}
+	if !r.types[t] {
+		return false
+	}
+	atomic.AddInt32(&stats.Sniffed, 1)
+	if r.verbose {
+		log.Printf("%s is %s by its content\n", displayPath(p), t)
+	}
+	return true
+}
+
+// sniffFile returns the MIME type of the file at p by its content, see
+// sniffType.
+func sniffFile(p string) (string, error) {
+	f, err := os.Open(exiftoolPath(p))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	b := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, b)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return sniffType(b[:n]), nil
+}
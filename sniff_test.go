@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSniffType(t *testing.T) {
+	for b, want := range map[string]string{
+		"II*\x00\x08\x00\x00\x00":              "image/tiff",
+		"MM\x00*\x00\x00\x00\x08":              "image/tiff",
+		"\xff\xd8\xff\xe0\x00\x10JFIF":         "image/jpeg",
+		"\x00\x00\x00\x14ftypqt  \x00\x00\x00": "video/quicktime",
+		"\x00\x00\x00\x18ftypmp42\x00\x00\x00": "video/mp4",
+		"\x00\x00\x00\x08wide\x00\x00\x00\x00": "video/quicktime",
+		"RIFF\x24\x00\x00\x00WAVEfmt ":         "audio/x-wav",
+		"ID3\x04\x00\x00\x00\x00\x00\x00":      "audio/mpeg",
+		"\xff\xfb\x90\x64":                     "audio/mpeg",
+		"8BPS\x00\x01":                         "image/x-photoshop",
+		"plain old text":                       "text/plain",
+		"":                                     "text/plain",
+	} {
+		equals(t, []string{b, sniffType([]byte(b))}, []string{b, want})
+	}
+}
+
+func TestSniffWalk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"scan.dat":  "II*\x00\x08\x00\x00\x00",
+		"movie":     "\x00\x00\x00\x14ftypqt  \x00\x00\x00",
+		"notes.txt": "not media",
+		"photo.jpg": "",
+	} {
+		equals(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), nil)
+	}
+
+	walk := func(sniff bool) ([]string, *statistics) {
+		r := newTestRunner(t, "test-config.yaml")
+		r.sniff = sniff
+		files := make(chan string, 10)
+		stats := &statistics{}
+		equals(t, filepath.Walk(dir, r.makeWalker(context.Background(), shard{}, files, stats)), nil)
+		close(files)
+		var got []string
+		for f := range files {
+			got = append(got, filepath.Base(f))
+		}
+		return got, stats
+	}
+	got, stats := walk(false)
+	equals(t, got, []string{"photo.jpg"})
+	equals(t, stats.Sniffed, int32(0))
+	got, stats = walk(true)
+	equals(t, got, []string{"movie", "photo.jpg", "scan.dat"})
+	equals(t, []int32{stats.Total, stats.Relevant, stats.Sniffed}, []int32{4, 3, 2})
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessHints returns when the file was last accessed, and whether it looks
+// offline, i.e. migrated to tape by an HSM, which leaves a stub with fewer
+// blocks on disk than its size.
+func accessHints(fi os.FileInfo) (time.Time, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec)), st.Blocks*512 < fi.Size()
+}
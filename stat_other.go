@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// accessHints has no hints where we don't know how the platform records
+// them.
+func accessHints(fi os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
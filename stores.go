@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+// objectStore is a cloud store of objects in buckets that can be listed and
+// downloaded: S3, Google Cloud Storage or Azure Blob Storage. Their objects
+// are checked by walkObjects and objectExtract alike.
+type objectStore interface {
+	// list calls fn with each key under prefix in bucket, and its size.
+	list(bucket, prefix string, fn func(key string, size int64) error) error
+	// download copies the object to w.
+	download(bucket, key string, w io.Writer) error
+}
+
+// objectSchemes are the URI schemes of the object stores, s3://, gs:// and
+// az://, in which the bucket is an Azure container.
+var objectSchemes = []string{"s3", "gs", "az"}
+
+// parseObjectURI splits an object store URI, like gs://bucket/prefix, into
+// its scheme, bucket and key or prefix.
+func parseObjectURI(s string) (scheme, bucket, key string, ok bool) {
+	for _, sc := range objectSchemes {
+		if !strings.HasPrefix(s, sc+"://") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(s, sc+"://"), "/", 2)
+		if parts[0] == "" {
+			return "", "", "", false
+		}
+		if len(parts) == 2 {
+			key = parts[1]
+		}
+		return sc, parts[0], key, true
+	}
+	return "", "", "", false
+}
+
+// isObject is whether p is in an object store rather than on disk.
+func isObject(p string) bool {
+	_, _, _, ok := parseObjectURI(p)
+	return ok
+}
+
+// objectStores are the stores for each scheme in use.
+type objectStores map[string]objectStore
+
+// newObjectStores returns the stores for the object store URIs, configured
+// from the environment.
+func newObjectStores(uris ...string) (objectStores, error) {
+	stores := objectStores{}
+	for _, u := range uris {
+		scheme, _, _, ok := parseObjectURI(u)
+		if !ok || stores[scheme] != nil {
+			continue
+		}
+		var err error
+		switch scheme {
+		case "s3":
+			stores[scheme], err = newS3Client()
+		case "gs":
+			stores[scheme], err = newGCSClient()
+		case "az":
+			stores[scheme], err = newAzureClient()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stores, nil
+}
+
+// download copies the object at the URI p to w.
+func (st objectStores) download(p string, w io.Writer) error {
+	scheme, bucket, key, ok := parseObjectURI(p)
+	if !ok || st[scheme] == nil {
+		return fmt.Errorf("can't read %s", p)
+	}
+	return st[scheme].download(bucket, key, w)
+}
+
+// s3 returns the S3 client, or nil if there's no S3 URI.
+func (st objectStores) s3() *s3Client {
+	c, _ := st["s3"].(*s3Client)
+	return c
+}
+
+// walkObjects is makeWalker for object stores. It lists the objects under
+// uri in st and sends those in the shard with relevant extensions to the
+// files channel as URIs like uri.
+func walkObjects(ctx context.Context, st objectStore, uri string, sh shard, files chan string, stats *statistics, types map[string]bool) error {
+	scheme, bucket, prefix, ok := parseObjectURI(uri)
+	if !ok {
+		return fmt.Errorf("not an object store URI: %s", uri)
+	}
+	return st.list(bucket, prefix, func(key string, size int64) error {
+		p := scheme + "://" + bucket + "/" + key
+		if strings.HasSuffix(key, "/") || !sh.mine(p) {
+			return nil
+		}
+		atomic.AddInt32(&stats.Total, 1)
+		if types[mime.TypeByExtension(path.Ext(key))] {
+			if err := sendFile(ctx, files, p); err != nil {
+				return err
+			}
+			atomic.AddInt32(&stats.Relevant, 1)
+		}
+		return nil
+	})
+}
+
+// objectExtract wraps extract so objects are downloaded from their store to
+// a temporary file which is extracted and then removed. Other paths are
+// passed through.
+func objectExtract(stores objectStores, eg *egress, extract func(string) (exif, error)) func(string) (exif, error) {
+	return func(p string) (exif, error) {
+		_, _, key, ok := parseObjectURI(p)
+		if !ok {
+			return extract(p)
+		}
+		return extractDownload(path.Base(key), func(w io.Writer) error {
+			return stores.download(p, eg.meter(w))
+		}, extract)
+	}
+}
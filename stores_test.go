@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseObjectURI(t *testing.T) {
+	for _, tc := range []struct {
+		uri                 string
+		scheme, bucket, key string
+		ok                  bool
+	}{
+		{"s3://media/ksc/a.jpg", "s3", "media", "ksc/a.jpg", true},
+		{"gs://media", "gs", "media", "", true},
+		{"az://container/ksc/", "az", "container", "ksc/", true},
+		{"gs:///ksc", "", "", "", false},
+		{"/data/ksc/a.jpg", "", "", "", false},
+		{"https://example.com/a.jpg", "", "", "", false},
+	} {
+		scheme, bucket, key, ok := parseObjectURI(tc.uri)
+		equals(t, []interface{}{scheme, bucket, key, ok}, []interface{}{tc.scheme, tc.bucket, tc.key, tc.ok})
+		equals(t, isObject(tc.uri), tc.ok)
+	}
+}
+
+func collect(t *testing.T, st objectStore, uri string) []string {
+	files := make(chan string, 10)
+	stats := &statistics{}
+	readConfig("")
+	equals(t, walkObjects(context.Background(), st, uri, shard{}, files, stats, defaultTypeSet), nil)
+	close(files)
+	var got []string
+	for f := range files {
+		got = append(got, f)
+	}
+	return got
+}
+
+func TestGCSClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/storage/v1/b/media/o" && r.URL.Query().Get("pageToken") == "":
+			equals(t, r.URL.Query().Get("prefix"), "ksc/")
+			fmt.Fprint(w, `{"nextPageToken": "next", "items": [{"name": "ksc/a.jpg", "size": "10"}, {"name": "ksc/notes.txt", "size": "10"}]}`)
+		case r.URL.Path == "/storage/v1/b/media/o":
+			fmt.Fprint(w, `{"items": [{"name": "ksc/b b.mp4", "size": "10"}]}`)
+		case r.URL.EscapedPath() == "/storage/v1/b/media/o/ksc%2Fb%20b.mp4":
+			equals(t, r.URL.Query().Get("alt"), "media")
+			fmt.Fprint(w, "not really an mp4")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	c := &gcsClient{client: ts.Client(), endpoint: ts.URL}
+
+	equals(t, collect(t, c, "gs://media/ksc/"), []string{"gs://media/ksc/a.jpg", "gs://media/ksc/b b.mp4"})
+	var b bytes.Buffer
+	equals(t, objectStores{"gs": c}.download("gs://media/ksc/b b.mp4", &b), nil)
+	equals(t, b.String(), "not really an mp4")
+}
+
+func TestAzureClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		equals(t, q.Get("sig"), "secret")
+		equals(t, r.Header.Get("x-ms-version"), azureVersion)
+		switch {
+		case r.URL.Path == "/media" && q.Get("marker") == "":
+			equals(t, q.Get("comp"), "list")
+			equals(t, q.Get("prefix"), "ksc/")
+			fmt.Fprint(w, `<EnumerationResults><Blobs>
+				<Blob><Name>ksc/a.jpg</Name><Properties><Content-Length>10</Content-Length></Properties></Blob>
+				<Blob><Name>ksc/notes.txt</Name><Properties><Content-Length>10</Content-Length></Properties></Blob>
+				</Blobs><NextMarker>next</NextMarker></EnumerationResults>`)
+		case r.URL.Path == "/media":
+			fmt.Fprint(w, `<EnumerationResults><Blobs>
+				<Blob><Name>ksc/b b.mp4</Name><Properties><Content-Length>10</Content-Length></Properties></Blob>
+				</Blobs><NextMarker/></EnumerationResults>`)
+		case r.URL.Path == "/media/ksc/b b.mp4":
+			fmt.Fprint(w, "not really an mp4")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	c := &azureClient{client: ts.Client(), endpoint: ts.URL, sas: url.Values{"sig": {"secret"}}}
+
+	equals(t, collect(t, c, "az://media/ksc/"), []string{"az://media/ksc/a.jpg", "az://media/ksc/b b.mp4"})
+	var b bytes.Buffer
+	equals(t, objectStores{"az": c}.download("az://media/ksc/b b.mp4", &b), nil)
+	equals(t, b.String(), "not really an mp4")
+
+	err := objectStores{"az": c}.download("az://media/ksc/missing.jpg", &b)
+	equals(t, err.Error(), "GET media/ksc/missing.jpg: 404 Not Found: 404 page not found")
+
+	// The SAS isn't in a failed request's error either.
+	ts.Close()
+	err = objectStores{"az": c}.download("az://media/ksc/a.jpg", &b)
+	equals(t, strings.HasPrefix(err.Error(), "GET media/ksc/a.jpg: "), true)
+	equals(t, strings.Contains(err.Error(), "secret"), false)
+}
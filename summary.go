@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// statusBreakdown is a rowWriter counting the files of each Status by media
+// type and by extension.
+type statusBreakdown struct {
+	mediaTypes map[string]map[string]int
+	extensions map[string]map[string]int
+}
+
+// newStatusBreakdown returns an empty statusBreakdown.
+func newStatusBreakdown() *statusBreakdown {
+	return &statusBreakdown{mediaTypes: map[string]map[string]int{}, extensions: map[string]map[string]int{}}
+}
+
+// countStatus counts a file with status under key in counts.
+func countStatus(counts map[string]map[string]int, key, status string) {
+	if key == "" {
+		key = "none"
+	}
+	if counts[key] == nil {
+		counts[key] = map[string]int{}
+	}
+	counts[key][status]++
+}
+
+// Write counts the row.
+func (sb *statusBreakdown) Write(row []string) error {
+	p, status := row[column("Path")], row[column("Status")]
+	name := filepath.Base(p)
+	if isURL(p) {
+		name = urlName(p)
+	}
+	countStatus(sb.mediaTypes, row[column("Media Type")], status)
+	countStatus(sb.extensions, strings.ToLower(filepath.Ext(name)), status)
+	return nil
+}
+
+// runSummary is the -summary file, for dashboards tracking periodic runs.
+type runSummary struct {
+	Started     time.Time `json:"started"`
+	Seconds     float64   `json:"seconds"`
+	PerSecond   float64   `json:"files_per_second"`
+	Interrupted bool      `json:"interrupted"`
+	Shard       string    `json:"shard,omitempty"`
+	Total       int32     `json:"total"`
+	Relevant    int32     `json:"relevant"`
+	Accepted    int32     `json:"accepted"`
+	// Rejected counts the Incomplete files too, as the log does.
+	Rejected int32            `json:"rejected"`
+	Counts   map[string]int32 `json:"counts"`
+	// Reasons counts why files were Incomplete, see reasonCounts.
+	Reasons map[string]int `json:"reasons"`
+	// MediaTypes and Extensions count the files of each Status by media
+	// type and extension.
+	MediaTypes map[string]map[string]int `json:"media_types"`
+	Extensions map[string]map[string]int `json:"extensions"`
+	// Quality is each delivery's metadata quality score, see scorecard.
+	Quality map[string]qualitySummary `json:"quality"`
+	// Cache is how well the -since dump worked, if there was one.
+	Cache *cacheSummary `json:"cache,omitempty"`
+}
+
+// newRunSummary returns the summary of a run started at started.
+func newRunSummary(started time.Time, stats *statistics, sb *statusBreakdown) runSummary {
+	s := runSummary{
+		Started:  started.UTC(),
+		Seconds:  time.Since(started).Seconds(),
+		Total:    stats.Total,
+		Relevant: stats.Relevant,
+		Accepted: stats.Accept,
+		Rejected: stats.Reject,
+		Counts: map[string]int32{
+			"derivatives_skipped": stats.Derived,
+			"similar":             stats.Similar,
+			"iptc_modified":       stats.Modified,
+			"bad_names":           stats.BadNames,
+			"bad_dates":           stats.BadDates,
+			"below_quality":       stats.BelowQuality,
+			"accepted_warnings":   stats.AcceptedWarnings,
+			"timed_out":           stats.TimedOut,
+			"permission_denied":   stats.PermissionDenied,
+			"empty":               stats.Empty,
+			"not_media":           stats.NotMedia,
+			"retried":             stats.Retried,
+			"warned":              stats.Warned,
+			"fixed":               stats.Fixed,
+			"ids_written":         stats.WroteID,
+			"previews":            stats.Previews,
+			"xmp_exported":        stats.XMPExported,
+			"sniffed":             stats.Sniffed,
+			"skipped":             stats.Skipped,
+			"unchanged":           stats.Unchanged,
+			"cached":              stats.Cached,
+		},
+		Reasons:    stats.Reasons.counted(),
+		MediaTypes: sb.mediaTypes,
+		Extensions: sb.extensions,
+		Quality:    stats.Quality.summary(),
+	}
+	if s.Seconds > 0 {
+		s.PerSecond = float64(stats.Accept+stats.Reject) / s.Seconds
+	}
+	return s
+}
+
+// writeSummary writes the summary as JSON to the file at p.
+func writeSummary(p string, s runSummary) error {
+	return writeJSON(p, s)
+}
+
+// writeJSON writes v as indented JSON to the file at p.
+func writeJSON(p string, v interface{}) error {
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(v)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
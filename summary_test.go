@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStatusBreakdown(t *testing.T) {
+	sb := newStatusBreakdown()
+	row := func(p, status, mediaType string) []string {
+		r := testRow(p, status, "")
+		r[column("Media Type")] = mediaType
+		return r
+	}
+	sb.Write(row("/media/a.JPG", "Accepted", "image"))
+	sb.Write(row("/media/b.jpg", "Incomplete", "image"))
+	sb.Write(row("/media/c.mp4", "Accepted", "video"))
+	sb.Write(row("/media/d", "Rejected", ""))
+	sb.Write(row("https://example.com/e.png?sig=1", "Accepted", "image"))
+	equals(t, sb.mediaTypes, map[string]map[string]int{
+		"image": {"Accepted": 2, "Incomplete": 1},
+		"video": {"Accepted": 1},
+		"none":  {"Rejected": 1},
+	})
+	equals(t, sb.extensions, map[string]map[string]int{
+		".jpg": {"Accepted": 1, "Incomplete": 1},
+		".mp4": {"Accepted": 1},
+		".png": {"Accepted": 1},
+		"none": {"Rejected": 1},
+	})
+}
+
+func TestWriteSummary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+
+	stats := &statistics{Total: 10, Relevant: 4, Accept: 3, Reject: 1, Derived: 2, Reasons: newReasonCounts(),
+		Quality: newScorecard(qualityConfig{Weights: map[string]float64{"Keywords": 1}})}
+	stats.Reasons.add("DateCreated")
+	e := newExif()
+	e.IPTC["Keywords"] = "moon"
+	stats.Quality.add("ksc", &e)
+	stats.Quality.add("ksc", nil)
+	sb := newStatusBreakdown()
+	sb.Write(testRow("a.jpg", "Accepted", ""))
+	s := newRunSummary(time.Now().Add(-2*time.Second), stats, sb)
+	equals(t, s.PerSecond > 1.9 && s.PerSecond <= 2, true)
+
+	p := filepath.Join(dir, "stats.json")
+	equals(t, writeSummary(p, s), nil)
+	b, err := ioutil.ReadFile(p)
+	equals(t, err, nil)
+	var got map[string]interface{}
+	equals(t, json.Unmarshal(b, &got), nil)
+	equals(t, got["total"], 10.0)
+	equals(t, got["accepted"], 3.0)
+	equals(t, got["counts"].(map[string]interface{})["derivatives_skipped"], 2.0)
+	equals(t, got["reasons"], map[string]interface{}{"DateCreated": 1.0})
+	equals(t, got["extensions"], map[string]interface{}{".jpg": map[string]interface{}{"Accepted": 1.0}})
+	equals(t, got["quality"], map[string]interface{}{"ksc": map[string]interface{}{"score": 50.0, "files": 2.0}})
+}
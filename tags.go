@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// What exiftool extracts, for tags in the exiftool section of the config.
+const (
+	// tagsAll extracts every tag, the default.
+	tagsAll = "all"
+	// tagsFields extracts just the tags the fields are read from, and those
+	// chkmd checks itself, which is much quicker on metadata heavy TIFFs.
+	tagsFields = "fields"
+)
+
+// checkedTags are the tags read other than through fieldSources: for the
+// file's type and size, exiftool's warnings, the IPTC digest and character
+// set, GPS references and previews for -thumbnails.
+var checkedTags = []string{
+	"File:FileName", "File:Directory", "File:FileSize", "File:FileType", "File:MIMEType",
+	"Warning", "IPTCDigest", "CurrentIPTCDigest", "IPTC:CodedCharacterSet", "XMP:MetadataDate",
+	"EXIF:GPSLatitudeRef", "EXIF:GPSLongitudeRef",
+	"ThumbnailImage", "PreviewImage", "JpgFromRaw",
+}
+
+// validTags returns an error if tags isn't one we know.
+func validTags(tags string) error {
+	switch tags {
+	case "", tagsAll, tagsFields:
+		return nil
+	}
+	return fmt.Errorf("exiftool: tags is %q, expected %s or %s", tags, tagsAll, tagsFields)
+}
+
+// sourceTags returns the group:tag names exiftool extracts a source from,
+// none for sources that aren't exiftool's, like Model, directory-default and
+// filename, or that are scripts.
+func sourceTags(s source) []string {
+	i := strings.Index(s.name, ":")
+	if i <= 0 || strings.HasPrefix(s.name, scriptPrefix) {
+		return nil
+	}
+	group := s.name[:i]
+	if group == "Model" || group == "metadata.yaml" || group == folderGroup || group == "filename" {
+		return nil
+	}
+	var tags []string
+	for _, tag := range strings.Fields(s.name[i+1:]) {
+		// Extracting the tag extracts all its languages.
+		tags = append(tags, group+":"+strings.TrimSuffix(tag, langSuffix))
+	}
+	return tags
+}
+
+// args returns the exiftool options, before the path, for the config: all
+// the tags, or with tagsFields a -TAG for each tag of the fields' sources,
+// mapped being the config's fields mapping, the checkedTags, technicalTags
+// and ExtraTags.
+func (pc poolConfig) args(mapped map[string][]source) []string {
+	if pc.Tags != tagsFields {
+		return exiftoolArgs
+	}
+	seen := map[string]bool{}
+	for field, sources := range fieldSources {
+		if m, ok := mapped[field]; ok {
+			sources = m
+		}
+		for _, s := range sources {
+			for _, tag := range sourceTags(s) {
+				seen[tag] = true
+			}
+		}
+	}
+	for _, tag := range append(append(append([]string{}, checkedTags...), technicalTags...), pc.ExtraTags...) {
+		seen[tag] = true
+	}
+	var tags []string
+	for tag := range seen {
+		tags = append(tags, "-"+tag)
+	}
+	sort.Strings(tags)
+	return append(append(outputArgs(), iptcCharsetArgs...), tags...)
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestSourceTags(t *testing.T) {
+	for _, v := range []struct {
+		name string
+		want []string
+	}{
+		{"IPTC:ObjectName", []string{"IPTC:ObjectName"}},
+		{"Composite:GPSLatitude GPSLongitude", []string{"Composite:GPSLatitude", "Composite:GPSLongitude"}},
+		{"Model:Title", nil},
+		{"metadata.yaml:Album", nil},
+		{"{{.IPTC.JobID}}-{{.Data.FileName}}", nil},
+	} {
+		equals(t, sourceTags(source{name: v.name}), v.want)
+	}
+}
+
+func TestPoolConfigArgs(t *testing.T) {
+	equals(t, poolConfig{}.args(nil), exiftoolArgs)
+	equals(t, poolConfig{Tags: tagsAll}.args(nil), exiftoolArgs)
+
+	has := func(args []string) map[string]bool {
+		m := map[string]bool{}
+		for _, a := range args {
+			m[a] = true
+		}
+		return m
+	}
+	args := has(poolConfig{Tags: tagsFields}.args(nil))
+	equals(t, args["-all"], false)
+	equals(t, args["-IPTC:ObjectName"], true)
+	equals(t, args["-Exif:GPSLatitude"], true)
+	equals(t, args["-EXIF:GPSLatitudeRef"], true)
+	equals(t, args["-File:MIMEType"], true)
+	equals(t, args["-CurrentIPTCDigest"], true)
+
+	sc, err := compileScripts("def headline(file):\n    return file.IPTC[\"Headline\"]\n")
+	equals(t, err, nil)
+	mapped, err := fieldMapping{"Title": {"XMP:Headline", "script:headline"}}.sources(sc)
+	equals(t, err, nil)
+	args = has(poolConfig{Tags: tagsFields, ExtraTags: []string{"IPTC:Headline"}}.args(mapped))
+	equals(t, args["-XMP:Headline"], true)
+	equals(t, args["-IPTC:Headline"], true)
+	equals(t, args["-IPTC:ObjectName"], false)
+
+	equals(t, validTags(""), nil)
+	equals(t, validTags(tagsFields), nil)
+	equals(t, validTags("some").Error(), `exiftool: tags is "some", expected all or fields`)
+}
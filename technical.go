@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+)
+
+// technicalColumns are only in the CSV output with -technical or when the
+// config's columns list them.
+var technicalColumns = []string{"Image Width", "Image Height", "Orientation", "Color Profile", "Bit Depth"}
+
+// technicalTags are the tags the technicalColumns and thresholds are read
+// from, which exiftool puts in the File, PNG or QuickTime group, and so on,
+// by format.
+var technicalTags = []string{
+	"ImageWidth", "ImageHeight", "Composite:ImageSize", "EXIF:ExifImageWidth", "EXIF:ExifImageHeight",
+	"EXIF:Orientation", "XMP:Orientation", "EXIF:ColorSpace", "ICC_Profile:ProfileDescription",
+	"BitsPerSample", "BitDepth", "Duration", "RIFF:SampleRate", "AudioSampleRate", "SampleRate",
+}
+
+// ImageWidth returns the width of the image or video in pixels, from the
+// file itself before the Exif, which editors don't always update.
+func (e exif) ImageWidth() string {
+	return e.dimension("ImageWidth", 0)
+}
+
+// ImageHeight returns the height of the image or video in pixels.
+func (e exif) ImageHeight() string {
+	return e.dimension("ImageHeight", 1)
+}
+
+// dimension returns the tag, or the Exif's, or the part i of the Composite
+// ImageSize, which is like 4000x3000.
+func (e exif) dimension(tag string, i int) string {
+	if v := e.Data[tag]; v != "" {
+		return v
+	}
+	if v := e.Exif["Exif"+tag]; v != "" {
+		return v
+	}
+	parts := strings.FieldsFunc(e.Data["ImageSize"], func(r rune) bool { return r == 'x' || r == ' ' })
+	if len(parts) == 2 {
+		return parts[i]
+	}
+	return ""
+}
+
+// Orientation returns how the image is to be rotated or flipped for
+// display, like "Rotate 90 CW".
+func (e exif) Orientation() string {
+	if v := e.Exif["Orientation"]; v != "" {
+		return v
+	}
+	return e.XMP["Orientation"]
+}
+
+// ColorProfile returns the name of the embedded ICC profile or, without
+// one, the Exif ColorSpace.
+func (e exif) ColorProfile() string {
+	if v := e.Data["ProfileDescription"]; v != "" {
+		return v
+	}
+	return e.Exif["ColorSpace"]
+}
+
+// BitDepth returns the bits per sample, once if every channel has the same,
+// as they usually do.
+func (e exif) BitDepth() string {
+	v := e.Data["BitDepth"]
+	if v == "" {
+		v = e.Data["BitsPerSample"]
+	}
+	if v == "" {
+		v = e.Exif["BitsPerSample"]
+	}
+	bits := strings.Fields(v)
+	for _, b := range bits {
+		if b != bits[0] {
+			return v
+		}
+	}
+	if len(bits) > 0 {
+		return bits[0]
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTechnical(t *testing.T) {
+	e, err := parseExifOutput(`[{
+  "SourceFile": "a.jpg",
+  "File:ImageWidth": 4000,
+  "File:ImageHeight": 3000,
+  "File:BitsPerSample": 8,
+  "EXIF:Orientation": "Rotate 90 CW",
+  "EXIF:ColorSpace": "sRGB",
+  "EXIF:ExifImageWidth": 6000,
+  "ICC_Profile:ProfileDescription": "Adobe RGB (1998)"
+}]`)
+	equals(t, err, nil)
+	equals(t, []string{e.ImageWidth(), e.ImageHeight(), e.Orientation(), e.ColorProfile(), e.BitDepth()},
+		[]string{"4000", "3000", "Rotate 90 CW", "Adobe RGB (1998)", "8"})
+
+	// A TIFF, with its size only in the Exif and Composite.
+	e = newExif()
+	e.Exif["ExifImageWidth"], e.Data["ImageSize"] = "6000", "6000x4000"
+	e.Exif["BitsPerSample"], e.Exif["ColorSpace"] = "16 16 16", "sRGB"
+	e.XMP["Orientation"] = "Horizontal (normal)"
+	equals(t, []string{e.ImageWidth(), e.ImageHeight(), e.Orientation(), e.ColorProfile(), e.BitDepth()},
+		[]string{"6000", "4000", "Horizontal (normal)", "sRGB", "16"})
+
+	e = newExif()
+	e.Data["BitsPerSample"] = "8 8 8 16"
+	equals(t, e.BitDepth(), "8 8 8 16")
+	equals(t, []string{e.ImageWidth(), e.ImageHeight(), e.Orientation(), e.ColorProfile()}, []string{"", "", "", ""})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// defaultTopTerms is how many of the most frequent Keywords and
+// Photographers the summary lists.
+const defaultTopTerms = 10
+
+// termFields are the columns termCounts counts, and whether each is a comma
+// separated list.
+var termFields = []struct {
+	column string
+	list   bool
+}{
+	{"Keywords", true},
+	{"Photographer", false},
+}
+
+// term is a Keyword or Photographer and how many files have it.
+type term struct {
+	Field string
+	Value string
+	Count int
+}
+
+// termCounts is a rowWriter that counts the files with each Keyword and
+// Photographer, so the taxonomy team can spot terms in common use that
+// aren't in the controlled vocabulary yet.
+type termCounts struct {
+	counts map[string]map[string]int
+}
+
+// newTermCounts returns an empty termCounts.
+func newTermCounts() *termCounts {
+	tc := &termCounts{counts: map[string]map[string]int{}}
+	for _, f := range termFields {
+		tc.counts[f.column] = map[string]int{}
+	}
+	return tc
+}
+
+// Write counts the row's Keywords and Photographer. A keyword repeated in a
+// file counts once.
+func (tc *termCounts) Write(row []string) error {
+	for _, f := range termFields {
+		values := []string{row[column(f.column)]}
+		if f.list {
+			values = splitKeywords(values[0])
+		}
+		seen := map[string]bool{}
+		for _, v := range values {
+			v = strings.TrimSpace(v)
+			if v != "" && !seen[v] {
+				seen[v] = true
+				tc.counts[f.column][v]++
+			}
+		}
+	}
+	return nil
+}
+
+// top returns up to n of field's terms, most frequent first. n < 0 returns
+// them all.
+func (tc *termCounts) top(field string, n int) []term {
+	var ts []term
+	for v, c := range tc.counts[field] {
+		ts = append(ts, term{field, v, c})
+	}
+	sort.Slice(ts, func(i, j int) bool {
+		if ts[i].Count != ts[j].Count {
+			return ts[i].Count > ts[j].Count
+		}
+		return ts[i].Value < ts[j].Value
+	})
+	if n >= 0 && len(ts) > n {
+		ts = ts[:n]
+	}
+	return ts
+}
+
+// summarize describes the top n terms of each field, a line each.
+func (tc *termCounts) summarize(n int) string {
+	var b strings.Builder
+	for _, f := range termFields {
+		ts := tc.top(f.column, n)
+		if len(ts) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\nMost frequent %s:\n", f.column)
+		for _, t := range ts {
+			fmt.Fprintf(&b, "%d files: %q\n", t.Count, t.Value)
+		}
+	}
+	return b.String()
+}
+
+// writeTerms writes a CSV of every Keyword and Photographer with its count.
+func writeTerms(p string, tc *termCounts) error {
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	err = w.Write([]string{"Field", "Term", "Count"})
+	for _, field := range termFields {
+		for _, t := range tc.top(field.column, -1) {
+			if err == nil {
+				err = w.Write([]string{t.Field, t.Value, fmt.Sprint(t.Count)})
+			}
+		}
+	}
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
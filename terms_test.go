@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTermCounts(t *testing.T) {
+	tc := newTermCounts()
+	row := func(kw, photographer string) []string {
+		r := make([]string, len(csvHeader))
+		r[column("Keywords")], r[column("Photographer")] = kw, photographer
+		return r
+	}
+	tc.Write(row("ISS, Expedition 43, spacewalk", "Terry Virts"))
+	tc.Write(row("ISS, spacewalk, ISS", "Terry Virts"))
+	tc.Write(row("ISS", "Bill Ingalls"))
+	tc.Write(row("", ""))
+
+	equals(t, tc.top("Keywords", -1), []term{
+		{"Keywords", "ISS", 3},
+		{"Keywords", "spacewalk", 2},
+		{"Keywords", "Expedition 43", 1},
+	})
+	equals(t, tc.top("Photographer", 1), []term{{"Photographer", "Terry Virts", 2}})
+	equals(t, tc.summarize(1), "\nMost frequent Keywords:\n3 files: \"ISS\"\n\nMost frequent Photographer:\n2 files: \"Terry Virts\"\n")
+	equals(t, newTermCounts().summarize(10), "")
+
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "terms.csv")
+	equals(t, writeTerms(p, tc), nil)
+	b, err := ioutil.ReadFile(p)
+	equals(t, err, nil)
+	equals(t, string(b), `Field,Term,Count
+Keywords,ISS,3
+Keywords,spacewalk,2
+Keywords,Expedition 43,1
+Photographer,Terry Virts,2
+Photographer,Bill Ingalls,1
+`)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// statusBelowQuality is the Status of a file whose metadata may be fine but
+// whose resolution, length or sample rate is under the thresholds, which
+// unlike missing metadata can't be fixed.
+const statusBelowQuality = "Below Quality Threshold"
+
+// thresholdConfig is the thresholds section of the config, the technical
+// minimums for AVAIL: megapixels for images, seconds of video and the
+// sample rate of audio, in Hz. 0 is no minimum.
+type thresholdConfig struct {
+	MinMegapixels float64 `yaml:"min_megapixels"`
+	MinDuration   float64 `yaml:"min_duration"`
+	MinSampleRate int     `yaml:"min_sample_rate"`
+}
+
+// validate checks the thresholds aren't negative.
+func (tc thresholdConfig) validate() error {
+	if tc.MinMegapixels < 0 || tc.MinDuration < 0 || tc.MinSampleRate < 0 {
+		return fmt.Errorf("thresholds: can't be negative")
+	}
+	return nil
+}
+
+// below returns how e falls short of the thresholds for its media type,
+// none if it doesn't or they can't be told.
+func (tc thresholdConfig) below(e exif) []string {
+	var problems []string
+	switch strings.Split(e.Data["MIMEType"], "/")[0] {
+	case "image":
+		if mp, ok := e.Megapixels(); ok && tc.MinMegapixels > 0 && mp < tc.MinMegapixels {
+			problems = append(problems, fmt.Sprintf("%.1f megapixels under %g", mp, tc.MinMegapixels))
+		}
+	case "video":
+		if d, ok := e.Duration(); ok && tc.MinDuration > 0 && d < tc.MinDuration {
+			problems = append(problems, fmt.Sprintf("%.1f seconds under %g", d, tc.MinDuration))
+		}
+	case "audio":
+		if sr, ok := e.SampleRate(); ok && tc.MinSampleRate > 0 && sr < tc.MinSampleRate {
+			problems = append(problems, fmt.Sprintf("Sample rate %d Hz under %d", sr, tc.MinSampleRate))
+		}
+	}
+	return problems
+}
+
+// Megapixels returns the image's width times height in millions, and
+// whether both are known.
+func (e exif) Megapixels() (float64, bool) {
+	w, werr := strconv.Atoi(e.ImageWidth())
+	h, herr := strconv.Atoi(e.ImageHeight())
+	if werr != nil || herr != nil {
+		return 0, false
+	}
+	return float64(w) * float64(h) / 1e6, true
+}
+
+// Duration returns the length of the video or audio in seconds, and whether
+// it's known. exiftool writes it like 12.34 s under 30 seconds and 0:01:23
+// over, either followed by (approx) if it's estimated.
+func (e exif) Duration() (float64, bool) {
+	d := strings.TrimSpace(strings.TrimSuffix(e.Data["Duration"], "(approx)"))
+	if d == "" {
+		return 0, false
+	}
+	if !strings.Contains(d, ":") {
+		s, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(d, "s")), 64)
+		return s, err == nil
+	}
+	var seconds float64
+	for _, part := range strings.Split(d, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, false
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds, true
+}
+
+// SampleRate returns the audio's samples per second, and whether it's
+// known: a WAV's from its RIFF format chunk, a video's audio track's, or an
+// MP3 or FLAC's own.
+func (e exif) SampleRate() (int, bool) {
+	for _, v := range []string{e.RIFF["SampleRate"], e.Data["AudioSampleRate"], e.Data["SampleRate"]} {
+		if sr, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return sr, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestThresholds(t *testing.T) {
+	tc := thresholdConfig{MinMegapixels: 4, MinDuration: 10, MinSampleRate: 44100}
+	image := func(w, h string) exif {
+		e := newExif()
+		e.Data["MIMEType"], e.Data["ImageWidth"], e.Data["ImageHeight"] = "image/jpeg", w, h
+		return e
+	}
+	equals(t, tc.below(image("2000", "1500")), []string{"3.0 megapixels under 4"})
+	equals(t, tc.below(image("4000", "3000")), []string(nil))
+	equals(t, tc.below(image("", "")), []string(nil))
+	equals(t, thresholdConfig{}.below(image("20", "15")), []string(nil))
+
+	video := func(d string) exif {
+		e := newExif()
+		e.Data["MIMEType"], e.Data["Duration"] = "video/mp4", d
+		return e
+	}
+	equals(t, tc.below(video("4.52 s")), []string{"4.5 seconds under 10"})
+	equals(t, tc.below(video("0:01:23")), []string(nil))
+	equals(t, tc.below(video("9.9 s (approx)")), []string{"9.9 seconds under 10"})
+
+	audio := newExif()
+	audio.Data["MIMEType"], audio.RIFF["SampleRate"] = "audio/x-wav", "22050"
+	equals(t, tc.below(audio), []string{"Sample rate 22050 Hz under 44100"})
+	audio.RIFF["SampleRate"], audio.Data["SampleRate"] = "", "48000"
+	equals(t, tc.below(audio), []string(nil))
+}
+
+func TestDuration(t *testing.T) {
+	for _, tc := range []struct {
+		d    string
+		want float64
+		ok   bool
+	}{
+		{"12.34 s", 12.34, true},
+		{"0:01:23", 83, true},
+		{"1:00:00 (approx)", 3600, true},
+		{"30", 30, true},
+		{"", 0, false},
+		{"soon", 0, false},
+	} {
+		e := newExif()
+		e.Data["Duration"] = tc.d
+		d, ok := e.Duration()
+		equals(t, []interface{}{d, ok}, []interface{}{tc.want, tc.ok})
+	}
+}
+
+func TestThresholdConfigValidate(t *testing.T) {
+	equals(t, thresholdConfig{MinMegapixels: 2}.validate(), nil)
+	equals(t, thresholdConfig{MinDuration: -1}.validate().Error(), "thresholds: can't be negative")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// previewTags are the embedded previews exiftool can extract, smallest
+// first.
+var previewTags = []string{"ThumbnailImage", "PreviewImage", "JpgFromRaw"}
+
+// thumbnails copies each checked file's embedded preview into dir, for
+// -thumbnails, so curators triaging the report can see what a file is
+// without opening it on slow storage. The previews are laid out like the
+// files under their root, named for the file with .jpg added.
+type thumbnails struct {
+	dir   string
+	roots dirList
+}
+
+// newThumbnails returns a thumbnails for the files under roots, making dir.
+func newThumbnails(dir string, roots ...string) (*thumbnails, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &thumbnails{dir: dir, roots: roots}, nil
+}
+
+// path returns where the preview of the file at p goes.
+func (th *thumbnails) path(p string) string {
+	rel, err := filepath.Rel(th.roots.rootOf(p), p)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(p)
+	}
+	return filepath.Join(th.dir, rel+".jpg")
+}
+
+// tag returns the smallest preview exiftool found in e, or "".
+func (th *thumbnails) tag(e exif) string {
+	for _, t := range previewTags {
+		if e.Exif[t] != "" || e.Data[t] != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+// extract writes the preview of the file at p, if it has one, returning
+// whether it did. S3 objects, URLs and files in archives are gone by the
+// time they're checked, so they have none.
+func (th *thumbnails) extract(p string, e exif) (bool, error) {
+	tag := th.tag(e)
+	if tag == "" || isURL(p) || isObject(p) || isArchiveMember(p) {
+		return false, nil
+	}
+	cmd := detach(exec.Command("exiftool", "-b", "-"+tag, exiftoolPath(p)))
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	b, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("exiftool: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if len(b) == 0 {
+		return false, errors.New("exiftool: empty " + tag)
+	}
+	out := th.path(p)
+	if err = os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return false, err
+	}
+	return true, ioutil.WriteFile(out, b, 0644)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestThumbnailPath(t *testing.T) {
+	th := &thumbnails{dir: "review", roots: dirList{"/archive"}}
+	values := []struct {
+		p    string
+		want string
+	}{
+		{"/archive/jsc2015e012345.jpg", "review/jsc2015e012345.jpg.jpg"},
+		{"/archive/2015/jsc2015e012345.tif", "review/2015/jsc2015e012345.tif.jpg"},
+		// Outside root, e.g. from -from, it's just the file name.
+		{"/elsewhere/jsc2015e012345.tif", "review/jsc2015e012345.tif.jpg"},
+	}
+	for _, v := range values {
+		equals(t, th.path(v.p), filepath.FromSlash(v.want))
+	}
+}
+
+func TestThumbnailTag(t *testing.T) {
+	th := &thumbnails{}
+	e := newExif()
+	equals(t, th.tag(e), "")
+	e.Data["PreviewImage"] = "(Binary data 61463 bytes, use -b option to extract)"
+	equals(t, th.tag(e), "PreviewImage")
+	e.Exif["ThumbnailImage"] = "(Binary data 5024 bytes, use -b option to extract)"
+	equals(t, th.tag(e), "ThumbnailImage")
+}
+
+func TestThumbnailSkips(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	th, err := newThumbnails(filepath.Join(dir, "review"), dir)
+	equals(t, err, nil)
+
+	// No preview, so exiftool isn't run.
+	extracted, err := th.extract(filepath.Join(dir, "image.jpg"), newExif())
+	equals(t, err, nil)
+	equals(t, extracted, false)
+
+	// S3 objects and URLs are gone by now.
+	e := newExif()
+	e.Exif["ThumbnailImage"] = "(Binary data 5024 bytes, use -b option to extract)"
+	for _, p := range []string{"s3://bucket/image.jpg", "https://example.com/image.jpg"} {
+		extracted, err = th.extract(p, e)
+		equals(t, err, nil)
+		equals(t, extracted, false)
+	}
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	defaultTicketSummary     = "chkmd: rejected assets in {{.Delivery}}"
+	defaultTicketDescription = `{{.Rejected}} of {{.Total}} assets in {{.Delivery}} ({{.Root}}) were not accepted.
+{{range $reason, $count := .Reasons}}
+ * {{$reason}}: {{$count}}{{end}}
+
+The attached report lists each file.`
+)
+
+// ticketConfig configures the -tickets integration. Summary and Description
+// are text/template strings executed with a ticketData. The password or API
+// token is read from the environment variable named by TokenEnv so it
+// doesn't end up in the config file.
+type ticketConfig struct {
+	System      string `yaml:"system"`
+	URL         string `yaml:"url"`
+	User        string `yaml:"user"`
+	TokenEnv    string `yaml:"token_env"`
+	Project     string `yaml:"project"`
+	IssueType   string `yaml:"issue_type"`
+	Table       string `yaml:"table"`
+	Summary     string `yaml:"summary"`
+	Description string `yaml:"description"`
+}
+
+// ticketData is what the ticket templates are executed with.
+type ticketData struct {
+	Delivery string
+	Root     string
+	Total    int
+	Rejected int
+	Reasons  map[string]int
+}
+
+// delivery holds the rows of one delivery folder we need for its ticket.
+type delivery struct {
+	root    string
+	name    string
+	total   int
+	rows    [][]string
+	reasons map[string]int
+}
+
+// rejectCollector is a rowWriter that groups rows that weren't accepted by
+// delivery folder. The deliveries are keyed by name, or with several roots
+// by root and name, as each root may have its own "." delivery.
+type rejectCollector struct {
+	roots      dirList
+	deliveries map[string]*delivery
+}
+
+// newRejectCollector returns a rejectCollector for the trees at roots.
+func newRejectCollector(roots ...string) *rejectCollector {
+	return &rejectCollector{roots: roots, deliveries: map[string]*delivery{}}
+}
+
+// Write records the row against its delivery.
+func (rc *rejectCollector) Write(row []string) error {
+	root := rc.roots.rootOf(row[column("Path")])
+	name := deliveryOf(root, row[column("Path")])
+	key := name
+	if len(rc.roots) > 1 {
+		key = root + " " + name
+	}
+	d := rc.deliveries[key]
+	if d == nil {
+		d = &delivery{root: root, name: name, reasons: map[string]int{}}
+		rc.deliveries[key] = d
+	}
+	d.total++
+	if !accepted(row[column("Status")]) {
+		d.rows = append(d.rows, row)
+		d.reasons[row[column("Reason")]]++
+	}
+	return nil
+}
+
+// ticketer is a ticketing system we can open and update tickets in.
+type ticketer interface {
+	// find returns the id of an open ticket with the summary, or "".
+	find(summary string) (string, error)
+	create(summary, description string) (string, error)
+	update(id, description string) error
+	attach(id, name string, data []byte) error
+}
+
+// openTickets opens, or updates if one is still open, a ticket for each
+// delivery that has rejects and attaches a report of just those rows.
+func openTickets(tc ticketConfig, rc *rejectCollector) error {
+	t, err := newTicketer(tc)
+	if err != nil {
+		return err
+	}
+	if tc.Summary == "" {
+		tc.Summary = defaultTicketSummary
+	}
+	if tc.Description == "" {
+		tc.Description = defaultTicketDescription
+	}
+	summary, err := template.New("summary").Parse(tc.Summary)
+	if err != nil {
+		return err
+	}
+	description, err := template.New("description").Parse(tc.Description)
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	for key, d := range rc.deliveries {
+		if len(d.rows) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		d := rc.deliveries[key]
+		name := d.name
+		data := ticketData{name, d.root, d.total, len(d.rows), d.reasons}
+		var s, desc bytes.Buffer
+		if err = summary.Execute(&s, data); err != nil {
+			return err
+		}
+		if err = description.Execute(&desc, data); err != nil {
+			return err
+		}
+		id, err := t.find(s.String())
+		if err != nil {
+			return err
+		}
+		if id == "" {
+			id, err = t.create(s.String(), desc.String())
+		} else {
+			err = t.update(id, desc.String())
+		}
+		if err != nil {
+			return fmt.Errorf("ticket for %s: %s", name, err)
+		}
+		report, err := rejectReport(d.rows)
+		if err != nil {
+			return err
+		}
+		err = t.attach(id, reportName(d.root, name), report)
+		if err != nil {
+			return fmt.Errorf("attaching report to %s: %s", id, err)
+		}
+	}
+	return nil
+}
+
+// rejectReport renders rows as a CSV report with the usual header.
+func rejectReport(rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// reportName names the attached report after the delivery.
+func reportName(root, name string) string {
+	if name == "." {
+		name = filepath.Base(root)
+	}
+	return fmt.Sprintf("%s-rejects-%s.csv", name, time.Now().Format("20060102"))
+}
+
+// newTicketer returns the ticketer for the configured system.
+func newTicketer(tc ticketConfig) (ticketer, error) {
+	c := ticketClient{
+		client: &http.Client{Timeout: time.Minute},
+		base:   strings.TrimRight(tc.URL, "/"),
+		user:   tc.User,
+		token:  os.Getenv(tc.TokenEnv),
+	}
+	switch strings.ToLower(tc.System) {
+	case "jira":
+		if tc.IssueType == "" {
+			tc.IssueType = "Task"
+		}
+		return jira{c, tc.Project, tc.IssueType}, nil
+	case "servicenow":
+		if tc.Table == "" {
+			tc.Table = "incident"
+		}
+		return serviceNow{c, tc.Table}, nil
+	}
+	return nil, fmt.Errorf("unknown ticket system %q, expected jira or servicenow", tc.System)
+}
+
+// ticketClient makes basic authenticated JSON requests to a ticket system.
+type ticketClient struct {
+	client *http.Client
+	base   string
+	user   string
+	token  string
+}
+
+// do sends body to the path and decodes the JSON response into v if it isn't
+// nil. Bodies that aren't an io.Reader are sent as JSON.
+func (c ticketClient) do(method, path string, body interface{}, header http.Header, v interface{}) error {
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		r = b
+	default:
+		j, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(j)
+		if header == nil {
+			header = http.Header{}
+		}
+		header.Set("Content-Type", "application/json")
+	}
+	req, err := http.NewRequest(method, c.base+path, r)
+	if err != nil {
+		return err
+	}
+	for k := range header {
+		req.Header.Set(k, header.Get(k))
+	}
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(c.user, c.token)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(b)))
+	}
+	if v != nil {
+		return json.NewDecoder(resp.Body).Decode(v)
+	}
+	return nil
+}
+
+// jira uses the Jira REST API v2.
+type jira struct {
+	ticketClient
+	project   string
+	issueType string
+}
+
+func (j jira) find(summary string) (string, error) {
+	jql := fmt.Sprintf(`project = %q AND summary ~ %q AND statusCategory != Done`, j.project, `"`+summary+`"`)
+	var res struct {
+		Issues []struct {
+			Key    string
+			Fields struct{ Summary string }
+		}
+	}
+	err := j.do("GET", "/rest/api/2/search?fields=summary&jql="+url.QueryEscape(jql), nil, nil, &res)
+	if err != nil {
+		return "", err
+	}
+	// summary ~ is a text search, so check for the exact summary.
+	for _, i := range res.Issues {
+		if i.Fields.Summary == summary {
+			return i.Key, nil
+		}
+	}
+	return "", nil
+}
+
+func (j jira) create(summary, description string) (string, error) {
+	issue := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]string{"key": j.project},
+			"issuetype":   map[string]string{"name": j.issueType},
+			"summary":     summary,
+			"description": description,
+		},
+	}
+	var res struct{ Key string }
+	err := j.do("POST", "/rest/api/2/issue", issue, nil, &res)
+	return res.Key, err
+}
+
+func (j jira) update(id, description string) error {
+	return j.do("POST", "/rest/api/2/issue/"+id+"/comment", map[string]string{"body": description}, nil, nil)
+}
+
+func (j jira) attach(id, name string, data []byte) error {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		return err
+	}
+	if _, err = fw.Write(data); err != nil {
+		return err
+	}
+	if err = mw.Close(); err != nil {
+		return err
+	}
+	h := http.Header{}
+	h.Set("Content-Type", mw.FormDataContentType())
+	h.Set("X-Atlassian-Token", "no-check")
+	return j.do("POST", "/rest/api/2/issue/"+id+"/attachments", &buf, h, nil)
+}
+
+// serviceNow uses the ServiceNow Table and Attachment APIs.
+type serviceNow struct {
+	ticketClient
+	table string
+}
+
+func (s serviceNow) find(summary string) (string, error) {
+	q := url.Values{
+		"sysparm_query":  {"active=true^short_description=" + summary},
+		"sysparm_fields": {"sys_id"},
+		"sysparm_limit":  {"1"},
+	}
+	var res struct {
+		Result []struct {
+			SysID string `json:"sys_id"`
+		}
+	}
+	if err := s.do("GET", "/api/now/table/"+s.table+"?"+q.Encode(), nil, nil, &res); err != nil {
+		return "", err
+	}
+	if len(res.Result) == 0 {
+		return "", nil
+	}
+	return res.Result[0].SysID, nil
+}
+
+func (s serviceNow) create(summary, description string) (string, error) {
+	var res struct {
+		Result struct {
+			SysID string `json:"sys_id"`
+		}
+	}
+	rec := map[string]string{"short_description": summary, "description": description}
+	err := s.do("POST", "/api/now/table/"+s.table, rec, nil, &res)
+	return res.Result.SysID, err
+}
+
+func (s serviceNow) update(id, description string) error {
+	return s.do("PATCH", "/api/now/table/"+s.table+"/"+id, map[string]string{"work_notes": description}, nil, nil)
+}
+
+func (s serviceNow) attach(id, name string, data []byte) error {
+	q := url.Values{"table_name": {s.table}, "table_sys_id": {id}, "file_name": {name}}
+	h := http.Header{}
+	h.Set("Content-Type", "text/csv")
+	return s.do("POST", "/api/now/attachment/file?"+q.Encode(), bytes.NewReader(data), h, nil)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDeliveryOf(t *testing.T) {
+	values := []struct {
+		root, path, want string
+	}{
+		{"/media", "/media/ksc-0601/img.jpg", "ksc-0601"},
+		{"/media", "/media/ksc-0601/raw/img.jpg", "ksc-0601"},
+		{"/media", "/media/img.jpg", "."},
+		{".", "jsc/img.jpg", "jsc"},
+	}
+	for _, v := range values {
+		equals(t, deliveryOf(v.root, v.path), v.want)
+	}
+}
+
+func testRow(p, status, reason string) []string {
+	row := make([]string, len(csvHeader))
+	row[0], row[1], row[2] = p, status, reason
+	return row
+}
+
+func TestRejectCollector(t *testing.T) {
+	rc := newRejectCollector("/media")
+	rc.Write(testRow("/media/a/1.jpg", "Accepted", ""))
+	rc.Write(testRow("/media/a/2.jpg", "Incomplete", "Minimum metadata not provided"))
+	rc.Write(testRow("/media/b/3.jpg", "Accepted", ""))
+	equals(t, rc.deliveries["a"].total, 2)
+	equals(t, len(rc.deliveries["a"].rows), 1)
+	equals(t, rc.deliveries["a"].reasons, map[string]int{"Minimum metadata not provided": 1})
+	equals(t, len(rc.deliveries["b"].rows), 0)
+}
+
+func TestRejectCollectorRoots(t *testing.T) {
+	rc := newRejectCollector("/mnt/a", "/mnt/b")
+	rc.Write(testRow("/mnt/a/1.jpg", "Rejected", "Not an image"))
+	rc.Write(testRow("/mnt/b/2.jpg", "Accepted", ""))
+	rc.Write(testRow("/mnt/b/d/3.jpg", "Rejected", "Not an image"))
+	equals(t, len(rc.deliveries), 3)
+	equals(t, rc.deliveries["/mnt/a ."].root, "/mnt/a")
+	equals(t, rc.deliveries["/mnt/b ."].total, 1)
+	equals(t, rc.deliveries["/mnt/b d"].name, "d")
+	equals(t, len(rc.deliveries["/mnt/b d"].rows), 1)
+}
+
+func TestOpenTicketsJira(t *testing.T) {
+	var created, commented, attached []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/2/search", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		equals(t, user+":"+pass, "bot:secret")
+		// Delivery "a" already has an open ticket.
+		w.Write([]byte(`{"issues": [{"key": "AV-1", "fields": {"summary": "chkmd: rejected assets in a"}}]}`))
+	})
+	mux.HandleFunc("/rest/api/2/issue", func(w http.ResponseWriter, r *http.Request) {
+		var issue struct {
+			Fields struct{ Summary string }
+		}
+		json.NewDecoder(r.Body).Decode(&issue)
+		created = append(created, issue.Fields.Summary)
+		w.Write([]byte(`{"key": "AV-2"}`))
+	})
+	mux.HandleFunc("/rest/api/2/issue/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/comment"):
+			commented = append(commented, r.URL.Path)
+		case strings.HasSuffix(r.URL.Path, "/attachments"):
+			equals(t, r.Header.Get("X-Atlassian-Token"), "no-check")
+			f, h, err := r.FormFile("file")
+			equals(t, err, nil)
+			b, _ := ioutil.ReadAll(f)
+			equals(t, strings.Count(string(b), "\n"), 2)
+			attached = append(attached, r.URL.Path+" "+strings.SplitN(h.Filename, "-", 2)[0])
+		}
+		w.Write([]byte(`{}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	os.Setenv("CHKMD_TEST_TOKEN", "secret")
+	tc := ticketConfig{System: "jira", URL: ts.URL, User: "bot", TokenEnv: "CHKMD_TEST_TOKEN", Project: "AV"}
+	rc := newRejectCollector("/media")
+	rc.Write(testRow("/media/a/1.jpg", "Incomplete", "Minimum metadata not provided"))
+	rc.Write(testRow("/media/b/2.jpg", "Rejected", "exit status 1"))
+	rc.Write(testRow("/media/c/3.jpg", "Accepted", ""))
+
+	equals(t, openTickets(tc, rc), nil)
+	equals(t, created, []string{"chkmd: rejected assets in b"})
+	equals(t, commented, []string{"/rest/api/2/issue/AV-1/comment"})
+	equals(t, attached, []string{"/rest/api/2/issue/AV-1/attachments a", "/rest/api/2/issue/AV-2/attachments b"})
+}
+
+func TestNewTicketerUnknown(t *testing.T) {
+	_, err := newTicketer(ticketConfig{System: "trello"})
+	equals(t, err.Error(), `unknown ticket system "trello", expected jira or servicenow`)
+}
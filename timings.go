@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// timingColumns are only in the CSV output with -timings or when the
+// config's columns list them.
+var timingColumns = []string{"Bytes", "Extraction ms"}
+
+// sizeExtract wraps extract to record the size of the file it extracts from,
+// which for objects and URLs is the download, even if extracting fails.
+func sizeExtract(extract func(string) (exif, error)) func(string) (exif, error) {
+	return func(p string) (exif, error) {
+		e, err := extract(p)
+		if fi, serr := os.Stat(exiftoolPath(p)); serr == nil {
+			e.size, e.sized = fi.Size(), true
+		}
+		return e, err
+	}
+}
+
+// Bytes returns the size of the file in bytes, or "" if it isn't known, as
+// for files from the -cache that have gone.
+func (e exif) Bytes() string {
+	if !e.sized {
+		return ""
+	}
+	return strconv.FormatInt(e.size, 10)
+}
+
+// ExtractionMs returns how many milliseconds getting the file's metadata
+// took, downloading and retrying included, or "" if it wasn't timed.
+func (e exif) ExtractionMs() string {
+	if e.took == 0 {
+		return ""
+	}
+	return strconv.FormatInt(int64(e.took/time.Millisecond), 10)
+}
+
+// timed records in e how long extracting the file at p took since start,
+// and its size if that wasn't, as it isn't for files from the -cache.
+func (e *exif) timed(p string, start time.Time) {
+	e.took = time.Since(start)
+	if !e.sized && !isURL(p) && !isObject(p) {
+		if fi, err := os.Stat(exiftoolPath(p)); err == nil {
+			e.size, e.sized = fi.Size(), true
+		}
+	}
+}
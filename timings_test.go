@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTimings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "big.tif")
+	equals(t, ioutil.WriteFile(p, make([]byte, 1234), 0644), nil)
+
+	// Sized even when extracting fails, as the slow files often do.
+	extract := sizeExtract(func(string) (exif, error) { return newExif(), errTimeout })
+	e, err := extract(p)
+	equals(t, err, errTimeout)
+	equals(t, e.Bytes(), "1234")
+	equals(t, e.ExtractionMs(), "")
+	e.timed(p, time.Now().Add(-1500*time.Millisecond))
+	equals(t, e.ExtractionMs(), "1500")
+
+	rows := make(chan []string, 1)
+	e.MakeErrorRow(rows, p, errTimeout)
+	row := <-rows
+	equals(t, []string{row[column("Bytes")], row[column("Extraction ms")]}, []string{"1234", "1500"})
+
+	// Files from the -cache weren't extracted from, so they're sized after.
+	e = newExif()
+	e.timed(p, time.Now())
+	equals(t, e.Bytes(), "1234")
+	e = newExif()
+	e.timed(filepath.Join(dir, "gone.tif"), time.Now())
+	equals(t, e.Bytes(), "")
+	e, _ = sizeExtract(func(string) (exif, error) { return newExif(), errors.New("no such file") })(filepath.Join(dir, "gone.tif"))
+	equals(t, e.Bytes(), "")
+}
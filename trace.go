@@ -0,0 +1,338 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// source is somewhere a field's value may come from.
+type source struct {
+	name string
+	get  func(exif) string
+}
+
+// tagSource is a source reading the tag from one of the exif maps.
+func tagSource(group, tag string) source {
+	return source{group + ":" + tag, func(e exif) string {
+		switch group {
+		case "IPTC":
+			return e.IPTC[tag]
+		case "Exif":
+			return e.Exif[tag]
+		case "XMP":
+			return e.xmp(tag)
+		case "ID3":
+			return e.ID3[tag]
+		case "RIFF":
+			return e.RIFF[tag]
+		case "PDF":
+			return e.PDF[tag]
+		case "Model":
+			return e.Model[tag]
+		case folderGroup, "metadata.yaml":
+			return e.Folder[tag]
+		case "filename":
+			return e.Filename[tag]
+		}
+		return e.Data[tag]
+	}}
+}
+
+// fieldSources lists, in order of precedence, where each field's value comes
+// from, unless the config maps it. The accessors read the first of them the
+// file has, see exif.field, and -trace-field lists them all, so this is the
+// one place a field's sources are.
+var fieldSources = map[string][]source{
+	"NasaID": {
+		// IPTC 3.1 p.2 contains a field 'Title' that may be used for this AFAICT.
+		// IPTC 6 p.38 (39)                        - OriginalTransmissionReference
+		// IPTC 7 p.17                             - photoshop:TransmissionReference
+		tagSource("IPTC", "OriginalTransmissionReference"),
+		// IPTC 1 p.7                              - Job Identifier
+		// IPTC 3.1 p.2                            - Job ID
+		// IPTC 5 p.15                             - JobID
+		tagSource("IPTC", "JobID"),
+		// Exif 1 p. 45 (54)                       - ImageUniqueID
+		// Exif 3 p.17 (21)                        - exif:ImageUniqueID
+		tagSource("Exif", "ImageUniqueID"),
+		// XMP 1 p.27 (35)                         - xmp:Identifier
+		// XMP 1 p.26 (34)                         - dc:identifier
+		tagSource("XMP", "Identifier"),
+		// XMP 2 p.33                              - photoshop:TransmissionReference
+		tagSource("XMP", "TransmissionReference"),
+		tagSource("Model", "NasaID"),
+		tagSource("filename", "NasaID"),
+		{"File:FileName", func(e exif) string {
+			name := e.Data["FileName"]
+			return strings.TrimSuffix(name, filepath.Ext(name))
+		}},
+	},
+	"Title": {
+		langAltSource("Title"),
+		// IPTC 3.1 p.2 - Says Title is usually used for file name or id.
+		// IPTC 6 p.26 (27)                        - ObjectName
+		// IPTC 7 p.10                             - dc:title
+		tagSource("IPTC", "ObjectName"),
+		// IPTC 6 p.39 (40)                        - Headline
+		// IPTC 7 p.17                             - photoshop:Headline
+		tagSource("IPTC", "Headline"),
+		// No such Exif???
+		// XMP 1 p.27                              - dc:title
+		// XMP 2 p.32                              - photoshop:Headline
+		tagSource("XMP", "Title"),
+		// ID3v2.4 4.2.1                           - TIT2
+		tagSource("ID3", "Title"),
+		// RIFF INFO                               - INAM
+		tagSource("RIFF", "Title"),
+		// PDF 1.7 14.3.3                          - Title
+		tagSource("PDF", "Title"),
+		// glTF asset.extras or JSON sidecar       - title
+		tagSource("Model", "Title"),
+	},
+	"AltText": {
+		langAltSource("AltTextAccessibility"),
+		langAltSource("ExtDescrAccessibility"),
+		// IPTC 4 (2021.1)                         - Iptc4xmpCore:AltTextAccessibility
+		tagSource("XMP", "AltTextAccessibility"),
+		// IPTC 4 (2021.1)                         - Iptc4xmpCore:ExtDescrAccessibility
+		tagSource("XMP", "ExtDescrAccessibility"),
+	},
+	"Description": {
+		langAltSource("Description"),
+		// IPTC 3.1 p.2                            - Description
+		// IPTC 6 p.39 (40 in PDF)                 - Caption/Abstract (/ not valid in field so -?)
+		// IPTC 7 p.18                             - dc:description
+		tagSource("IPTC", "Caption-Abstract"),
+		// Exif 1 p.22 (28)                        - ImageDescription
+		// Exif 3 p.6 (10)                         - dc:description
+		tagSource("Exif", "ImageDescription"),
+		// XMP 1 p.25 (33)                         - dc:description
+		tagSource("XMP", "Description"),
+		// ID3v2.4 4.10                            - COMM
+		tagSource("ID3", "Comment"),
+		// EBU Tech 3285 (bext)                    - Description
+		tagSource("RIFF", "Description"),
+		// RIFF INFO                               - ICMT
+		tagSource("RIFF", "Comment"),
+		// PDF 1.7 14.3.3                          - Subject
+		tagSource("PDF", "Subject"),
+		// glTF asset.extras or JSON sidecar       - description
+		tagSource("Model", "Description"),
+	},
+	"DateCreated": {
+		// IPTC 3.1 p.1
+		// IPTC 6 pp. 34-35
+		// IPTC 7 p. 14                            - photoshop:DateCreated
+		// IPTC 7 p. 14                            - photoshop:TimeCreated
+		{"IPTC:DateCreated TimeCreated", func(e exif) string {
+			return strings.TrimSpace(e.IPTC["DateCreated"] + " " + e.IPTC["TimeCreated"])
+		}},
+		// Exif 1 p.30 (36 in PDF)                 - DateTimeOriginal
+		// Exif 3 p.9 (13 in PDF)                  - exif:DateTimeOriginal
+		tagSource("Exif", "DateTimeOriginal"),
+		// XMP 1 p.27 (35 in PDF)                  - xmp:CreateDate ??? The digital or original.
+		// XMP 2 p.32                              - photoshop:DateCreated
+		tagSource("XMP", "DateCreated"),
+		// ID3v2.4 4.2.5                           - TDRC
+		tagSource("ID3", "RecordingTime"),
+		// EBU Tech 3285 (bext)                    - OriginationDate OriginationTime
+		tagSource("RIFF", "DateTimeOriginal"),
+		// RIFF INFO                               - ICRD
+		tagSource("RIFF", "DateCreated"),
+		// A document's xmp:CreateDate, unlike an image's, is when the work
+		// was made.
+		// XMP 1 p.27 (35)                         - xmp:CreateDate
+		{"XMP:CreateDate", func(e exif) string {
+			if !e.isDocument() {
+				return ""
+			}
+			return e.XMP["CreateDate"]
+		}},
+		// PDF 1.7 14.3.3                          - CreationDate
+		{"PDF:CreateDate", func(e exif) string {
+			if !e.isDocument() {
+				return ""
+			}
+			return e.PDF["CreateDate"]
+		}},
+		tagSource("Model", "DateCreated"),
+		tagSource("filename", "DateCreated"),
+	},
+	"Keywords": {
+		// IPTC 3.1 p.2                            - Keywords
+		// IPTC 6 p.31 (32 in PDF)                 - Keywords
+		// IPTC 7 p.12                             - dc:subject
+		tagSource("IPTC", "Keywords"),
+		// Exif 1 p.28 (34 in PDF) says USerComment may be used for Keywords, but
+		// keywords isn't in Exif 3
+		// XMP 1 p.26 (34 in PDF)                  - dc:subject
+		tagSource("XMP", "Subject"),
+		// PDF 1.7 14.3.3                          - Keywords
+		tagSource("PDF", "Keywords"),
+		tagSource("Model", "Keywords"),
+		// Default keywords from metadata.yaml
+		tagSource(folderGroup, "Keywords"),
+	},
+	"City": {
+		// IPTC 6 p.37 (38)                        - City
+		// IPTC 7 p.16                             - photoshop:City
+		tagSource("IPTC", "City"),
+		// XMP 2 p.32                              - photoshop:City
+		tagSource("XMP", "City"),
+	},
+	"State": {
+		// IPTC 6 p.37 (38)                        - Province-State
+		// IPTC 7 p.16                             - photoshop:State
+		tagSource("IPTC", "Province-State"),
+		// XMP 2 p.32                              - photoshop:State
+		tagSource("XMP", "State"),
+	},
+	"Country": {
+		// IPTC 6 p.38 (39)                        - Country-PrimaryLocationName
+		// IPTC 7 p.17                             - photoshop:Country
+		tagSource("IPTC", "Country-PrimaryLocationName"),
+		// XMP 2 p.32                              - photoshop:Country
+		tagSource("XMP", "Country"),
+	},
+	"GPS": {
+		// Exif 2 p.55 (63)                        - GPSLatitude, GPSLongitude
+		{"Exif:GPSLatitude GPSLongitude", func(e exif) string {
+			return gpsSource(e.Exif["GPSLatitude"], e.Exif["GPSLatitudeRef"], e.Exif["GPSLongitude"], e.Exif["GPSLongitudeRef"])
+		}},
+		{"XMP:GPSLatitude GPSLongitude", func(e exif) string {
+			return gpsSource(e.XMP["GPSLatitude"], "", e.XMP["GPSLongitude"], "")
+		}},
+		{"Composite:GPSLatitude GPSLongitude", func(e exif) string {
+			return gpsSource(e.Data["GPSLatitude"], "", e.Data["GPSLongitude"], "")
+		}},
+	},
+	"MediaType": {
+		// XMP 1 p.26 (35)                         - dc:format
+		tagSource("XMP", "Format"),
+		// This just pulls from exiftool fileinfo.
+		tagSource("File", "MIMEType"),
+	},
+	"FileFormat": {
+		// We pull this from the file data provided by exiftool
+		tagSource("File", "FileType"),
+	},
+	"Photographer": {
+		// IPTC 6 p.36 (37)                        - By-line
+		// IPTC 7 p.15                             - dc:creator
+		tagSource("IPTC", "By-line"),
+		// Exif 1 p.23 (29)                        - Artist
+		// Exif 2 p.40 (45)                        - Artist
+		// Exif 3 p.6  (10)                        - dc:creator
+		tagSource("Exif", "Artist"),
+		// XMP 1 p.25  (33)                        - dc:creator
+		tagSource("XMP", "Artist"),
+		// PDF 1.7 14.3.3                          - Author
+		tagSource("PDF", "Author"),
+		// glTF asset.extras or JSON sidecar       - author
+		tagSource("Model", "Author"),
+		tagSource(folderGroup, "Photographer"),
+	},
+	"Center": {
+		// IPTC 6 p.39 (40)                        - Credit
+		// IPTC 7 p.17                             - photoshop:Credit
+		tagSource("IPTC", "Credit"),
+		// IPTC 6 p.39 (40)                        - Source
+		// IPTC 7 p.17                             - photoshop:Source
+		tagSource("IPTC", "Source"),
+		// XMP 2 p.32                              - photoshop:Credit
+		tagSource("XMP", "Credit"),
+		tagSource(folderGroup, "Center"),
+		tagSource("filename", "Center"),
+	},
+	"Credit": {
+		// IPTC 6 p.40 (41)                        - Writer-Editor
+		// IPTC 7 p.17                             - photoshop:CaptionWriter
+		tagSource("IPTC", "Writer-Editor"),
+		// IPTC 6 p.39 (40)                        - Credit
+		tagSource("IPTC", "Credit"),
+		// XMP 2 p.32                              - photoshop:CaptionWriter
+		tagSource("XMP", "CaptionWriter"),
+		tagSource(folderGroup, "Credit"),
+	},
+	"Album": {
+		tagSource(folderGroup, "Album"),
+	},
+	"Copyright": {
+		// IPTC 6 p.42 (43)                        - CopyrightNotice
+		// IPTC 7 p.11                             - dc:rights
+		tagSource("IPTC", "CopyrightNotice"),
+		// Exif 3 p.6 (10)                         - Copyright
+		tagSource("Exif", "Copyright"),
+		// XMP 1 p.26 (34)                         - dc:rights
+		tagSource("XMP", "Rights"),
+	},
+	"UsageTerms": {
+		// IPTC 7 p.19                             - xmpRights:UsageTerms
+		tagSource("XMP", "UsageTerms"),
+	},
+}
+
+// gpsSource formats the coordinates for a trace, or returns "" if they
+// don't parse.
+func gpsSource(lat, latRef, lon, lonRef string) string {
+	e := newExif()
+	e.Exif["GPSLatitude"], e.Exif["GPSLatitudeRef"] = lat, latRef
+	e.Exif["GPSLongitude"], e.Exif["GPSLongitudeRef"] = lon, lonRef
+	return e.GPSLocation()
+}
+
+// traceFields returns the fields traced for a -trace-field name. Location is
+// made of City, State and Country, or GPS when they're all empty.
+func traceFields(field string) ([]string, error) {
+	if field == "Location" {
+		return []string{"City", "State", "Country", "GPS"}, nil
+	}
+	if _, ok := fieldSources[field]; !ok {
+		return nil, fmt.Errorf("can't trace unknown field %q", field)
+	}
+	return []string{field}, nil
+}
+
+// trace describes how field was resolved for the file at p: each source in
+// order with its value, which one was used, and any -fix correction that
+// was written for it.
+func trace(p string, e exif, field string, correction map[string]string) string {
+	fields, err := traceFields(field)
+	if err != nil {
+		return err.Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s:\n", p, field)
+	for _, f := range fields {
+		if len(fields) > 1 {
+			fmt.Fprintf(&b, "  %s:\n", f)
+		}
+		used := false
+		for i, s := range e.sources(f) {
+			v := s.get(e)
+			mark := ""
+			if v != "" && !used {
+				mark, used = "  <- used", true
+			}
+			if v == "" {
+				v = "(empty)"
+			} else {
+				v = fmt.Sprintf("%q", v)
+			}
+			fmt.Fprintf(&b, "  %d. %s = %s%s\n", i+1, s.name, v, mark)
+		}
+		if !used {
+			b.WriteString("  no value found\n")
+		}
+	}
+	for _, f := range fixFields {
+		name := strings.Replace(f.column, " ", "", -1)
+		for _, tf := range fields {
+			if v := correction[f.column]; v != "" && name == tf {
+				fmt.Fprintf(&b, "  -fix correction %s = %q\n", f.column, v)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package main
+
+import "testing"
+
+// TestFieldSourcesMatchAccessors checks that the traced chains pick the same
+// value as the accessors do.
+func TestFieldSourcesMatchAccessors(t *testing.T) {
+	accessors := map[string]func(exif) string{
+		"NasaID":       exif.NasaID,
+		"Title":        exif.Title,
+		"AltText":      exif.AltText,
+		"Description":  exif.Description,
+		"Keywords":     exif.Keywords,
+		"Photographer": exif.Photographer,
+		"Center":       exif.Center,
+		"Credit":       exif.Credit,
+		"Album":        exif.Album,
+		"Copyright":    exif.Copyright,
+		"UsageTerms":   exif.UsageTerms,
+	}
+	for field, get := range accessors {
+		sources := fieldSources[field]
+		for i := range sources {
+			// Fill in this source and every one after it, so the
+			// accessor should use this one.
+			e := newExif()
+			e.Data["FileName"] = "file.jpg"
+			// So the -lang sources, like XMP:Title-lang, are read.
+			e.lang = "lang"
+			for j, s := range sources[i:] {
+				group, tag := splitSource(s.name)
+				v := field + "-" + string(rune('a'+i+j))
+				switch group {
+				case "IPTC":
+					e.IPTC[tag] = v
+				case "Exif":
+					e.Exif[tag] = v
+				case "XMP":
+					e.XMP[tag] = v
+				case "ID3":
+					e.ID3[tag] = v
+				case "RIFF":
+					e.RIFF[tag] = v
+				case "PDF":
+					e.PDF[tag] = v
+				case "Model":
+					e.Model[tag] = v
+				case folderGroup:
+					e.Folder[tag] = v
+				case "filename":
+					e.Filename[tag] = v
+				}
+			}
+			equals(t, sources[i].get(e), get(e))
+		}
+	}
+}
+
+func splitSource(name string) (string, string) {
+	for i := range name {
+		if name[i] == ':' {
+			return name[:i], name[i+1:]
+		}
+	}
+	return name, ""
+}
+
+func TestTrace(t *testing.T) {
+	e := newExif()
+	e.Exif["ImageDescription"] = "From Exif"
+	e.XMP["Description"] = "From XMP"
+	got := trace("a.jpg", e, "Description", map[string]string{"Description": "From CSV", "Title": "A Title"})
+	equals(t, got, `a.jpg Description:
+  1. IPTC:Caption-Abstract = (empty)
+  2. Exif:ImageDescription = "From Exif"  <- used
+  3. XMP:Description = "From XMP"
+  4. ID3:Comment = (empty)
+  5. RIFF:Description = (empty)
+  6. RIFF:Comment = (empty)
+  7. PDF:Subject = (empty)
+  8. Model:Description = (empty)
+  -fix correction Description = "From CSV"
+`)
+
+	e = newExif()
+	e.XMP["City"] = "Houston"
+	got = trace("a.jpg", e, "Location", nil)
+	equals(t, got, `a.jpg Location:
+  City:
+  1. IPTC:City = (empty)
+  2. XMP:City = "Houston"  <- used
+  State:
+  1. IPTC:Province-State = (empty)
+  2. XMP:State = (empty)
+  no value found
+  Country:
+  1. IPTC:Country-PrimaryLocationName = (empty)
+  2. XMP:Country = (empty)
+  no value found
+  GPS:
+  1. Exif:GPSLatitude GPSLongitude = (empty)
+  2. XMP:GPSLatitude GPSLongitude = (empty)
+  3. Composite:GPSLatitude GPSLongitude = (empty)
+  no value found
+`)
+	_, err := traceFields("Caption")
+	equals(t, err.Error(), `can't trace unknown field "Caption"`)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Why a file can't be checked, found before exiftool is run on it, which
+// would only say it exited with status 1.
+var (
+	errPermission = errors.New("permission denied")
+	errEmptyFile  = errors.New("empty file")
+	errNotMedia   = errors.New("not a media file")
+)
+
+// notMediaTypes are what a file's content is when it isn't media whatever
+// its name says, like an HTML error page saved as a .jpg, unless they're
+// among the config's MIME types.
+var notMediaTypes = map[string]bool{
+	"text/html":  true,
+	"text/plain": true,
+}
+
+// preflightExtract wraps extract so a file that can't be read, is empty, or
+// by its content isn't one of types nor media at all is an error saying so,
+// without running exiftool.
+func preflightExtract(types map[string]bool, extract func(string) (exif, error)) func(string) (exif, error) {
+	return func(p string) (exif, error) {
+		if err := preflight(p, types); err != nil {
+			return newExif(), err
+		}
+		return extract(p)
+	}
+}
+
+// preflight returns why the file at p can't be checked, if it can't. Other
+// errors are left to exiftool to report as it does.
+func preflight(p string, types map[string]bool) error {
+	f, err := os.Open(exiftoolPath(p))
+	if os.IsPermission(err) {
+		return errPermission
+	}
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	b := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, b)
+	switch {
+	case os.IsPermission(err):
+		return errPermission
+	case n == 0 && err == io.EOF:
+		return errEmptyFile
+	}
+	// XMP sidecars, read with the files they're beside, are text.
+	if t := sniffType(b[:n]); notMediaTypes[t] && !types[t] && !strings.EqualFold(filepath.Ext(p), ".xmp") {
+		return errNotMedia
+	}
+	return nil
+}
+
+// countUnreadable counts a file that couldn't be checked by why, if it's
+// one preflight found.
+func countUnreadable(stats *statistics, err error) {
+	switch err {
+	case errPermission:
+		atomic.AddInt32(&stats.PermissionDenied, 1)
+	case errEmptyFile:
+		atomic.AddInt32(&stats.Empty, 1)
+	case errNotMedia:
+		atomic.AddInt32(&stats.NotMedia, 1)
+	}
+}
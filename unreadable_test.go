@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPreflight(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		equals(t, ioutil.WriteFile(p, []byte(content), 0644), nil)
+		return p
+	}
+	jpeg := write("a.jpg", "\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+	empty := write("empty.jpg", "")
+	html := write("error.jpg", "<!DOCTYPE html><html><body>404 Not Found</body></html>")
+	text := write("notes.jpg", "Launch of Apollo 11\n")
+	types := map[string]bool{"image/jpeg": true}
+
+	extracted := 0
+	extract := preflightExtract(types, func(p string) (exif, error) {
+		extracted++
+		return newExif(), nil
+	})
+	for p, want := range map[string]error{
+		jpeg:                           nil,
+		empty:                          errEmptyFile,
+		html:                           errNotMedia,
+		text:                           errNotMedia,
+		filepath.Join(dir, "gone.jpg"): nil,
+	} {
+		_, err := extract(p)
+		equals(t, []interface{}{p, err}, []interface{}{p, want})
+	}
+	// What isn't found is left to exiftool.
+	equals(t, extracted, 2)
+	equals(t, preflight(text, map[string]bool{"text/plain": true}), nil)
+
+	if os.Geteuid() != 0 {
+		secret := write("secret.jpg", "\xff\xd8\xff\xe0")
+		equals(t, os.Chmod(secret, 0), nil)
+		equals(t, preflight(secret, types), errPermission)
+	}
+
+	stats := &statistics{}
+	for _, err := range []error{errPermission, errEmptyFile, errEmptyFile, errNotMedia, errTimeout, errors.New("exit status 1")} {
+		countUnreadable(stats, err)
+	}
+	equals(t, []int32{stats.PermissionDenied, stats.Empty, stats.NotMedia}, []int32{1, 2, 1})
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// urlClient downloads http(s) inputs. Large videos can take a while.
+var urlClient = &http.Client{Timeout: 30 * time.Minute}
+
+// isURL is whether p is an http(s) URL rather than a path.
+func isURL(p string) bool {
+	return strings.HasPrefix(p, "https://") || strings.HasPrefix(p, "http://")
+}
+
+// displayPath returns p as it should appear in the output. Pre-signed URLs
+// have their query string, which holds the signature, removed.
+func displayPath(p string) string {
+	if !isURL(p) {
+		return p
+	}
+	if i := strings.Index(p, "?"); i >= 0 {
+		return p[:i]
+	}
+	return p
+}
+
+// urlName returns the file name in a URL's path.
+func urlName(p string) string {
+	u, err := url.Parse(p)
+	if err != nil {
+		return path.Base(displayPath(p))
+	}
+	return path.Base(u.Path)
+}
+
+// downloadURL copies the body of a GET of u to w.
+func downloadURL(u string, w io.Writer) error {
+	resp, err := urlClient.Get(u)
+	if err != nil {
+		// The error has the URL in it, signature and all.
+		return fmt.Errorf("GET %s failed", displayPath(u))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", displayPath(u), resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// extractDownload downloads a file named name to a temporary file which is
+// extracted and then removed. The file's name is used for its NasaID
+// fallback rather than the temporary one's.
+func extractDownload(name string, download func(io.Writer) error, extract func(string) (exif, error)) (exif, error) {
+	f, err := ioutil.TempFile("", "chkmd-*"+path.Ext(name))
+	if err != nil {
+		return newExif(), err
+	}
+	defer os.Remove(f.Name())
+	err = download(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return newExif(), err
+	}
+	e, err := extract(f.Name())
+	if err == nil {
+		e.Data["FileName"] = name
+	}
+	return e, err
+}
+
+// urlExtract wraps extract so http(s) URLs, including pre-signed ones, are
+// downloaded to a temporary file to extract. Other paths are passed through.
+func urlExtract(eg *egress, extract func(string) (exif, error)) func(string) (exif, error) {
+	return func(p string) (exif, error) {
+		if !isURL(p) {
+			return extract(p)
+		}
+		return extractDownload(urlName(p), func(w io.Writer) error {
+			return downloadURL(p, eg.meter(w))
+		}, extract)
+	}
+}
+
+// walkURLs is makeWalker for a list of URLs, one per line. It sends those
+// in the shard with relevant extensions to the files channel.
+func walkURLs(ctx context.Context, r io.Reader, sh shard, files chan string, stats *statistics, types map[string]bool) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for s.Scan() {
+		u := strings.TrimSpace(s.Text())
+		if u == "" || strings.HasPrefix(u, "#") {
+			continue
+		}
+		if !isURL(u) {
+			return fmt.Errorf("not an http(s) URL: %s", displayPath(u))
+		}
+		if !sh.mine(u) {
+			continue
+		}
+		atomic.AddInt32(&stats.Total, 1)
+		if types[mime.TypeByExtension(path.Ext(urlName(u)))] {
+			if err := sendFile(ctx, files, u); err != nil {
+				return err
+			}
+			atomic.AddInt32(&stats.Relevant, 1)
+		}
+	}
+	return s.Err()
+}
+
+// readURLs walks the URLs in the file at p, or stdin if p is "-".
+func readURLs(ctx context.Context, p string, sh shard, files chan string, stats *statistics, types map[string]bool) error {
+	if p == "-" {
+		return walkURLs(ctx, os.Stdin, sh, files, stats, types)
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return walkURLs(ctx, f, sh, files, stats, types)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDisplayPath(t *testing.T) {
+	equals(t, displayPath("https://b.s3.amazonaws.com/ksc/a.jpg?X-Amz-Signature=abc"), "https://b.s3.amazonaws.com/ksc/a.jpg")
+	equals(t, displayPath("/media/a?.jpg"), "/media/a?.jpg")
+	equals(t, urlName("https://b.s3.amazonaws.com/ksc/a%20b.jpg?X-Amz-Signature=abc"), "a b.jpg")
+}
+
+func TestWalkURLs(t *testing.T) {
+	readConfig("")
+	files := make(chan string, 10)
+	stats := &statistics{}
+	in := "https://x/a.jpg?sig=1\n\n# comment\nhttps://x/notes.txt?sig=2\nhttp://x/b.mp4\n"
+	equals(t, walkURLs(context.Background(), strings.NewReader(in), shard{}, files, stats, defaultTypeSet), nil)
+	close(files)
+	var got []string
+	for f := range files {
+		got = append(got, f)
+	}
+	equals(t, got, []string{"https://x/a.jpg?sig=1", "http://x/b.mp4"})
+	equals(t, stats.Total, int32(3))
+	equals(t, stats.Relevant, int32(2))
+
+	err := walkURLs(context.Background(), strings.NewReader("/media/a.jpg\n"), shard{}, make(chan string, 1), stats, defaultTypeSet)
+	equals(t, err.Error(), "not an http(s) URL: /media/a.jpg")
+}
+
+func TestURLExtract(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sig") != "ok" {
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, "not really a jpeg")
+	}))
+	defer ts.Close()
+
+	var tmp string
+	extract := urlExtract(nil, func(p string) (exif, error) {
+		tmp = p
+		b, err := ioutil.ReadFile(p)
+		equals(t, err, nil)
+		equals(t, string(b), "not really a jpeg")
+		equals(t, filepath.Ext(p), ".jpg")
+		e := newExif()
+		e.Data["FileName"] = filepath.Base(p)
+		return e, nil
+	})
+	e, err := extract(ts.URL + "/ksc/KSC-1.jpg?sig=ok")
+	equals(t, err, nil)
+	equals(t, e.NasaID(), "KSC-1")
+	_, err = ioutil.ReadFile(tmp)
+	equals(t, err != nil, true)
+
+	_, err = extract(ts.URL + "/ksc/KSC-1.jpg?sig=bad")
+	equals(t, err.Error(), "GET "+ts.URL+"/ksc/KSC-1.jpg: 403 Forbidden")
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// verifyFields are the columns compared against the published record.
+var verifyFields = []string{"Title", "Description", "Date Created", "Location", "Keywords", "Photographer", "Center"}
+
+// exportKey normalizes the column or property names of an AVAIL export so
+// "NASA ID", "nasa_id" and "nasaId" are all "nasaid".
+func exportKey(k string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "_", "", "-", "").Replace(k))
+}
+
+// readExport reads an export of published AVAIL records, either a CSV with a
+// header row or a JSON array of objects, keyed by NASA ID.
+func readExport(p string) (map[string]map[string]string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var records []map[string]string
+	if strings.HasSuffix(strings.ToLower(p), ".json") {
+		var v interface{}
+		if err = json.NewDecoder(f).Decode(&v); err != nil {
+			return nil, err
+		}
+		objs, err := exportObjects(v)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range objs {
+			rec := map[string]string{}
+			for k, v := range o {
+				rec[exportKey(k)] = exportValue(v)
+			}
+			records = append(records, rec)
+		}
+	} else {
+		rows, err := csv.NewReader(f).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for i, row := range rows {
+			if i == 0 {
+				continue
+			}
+			rec := map[string]string{}
+			for j, h := range rows[0] {
+				if j < len(row) {
+					rec[exportKey(h)] = row[j]
+				}
+			}
+			records = append(records, rec)
+		}
+	}
+	byID := map[string]map[string]string{}
+	for _, rec := range records {
+		if id := rec["nasaid"]; id != "" {
+			byID[id] = rec
+		}
+	}
+	return byID, nil
+}
+
+// exportObjects returns the records of a JSON export, either an array of
+// them or a search result like the images API's, with each record in the
+// data of an item in the collection.
+func exportObjects(v interface{}) ([]map[string]interface{}, error) {
+	var objs []map[string]interface{}
+	switch t := v.(type) {
+	case []interface{}:
+		for _, item := range t {
+			if o, ok := item.(map[string]interface{}); ok {
+				objs = append(objs, o)
+			}
+		}
+		return objs, nil
+	case map[string]interface{}:
+		collection, ok := t["collection"].(map[string]interface{})
+		if !ok {
+			break
+		}
+		items, _ := collection["items"].([]interface{})
+		for _, item := range items {
+			item, _ := item.(map[string]interface{})
+			data, _ := item["data"].([]interface{})
+			for _, d := range data {
+				if o, ok := d.(map[string]interface{}); ok {
+					objs = append(objs, o)
+				}
+			}
+		}
+		return objs, nil
+	}
+	return nil, fmt.Errorf("expected an array of records or a collection of items")
+}
+
+// exportValue flattens a JSON value, joining lists like keywords with ", ".
+func exportValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case []interface{}:
+		var parts []string
+		for _, item := range t {
+			parts = append(parts, exportValue(item))
+		}
+		return strings.Join(parts, ", ")
+	}
+	return fmt.Sprint(v)
+}
+
+// sameValue compares a local and published value of the field, allowing for
+// differences in formatting that don't matter.
+func sameValue(field, local, published string) bool {
+	local, published = strings.TrimSpace(local), strings.TrimSpace(published)
+	switch field {
+	case "Keywords":
+		return keywordSet(local) == keywordSet(published)
+	case "Date Created":
+		lt, lerr := time.Parse(time.RFC3339, local)
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02", exifDateZone, exifDate, exifDateOnly} {
+			pt, err := time.Parse(layout, published)
+			if err != nil || lerr != nil {
+				continue
+			}
+			if len(published) == len(layout) && !strings.Contains(layout, "15") {
+				// A published date without a time matches any time that day.
+				return lt.Format("2006-01-02") == pt.Format("2006-01-02")
+			}
+			return lt.Equal(pt)
+		}
+	}
+	return strings.Join(strings.Fields(local), " ") == strings.Join(strings.Fields(published), " ")
+}
+
+// keywordSet returns the comma or semicolon separated keywords lowercased,
+// sorted and de-duplicated, so order and case don't count as drift.
+func keywordSet(s string) string {
+	seen := map[string]bool{}
+	var kws []string
+	for _, kw := range splitKeywords(s) {
+		kw = strings.ToLower(kw)
+		if !seen[kw] {
+			seen[kw] = true
+			kws = append(kws, kw)
+		}
+	}
+	sort.Strings(kws)
+	return strings.Join(kws, ",")
+}
+
+// drift is the difference between a local file and its published record.
+type drift struct {
+	NasaID    string
+	Path      string
+	Field     string
+	Local     string
+	Published string
+}
+
+// driftCheck is a rowWriter comparing each row to the published record with
+// the same NASA ID.
+type driftCheck struct {
+	published map[string]map[string]string
+	seen      map[string]bool
+	drifts    []drift
+}
+
+// newDriftCheck returns a driftCheck against the published records.
+func newDriftCheck(published map[string]map[string]string) *driftCheck {
+	return &driftCheck{published: published, seen: map[string]bool{}}
+}
+
+// Write compares the row to its published record.
+func (dc *driftCheck) Write(row []string) error {
+	id, p := row[column("NASA ID")], row[column("Path")]
+	if id == "" {
+		return nil
+	}
+	dc.seen[id] = true
+	rec, ok := dc.published[id]
+	if !ok {
+		dc.drifts = append(dc.drifts, drift{id, p, "Not published", "", ""})
+		return nil
+	}
+	for _, f := range verifyFields {
+		i := column(f)
+		if i < 0 {
+			continue
+		}
+		pub, ok := rec[exportKey(f)]
+		if ok && !sameValue(f, row[i], pub) {
+			dc.drifts = append(dc.drifts, drift{id, p, f, row[i], pub})
+		}
+	}
+	return nil
+}
+
+// results adds a drift for each published record with no local file, and
+// returns all the drifts.
+func (dc *driftCheck) results() []drift {
+	var ids []string
+	for id := range dc.published {
+		if !dc.seen[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	out := append([]drift{}, dc.drifts...)
+	for _, id := range ids {
+		out = append(out, drift{id, "", "Not found locally", "", ""})
+	}
+	return out
+}
+
+// writeDrift writes the drifts as CSV to p, or stderr if p is empty.
+func writeDrift(p string, drifts []drift) error {
+	out := os.Stderr
+	if p != "" {
+		f, err := os.Create(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := csv.NewWriter(out)
+	err := w.Write([]string{"NASA ID", "Path", "Field", "Local", "Published"})
+	for _, d := range drifts {
+		if err == nil {
+			err = w.Write([]string{d.NasaID, d.Path, d.Field, d.Local, d.Published})
+		}
+	}
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSameValue(t *testing.T) {
+	values := []struct {
+		field, local, published string
+		want                    bool
+	}{
+		{"Title", "Launch", "Launch", true},
+		{"Title", "Launch  of Apollo", " Launch of Apollo", true},
+		{"Title", "Launch", "Landing", false},
+		{"Keywords", "Moon, Apollo", "apollo; moon", true},
+		{"Keywords", "Moon, Apollo", "Moon", false},
+		{"Date Created", "1969-07-16T13:32:00Z", "1969-07-16T13:32:00Z", true},
+		{"Date Created", "1969-07-16T13:32:00Z", "1969-07-16", true},
+		{"Date Created", "1969-07-16T13:32:00Z", "1969-07-17", false},
+		{"Date Created", "1969-07-16T13:32:00Z", "1969:07:16 13:32:00", true},
+	}
+	for _, v := range values {
+		equals(t, sameValue(v.field, v.local, v.published), v.want)
+	}
+}
+
+func TestReadExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	csvPath := filepath.Join(dir, "export.csv")
+	ioutil.WriteFile(csvPath, []byte("NASA ID,Title\nKSC-1,Launch\n,No ID\n"), 0644)
+	jsonPath := filepath.Join(dir, "export.json")
+	ioutil.WriteFile(jsonPath, []byte(`[{"nasa_id": "KSC-1", "title": "Launch", "keywords": ["Moon", "Apollo"]}]`), 0644)
+
+	got, err := readExport(csvPath)
+	equals(t, err, nil)
+	equals(t, got, map[string]map[string]string{"KSC-1": {"nasaid": "KSC-1", "title": "Launch"}})
+	got, err = readExport(jsonPath)
+	equals(t, err, nil)
+	equals(t, got, map[string]map[string]string{"KSC-1": {"nasaid": "KSC-1", "title": "Launch", "keywords": "Moon, Apollo"}})
+
+	// A search result from the images API.
+	ioutil.WriteFile(jsonPath, []byte(`{"collection": {"items": [{"href": "x", "data": [{"nasa_id": "KSC-1", "title": "Launch", "date_created": "1969-07-16T13:32:00Z"}]}]}}`), 0644)
+	got, err = readExport(jsonPath)
+	equals(t, err, nil)
+	equals(t, got, map[string]map[string]string{"KSC-1": {"nasaid": "KSC-1", "title": "Launch", "datecreated": "1969-07-16T13:32:00Z"}})
+	ioutil.WriteFile(jsonPath, []byte(`{"nasa_id": "KSC-1"}`), 0644)
+	_, err = readExport(jsonPath)
+	equals(t, err.Error(), "expected an array of records or a collection of items")
+}
+
+func TestDriftCheck(t *testing.T) {
+	dc := newDriftCheck(map[string]map[string]string{
+		"KSC-1": {"nasaid": "KSC-1", "title": "Launch", "keywords": "apollo, moon"},
+		"KSC-2": {"nasaid": "KSC-2", "title": "Landing"},
+	})
+	row := testRow("/media/a/KSC-1.jpg", "Accepted", "")
+	row[column("NASA ID")], row[column("Title")], row[column("Keywords")] = "KSC-1", "Liftoff", "Moon, Apollo"
+	dc.Write(row)
+	row = testRow("/media/a/KSC-3.jpg", "Accepted", "")
+	row[column("NASA ID")] = "KSC-3"
+	dc.Write(row)
+	equals(t, dc.results(), []drift{
+		{"KSC-1", "/media/a/KSC-1.jpg", "Title", "Liftoff", "Launch"},
+		{"KSC-3", "/media/a/KSC-3.jpg", "Not published", "", ""},
+		{"KSC-2", "", "Not found locally", "", ""},
+	})
+}
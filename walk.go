@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// parallelWalker walks a tree like filepath.Walk, but reads up to walkers
+// directories at once, since reading them one after another can't find
+// files fast enough on a big NFS archive to keep processFiles busy. The
+// entries of each directory are visited in lexical order, but directories
+// are read in no particular order, so fn must be safe for concurrent use.
+type parallelWalker struct {
+	ctx  context.Context
+	fn   filepath.WalkFunc
+	sem  chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+	err  error
+	done chan struct{}
+}
+
+// walkParallel walks the tree at root calling fn for each file and
+// directory, as filepath.Walk does, with up to walkers directories being
+// read at once. It stops at the first error fn returns, other than SkipDir,
+// or when ctx is done.
+func walkParallel(ctx context.Context, root string, walkers int, fn filepath.WalkFunc) error {
+	fi, err := os.Lstat(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	if walkers < 1 {
+		walkers = 1
+	}
+	// The walk itself is one walker, so with one there are no goroutines
+	// and it's filepath.Walk's order.
+	w := &parallelWalker{ctx: ctx, fn: fn, sem: make(chan struct{}, walkers-1), done: make(chan struct{})}
+	w.visit(root, fi)
+	w.wg.Wait()
+	return w.err
+}
+
+// fail stops the walk with err, keeping the first.
+func (w *parallelWalker) fail(err error) {
+	w.once.Do(func() {
+		w.err = err
+		close(w.done)
+	})
+}
+
+// stopped is whether the walk has failed or ctx is done.
+func (w *parallelWalker) stopped() bool {
+	select {
+	case <-w.done:
+		return true
+	case <-w.ctx.Done():
+		w.fail(w.ctx.Err())
+		return true
+	default:
+		return false
+	}
+}
+
+// visit calls fn for p and, if it's a directory, its entries, reading each
+// subdirectory in a new goroutine when there's a walker free and in this
+// one when there isn't.
+func (w *parallelWalker) visit(p string, fi os.FileInfo) {
+	if w.stopped() {
+		return
+	}
+	err := w.fn(p, fi, nil)
+	if err != nil {
+		if err != filepath.SkipDir {
+			w.fail(err)
+		}
+		return
+	}
+	if !fi.IsDir() {
+		return
+	}
+	entries, err := ioutil.ReadDir(p)
+	if err != nil {
+		if err = w.fn(p, fi, err); err != nil && err != filepath.SkipDir {
+			w.fail(err)
+		}
+		return
+	}
+	for _, e := range entries {
+		child := filepath.Join(p, e.Name())
+		if !e.IsDir() {
+			w.visit(child, e)
+			continue
+		}
+		select {
+		case w.sem <- struct{}{}:
+			w.wg.Add(1)
+			go func(e os.FileInfo) {
+				defer w.wg.Done()
+				defer func() { <-w.sem }()
+				w.visit(child, e)
+			}(e)
+		default:
+			w.visit(child, e)
+		}
+	}
+}
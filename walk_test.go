@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// testTree makes a tree of directories and files to walk.
+func testTree(tb testing.TB) string {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(tb, err, nil)
+	for _, p := range []string{"a/1.jpg", "a/b/2.jpg", "a/b/c/3.jpg", "d/4.jpg", "d/e/5.jpg", "f/6.jpg", "7.jpg"} {
+		p = filepath.Join(dir, p)
+		equals(tb, os.MkdirAll(filepath.Dir(p), 0755), nil)
+		equals(tb, ioutil.WriteFile(p, nil, 0644), nil)
+	}
+	return dir
+}
+
+// visited returns a WalkFunc collecting the paths it's called with.
+func visited(paths *[]string, skip string) filepath.WalkFunc {
+	var mu sync.Mutex
+	return func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		*paths = append(*paths, p)
+		if fi.IsDir() && filepath.Base(p) == skip {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+}
+
+func TestWalkParallel(t *testing.T) {
+	dir := testTree(t)
+	defer os.RemoveAll(dir)
+
+	var want []string
+	equals(t, filepath.Walk(dir, visited(&want, "d")), nil)
+
+	// One walker is filepath.Walk, in order.
+	var got []string
+	equals(t, walkParallel(context.Background(), dir, 1, visited(&got, "d")), nil)
+	equals(t, got, want)
+
+	// More visit the same, in any order.
+	got = nil
+	equals(t, walkParallel(context.Background(), dir, 4, visited(&got, "d")), nil)
+	sort.Strings(got)
+	sort.Strings(want)
+	equals(t, got, want)
+}
+
+func TestWalkParallelStops(t *testing.T) {
+	dir := testTree(t)
+	defer os.RemoveAll(dir)
+
+	failed := errors.New("failed")
+	err := walkParallel(context.Background(), dir, 4, func(p string, fi os.FileInfo, err error) error {
+		if filepath.Base(p) == "3.jpg" {
+			return failed
+		}
+		return nil
+	})
+	equals(t, err, failed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var got []string
+	equals(t, walkParallel(ctx, dir, 4, visited(&got, "")), context.Canceled)
+	equals(t, len(got), 0)
+
+	err = walkParallel(context.Background(), filepath.Join(dir, "missing"), 4, visited(&got, ""))
+	equals(t, os.IsNotExist(err), true)
+}
@@ -0,0 +1,17 @@
+package main
+
+// statusWarned is the Status of a file that passes the rules but has
+// Warnings, like a missing Title or an implausible Date Created: it can be
+// ingested, but curators may want to look at it first.
+const statusWarned = "Accepted with warnings"
+
+// warningsColumn is the column of a file's warnings, last so as not to move
+// the columns import templates expect, and apart from exiftool's Extraction
+// Warnings.
+const warningsColumn = "Metadata Warnings"
+
+// accepted returns if a file with the Status can be ingested, with warnings
+// or without.
+func accepted(status string) bool {
+	return status == "Accepted" || status == statusWarned
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestAccepted(t *testing.T) {
+	for status, want := range map[string]bool{
+		"Accepted":         true,
+		statusWarned:       true,
+		"Incomplete":       false,
+		"Rejected":         false,
+		statusBelowQuality: false,
+	} {
+		equals(t, []interface{}{status, accepted(status)}, []interface{}{status, want})
+	}
+	equals(t, objectStatus(statusWarned), objectAccepted)
+
+	stats := &statistics{Accept: 2, Reject: 1}
+	recount(stats, "Accepted", statusWarned)
+	equals(t, []int32{stats.Accept, stats.Reject, stats.AcceptedWarnings}, []int32{2, 1, 1})
+	recount(stats, statusWarned, "Incomplete")
+	equals(t, []int32{stats.Accept, stats.Reject, stats.AcceptedWarnings}, []int32{1, 2, 0})
+	recount(stats, "Rejected", statusWarned)
+	equals(t, []int32{stats.Accept, stats.Reject, stats.AcceptedWarnings}, []int32{2, 1, 1})
+}
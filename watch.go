@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// fileState is what we compare between scans to see if a file changed.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// watcher debounces changes to files between scans: a changed file is only
+// ready once it has stayed the same for the quiet period, so a file saved
+// over and over is checked once, after the last save.
+type watcher struct {
+	quiet   time.Duration
+	seen    map[string]fileState
+	pending map[string]time.Time
+	started bool
+}
+
+// newWatcher returns a watcher with the quiet period.
+func newWatcher(quiet time.Duration) *watcher {
+	return &watcher{quiet: quiet, seen: map[string]fileState{}, pending: map[string]time.Time{}}
+}
+
+// scan takes the files found at now and returns those ready to check. On the
+// first scan that's every file, as for a normal run.
+func (w *watcher) scan(now time.Time, files map[string]fileState) []string {
+	var ready []string
+	for p, st := range files {
+		if old, ok := w.seen[p]; ok && old == st {
+			continue
+		}
+		w.seen[p] = st
+		if !w.started {
+			ready = append(ready, p)
+			continue
+		}
+		w.pending[p] = now
+	}
+	for p := range w.seen {
+		if _, ok := files[p]; !ok {
+			delete(w.seen, p)
+			delete(w.pending, p)
+		}
+	}
+	for p, changed := range w.pending {
+		if now.Sub(changed) >= w.quiet {
+			ready = append(ready, p)
+			delete(w.pending, p)
+		}
+	}
+	w.started = true
+	return ready
+}
+
+// statFiles returns the state of the relevant files under root.
+func statFiles(root string, types map[string]bool) (map[string]fileState, error) {
+	files := map[string]fileState{}
+	err := filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			// It may have been removed since being listed.
+			return nil
+		}
+		if !fi.IsDir() && types[mime.TypeByExtension(filepath.Ext(p))] {
+			files[p] = fileState{fi.Size(), fi.ModTime()}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// watchDir is makeWalker for -watch. It scans root every interval, sending
+// files to the files channel once they've been quiet long enough. It only
+// returns if a scan fails or ctx is done.
+func watchDir(ctx context.Context, root string, interval time.Duration, w *watcher, files chan string, stats *statistics, types map[string]bool) error {
+	for {
+		found, err := statFiles(root, types)
+		if err != nil {
+			return err
+		}
+		for _, p := range w.scan(time.Now(), found) {
+			atomic.AddInt32(&stats.Total, 1)
+			atomic.AddInt32(&stats.Relevant, 1)
+			if err := sendFile(ctx, files, p); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// dedupRows is a rowWriter passing on only rows that differ from the last
+// one for the same path, so a file saved without changing its metadata
+// isn't reported again.
+type dedupRows struct {
+	w    rowWriter
+	last map[string]string
+}
+
+// newDedupRows returns a dedupRows writing to w.
+func newDedupRows(w rowWriter) *dedupRows {
+	return &dedupRows{w: w, last: map[string]string{}}
+}
+
+// Write passes the row on if it's new.
+func (d *dedupRows) Write(row []string) error {
+	key := strings.Join(row, "\x00")
+	if d.last[row[0]] == key {
+		return nil
+	}
+	d.last[row[0]] = key
+	return d.w.Write(row)
+}
+
+// flushWriter is a csvWriter flushing every row, so -watch output is
+// written as it happens.
+type flushWriter struct {
+	csvWriter
+}
+
+// Write writes and flushes the row.
+func (f flushWriter) Write(row []string) error {
+	if err := f.csvWriter.Write(row); err != nil {
+		return err
+	}
+	f.Flush()
+	return f.Error()
+}
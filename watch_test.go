@@ -0,0 +1,40 @@
+package main
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWatcherScan(t *testing.T) {
+	start := time.Date(2015, 6, 1, 10, 0, 0, 0, time.UTC)
+	at := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+	w := newWatcher(5 * time.Second)
+
+	// Everything is checked on the first scan.
+	got := w.scan(at(0), map[string]fileState{"a.jpg": {1, at(0)}, "b.jpg": {1, at(0)}})
+	sort.Strings(got)
+	equals(t, got, []string{"a.jpg", "b.jpg"})
+
+	// a.jpg is saved three times in a row, then left alone.
+	equals(t, len(w.scan(at(2), map[string]fileState{"a.jpg": {2, at(1)}, "b.jpg": {1, at(0)}})), 0)
+	equals(t, len(w.scan(at(4), map[string]fileState{"a.jpg": {3, at(3)}, "b.jpg": {1, at(0)}})), 0)
+	equals(t, len(w.scan(at(6), map[string]fileState{"a.jpg": {3, at(5)}, "b.jpg": {1, at(0)}})), 0)
+	equals(t, len(w.scan(at(10), map[string]fileState{"a.jpg": {3, at(5)}, "b.jpg": {1, at(0)}})), 0)
+	equals(t, w.scan(at(11), map[string]fileState{"a.jpg": {3, at(5)}, "b.jpg": {1, at(0)}}), []string{"a.jpg"})
+	equals(t, len(w.scan(at(20), map[string]fileState{"a.jpg": {3, at(5)}, "b.jpg": {1, at(0)}})), 0)
+
+	// A new file that's removed before it's quiet is forgotten.
+	equals(t, len(w.scan(at(21), map[string]fileState{"a.jpg": {3, at(5)}, "c.jpg": {1, at(21)}})), 0)
+	equals(t, len(w.scan(at(30), map[string]fileState{"a.jpg": {3, at(5)}})), 0)
+}
+
+func TestDedupRows(t *testing.T) {
+	var rec rowRecorder
+	d := newDedupRows(&rec)
+	d.Write(testRow("a.jpg", "Incomplete", "Minimum metadata not provided"))
+	d.Write(testRow("a.jpg", "Incomplete", "Minimum metadata not provided"))
+	d.Write(testRow("b.jpg", "Incomplete", "Minimum metadata not provided"))
+	d.Write(testRow("a.jpg", "Accepted", ""))
+	equals(t, len(rec.rows), 3)
+}
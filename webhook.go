@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The X-Chkmd-Event header says what a webhook POST is.
+const (
+	// eventFile is one file's webhookPayload.
+	eventFile = "file"
+	// eventBatch is a list of files' webhookPayloads, with -webhook-batch.
+	eventBatch = "batch"
+	// eventSummary is the runSummary, once the run is done.
+	eventSummary = "summary"
+)
+
+// webhook posts each file's result, with all of its metadata, to a URL as
+// it's checked, so ingest can start without extracting it again, and the
+// summary when the run is done, so orchestration can carry on. With batch
+// above 1 the results are posted that many at a time instead.
+type webhook struct {
+	url    string
+	client *http.Client
+	batch  int
+
+	sync.Mutex
+	pending []webhookPayload
+}
+
+// newWebhook returns a webhook posting to url, batch results at a time.
+func newWebhook(url string, batch int) *webhook {
+	return &webhook{url: url, client: &http.Client{Timeout: 30 * time.Second}, batch: batch}
+}
+
+// webhookPayload is the JSON posted for each file. Fields is the row keyed
+// by column, Checks whether each field usable in rules is present, and
+// Metadata every tag exiftool extracted by group.
+type webhookPayload struct {
+	Path     string                       `json:"path"`
+	Status   string                       `json:"status"`
+	Reason   string                       `json:"reason"`
+	Fields   map[string]string            `json:"fields"`
+	Checks   map[string]bool              `json:"checks"`
+	Metadata map[string]map[string]string `json:"metadata"`
+}
+
+// newPayload returns the webhookPayload for a row and the exif it was made
+// from, which hooks are given too.
+func newPayload(row []string, e exif) webhookPayload {
+	pl := webhookPayload{
+		Path:   row[column("Path")],
+		Status: row[column("Status")],
+		Reason: row[column("Reason")],
+		Fields: map[string]string{},
+		Checks: map[string]bool{},
+		Metadata: map[string]map[string]string{
+			"File":          e.Data,
+			"EXIF":          e.Exif,
+			"IPTC":          e.IPTC,
+			"XMP":           e.XMP,
+			"ID3":           e.ID3,
+			"RIFF":          e.RIFF,
+			"PDF":           e.PDF,
+			"Model":         e.Model,
+			"metadata.yaml": e.Folder,
+			"filename":      e.Filename,
+		},
+	}
+	for i, h := range csvHeader {
+		pl.Fields[h] = row[i]
+	}
+	for name, has := range fieldChecks {
+		pl.Checks[name] = has(e)
+	}
+	return pl
+}
+
+// send posts the payload for the row, or once there's a batch of them, the
+// batch. A failure is returned but doesn't stop the file being reported.
+func (h *webhook) send(row []string, e exif) error {
+	if h.batch <= 1 {
+		return h.post(eventFile, newPayload(row, e))
+	}
+	h.Lock()
+	h.pending = append(h.pending, newPayload(row, e))
+	var batch []webhookPayload
+	if len(h.pending) >= h.batch {
+		batch, h.pending = h.pending, nil
+	}
+	h.Unlock()
+	if batch == nil {
+		return nil
+	}
+	return h.post(eventBatch, batch)
+}
+
+// finish posts what's left of the last batch, then the summary.
+func (h *webhook) finish(s runSummary) error {
+	h.Lock()
+	batch := h.pending
+	h.pending = nil
+	h.Unlock()
+	if len(batch) > 0 {
+		if err := h.post(eventBatch, batch); err != nil {
+			return err
+		}
+	}
+	return h.post(eventSummary, s)
+}
+
+// post posts v as JSON, saying it's an event.
+func (h *webhook) post(event string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", h.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Chkmd-Event", event)
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s: %s", h.url, resp.Status)
+	}
+	return nil
+}
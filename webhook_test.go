@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookSend(t *testing.T) {
+	var got webhookPayload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		equals(t, r.Header.Get("Content-Type"), "application/json")
+		equals(t, json.NewDecoder(r.Body).Decode(&got), nil)
+	}))
+	defer ts.Close()
+
+	e := newExif()
+	e.IPTC["ObjectName"] = "Launch"
+	e.Data["FileName"] = "KSC-1.jpg"
+	row := testRow("/media/a/KSC-1.jpg", "Incomplete", "Minimum metadata not provided")
+	row[column("Title")] = "Launch"
+	equals(t, newWebhook(ts.URL, 0).send(row, e), nil)
+
+	equals(t, got.Path, "/media/a/KSC-1.jpg")
+	equals(t, got.Status, "Incomplete")
+	equals(t, got.Fields["Title"], "Launch")
+	equals(t, got.Checks["Title"], true)
+	equals(t, got.Checks["DateCreated"], false)
+	equals(t, got.Metadata["IPTC"], map[string]string{"ObjectName": "Launch"})
+	equals(t, got.Metadata["File"], map[string]string{"FileName": "KSC-1.jpg"})
+}
+
+func TestWebhookError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	err := newWebhook(ts.URL, 0).send(testRow("a.jpg", "Accepted", ""), newExif())
+	equals(t, err.Error(), "webhook "+ts.URL+": 503 Service Unavailable")
+}
+
+func TestWebhookBatch(t *testing.T) {
+	var events []string
+	var batches [][]webhookPayload
+	var summary runSummary
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := r.Header.Get("X-Chkmd-Event")
+		events = append(events, event)
+		if event == eventSummary {
+			equals(t, json.NewDecoder(r.Body).Decode(&summary), nil)
+			return
+		}
+		var batch []webhookPayload
+		equals(t, json.NewDecoder(r.Body).Decode(&batch), nil)
+		batches = append(batches, batch)
+	}))
+	defer ts.Close()
+
+	h := newWebhook(ts.URL, 2)
+	for _, p := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		equals(t, h.send(testRow(p, "Accepted", ""), newExif()), nil)
+	}
+	equals(t, len(batches), 1)
+	equals(t, h.finish(runSummary{Total: 3, Accepted: 3}), nil)
+
+	equals(t, events, []string{eventBatch, eventBatch, eventSummary})
+	equals(t, len(batches[0]), 2)
+	equals(t, batches[1][0].Path, "c.jpg")
+	equals(t, summary.Total, int32(3))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// workingSetEntry is a file's line in a -working-set manifest. Accessed and
+// Offline are hints, where the platform has them, for which files an HSM may
+// have to recall from tape.
+type workingSetEntry struct {
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	Modified string `json:"modified"`
+	Accessed string `json:"accessed,omitempty"`
+	Offline  bool   `json:"offline,omitempty"`
+}
+
+// newWorkingSetEntry returns the entry for the file at p.
+func newWorkingSetEntry(p string, fi os.FileInfo) workingSetEntry {
+	we := workingSetEntry{
+		Path:     p,
+		Size:     fi.Size(),
+		Modified: fi.ModTime().UTC().Format(time.RFC3339),
+	}
+	accessed, offline := accessHints(fi)
+	if !accessed.IsZero() {
+		we.Accessed = accessed.UTC().Format(time.RFC3339)
+	}
+	we.Offline = offline
+	return we
+}
+
+// workingSet is what a -working-set manifest lists.
+type workingSet struct {
+	Files   int
+	Size    int64
+	Offline int
+}
+
+func (ws workingSet) String() string {
+	return fmt.Sprintf("%d files, %s, %d offline", ws.Files, byteSize(ws.Size), ws.Offline)
+}
+
+// writeWorkingSet writes a manifest of the files, as JSON lines, to the file
+// at p. It only stats them, so nothing's read that would recall them from
+// tape, and the storage team can stage them before the real run.
+func writeWorkingSet(p string, files <-chan string) (workingSet, error) {
+	var ws workingSet
+	f, err := os.Create(p)
+	if err != nil {
+		return ws, err
+	}
+	enc := json.NewEncoder(f)
+	for file := range files {
+		if err != nil {
+			// Keep draining so the walk can finish.
+			continue
+		}
+		fi, serr := os.Stat(file)
+		if serr != nil {
+			err = serr
+			continue
+		}
+		we := newWorkingSetEntry(file, fi)
+		ws.Files++
+		ws.Size += we.Size
+		if we.Offline {
+			ws.Offline++
+		}
+		err = enc.Encode(we)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return ws, err
+}
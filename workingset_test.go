@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteWorkingSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	a := filepath.Join(dir, "a.jpg")
+	equals(t, ioutil.WriteFile(a, []byte("0123456789"), 0644), nil)
+	b := filepath.Join(dir, "b.jpg")
+	equals(t, ioutil.WriteFile(b, []byte("01234"), 0644), nil)
+
+	files := make(chan string, 2)
+	files <- a
+	files <- b
+	close(files)
+	p := filepath.Join(dir, "working-set.jsonl")
+	ws, err := writeWorkingSet(p, files)
+	equals(t, err, nil)
+	equals(t, ws.Files, 2)
+	equals(t, ws.Size, int64(15))
+	equals(t, ws.String(), "2 files, 15B, 0 offline")
+
+	out, err := ioutil.ReadFile(p)
+	equals(t, err, nil)
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	equals(t, len(lines), 2)
+	var we workingSetEntry
+	equals(t, json.Unmarshal([]byte(lines[0]), &we), nil)
+	equals(t, we.Path, a)
+	equals(t, we.Size, int64(10))
+	equals(t, we.Offline, false)
+	fi, err := os.Stat(a)
+	equals(t, err, nil)
+	equals(t, we, newWorkingSetEntry(a, fi))
+
+	// A file that's gone is an error, once the files are drained.
+	files = make(chan string, 2)
+	files <- filepath.Join(dir, "missing.jpg")
+	files <- a
+	close(files)
+	_, err = writeWorkingSet(p, files)
+	equals(t, os.IsNotExist(err), true)
+	equals(t, len(files), 0)
+}
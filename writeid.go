@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// idWriter writes each Accepted file's NASA ID to a tag, for -write-id, so
+// the ID chkmd settled on, even when it fell back to the file name, is kept
+// in the file for the tools that handle it next.
+type idWriter struct {
+	tag string
+}
+
+// newIDWriter returns an idWriter for tag, which is an exiftool group and
+// tag like XMP-dc:Identifier. The group must be one we read back, EXIF, IPTC
+// or XMP, so we can tell whether the file already has the ID.
+func newIDWriter(tag string) (*idWriter, error) {
+	i := strings.Index(tag, ":")
+	if i <= 0 || i == len(tag)-1 {
+		return nil, fmt.Errorf("-write-id %q isn't a group:tag like XMP-dc:Identifier", tag)
+	}
+	w := &idWriter{tag: tag}
+	if w.tags(newExif()) == nil {
+		return nil, fmt.Errorf("-write-id %q isn't an EXIF, IPTC or XMP tag", tag)
+	}
+	return w, nil
+}
+
+// tags returns the exif map the tag is read back into.
+func (w *idWriter) tags(e exif) map[string]string {
+	group := strings.ToUpper(w.tag[:strings.Index(w.tag, ":")])
+	switch {
+	case group == "EXIF":
+		return e.Exif
+	case group == "IPTC":
+		return e.IPTC
+	case group == "XMP" || strings.HasPrefix(group, "XMP-"):
+		return e.XMP
+	}
+	return nil
+}
+
+// current returns the file's value for the tag.
+func (w *idWriter) current(e exif) string {
+	return w.tags(e)[w.tag[strings.Index(w.tag, ":")+1:]]
+}
+
+// write writes e's NASA ID to the tag in the file at p, unless it's already
+// there, returning whether it did. S3 objects, URLs and files in archives
+// are only copied out to check them, so they're left alone. Like -fix, exiftool keeps the
+// unmodified file as p_original.
+func (w *idWriter) write(p string, e exif) (bool, error) {
+	id := e.NasaID()
+	if isURL(p) || isObject(p) || isArchiveMember(p) || id == "" || w.current(e) == id {
+		return false, nil
+	}
+	out, err := detach(exec.Command("exiftool", "-"+w.tag+"="+id, exiftoolPath(p))).CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("exiftool: %s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return true, nil
+}
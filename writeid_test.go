@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestNewIDWriter(t *testing.T) {
+	values := []struct {
+		tag string
+		err string
+	}{
+		{"XMP-dc:Identifier", ""},
+		{"XMP:Identifier", ""},
+		{"IPTC:OriginalTransmissionReference", ""},
+		{"EXIF:ImageUniqueID", ""},
+		{"Identifier", `-write-id "Identifier" isn't a group:tag like XMP-dc:Identifier`},
+		{"XMP-dc:", `-write-id "XMP-dc:" isn't a group:tag like XMP-dc:Identifier`},
+		{"QuickTime:Title", `-write-id "QuickTime:Title" isn't an EXIF, IPTC or XMP tag`},
+	}
+	for _, v := range values {
+		_, err := newIDWriter(v.tag)
+		if v.err == "" {
+			equals(t, err, nil)
+		} else {
+			equals(t, err.Error(), v.err)
+		}
+	}
+}
+
+func TestIDWriterSkips(t *testing.T) {
+	w, err := newIDWriter("XMP-dc:Identifier")
+	equals(t, err, nil)
+
+	// The file already has its NASA ID in the tag.
+	e := newExif()
+	e.Data["FileName"] = "jsc2015e012345.jpg"
+	e.XMP["Identifier"] = "jsc2015e012345"
+	equals(t, w.current(e), "jsc2015e012345")
+	wrote, err := w.write("jsc2015e012345.jpg", e)
+	equals(t, err, nil)
+	equals(t, wrote, false)
+
+	// S3 objects and URLs aren't written to.
+	e = newExif()
+	e.Data["FileName"] = "jsc2015e012345.jpg"
+	for _, p := range []string{"s3://bucket/jsc2015e012345.jpg", "https://example.com/jsc2015e012345.jpg"} {
+		wrote, err = w.write(p, e)
+		equals(t, err, nil)
+		equals(t, wrote, false)
+	}
+}
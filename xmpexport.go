@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// xmpExporter writes an XMP sidecar of each checked file's resolved
+// metadata into dir, for -export-xmp, so tools downstream have one
+// consistent representation however the file's own metadata is written.
+// The sidecars are laid out like the files under their root, named for the
+// file with its extension replaced by .xmp, as sidecars usually are.
+type xmpExporter struct {
+	dir   string
+	roots dirList
+}
+
+// newXMPExporter returns an xmpExporter for the files under roots, making
+// dir.
+func newXMPExporter(dir string, roots ...string) (*xmpExporter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &xmpExporter{dir: dir, roots: roots}, nil
+}
+
+// path returns where the sidecar of the file at p goes.
+func (x *xmpExporter) path(p string) string {
+	rel, err := filepath.Rel(x.roots.rootOf(p), p)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(p)
+	}
+	return sidecarPath(filepath.Join(x.dir, rel))
+}
+
+// write writes the sidecar of the file at p.
+func (x *xmpExporter) write(p string, e exif) error {
+	out := x.path(p)
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(out, resolvedXMP(e), 0644)
+}
+
+// resolvedXMP returns an XMP packet of e's Title, Description, Date Created,
+// Keywords and Photographer, in the Dublin Core and Photoshop namespaces.
+// Fields e doesn't have are left out.
+func resolvedXMP(e exif) []byte {
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString(" <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString("  <rdf:Description rdf:about=\"\"\n")
+	b.WriteString("    xmlns:dc=\"http://purl.org/dc/elements/1.1/\"\n")
+	b.WriteString("    xmlns:photoshop=\"http://ns.adobe.com/photoshop/1.0/\">\n")
+	writeXMPList(&b, "dc:title", "Alt", e.Title())
+	writeXMPList(&b, "dc:description", "Alt", e.Description())
+	writeXMPList(&b, "dc:creator", "Seq", e.Photographer())
+	writeXMPList(&b, "dc:subject", "Bag", e.KeywordList()...)
+	if d := xmpDate(e); d != "" {
+		b.WriteString("   <photoshop:DateCreated>" + d + "</photoshop:DateCreated>\n")
+	}
+	b.WriteString("  </rdf:Description>\n")
+	b.WriteString(" </rdf:RDF>\n")
+	b.WriteString("</x:xmpmeta>\n")
+	b.WriteString("<?xpacket end=\"w\"?>\n")
+	return b.Bytes()
+}
+
+// writeXMPList writes the property as an rdf:Alt, Seq or Bag of the
+// non-empty items, the Alt's being the x-default language. It writes
+// nothing if there are none.
+func writeXMPList(b *bytes.Buffer, property, kind string, items ...string) {
+	var lis []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		var v bytes.Buffer
+		xml.EscapeText(&v, []byte(item))
+		if kind == "Alt" {
+			lis = append(lis, "<rdf:li xml:lang=\"x-default\">"+v.String()+"</rdf:li>")
+		} else {
+			lis = append(lis, "<rdf:li>"+v.String()+"</rdf:li>")
+		}
+	}
+	if len(lis) == 0 {
+		return
+	}
+	b.WriteString("   <" + property + ">\n    <rdf:" + kind + ">\n")
+	for _, li := range lis {
+		b.WriteString("     " + li + "\n")
+	}
+	b.WriteString("    </rdf:" + kind + ">\n   </" + property + ">\n")
+}
+
+// xmpDate returns the Date Created as XMP writes dates, an ISO 8601 date,
+// with the time and zone if it has them, or "" if it doesn't parse.
+func xmpDate(e exif) string {
+	t, err := e.DateCreated()
+	if err != nil {
+		return ""
+	}
+	d := e.dateCreatedText()
+	switch {
+	case len(d) <= len(exifDateOnly):
+		return t.Format("2006-01-02")
+	case strings.ContainsAny(d[len(exifDateOnly):], "+-Z"):
+		return t.Format("2006-01-02T15:04:05Z07:00")
+	}
+	return t.Format("2006-01-02T15:04:05")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestXMPExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chkmd")
+	equals(t, err, nil)
+	defer os.RemoveAll(dir)
+	x, err := newXMPExporter(filepath.Join(dir, "xmp"), "/media")
+	equals(t, err, nil)
+	equals(t, x.path("/media/a/KSC-1.jpg"), filepath.Join(dir, "xmp", "a", "KSC-1.xmp"))
+	equals(t, x.path("/elsewhere/KSC-2.tif"), filepath.Join(dir, "xmp", "KSC-2.xmp"))
+
+	e := newExif()
+	e.IPTC["ObjectName"] = "Launch & Landing"
+	e.IPTC["Caption-Abstract"] = "The <first> launch"
+	e.IPTC["By-line"] = "Bill Ingalls"
+	e.IPTC["Keywords"] = "shuttle, launch"
+	e.Lists["IPTC:Keywords"] = []string{"shuttle", "launch"}
+	e.Exif["DateTimeOriginal"] = "2014:06:07 10:11:12"
+	equals(t, x.write("/media/a/KSC-1.jpg", e), nil)
+	b, err := ioutil.ReadFile(x.path("/media/a/KSC-1.jpg"))
+	equals(t, err, nil)
+	var doc struct {
+		Title       []string `xml:"RDF>Description>title>Alt>li"`
+		Description []string `xml:"RDF>Description>description>Alt>li"`
+		Creator     []string `xml:"RDF>Description>creator>Seq>li"`
+		Subject     []string `xml:"RDF>Description>subject>Bag>li"`
+		DateCreated string   `xml:"RDF>Description>DateCreated"`
+	}
+	equals(t, xml.Unmarshal(b, &doc), nil)
+	equals(t, doc.Title, []string{"Launch & Landing"})
+	equals(t, doc.Description, []string{"The <first> launch"})
+	equals(t, doc.Creator, []string{"Bill Ingalls"})
+	equals(t, doc.Subject, []string{"shuttle", "launch"})
+	equals(t, doc.DateCreated, "2014-06-07T10:11:12")
+	equals(t, strings.Contains(string(b), "dc:rights"), false)
+}
+
+func TestXMPDate(t *testing.T) {
+	for d, want := range map[string]string{
+		"2014:06:07":                "2014-06-07",
+		"2014:06:07 10:11:12":       "2014-06-07T10:11:12",
+		"2014:06:07 10:11:12-04:00": "2014-06-07T10:11:12-04:00",
+		"yesterday":                 "",
+	} {
+		e := newExif()
+		e.Exif["DateTimeOriginal"] = d
+		equals(t, xmpDate(e), want)
+	}
+}